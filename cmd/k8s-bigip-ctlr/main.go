@@ -119,19 +119,26 @@ var (
 	customResourceMode *bool
 	controllerMode     *string
 	defaultRouteDomain *int
-
-	pythonBaseDir    *string
-	logLevel         *string
-	ccclLogLevel     *string
-	logFile          *string
-	verifyInterval   *int
-	nodePollInterval *int
-	syncInterval     *int
-	printVersion     *bool
-	httpAddress      *string
-	dgPath           string
-	disableTeems     *bool
-	enableIPV6       *bool
+	tlsProfileFailOpen *bool
+	strictCertHost     *bool
+
+	pythonBaseDir          *string
+	logLevel               *string
+	ccclLogLevel           *string
+	logFile                *string
+	verifyInterval         *int
+	nodePollInterval       *int
+	poolMemberDrainTimeout *int
+	routeGroupDeleteGrace  *int
+	maxResourceRetries     *int
+	clusterName            *string
+	poolMetadataLabels     *[]string
+	syncInterval           *int
+	printVersion           *bool
+	httpAddress            *string
+	dgPath                 string
+	disableTeems           *bool
+	enableIPV6             *bool
 
 	namespaces             *[]string
 	useNodeInternal        *bool
@@ -169,6 +176,7 @@ var (
 	trustedCertsCfgmap     *string
 	agent                  *string
 	logAS3Response         *bool
+	dryRun                 *bool
 	shareNodes             *bool
 	overriderAS3CfgmapName *string
 	filterTenants          *bool
@@ -179,6 +187,7 @@ var (
 
 	routeVserverAddr *string
 	routeLabel       *string
+	routerName       *string
 	routeHttpVs      *string
 	routeHttpsVs     *string
 	clientSSL        *string
@@ -235,6 +244,17 @@ func _init() {
 		"Optional, interval (in seconds) at which to verify the BIG-IP configuration.")
 	nodePollInterval = globalFlags.Int("node-poll-interval", 30,
 		"Optional, interval (in seconds) at which to poll for cluster nodes.")
+	poolMemberDrainTimeout = globalFlags.Int("pool-member-drain-timeout", 0,
+		"Optional, duration (in seconds) for which pool members of a deleted service are disabled before they are removed.")
+	routeGroupDeleteGrace = globalFlags.Int("route-group-delete-grace-period", 0,
+		"Optional, duration (in seconds) for which a route group has to be without routes before its virtuals are deleted.")
+	maxResourceRetries = globalFlags.Int("max-resource-retries", controller.DefaultMaxResourceRetries,
+		"Optional, number of retries of a resource that fails to be processed before it is dropped. "+
+			"The retries back off exponentially, the default retries a resource for about an hour.")
+	clusterName = globalFlags.String("cluster-name", "",
+		"Optional, name of the cluster used to tag the pool members in multi-cluster deployments.")
+	poolMetadataLabels = globalFlags.StringArray("pool-metadata-labels", []string{},
+		"Optional, namespace and service label(s) added as metadata to the pools of the service.")
 	syncInterval = globalFlags.Int("periodic-sync-interval", 30,
 		"Optional, interval (in seconds) at which to queue resources.")
 	printVersion = globalFlags.Bool("version", false,
@@ -252,6 +272,10 @@ func _init() {
 		"Optional, to put the controller to process desired resources.")
 	defaultRouteDomain = globalFlags.Int("default-route-domain", 0,
 		"Optional, CIS uses this value as default Route Domain in BIG-IP ")
+	tlsProfileFailOpen = globalFlags.Bool("tls-profile-fail-open", false,
+		"Optional, serve the VirtualServers whose TLSProfile can't be resolved without TLS instead of not creating their virtuals.")
+	strictCertHost = globalFlags.Bool("strict-certificate-host", false,
+		"Optional, reject the certificates of VirtualServers and Routes which don't cover their host instead of logging a warning.")
 
 	globalFlags.Usage = func() {
 		fmt.Fprintf(os.Stderr, "  Global:\n%s\n", globalFlags.FlagUsagesWrapped(width))
@@ -265,7 +289,8 @@ func _init() {
 	bigIPPassword = bigIPFlags.String("bigip-password", "",
 		"Required, password for the Big-IP user account.")
 	bigIPPartitions = bigIPFlags.StringArray("bigip-partition", []string{},
-		"Required, partition(s) for the Big-IP kubernetes objects.")
+		"Required, partition(s) for the Big-IP kubernetes objects. The virtuals are placed in the first partition, "+
+			"the VirtualServer pools can be placed in any of the partitions.")
 	credsDir = bigIPFlags.String("credentials-directory", "",
 		"Optional, directory that contains the BIG-IP username, password, and/or "+
 			"url files. To be used instead of username, password, and/or url arguments.")
@@ -279,6 +304,8 @@ func _init() {
 		"Optional, time (in seconds) that CIS waits to post the available AS3 declaration.")
 	logAS3Response = bigIPFlags.Bool("log-as3-response", false,
 		"Optional, when set to true, add the body of AS3 API response in Controller logs.")
+	dryRun = bigIPFlags.Bool("dry-run", false,
+		"Optional, when set to true, CIS logs the changes to the BIG-IP configuration instead of posting them.")
 	shareNodes = bigIPFlags.Bool("share-nodes", false,
 		"Optional, when set to true, node will be shared among partition.")
 	enableTLS = bigIPFlags.String("tls-version", "1.2",
@@ -383,6 +410,8 @@ func _init() {
 		"Optional, bind address for virtual server for Route objects.")
 	routeLabel = osRouteFlags.String("route-label", "",
 		"Optional, label for which Route objects to watch.")
+	routerName = osRouteFlags.String("router-name", controller.F5RouterName,
+		"Optional, the router name of the Route admit status, distinct for each CIS instance watching the same Routes")
 	routeHttpVs = osRouteFlags.String("route-http-vserver", "ose-vserver",
 		"Optional, the name to be used for the OpenShift Route http vserver")
 	routeHttpsVs = osRouteFlags.String("route-https-vserver", "https-ose-vserver",
@@ -832,23 +861,33 @@ func initController(
 
 	ctlr := controller.NewController(
 		controller.Params{
-			Config:             config,
-			Namespaces:         *namespaces,
-			NamespaceLabel:     *namespaceLabel,
-			Partition:          (*bigIPPartitions)[0],
-			Agent:              agent,
-			PoolMemberType:     *poolMemberType,
-			VXLANName:          vxlanName,
-			VXLANMode:          vxlanMode,
-			UseNodeInternal:    *useNodeInternal,
-			NodePollInterval:   *nodePollInterval,
-			NodeLabelSelector:  *nodeLabelSelector,
-			IPAM:               *ipam,
-			ShareNodes:         *shareNodes,
-			DefaultRouteDomain: *defaultRouteDomain,
-			Mode:               controller.ControllerMode(*controllerMode),
-			RouteSpecConfigmap: *routeSpecConfigmap,
-			RouteLabel:         *routeLabel,
+			Config:                 config,
+			Namespaces:             *namespaces,
+			NamespaceLabel:         *namespaceLabel,
+			Partition:              (*bigIPPartitions)[0],
+			Agent:                  agent,
+			PoolMemberType:         *poolMemberType,
+			VXLANName:              vxlanName,
+			VXLANMode:              vxlanMode,
+			UseNodeInternal:        *useNodeInternal,
+			NodePollInterval:       *nodePollInterval,
+			NodeLabelSelector:      *nodeLabelSelector,
+			IPAM:                   *ipam,
+			ShareNodes:             *shareNodes,
+			DefaultRouteDomain:     *defaultRouteDomain,
+			Mode:                   controller.ControllerMode(*controllerMode),
+			RouteSpecConfigmap:     *routeSpecConfigmap,
+			RouteLabel:             *routeLabel,
+			RouterName:             *routerName,
+			PoolMemberDrainTimeout: *poolMemberDrainTimeout,
+			RouteGroupDeleteGrace:  *routeGroupDeleteGrace,
+			ClusterName:            *clusterName,
+			PoolMetadataLabels:     *poolMetadataLabels,
+			MaxResourceRetries:     *maxResourceRetries,
+			PoolPartitions:         (*bigIPPartitions)[1:],
+			DryRun:                 *dryRun,
+			TLSProfileFailOpen:     *tlsProfileFailOpen,
+			StrictCertificateHost:  *strictCertHost,
 		},
 	)
 
@@ -991,6 +1030,8 @@ func main() {
 	if *customResourceMode || *controllerMode != "" {
 		getGTMCredentials()
 		ctlr := initController(config)
+		// Expose LTM configuration of CIS along with the "/health" and "/metrics" endpoints of the agent
+		http.Handle("/ltmconfig", ctlr.LTMConfigHandler())
 		ctlr.TeemData = td
 		if !(*disableTeems) {
 			key, err := ctlr.Agent.GetBigipRegKey()
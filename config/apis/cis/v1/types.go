@@ -1,6 +1,8 @@
 package v1
 
 import (
+	"encoding/json"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -22,6 +24,8 @@ type VirtualServer struct {
 type VirtualServerStatus struct {
 	VSAddress string `json:"vsAddress,omitempty"`
 	StatusOk  string `json:"status,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Message   string `json:"message,omitempty"`
 }
 
 // VirtualServerSpec is the spec of the VirtualServer resource.
@@ -35,20 +39,38 @@ type VirtualServerSpec struct {
 	VirtualServerHTTPSPort int32            `json:"virtualServerHTTPSPort,omitempty"`
 	Pools                  []Pool           `json:"pools,omitempty"`
 	TLSProfileName         string           `json:"tlsProfileName,omitempty"`
+	TLSProfileNames        []string         `json:"tlsProfileNames,omitempty"`
 	HTTPTraffic            string           `json:"httpTraffic,omitempty"`
+	HTTPRedirectCode       int32            `json:"httpRedirectCode,omitempty"`
 	SNAT                   string           `json:"snat,omitempty"`
 	WAF                    string           `json:"waf,omitempty"`
+	ForwardingPolicyOrder  string           `json:"forwardingPolicyOrder,omitempty"`
 	RewriteAppRoot         string           `json:"rewriteAppRoot,omitempty"`
 	AllowVLANs             []string         `json:"allowVlans,omitempty"`
+	RejectVLANs            []string         `json:"rejectVlans,omitempty"`
+	VLANsEnabled           *bool            `json:"vlansEnabled,omitempty"`
 	IRules                 []string         `json:"iRules,omitempty"`
 	ServiceIPAddress       []ServiceAddress `json:"serviceAddress,omitempty"`
 	PolicyName             string           `json:"policyName,omitempty"`
 	PersistenceProfile     string           `json:"persistenceProfile,omitempty"`
+	Persistence            *Persistence     `json:"persistence,omitempty"`
 	ProfileMultiplex       string           `json:"profileMultiplex,omitempty"`
 	DOS                    string           `json:"dos,omitempty"`
 	BotDefense             string           `json:"botDefense,omitempty"`
 	Profiles               ProfileSpec      `json:"profiles,omitempty"`
 	AllowSourceRange       []string         `json:"allowSourceRange,omitempty"`
+	DenySourceRange        []string         `json:"denySourceRange,omitempty"`
+	InsertXForwardedFor    bool             `json:"insertXForwardedFor,omitempty"`
+	Enabled                *bool            `json:"enabled,omitempty"`
+	RouteDomain            *int32           `json:"routeDomain,omitempty"`
+	PoolMemberType         string           `json:"poolMemberType,omitempty"`
+	SorryPage              *SorryPage       `json:"sorryPage,omitempty"`
+	ClonePools             *ClonePools      `json:"clonePools,omitempty"`
+	// ConnectionRateLimit is the maximum number of new connections per second of the virtual
+	ConnectionRateLimit int32 `json:"connectionRateLimit,omitempty"`
+	// SkipForwardingPolicy omits the generated forwarding policy, the requests are routed by
+	// the iRules and the pool with the path / serves as the default pool of the virtual
+	SkipForwardingPolicy bool `json:"skipForwardingPolicy,omitempty"`
 }
 
 // ServiceAddress Service IP address definition (BIG-IP virtual-address).
@@ -58,32 +80,106 @@ type ServiceAddress struct {
 	RouteAdvertisement string `json:"routeAdvertisement,omitempty"`
 	TrafficGroup       string `json:"trafficGroup,omitempty,omitempty"`
 	SpanningEnabled    bool   `json:"spanningEnabled,omitempty"`
+	// Mask is the netmask or prefix length of a network virtual address
+	Mask string `json:"mask,omitempty"`
+}
+
+// ClonePools are the pools the virtual replicates the client side (ingress)
+// or the server side (egress) traffic to, e.g. for an IDS
+type ClonePools struct {
+	Ingress *ClonePool `json:"ingress,omitempty"`
+	Egress  *ClonePool `json:"egress,omitempty"`
+}
+
+// ClonePool refers to an existing BIG-IP pool by its path with reference bigip,
+// otherwise to a pool of the resource by its name
+type ClonePool struct {
+	Name      string `json:"name"`
+	Reference string `json:"reference,omitempty"`
+}
+
+// SorryPage is the fixed response served by the VirtualServer instead of
+// forwarding the requests to its pools, e.g. during a maintenance
+type SorryPage struct {
+	StatusCode  int32  `json:"statusCode,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        string `json:"body"`
 }
 
 // Pool defines a pool object in BIG-IP.
 type Pool struct {
-	Name             string    `json:"name,omitempty"`
-	Path             string    `json:"path,omitempty"`
-	Service          string    `json:"service"`
-	ServicePort      int32     `json:"servicePort"`
-	NodeMemberLabel  string    `json:"nodeMemberLabel,omitempty"`
-	Monitor          Monitor   `json:"monitor"`
-	Monitors         []Monitor `json:"monitors"`
-	Rewrite          string    `json:"rewrite,omitempty"`
-	Balance          string    `json:"loadBalancingMethod,omitempty"`
-	ServiceNamespace string    `json:"serviceNamespace,omitempty"`
+	Name             string        `json:"name,omitempty"`
+	Path             string        `json:"path,omitempty"`
+	Service          string        `json:"service"`
+	ServicePort      int32         `json:"servicePort"`
+	NodeMemberLabel  string        `json:"nodeMemberLabel,omitempty"`
+	Monitor          Monitor       `json:"monitor"`
+	Monitors         []Monitor     `json:"monitors"`
+	Rewrite          string        `json:"rewrite,omitempty"`
+	StripPathPrefix  string        `json:"stripPathPrefix,omitempty"`
+	Partition        string        `json:"partition,omitempty"`
+	Balance          string        `json:"loadBalancingMethod,omitempty"`
+	ServiceNamespace string        `json:"serviceNamespace,omitempty"`
+	Headers          []HeaderMatch `json:"headers,omitempty"`
+	// ServiceDownAction is the connection handling when a member is non-responsive: none, reset, drop or reselect
+	ServiceDownAction string `json:"serviceDownAction,omitempty"`
+	// ReselectTries is the maximum number of attempts to find a responsive member for a connection
+	ReselectTries int32 `json:"reselectTries,omitempty"`
+	// SNAT overrides the SNAT of the virtual for the connections to the pool,
+	// none preserves the client IP
+	SNAT string `json:"snat,omitempty"`
+	// ServerName is the server name presented in SNI to the backends of the pool with reencrypt termination
+	ServerName string `json:"serverName,omitempty"`
+	// HashKey is the key of the consistent hash persisting the connections to the pool
+	// when the loadBalancingMethod is carp or hash, defaults to the source IP
+	HashKey *HashKey `json:"hashKey,omitempty"`
+	// MinimumMonitors is the number of monitors which must be up for a member to be available
+	MinimumMonitors int `json:"minimumMonitors,omitempty"`
+}
+
+// HashKey defines the source of the key hashed to select the pool member
+type HashKey struct {
+	// Source is one of source-ip, header or cookie
+	Source string `json:"source"`
+	// Name is the name of the header or cookie
+	Name string `json:"name,omitempty"`
+	// Timeout is the lifetime of the persistence record in seconds, defaults to 180
+	Timeout int32 `json:"timeout,omitempty"`
+}
+
+// HeaderMatch defines a request header to be matched for routing to the pool
+type HeaderMatch struct {
+	Name string `json:"name"`
+	// Operator is either equals or contains, defaults to equals
+	Operator string   `json:"operator,omitempty"`
+	Values   []string `json:"values"`
 }
 
 // Monitor defines a monitor object in BIG-IP.
 type Monitor struct {
-	Type       string `json:"type"`
-	Send       string `json:"send"`
-	Recv       string `json:"recv"`
-	Interval   int    `json:"interval"`
-	Timeout    int    `json:"timeout"`
-	TargetPort int32  `json:"targetPort"`
-	Name       string `json:"name,omitempty"`
-	Reference  string `json:"reference,omitempty"`
+	Type           string `json:"type"`
+	Send           string `json:"send"`
+	Recv           string `json:"recv"`
+	Interval       int    `json:"interval"`
+	Timeout        int    `json:"timeout"`
+	TargetPort     int32  `json:"targetPort"`
+	Name           string `json:"name,omitempty"`
+	Reference      string `json:"reference,omitempty"`
+	AutoHostHeader bool   `json:"autoHostHeader,omitempty"`
+	// TargetAddress is the fixed IP address probed instead of the pool members
+	TargetAddress string `json:"targetAddress,omitempty"`
+	// Transparent probes the targetAddress and targetPort through the pool members
+	Transparent bool `json:"transparent,omitempty"`
+	// Adaptive enables adaptive response time monitoring for http, https and tcp monitors
+	Adaptive bool `json:"adaptive,omitempty"`
+	// AdaptiveDivergenceType is absolute (milliseconds) or relative (percentage)
+	AdaptiveDivergenceType  string `json:"adaptiveDivergenceType,omitempty"`
+	AdaptiveDivergenceValue *int   `json:"adaptiveDivergenceValue,omitempty"`
+	// AdaptiveLimit is the response latency in milliseconds beyond which the probe fails
+	AdaptiveLimit *int `json:"adaptiveLimit,omitempty"`
+	// MemberAddresses restricts the monitor of the pool to the members of the addresses, these are
+	// the node addresses in nodeport mode as the pod addresses change when the pods are rescheduled
+	MemberAddresses []string `json:"memberAddresses,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -115,10 +211,32 @@ type TLSProfileSpec struct {
 
 // TLS contains required fields for TLS termination
 type TLS struct {
-	Termination string `json:"termination"`
-	ClientSSL   string `json:"clientSSL"`
-	ServerSSL   string `json:"serverSSL"`
-	Reference   string `json:"reference"`
+	Termination string      `json:"termination"`
+	ClientSSL   string      `json:"clientSSL"`
+	ServerSSL   string      `json:"serverSSL"`
+	Reference   string      `json:"reference"`
+	ClientAuth  *ClientAuth `json:"clientAuth,omitempty"`
+	// Renegotiation enables TLS renegotiation on the clientSSL and serverSSL profiles, defaults to true
+	Renegotiation *bool `json:"renegotiation,omitempty"`
+	// ALPNProtocols is the list of protocols negotiated with ALPN on the clientSSL profile
+	ALPNProtocols []string `json:"alpnProtocols,omitempty"`
+	// ServerName is the name the server certificate of the backends must match on the serverSSL profile
+	ServerName string `json:"serverName,omitempty"`
+	// IgnoreServerNameCheck accepts the server certificate of the backends regardless of its name
+	IgnoreServerNameCheck bool `json:"ignoreServerNameCheck,omitempty"`
+	// TLSOptions is the list of protocol versions disabled on the clientSSL profile, e.g. no-tlsv1
+	TLSOptions []string `json:"tlsOptions,omitempty"`
+	// ClientSSLs are the Secrets of an RSA and an ECDSA certificate of the same hosts, used instead of
+	// clientSSL to let BIG-IP select the certificate supported by the client
+	ClientSSLs []string `json:"clientSSLs,omitempty"`
+}
+
+// ClientAuth defines the client certificate authentication of the clientSSL profile
+type ClientAuth struct {
+	// Mode is either require or ignore
+	Mode string `json:"mode,omitempty"`
+	// CACertificate is the name of the Secret holding the CA bundle in ca.crt
+	CACertificate string `json:"caCertificate,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -185,6 +303,8 @@ type TransportServer struct {
 type TransportServerStatus struct {
 	VSAddress string `json:"vsAddress,omitempty"`
 	StatusOk  string `json:"status,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Message   string `json:"message,omitempty"`
 }
 
 // TransportServerSpec is the spec of the VirtualServer resource.
@@ -197,16 +317,89 @@ type TransportServerSpec struct {
 	SNAT                 string           `json:"snat"`
 	Pool                 Pool             `json:"pool"`
 	AllowVLANs           []string         `json:"allowVlans,omitempty"`
+	RejectVLANs          []string         `json:"rejectVlans,omitempty"`
+	VLANsEnabled         *bool            `json:"vlansEnabled,omitempty"`
 	Type                 string           `json:"type,omitempty"`
 	ServiceIPAddress     []ServiceAddress `json:"serviceAddress"`
 	IPAMLabel            string           `json:"ipamLabel"`
 	IRules               []string         `json:"iRules,omitempty"`
 	PolicyName           string           `json:"policyName,omitempty"`
 	PersistenceProfile   string           `json:"persistenceProfile,omitempty"`
-	ProfileL4            string           `json:"profileL4,omitempty"`
+	Persistence          *Persistence     `json:"persistence,omitempty"`
+	ProfileL4            *ProfileL4       `json:"profileL4,omitempty"`
 	DOS                  string           `json:"dos,omitempty"`
 	BotDefense           string           `json:"botDefense,omitempty"`
 	Profiles             ProfileSpec      `json:"profiles,omitempty"`
+	Enabled              *bool            `json:"enabled,omitempty"`
+	TranslateAddress     *bool            `json:"translateAddress,omitempty"`
+	TranslatePort        *bool            `json:"translatePort,omitempty"`
+	RouteDomain          *int32           `json:"routeDomain,omitempty"`
+	PoolMemberType       string           `json:"poolMemberType,omitempty"`
+	ClonePools           *ClonePools      `json:"clonePools,omitempty"`
+	// ConnectionRateLimit is the maximum number of new connections per second of the virtual
+	ConnectionRateLimit int32 `json:"connectionRateLimit,omitempty"`
+}
+
+// Persistence defines the persistence of the TransportServer and VirtualServer,
+// matching across pools and virtuals is valid for source-address and universal persistence
+type Persistence struct {
+	Type                string `json:"type"`
+	Timeout             int    `json:"timeout,omitempty"`
+	MatchAcrossServices bool   `json:"matchAcrossServices,omitempty"`
+	MatchAcrossPools    bool   `json:"matchAcrossPools,omitempty"`
+	MatchAcrossVirtuals bool   `json:"matchAcrossVirtuals,omitempty"`
+}
+
+// ProfileL4 is the L4 profile of the TransportServer, given either as the path of an
+// existing profile or as an object with the fastL4 options of a profile created for the virtual
+type ProfileL4 struct {
+	Reference           string `json:"-"`
+	LooseInitialization *bool  `json:"looseInitialization,omitempty"`
+	ResetOnTimeout      *bool  `json:"resetOnTimeout,omitempty"`
+	// IdleTimeout and TCPHandshakeTimeout are in seconds, -1 means indefinite
+	IdleTimeout         *int32 `json:"idleTimeout,omitempty"`
+	TCPHandshakeTimeout *int32 `json:"tcpHandshakeTimeout,omitempty"`
+}
+
+// profileL4Options is used to (un)marshal the object form of ProfileL4
+type profileL4Options struct {
+	LooseInitialization *bool  `json:"looseInitialization,omitempty"`
+	ResetOnTimeout      *bool  `json:"resetOnTimeout,omitempty"`
+	IdleTimeout         *int32 `json:"idleTimeout,omitempty"`
+	TCPHandshakeTimeout *int32 `json:"tcpHandshakeTimeout,omitempty"`
+}
+
+// UnmarshalJSON accepts the profile path as well as the fastL4 options
+func (p *ProfileL4) UnmarshalJSON(data []byte) error {
+	var ref string
+	if err := json.Unmarshal(data, &ref); err == nil {
+		*p = ProfileL4{Reference: ref}
+		return nil
+	}
+	var opts profileL4Options
+	if err := json.Unmarshal(data, &opts); err != nil {
+		return err
+	}
+	*p = ProfileL4{
+		LooseInitialization: opts.LooseInitialization,
+		ResetOnTimeout:      opts.ResetOnTimeout,
+		IdleTimeout:         opts.IdleTimeout,
+		TCPHandshakeTimeout: opts.TCPHandshakeTimeout,
+	}
+	return nil
+}
+
+// MarshalJSON returns the profile path when set and the fastL4 options otherwise
+func (p ProfileL4) MarshalJSON() ([]byte, error) {
+	if p.Reference != "" {
+		return json.Marshal(p.Reference)
+	}
+	return json.Marshal(profileL4Options{
+		LooseInitialization: p.LooseInitialization,
+		ResetOnTimeout:      p.ResetOnTimeout,
+		IdleTimeout:         p.IdleTimeout,
+		TCPHandshakeTimeout: p.TCPHandshakeTimeout,
+	})
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -258,16 +451,22 @@ type ExternalDNSList struct {
 }
 
 type PolicySpec struct {
-	L7Policies  L7PolicySpec  `json:"l7Policies,omitempty"`
-	L3Policies  L3PolicySpec  `json:"l3Policies,omitempty"`
-	LtmPolicies LtmIRulesSpec `json:"ltmPolicies,omitempty"`
-	IRules      LtmIRulesSpec `json:"iRules,omitempty"`
-	Profiles    ProfileSpec   `json:"profiles,omitempty"`
-	SNAT        string        `json:"snat,omitempty"`
+	L7Policies       L7PolicySpec  `json:"l7Policies,omitempty"`
+	L3Policies       L3PolicySpec  `json:"l3Policies,omitempty"`
+	LtmPolicies      LtmIRulesSpec `json:"ltmPolicies,omitempty"`
+	IRules           LtmIRulesSpec `json:"iRules,omitempty"`
+	Profiles         ProfileSpec   `json:"profiles,omitempty"`
+	SNAT             string        `json:"snat,omitempty"`
+	TranslateAddress *bool         `json:"translateAddress,omitempty"`
+	TranslatePort    *bool         `json:"translatePort,omitempty"`
+	// Monitors are attached to the VirtualServer pools without own monitors
+	Monitors []Monitor `json:"monitors,omitempty"`
 }
 
 type L7PolicySpec struct {
 	WAF string `json:"waf,omitempty"`
+	// ForwardingPolicyOrder evaluates the forwarding policy after-waf (default) or before-waf enforcement
+	ForwardingPolicyOrder string `json:"forwardingPolicyOrder,omitempty"`
 }
 
 type L3PolicySpec struct {
@@ -275,24 +474,59 @@ type L3PolicySpec struct {
 	BotDefense       string   `json:"botDefense,omitempty"`
 	FirewallPolicy   string   `json:"firewallPolicy,omitempty"`
 	AllowSourceRange []string `json:"allowSourceRange,omitempty"`
+	DenySourceRange  []string `json:"denySourceRange,omitempty"`
+	// ConnectionRateLimit is the maximum number of new connections per second of the virtual,
+	// it takes precedence over the limit of the VirtualServer and TransportServer
+	ConnectionRateLimit int32 `json:"connectionRateLimit,omitempty"`
 }
 
 type LtmIRulesSpec struct {
-	Secure   string `json:"secure,omitempty"`
-	InSecure string `json:"insecure,omitempty"`
-	Priority string `json:"priority,omitempty"`
+	Secure       string          `json:"secure,omitempty"`
+	InSecure     string          `json:"insecure,omitempty"`
+	Priority     string          `json:"priority,omitempty"`
+	SecureList   []IRulePriority `json:"secureList,omitempty"`
+	InSecureList []IRulePriority `json:"insecureList,omitempty"`
+}
+
+// IRulePriority is an iRule with its priority, iRules with lower priority are attached first
+type IRulePriority struct {
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
 }
 
+// ProfileSpec defines the profiles of the Policy, logProfiles is retained for
+// compatibility and is attached as security log profiles. The HTTP analyticsProfile
+// is attached to HTTP and HTTPS virtuals only.
 type ProfileSpec struct {
-	TCP                ProfileTCP `json:"tcp,omitempty"`
-	UDP                string     `json:"udp,omitempty"`
-	HTTP               string     `json:"http,omitempty"`
-	HTTP2              string     `json:"http2,omitempty"`
-	RewriteProfile     string     `json:"rewriteProfile,omitempty"`
-	PersistenceProfile string     `json:"persistenceProfile,omitempty"`
-	LogProfiles        []string   `json:"logProfiles,omitempty"`
-	ProfileL4          string     `json:"profileL4,omitempty"`
-	ProfileMultiplex   string     `json:"profileMultiplex,omitempty"`
+	TCP                 ProfileTCP `json:"tcp,omitempty"`
+	UDP                 string     `json:"udp,omitempty"`
+	SCTP                string     `json:"sctp,omitempty"`
+	HTTP                string     `json:"http,omitempty"`
+	HTTP2               string     `json:"http2,omitempty"`
+	RewriteProfile      string     `json:"rewriteProfile,omitempty"`
+	PersistenceProfile  string     `json:"persistenceProfile,omitempty"`
+	LogProfiles         []string   `json:"logProfiles,omitempty"`
+	SecurityLogProfiles []string   `json:"securityLogProfiles,omitempty"`
+	RequestLogProfile   string     `json:"requestLogProfile,omitempty"`
+	ProfileL4           string     `json:"profileL4,omitempty"`
+	ProfileMultiplex    string     `json:"profileMultiplex,omitempty"`
+	AnalyticsProfile    string     `json:"analyticsProfile,omitempty"`
+	TCPAnalyticsProfile string     `json:"tcpAnalyticsProfile,omitempty"`
+	// InsertXForwardedFor inserts the X-Forwarded-For header on HTTP and HTTPS virtuals
+	InsertXForwardedFor bool `json:"insertXForwardedFor,omitempty"`
+	// AllowH2C attaches an HTTP/2 profile on HTTP virtuals to accept HTTP/2 over cleartext
+	AllowH2C bool `json:"allowH2C,omitempty"`
+	// HTTPOptions creates an HTTP profile for the virtual, mutually exclusive with HTTP
+	HTTPOptions *HTTPProfileOptions `json:"httpOptions,omitempty"`
+}
+
+// HTTPProfileOptions are the options of the HTTP profile created for HTTP and HTTPS virtuals
+type HTTPProfileOptions struct {
+	// ServerAgentName is the Server header of the responses generated by BIG-IP
+	ServerAgentName string `json:"serverAgentName,omitempty"`
+	// ProxyType is reverse, transparent or explicit
+	ProxyType           string `json:"proxyType,omitempty"`
+	InsertXForwardedFor bool   `json:"insertXForwardedFor,omitempty"`
 }
 type ProfileTCP struct {
 	Client string `json:"client,omitempty"`
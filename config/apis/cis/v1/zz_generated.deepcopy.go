@@ -25,14 +25,74 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientAuth) DeepCopyInto(out *ClientAuth) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientAuth.
+func (in *ClientAuth) DeepCopy() *ClientAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClonePool) DeepCopyInto(out *ClonePool) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClonePool.
+func (in *ClonePool) DeepCopy() *ClonePool {
+	if in == nil {
+		return nil
+	}
+	out := new(ClonePool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClonePools) DeepCopyInto(out *ClonePools) {
+	*out = *in
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(ClonePool)
+		**out = **in
+	}
+	if in.Egress != nil {
+		in, out := &in.Egress, &out.Egress
+		*out = new(ClonePool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClonePools.
+func (in *ClonePools) DeepCopy() *ClonePools {
+	if in == nil {
+		return nil
+	}
+	out := new(ClonePools)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DNSPool) DeepCopyInto(out *DNSPool) {
 	*out = *in
-	out.Monitor = in.Monitor
+	in.Monitor.DeepCopyInto(&out.Monitor)
 	if in.Monitors != nil {
 		in, out := &in.Monitors, &out.Monitors
 		*out = make([]Monitor, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	return
 }
@@ -130,6 +190,75 @@ func (in *ExternalDNSSpec) DeepCopy() *ExternalDNSSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPProfileOptions) DeepCopyInto(out *HTTPProfileOptions) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPProfileOptions.
+func (in *HTTPProfileOptions) DeepCopy() *HTTPProfileOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPProfileOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HashKey) DeepCopyInto(out *HashKey) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HashKey.
+func (in *HashKey) DeepCopy() *HashKey {
+	if in == nil {
+		return nil
+	}
+	out := new(HashKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HeaderMatch) DeepCopyInto(out *HeaderMatch) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HeaderMatch.
+func (in *HeaderMatch) DeepCopy() *HeaderMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(HeaderMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IRulePriority) DeepCopyInto(out *IRulePriority) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IRulePriority.
+func (in *IRulePriority) DeepCopy() *IRulePriority {
+	if in == nil {
+		return nil
+	}
+	out := new(IRulePriority)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngressLink) DeepCopyInto(out *IngressLink) {
 	*out = *in
@@ -236,6 +365,16 @@ func (in *IngressLinkStatus) DeepCopy() *IngressLinkStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *L3PolicySpec) DeepCopyInto(out *L3PolicySpec) {
 	*out = *in
+	if in.AllowSourceRange != nil {
+		in, out := &in.AllowSourceRange, &out.AllowSourceRange
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DenySourceRange != nil {
+		in, out := &in.DenySourceRange, &out.DenySourceRange
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -268,6 +407,16 @@ func (in *L7PolicySpec) DeepCopy() *L7PolicySpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LtmIRulesSpec) DeepCopyInto(out *LtmIRulesSpec) {
 	*out = *in
+	if in.SecureList != nil {
+		in, out := &in.SecureList, &out.SecureList
+		*out = make([]IRulePriority, len(*in))
+		copy(*out, *in)
+	}
+	if in.InSecureList != nil {
+		in, out := &in.InSecureList, &out.InSecureList
+		*out = make([]IRulePriority, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -284,6 +433,21 @@ func (in *LtmIRulesSpec) DeepCopy() *LtmIRulesSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Monitor) DeepCopyInto(out *Monitor) {
 	*out = *in
+	if in.AdaptiveDivergenceValue != nil {
+		in, out := &in.AdaptiveDivergenceValue, &out.AdaptiveDivergenceValue
+		*out = new(int)
+		**out = **in
+	}
+	if in.AdaptiveLimit != nil {
+		in, out := &in.AdaptiveLimit, &out.AdaptiveLimit
+		*out = new(int)
+		**out = **in
+	}
+	if in.MemberAddresses != nil {
+		in, out := &in.MemberAddresses, &out.MemberAddresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -297,6 +461,22 @@ func (in *Monitor) DeepCopy() *Monitor {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Persistence) DeepCopyInto(out *Persistence) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Persistence.
+func (in *Persistence) DeepCopy() *Persistence {
+	if in == nil {
+		return nil
+	}
+	out := new(Persistence)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Policy) DeepCopyInto(out *Policy) {
 	*out = *in
@@ -361,10 +541,27 @@ func (in *PolicyList) DeepCopyObject() runtime.Object {
 func (in *PolicySpec) DeepCopyInto(out *PolicySpec) {
 	*out = *in
 	out.L7Policies = in.L7Policies
-	out.L3Policies = in.L3Policies
-	out.LtmPolicies = in.LtmPolicies
-	out.IRules = in.IRules
+	in.L3Policies.DeepCopyInto(&out.L3Policies)
+	in.LtmPolicies.DeepCopyInto(&out.LtmPolicies)
+	in.IRules.DeepCopyInto(&out.IRules)
 	in.Profiles.DeepCopyInto(&out.Profiles)
+	if in.TranslateAddress != nil {
+		in, out := &in.TranslateAddress, &out.TranslateAddress
+		*out = new(bool)
+		**out = **in
+	}
+	if in.TranslatePort != nil {
+		in, out := &in.TranslatePort, &out.TranslatePort
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Monitors != nil {
+		in, out := &in.Monitors, &out.Monitors
+		*out = make([]Monitor, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -381,7 +578,26 @@ func (in *PolicySpec) DeepCopy() *PolicySpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Pool) DeepCopyInto(out *Pool) {
 	*out = *in
-	out.Monitor = in.Monitor
+	in.Monitor.DeepCopyInto(&out.Monitor)
+	if in.Monitors != nil {
+		in, out := &in.Monitors, &out.Monitors
+		*out = make([]Monitor, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]HeaderMatch, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.HashKey != nil {
+		in, out := &in.HashKey, &out.HashKey
+		*out = new(HashKey)
+		**out = **in
+	}
 	return
 }
 
@@ -395,6 +611,42 @@ func (in *Pool) DeepCopy() *Pool {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProfileL4) DeepCopyInto(out *ProfileL4) {
+	*out = *in
+	if in.LooseInitialization != nil {
+		in, out := &in.LooseInitialization, &out.LooseInitialization
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ResetOnTimeout != nil {
+		in, out := &in.ResetOnTimeout, &out.ResetOnTimeout
+		*out = new(bool)
+		**out = **in
+	}
+	if in.IdleTimeout != nil {
+		in, out := &in.IdleTimeout, &out.IdleTimeout
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TCPHandshakeTimeout != nil {
+		in, out := &in.TCPHandshakeTimeout, &out.TCPHandshakeTimeout
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfileL4.
+func (in *ProfileL4) DeepCopy() *ProfileL4 {
+	if in == nil {
+		return nil
+	}
+	out := new(ProfileL4)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProfileSpec) DeepCopyInto(out *ProfileSpec) {
 	*out = *in
@@ -403,6 +655,16 @@ func (in *ProfileSpec) DeepCopyInto(out *ProfileSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.SecurityLogProfiles != nil {
+		in, out := &in.SecurityLogProfiles, &out.SecurityLogProfiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HTTPOptions != nil {
+		in, out := &in.HTTPOptions, &out.HTTPOptions
+		*out = new(HTTPProfileOptions)
+		**out = **in
+	}
 	return
 }
 
@@ -432,9 +694,50 @@ func (in *ServiceAddress) DeepCopy() *ServiceAddress {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SorryPage) DeepCopyInto(out *SorryPage) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SorryPage.
+func (in *SorryPage) DeepCopy() *SorryPage {
+	if in == nil {
+		return nil
+	}
+	out := new(SorryPage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TLS) DeepCopyInto(out *TLS) {
 	*out = *in
+	if in.ClientAuth != nil {
+		in, out := &in.ClientAuth, &out.ClientAuth
+		*out = new(ClientAuth)
+		**out = **in
+	}
+	if in.Renegotiation != nil {
+		in, out := &in.Renegotiation, &out.Renegotiation
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ALPNProtocols != nil {
+		in, out := &in.ALPNProtocols, &out.ALPNProtocols
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TLSOptions != nil {
+		in, out := &in.TLSOptions, &out.TLSOptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClientSSLs != nil {
+		in, out := &in.ClientSSLs, &out.ClientSSLs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -516,7 +819,7 @@ func (in *TLSProfileSpec) DeepCopyInto(out *TLSProfileSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	out.TLS = in.TLS
+	in.TLS.DeepCopyInto(&out.TLS)
 	return
 }
 
@@ -594,12 +897,22 @@ func (in *TransportServerList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TransportServerSpec) DeepCopyInto(out *TransportServerSpec) {
 	*out = *in
-	out.Pool = in.Pool
+	in.Pool.DeepCopyInto(&out.Pool)
 	if in.AllowVLANs != nil {
 		in, out := &in.AllowVLANs, &out.AllowVLANs
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.RejectVLANs != nil {
+		in, out := &in.RejectVLANs, &out.RejectVLANs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VLANsEnabled != nil {
+		in, out := &in.VLANsEnabled, &out.VLANsEnabled
+		*out = new(bool)
+		**out = **in
+	}
 	if in.ServiceIPAddress != nil {
 		in, out := &in.ServiceIPAddress, &out.ServiceIPAddress
 		*out = make([]ServiceAddress, len(*in))
@@ -610,6 +923,41 @@ func (in *TransportServerSpec) DeepCopyInto(out *TransportServerSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Persistence != nil {
+		in, out := &in.Persistence, &out.Persistence
+		*out = new(Persistence)
+		**out = **in
+	}
+	if in.ProfileL4 != nil {
+		in, out := &in.ProfileL4, &out.ProfileL4
+		*out = new(ProfileL4)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.TranslateAddress != nil {
+		in, out := &in.TranslateAddress, &out.TranslateAddress
+		*out = new(bool)
+		**out = **in
+	}
+	if in.TranslatePort != nil {
+		in, out := &in.TranslatePort, &out.TranslatePort
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RouteDomain != nil {
+		in, out := &in.RouteDomain, &out.RouteDomain
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ClonePools != nil {
+		in, out := &in.ClonePools, &out.ClonePools
+		*out = new(ClonePools)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -706,6 +1054,13 @@ func (in *VirtualServerSpec) DeepCopyInto(out *VirtualServerSpec) {
 	if in.Pools != nil {
 		in, out := &in.Pools, &out.Pools
 		*out = make([]Pool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TLSProfileNames != nil {
+		in, out := &in.TLSProfileNames, &out.TLSProfileNames
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 	if in.AllowVLANs != nil {
@@ -713,6 +1068,16 @@ func (in *VirtualServerSpec) DeepCopyInto(out *VirtualServerSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.RejectVLANs != nil {
+		in, out := &in.RejectVLANs, &out.RejectVLANs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VLANsEnabled != nil {
+		in, out := &in.VLANsEnabled, &out.VLANsEnabled
+		*out = new(bool)
+		**out = **in
+	}
 	if in.IRules != nil {
 		in, out := &in.IRules, &out.IRules
 		*out = make([]string, len(*in))
@@ -723,6 +1088,41 @@ func (in *VirtualServerSpec) DeepCopyInto(out *VirtualServerSpec) {
 		*out = make([]ServiceAddress, len(*in))
 		copy(*out, *in)
 	}
+	if in.Persistence != nil {
+		in, out := &in.Persistence, &out.Persistence
+		*out = new(Persistence)
+		**out = **in
+	}
+	if in.AllowSourceRange != nil {
+		in, out := &in.AllowSourceRange, &out.AllowSourceRange
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DenySourceRange != nil {
+		in, out := &in.DenySourceRange, &out.DenySourceRange
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RouteDomain != nil {
+		in, out := &in.RouteDomain, &out.RouteDomain
+		*out = new(int32)
+		**out = **in
+	}
+	if in.SorryPage != nil {
+		in, out := &in.SorryPage, &out.SorryPage
+		*out = new(SorryPage)
+		**out = **in
+	}
+	if in.ClonePools != nil {
+		in, out := &in.ClonePools, &out.ClonePools
+		*out = new(ClonePools)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -75,6 +75,7 @@ func NewAgent(params AgentParams) *Agent {
 		incomingTenantDeclMap: make(map[string]as3Tenant),
 		retryTenantDeclMap:    make(map[string]*tenantParams),
 		tenantPriorityMap:     make(map[string]int),
+		ltmConfig:             make(LTMConfig),
 		userAgent:             params.UserAgent,
 		HttpAddress:           params.HttpAddress,
 	}
@@ -200,6 +201,23 @@ func (agent *Agent) IsBigIPAppServicesAvailable() error {
 		bigIPAS3Version, as3SupportedVersion)
 }
 
+// setLastSyncTimeSource sets the source of the last sync time exposed on the health endpoint
+func (agent *Agent) setLastSyncTimeSource(lastSyncTime func() time.Time) {
+	agent.syncTimeMutex.Lock()
+	defer agent.syncTimeMutex.Unlock()
+	agent.lastSyncTime = lastSyncTime
+}
+
+// getLastSyncTime returns the last sync time of the controller, the zero time if not known
+func (agent *Agent) getLastSyncTime() time.Time {
+	agent.syncTimeMutex.RLock()
+	defer agent.syncTimeMutex.RUnlock()
+	if agent.lastSyncTime == nil {
+		return time.Time{}
+	}
+	return agent.lastSyncTime()
+}
+
 func (agent *Agent) PostConfig(rsConfig ResourceConfigRequest) {
 	// Always push latest activeConfig to channel
 	// Case1: Put latest config into the channel
@@ -207,9 +225,37 @@ func (agent *Agent) PostConfig(rsConfig ResourceConfigRequest) {
 	// Either Case1 or Case2 executes, which ensures the above
 	select {
 	case agent.postChan <- rsConfig:
-	case <-agent.postChan:
-		agent.postChan <- rsConfig
+	case pendingConfig := <-agent.postChan:
+		agent.postChan <- mergeResourceConfigRequest(pendingConfig, rsConfig)
+
+	}
+}
 
+// mergeResourceConfigRequest adds the partitions of the pending config missing in the latest config,
+// as the config requests carry only the updated partitions a superseded request can't be dropped
+func mergeResourceConfigRequest(pendingConfig, latestConfig ResourceConfigRequest) ResourceConfigRequest {
+	if latestConfig.ltmConfig == nil {
+		latestConfig.ltmConfig = make(LTMConfig)
+	}
+	for prtn, partitionConfig := range pendingConfig.ltmConfig {
+		if _, ok := latestConfig.ltmConfig[prtn]; !ok {
+			latestConfig.ltmConfig[prtn] = partitionConfig
+		}
+	}
+	return latestConfig
+}
+
+// updateLTMConfig merges the partitions of the config request into the LTM config of all partitions
+func (agent *Agent) updateLTMConfig(rsConfig ResourceConfigRequest) {
+	if agent.ltmConfig == nil {
+		agent.ltmConfig = make(LTMConfig)
+	}
+	for prtn, partitionConfig := range rsConfig.ltmConfig {
+		if len(partitionConfig.ResourceMap) == 0 {
+			delete(agent.ltmConfig, prtn)
+		} else {
+			agent.ltmConfig[prtn] = partitionConfig
+		}
 	}
 }
 
@@ -223,9 +269,11 @@ func (agent *Agent) agentWorker() {
 
 		// Fetch the latest config from channel
 		select {
-		case rsConfig = <-agent.postChan:
+		case latestConfig := <-agent.postChan:
+			rsConfig = mergeResourceConfigRequest(rsConfig, latestConfig)
 		case <-time.After(1 * time.Microsecond):
 		}
+		agent.updateLTMConfig(rsConfig)
 		if !(agent.EnableIPV6) {
 			agent.PostGTMConfig(rsConfig)
 		}
@@ -237,9 +285,6 @@ func (agent *Agent) agentWorker() {
 			continue
 		}
 
-		var updatedTenants []string
-		// initializing the priority tenants
-		var priorityTenants []string
 		/*
 			For every incoming post request, create a new tenantResponseMap.
 			tenantResponseMap will be updated with responses during postConfig.
@@ -249,25 +294,70 @@ func (agent *Agent) agentWorker() {
 		agent.tenantResponseMap = make(map[string]tenantResponse)
 
 		for tenant := range agent.incomingTenantDeclMap {
-			if _, ok := agent.tenantPriorityMap[tenant]; ok {
-				priorityTenants = append(priorityTenants, tenant)
-			} else {
-				updatedTenants = append(updatedTenants, tenant)
-			}
 			agent.tenantResponseMap[tenant] = tenantResponse{}
 		}
 
-		// Update the priority tenants first
-		if len(priorityTenants) > 0 {
-			agent.postTenantsDeclaration(decl, rsConfig, priorityTenants)
+		// Update the priority tenants first, the remaining tenants next and the emptied tenants at last
+		for _, tenants := range agent.getTenantPostOrder() {
+			agent.postTenantsDeclaration(decl, rsConfig, tenants)
 		}
-		// Updating the remaining tenants
-		agent.postTenantsDeclaration(decl, rsConfig, updatedTenants)
 
 		agent.declUpdate.Unlock()
 	}
 }
 
+// getTenantPostOrder groups the incoming tenants into batches in the order they are to be posted.
+// Prioritized tenants are grouped by priority in descending order followed by the remaining tenants.
+// Tenants with emptied configuration are posted at last so that the resources moved out of them
+// are available in their new tenants before they are removed.
+func (agent *Agent) getTenantPostOrder() [][]string {
+	priorityTenants := make(map[int][]string)
+	var priorities []int
+	var updatedTenants, emptiedTenants []string
+	for tenant, tenantDecl := range agent.incomingTenantDeclMap {
+		if isEmptiedTenantDecl(tenantDecl) {
+			emptiedTenants = append(emptiedTenants, tenant)
+			continue
+		}
+		if priority, ok := agent.tenantPriorityMap[tenant]; ok {
+			if _, found := priorityTenants[priority]; !found {
+				priorities = append(priorities, priority)
+			}
+			priorityTenants[priority] = append(priorityTenants[priority], tenant)
+		} else {
+			updatedTenants = append(updatedTenants, tenant)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(priorities)))
+
+	var tenantBatches [][]string
+	for _, priority := range priorities {
+		sort.Strings(priorityTenants[priority])
+		tenantBatches = append(tenantBatches, priorityTenants[priority])
+	}
+	for _, tenants := range [][]string{updatedTenants, emptiedTenants} {
+		if len(tenants) > 0 {
+			sort.Strings(tenants)
+			tenantBatches = append(tenantBatches, tenants)
+		}
+	}
+	return tenantBatches
+}
+
+// isEmptiedTenantDecl checks whether the tenant declaration removes or flushes all the resources of the tenant
+func isEmptiedTenantDecl(tenantDecl as3Tenant) bool {
+	sharedApp, ok := tenantDecl[as3SharedApplication].(as3Application)
+	if !ok {
+		return true
+	}
+	for key := range sharedApp {
+		if key != "class" && key != "template" {
+			return false
+		}
+	}
+	return true
+}
+
 // Post the tenants declaration
 func (agent *Agent) postTenantsDeclaration(decl as3Declaration, rsConfig ResourceConfigRequest, tenants []string) {
 	cfg := agentConfig{
@@ -278,7 +368,7 @@ func (agent *Agent) postTenantsDeclaration(decl as3Declaration, rsConfig Resourc
 
 	agent.publishConfig(cfg)
 
-	go agent.updatePoolMembers(rsConfig)
+	go agent.updatePoolMembers(agent.ltmConfig.GetAllPoolMembers())
 
 	agent.updateTenantResponse(true)
 
@@ -342,16 +432,21 @@ func (agent *Agent) updateRetryMap(tenant string, resp tenantResponse, tenDecl i
 	}
 }
 
-func (agent *Agent) updatePoolMembers(rsConfig ResourceConfigRequest) {
-	allPoolMembers := rsConfig.ltmConfig.GetAllPoolMembers()
-
+// updatePoolMembers writes the pool members of all partitions to VxlanMgr
+func (agent *Agent) updatePoolMembers(allPoolMembers []PoolMember) {
 	// Convert allPoolMembers to rsc.Members so that vxlan Manger accepts
 	var allPoolMems []rsc.Member
 
 	for _, poolMem := range allPoolMembers {
+		// cluster tag is not known to vxlan Manager
 		allPoolMems = append(
 			allPoolMems,
-			rsc.Member(poolMem),
+			rsc.Member{
+				Address: poolMem.Address,
+				Port:    poolMem.Port,
+				SvcPort: poolMem.SvcPort,
+				Session: poolMem.Session,
+			},
 		)
 	}
 	if agent.EventChan != nil {
@@ -709,9 +804,68 @@ func (agent *Agent) createAS3LTMConfigADC(config ResourceConfigRequest) as3ADC {
 		}
 		as3JSONDecl[tenantName] = tenantDecl
 	}
+	processPartitionPoolsForAS3(config, as3JSONDecl)
 	return as3JSONDecl
 }
 
+// processPartitionPoolsForAS3 creates the pools placed in another partition than their virtual,
+// along with their monitors, in the tenant of the pool partition
+func processPartitionPoolsForAS3(config ResourceConfigRequest, as3JSONDecl as3ADC) {
+	for _, partitionConfig := range config.ltmConfig {
+		for _, cfg := range partitionConfig.ResourceMap {
+			// resource config with the pools and monitors of each partition
+			partitionCfgs := make(map[string]*ResourceConfig)
+			getPartitionCfg := func(partition string) *ResourceConfig {
+				if _, ok := partitionCfgs[partition]; !ok {
+					partitionCfgs[partition] = &ResourceConfig{Virtual: cfg.Virtual}
+					partitionCfgs[partition].Virtual.Partition = partition
+				}
+				return partitionCfgs[partition]
+			}
+			for _, pool := range cfg.Pools {
+				if isPartitionPool(cfg, pool.Partition) {
+					partitionCfg := getPartitionCfg(pool.Partition)
+					partitionCfg.Pools = append(partitionCfg.Pools, pool)
+				}
+			}
+			for _, monitor := range cfg.Monitors {
+				if isPartitionPool(cfg, monitor.Partition) {
+					partitionCfg := getPartitionCfg(monitor.Partition)
+					partitionCfg.Monitors = append(partitionCfg.Monitors, monitor)
+				}
+			}
+			for partition, partitionCfg := range partitionCfgs {
+				sharedApp := getTenantSharedApp(as3JSONDecl, partition, config.defaultRouteDomain)
+				createMonitorDecl(partitionCfg, sharedApp)
+				createPoolDecl(partitionCfg, sharedApp, config.shareNodes, partition)
+			}
+		}
+	}
+}
+
+// isPartitionPool checks whether the pool or monitor of the partition is placed in another partition than its virtual
+func isPartitionPool(cfg *ResourceConfig, partition string) bool {
+	return partition != "" && cfg.Virtual.Partition != "" && partition != cfg.Virtual.Partition
+}
+
+// getTenantSharedApp returns the shared application of the tenant, which is created if it's not declared yet
+func getTenantSharedApp(as3JSONDecl as3ADC, tenantName string, defaultRouteDomain int) as3Application {
+	tenantDecl, ok := as3JSONDecl[tenantName].(as3Tenant)
+	if !ok {
+		tenantDecl = as3Tenant{"class": "Tenant"}
+		as3JSONDecl[tenantName] = tenantDecl
+	}
+	sharedApp, ok := tenantDecl[as3SharedApplication].(as3Application)
+	if !ok {
+		sharedApp = as3Application{}
+		sharedApp["class"] = "Application"
+		sharedApp["template"] = "shared"
+		tenantDecl["defaultRouteDomain"] = defaultRouteDomain
+		tenantDecl[as3SharedApplication] = sharedApp
+	}
+	return sharedApp
+}
+
 func processIRulesForAS3(rsMap ResourceMap, sharedApp as3Application) {
 	for _, rsCfg := range rsMap {
 		// Create irule declaration
@@ -825,6 +979,10 @@ func processResourcesForAS3(rsMap ResourceMap, sharedApp as3Application, shareNo
 // Create policy declaration
 func createPoliciesDecl(cfg *ResourceConfig, sharedApp as3Application) {
 	_, port := extractVirtualAddressAndPort(cfg.Virtual.Destination)
+	var wafPolicyName string
+	if isWAFInForwardingPolicy(cfg) {
+		wafPolicyName = cfg.FindPolicy(PolicyControlForward).Name
+	}
 	for _, pl := range cfg.Policies {
 		//Create EndpointPolicy
 		ep := &as3EndpointPolicy{}
@@ -845,46 +1003,163 @@ func createPoliciesDecl(cfg *ResourceConfig, sharedApp as3Application) {
 
 			ep.Rules = append(ep.Rules, rulesData)
 		}
+		if pl.Name == wafPolicyName {
+			addWAFPolicyActions(ep, cfg.Virtual.WAF)
+		}
 		//Setting Endpoint_Policy Name
 		sharedApp[pl.Name] = ep
 	}
 }
 
+// isWAFInForwardingPolicy returns whether the WAF policy is enforced by the forwarding policy
+// rather than on the virtual, so that the forwarding policy is evaluated first
+func isWAFInForwardingPolicy(cfg *ResourceConfig) bool {
+	return cfg.Virtual.WAF != "" && cfg.Virtual.ForwardingPolicyOrder == ForwardingPolicyBeforeWAF &&
+		cfg.FindPolicy(PolicyControlForward) != nil
+}
+
+// addWAFPolicyActions enables the WAF policy after the forwarding actions of the rules, the
+// last rule matches the requests not forwarded by the others
+func addWAFPolicyActions(ep *as3EndpointPolicy, waf string) {
+	wafAction := &as3Action{
+		Type:   "waf",
+		Event:  PolicyEventRequest,
+		Policy: &as3ResourcePointer{BigIP: waf},
+	}
+	for _, rl := range ep.Rules {
+		if isRequestForwardRule(rl) {
+			rl.Actions = append(rl.Actions, wafAction)
+		}
+	}
+	ep.Class = "Endpoint_Policy"
+	ep.Rules = append(ep.Rules, &as3Rule{
+		Name:    "waf_default",
+		Actions: []*as3Action{wafAction},
+	})
+}
+
+// isRequestForwardRule returns whether the rule forwards the requests on request conditions only,
+// the rules evaluated on the response can't carry the request action of the WAF policy
+func isRequestForwardRule(rl *as3Rule) bool {
+	for _, cnd := range rl.Conditions {
+		if cnd.Event != PolicyEventRequest {
+			return false
+		}
+	}
+	for _, act := range rl.Actions {
+		if act.Type == "forward" && act.Event == PolicyEventRequest {
+			return true
+		}
+	}
+	return false
+}
+
 // Create AS3 Pools for CRD
 func createPoolDecl(cfg *ResourceConfig, sharedApp as3Application, shareNodes bool, tenant string) {
 	for _, v := range cfg.Pools {
+		if isPartitionPool(cfg, v.Partition) {
+			continue
+		}
 		pool := &as3Pool{}
 		pool.LoadBalancingMode = v.Balance
+		pool.ServiceDownAction = v.ServiceDownAction
+		pool.ReselectTries = v.ReselectTries
+		pool.MinimumMonitors = v.MinimumMonitors
 		pool.Class = "Pool"
-		for _, val := range v.Members {
+		for _, val := range v.getMonitoredMembers() {
 			var member as3PoolMember
 			member.AddressDiscovery = "static"
 			member.ServicePort = val.Port
-			member.ServerAddresses = append(member.ServerAddresses, val.Address)
+			member.Ratio = val.Ratio
+			member.ServerAddresses = append(member.ServerAddresses,
+				getAddressWithRouteDomain(val.Address, cfg.Virtual.RouteDomain))
 			if shareNodes {
 				member.ShareNodes = shareNodes
 			}
-			pool.Members = append(pool.Members, member)
-		}
-		for _, val := range v.MonitorNames {
-			var monitor as3ResourcePointer
-			//Reference existing health monitor from BIGIP
-			if val.Reference == BIGIP {
-				monitor.BigIP = val.Name
-			} else {
-				use := strings.Split(val.Name, "/")
-				monitor.Use = fmt.Sprintf("/%s/%s/%s",
-					tenant,
-					as3SharedApplication,
-					use[len(use)-1],
-				)
+			switch val.Session {
+			case MemberSessionDisabled:
+				// disabled members serve only the active and persistent connections
+				member.AdminState = "disable"
+			case MemberSessionForceOffline:
+				// forced offline members serve only the active connections
+				member.AdminState = "offline"
+			}
+			if val.Cluster != "" {
+				member.Remark = fmt.Sprintf("cluster: %v", val.Cluster)
 			}
-			pool.Monitors = append(pool.Monitors, monitor)
+			member.Monitors = createMonitorPointers(val.MonitorNames, tenant)
+			member.MinimumMonitors = val.MinimumMonitors
+			pool.Members = append(pool.Members, member)
 		}
+		pool.Monitors = createMonitorPointers(v.getPoolMonitorNames(), tenant)
+		pool.Remark = getPoolMetadataRemark(v.Metadata)
 		sharedApp[v.Name] = pool
 	}
 }
 
+// getPoolMetadataRemark formats the metadata of the pool as sorted key=value pairs, without the
+// characters not allowed in the AS3 remark
+func getPoolMetadataRemark(metadata map[string]string) string {
+	if len(metadata) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	remark := strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' || r == '\\' {
+			return -1
+		}
+		return r
+	}, strings.Join(pairs, ","))
+	return truncateAS3Remark(remark)
+}
+
+// truncateAS3Remark truncates the remark to the 64 characters allowed by AS3
+func truncateAS3Remark(remark string) string {
+	if runes := []rune(remark); len(runes) > 64 {
+		return string(runes[:64])
+	}
+	return remark
+}
+
+// createMonitorPointers returns the AS3 references of the monitors
+func createMonitorPointers(monitorNames []MonitorName, tenant string) []as3ResourcePointer {
+	var monitors []as3ResourcePointer
+	for _, val := range monitorNames {
+		var monitor as3ResourcePointer
+		//Reference existing health monitor from BIGIP
+		if val.Reference == BIGIP {
+			monitor.BigIP = val.Name
+		} else {
+			monitor.Use = getMonitorUsePath(val.Name, tenant)
+		}
+		monitors = append(monitors, monitor)
+	}
+	return monitors
+}
+
+// getMonitorUsePath returns the AS3 path of the monitor in the partition it is named with,
+// monitors named without partition belong to the tenant of the pool
+func getMonitorUsePath(monitorName, tenant string) string {
+	partition := tenant
+	name := monitorName
+	if strings.HasPrefix(monitorName, "/") {
+		path := strings.Split(strings.TrimPrefix(monitorName, "/"), "/")
+		if len(path) > 1 && path[0] != "" {
+			partition = path[0]
+		}
+		name = path[len(path)-1]
+	}
+	return fmt.Sprintf("/%s/%s/%s",
+		partition,
+		as3SharedApplication,
+		name,
+	)
+}
+
 func updateVirtualToHTTPS(v *as3Service) {
 	v.Class = "Service_HTTPS"
 	redirect80 := false
@@ -898,16 +1173,10 @@ func processIrulesForCRD(cfg *ResourceConfig, svc *as3Service) {
 		splits := strings.Split(v, "/")
 		iRuleName := splits[len(splits)-1]
 
-		var iRuleNoPort string
-		lastIndex := strings.LastIndex(iRuleName, "_")
-		if lastIndex > 0 {
-			iRuleNoPort = iRuleName[:lastIndex]
-		} else {
-			iRuleNoPort = iRuleName
-		}
-		if strings.HasSuffix(iRuleNoPort, HttpRedirectIRuleName) ||
-			strings.HasSuffix(iRuleNoPort, HttpRedirectNoHostIRuleName) ||
-			strings.HasSuffix(iRuleName, TLSIRuleName) {
+		if isHttpRedirectIRule(iRuleName) ||
+			strings.HasSuffix(iRuleName, TLSIRuleName) ||
+			strings.HasSuffix(iRuleName, SorryPageIRuleName) ||
+			strings.HasSuffix(iRuleName, PoolSNATIRuleName) {
 
 			IRules = append(IRules, iRuleName)
 		} else {
@@ -923,6 +1192,7 @@ func processIrulesForCRD(cfg *ResourceConfig, svc *as3Service) {
 // Create AS3 Service for CRD
 func createServiceDecl(cfg *ResourceConfig, sharedApp as3Application, tenant string) {
 	svc := &as3Service{}
+	setServiceEnabled(cfg, svc)
 	numPolicies := len(cfg.Virtual.Policies)
 	switch {
 	case numPolicies == 1:
@@ -949,7 +1219,10 @@ func createServiceDecl(cfg *ResourceConfig, sharedApp as3Application, tenant str
 	case numPolicies == 0:
 		// No policies since we need to handle the pool name.
 		ps := strings.Split(cfg.Virtual.PoolName, "/")
-		if cfg.Virtual.PoolName != "" {
+		if strings.HasPrefix(cfg.Virtual.PoolName, "/") {
+			// pool of another partition is referred to with its full path
+			svc.Pool = cfg.Virtual.PoolName
+		} else if cfg.Virtual.PoolName != "" {
 			svc.Pool = fmt.Sprintf("/%s/%s/%s",
 				tenant,
 				as3SharedApplication,
@@ -959,19 +1232,22 @@ func createServiceDecl(cfg *ResourceConfig, sharedApp as3Application, tenant str
 	if cfg.Virtual.TLSTermination != TLSPassthrough {
 		svc.Layer4 = cfg.Virtual.IpProtocol
 		svc.Source = "0.0.0.0/0"
-		svc.TranslateServerAddress = true
-		svc.TranslateServerPort = true
+		translate := true
+		svc.TranslateServerAddress = &translate
+		svc.TranslateServerPort = &translate
 		svc.Class = "Service_HTTP"
 	} else {
-		if len(cfg.Virtual.PersistenceProfile) == 0 {
+		if len(cfg.Virtual.PersistenceProfile) == 0 && cfg.Virtual.Persistence == nil {
 			cfg.Virtual.PersistenceProfile = "tls-session-id"
 		}
 		svc.Class = "Service_TCP"
 	}
-	if len(cfg.Virtual.PersistenceProfile) > 0 {
-		svc.PersistenceMethods = &[]string{cfg.Virtual.PersistenceProfile}
+	if cfg.Virtual.Persistence != nil {
+		createPersistDecl(cfg, svc, sharedApp)
+	} else if len(cfg.Virtual.PersistenceProfile) > 0 {
+		svc.PersistenceMethods = &[]as3MultiTypeParam{getPersistenceMethod(cfg)}
 		if cfg.Virtual.PersistenceProfile == "none" {
-			svc.PersistenceMethods = &[]string{}
+			svc.PersistenceMethods = &[]as3MultiTypeParam{}
 		}
 	}
 	if len(cfg.Virtual.ProfileDOS) > 0 {
@@ -979,9 +1255,15 @@ func createServiceDecl(cfg *ResourceConfig, sharedApp as3Application, tenant str
 			BigIP: cfg.Virtual.ProfileDOS,
 		}
 	}
+	// Bot Defense profile is supported only on the HTTP and HTTPS virtuals
 	if len(cfg.Virtual.ProfileBotDefense) > 0 {
-		svc.ProfileBotDefense = &as3ResourcePointer{
-			BigIP: cfg.Virtual.ProfileBotDefense,
+		if svc.Class == "Service_HTTP" {
+			svc.ProfileBotDefense = &as3ResourcePointer{
+				BigIP: cfg.Virtual.ProfileBotDefense,
+			}
+		} else {
+			log.Warningf("[AS3] Skipping botDefense profile %v on the L4 virtual %v",
+				cfg.Virtual.ProfileBotDefense, cfg.Virtual.Name)
 		}
 	}
 
@@ -1011,6 +1293,8 @@ func createServiceDecl(cfg *ResourceConfig, sharedApp as3Application, tenant str
 			BigIP: cfg.Virtual.ProfileMultiplex,
 		}
 	}
+	svc.ClonePools = createClonePoolsDecl(cfg.Virtual.ClonePools)
+	svc.RateLimit = cfg.Virtual.ConnectionRateLimit
 	// updating the virtual server to https if a passthrough datagroup is found
 	name := getRSCfgResName(cfg.Virtual.Name, PassthroughHostsDgName)
 	mapKey := NameRef{
@@ -1047,13 +1331,54 @@ func createServiceDecl(cfg *ResourceConfig, sharedApp as3Application, tenant str
 		}
 	}
 
-	//Attaching WAF policy
-	if cfg.Virtual.WAF != "" {
+	// Accept HTTP/2 over cleartext on the HTTP virtual
+	if cfg.Virtual.AllowH2C && svc.Class == "Service_HTTP" {
+		createH2CHTTP2Decl(svc, cfg.Virtual.Name, sharedApp)
+	}
+
+	// Insert X-Forwarded-For header on HTTP and HTTPS virtuals only
+	if cfg.Virtual.HTTPProfileOptions != nil && svc.Class != "Service_TCP" {
+		createHTTPProfileDecl(cfg, svc, sharedApp)
+	} else if cfg.Virtual.InsertXForwardedFor && svc.Class != "Service_TCP" {
+		if svc.ProfileHTTP == nil {
+			httpProfileName := fmt.Sprintf("%s_http_xff", cfg.Virtual.Name)
+			sharedApp[httpProfileName] = &as3HTTPProfile{
+				Class:         "HTTP_Profile",
+				XForwardedFor: true,
+			}
+			svc.ProfileHTTP = &as3ResourcePointer{Use: httpProfileName}
+		} else {
+			log.Debugf("X-Forwarded-For insertion on virtual %v is governed by the referenced HTTP profile",
+				cfg.Virtual.Name)
+		}
+	}
+
+	//Attaching WAF policy, unless it is enforced after the forwarding policy
+	if cfg.Virtual.WAF != "" && !isWAFInForwardingPolicy(cfg) {
 		svc.WAF = &as3ResourcePointer{
 			BigIP: fmt.Sprintf("%v", cfg.Virtual.WAF),
 		}
 	}
 
+	// Attach request logging profile on HTTP and HTTPS virtuals only
+	if cfg.Virtual.RequestLogProfile != "" && svc.Class != "Service_TCP" {
+		svc.ProfileTrafficLog = &as3ResourcePointer{
+			BigIP: cfg.Virtual.RequestLogProfile,
+		}
+	}
+
+	// Attach HTTP analytics profile on HTTP and HTTPS virtuals only
+	if cfg.Virtual.AnalyticsProfile != "" && svc.Class != "Service_TCP" {
+		svc.ProfileAnalytics = &as3ResourcePointer{
+			BigIP: cfg.Virtual.AnalyticsProfile,
+		}
+	}
+	if cfg.Virtual.TCPAnalyticsProfile != "" {
+		svc.ProfileAnalyticsTcp = &as3ResourcePointer{
+			BigIP: cfg.Virtual.TCPAnalyticsProfile,
+		}
+	}
+
 	virtualAddress, port := extractVirtualAddressAndPort(cfg.Virtual.Destination)
 	// verify that ip address and port exists.
 	if virtualAddress != "" && port != 0 {
@@ -1075,6 +1400,27 @@ func createServiceDecl(cfg *ResourceConfig, sharedApp as3Application, tenant str
 	sharedApp[cfg.Virtual.Name] = svc
 }
 
+// getPersistenceMethod returns the persistence method of the virtual, a persistence profile
+// other than the AS3 basic persistence methods is referred as an existing BIG-IP profile
+// by its full path, an unqualified name belongs to the partition of the virtual
+func getPersistenceMethod(cfg *ResourceConfig) as3MultiTypeParam {
+	profile := strings.TrimSpace(cfg.Virtual.PersistenceProfile)
+	switch profile {
+	case "cookie", "destination-address", "msrdp", "source-address", "tls-session-id":
+		return profile
+	}
+	parts := strings.Split(strings.TrimPrefix(profile, "/"), "/")
+	switch len(parts) {
+	case 2:
+		return &as3ResourcePointer{BigIP: JoinBigipPath(parts[0], parts[1])}
+	case 1:
+		return &as3ResourcePointer{BigIP: JoinBigipPath(cfg.Virtual.Partition, parts[0])}
+	default:
+		log.Warningf("Persistence profile name '%v' is formatted incorrectly.", profile)
+		return profile
+	}
+}
+
 // Create AS3 Service Address for Virtual Server Address
 func createServiceAddressDecl(cfg *ResourceConfig, virtualAddress string, sharedApp as3Application) string {
 	var name string
@@ -1087,6 +1433,10 @@ func createServiceAddressDecl(cfg *ResourceConfig, virtualAddress string, shared
 		serviceAddress.SpanningEnabled = sa.SpanningEnabled
 		serviceAddress.TrafficGroup = sa.TrafficGroup
 		serviceAddress.VirtualAddress = virtualAddress
+		if cfg.Virtual.VirtualAddress != nil && cfg.Virtual.VirtualAddress.PrefixLen != 0 {
+			// network virtual address
+			serviceAddress.VirtualAddress = fmt.Sprintf("%s/%d", virtualAddress, cfg.Virtual.VirtualAddress.PrefixLen)
+		}
 		name = "crd_service_address_" + strings.Replace(virtualAddress, ".", "_", -1)
 		sharedApp[name] = serviceAddress
 	}
@@ -1153,6 +1503,18 @@ func createRuleCondition(rl *Rule, rulesData *as3Rule, port int) {
 			if c.Equals {
 				condition.Path.Operand = "equals"
 			}
+		} else if c.HTTPHeader {
+			condition.Type = "httpHeader"
+			condition.Name = c.Name
+			condition.All = &as3PolicyCompareString{
+				Values: c.Values,
+			}
+			if c.Equals {
+				condition.All.Operand = "equals"
+			}
+			if c.Contains {
+				condition.All.Operand = "contains"
+			}
 		} else if c.Tcp {
 			if c.Address && len(c.Values) > 0 {
 				condition.Type = "tcp"
@@ -1162,7 +1524,10 @@ func createRuleCondition(rl *Rule, rulesData *as3Rule, port int) {
 			}
 		}
 		if c.Request {
-			condition.Event = "request"
+			condition.Event = PolicyEventRequest
+		}
+		if rl.Event == PolicyEventResponse {
+			condition.Event = PolicyEventResponse
 		}
 
 		rulesData.Conditions = append(rulesData.Conditions, condition)
@@ -1177,17 +1542,32 @@ func createRuleAction(rl *Rule, rulesData *as3Rule) {
 			action.Type = "forward"
 		}
 		if v.Request {
-			action.Event = "request"
+			action.Event = PolicyEventRequest
+		}
+		if v.Response || rl.Event == PolicyEventResponse {
+			action.Event = PolicyEventResponse
 		}
 		if v.Redirect {
 			action.Type = "httpRedirect"
 		}
-		if v.HTTPHost {
+		if v.Reset {
+			action.Type = "drop"
+		}
+		if v.HTTPHost || v.HTTPHeader {
 			action.Type = "httpHeader"
 		}
 		if v.HTTPURI {
 			action.Type = "httpUri"
 		}
+		if v.Persist != "" {
+			action.Type = "persist"
+			persist := &as3ActionPersist{Key: v.PersistKey, Timeout: v.Timeout}
+			if v.Persist == BalanceCARP {
+				action.Carp = persist
+			} else {
+				action.Hash = persist
+			}
+		}
 		if v.Location != "" {
 			action.Location = v.Location
 		}
@@ -1200,12 +1580,38 @@ func createRuleAction(rl *Rule, rulesData *as3Rule) {
 		}
 		// handle uri rewrite.
 		if v.Replace && v.HTTPURI {
-			action.Replace = &as3ActionReplaceMap{
-				Value: v.Value,
+			if v.HTTPPath {
+				// only the path is replaced preserving the query string
+				action.Replace = &as3ActionReplaceMap{
+					Path: v.Value,
+				}
+			} else {
+				action.Replace = &as3ActionReplaceMap{
+					Value: v.Value,
+				}
+			}
+		}
+		// Handle header insert and remove.
+		if v.Insert && v.HTTPHeader && v.Header != nil {
+			action.Insert = &as3ActionReplaceMap{
+				Name:  v.Header.Name,
+				Value: v.Header.Value,
+			}
+		}
+		if v.Remove && v.HTTPHeader && v.Header != nil {
+			action.Remove = &as3ActionReplaceMap{
+				Name: v.Header.Name,
 			}
 		}
 		p := strings.Split(v.Pool, "/")
-		if v.Pool != "" {
+		if strings.HasPrefix(v.Pool, "/") {
+			// pool of another partition is referred to with its full path
+			action.Select = &as3ActionForwardSelect{
+				Pool: &as3ResourcePointer{
+					BigIP: v.Pool,
+				},
+			}
+		} else if v.Pool != "" {
 			action.Select = &as3ActionForwardSelect{
 				Pool: &as3ResourcePointer{
 					Use: p[len(p)-1],
@@ -1332,6 +1738,7 @@ func processCustomProfilesForAS3(rsMap ResourceMap, sharedApp as3Application) {
 					// then it indicates that secure-serverssl needs to be added
 					tlsClient.ValidateCertificate = true
 				}
+				createServerNameTLSClients(prof, svcName, tlsClient, sharedApp)
 			}
 		}
 	}
@@ -1365,30 +1772,171 @@ func createUpdateTLSServer(prof CustomProfile, svcName string, sharedApp as3Appl
 			svc.ServerTLS = tlsServerName
 			updateVirtualToHTTPS(svc)
 		}
+		if prof.PeerCertMode == PeerCertRequired && prof.CAFile != "" {
+			createClientAuthDecl(prof, tlsServer, tlsServerName, sharedApp)
+		}
+		if containsString(prof.ALPNProtocols, ALPNHTTP2) {
+			createALPNHTTP2Decl(svc, svcName, sharedApp)
+		}
+		setTLSServerOptions(tlsServer, prof)
 
-		tlsServer.Certificates = append(
-			tlsServer.Certificates,
-			as3TLSServerCertificates{
+		// the certificate is matched to each of the server names the profile is selected for
+		tlsServerCerts := []as3TLSServerCertificates{}
+		for _, serverName := range prof.SNIServerNames {
+			tlsServerCerts = append(tlsServerCerts, as3TLSServerCertificates{
 				Certificate: certName,
-			},
-		)
+				MatchToSNI:  serverName,
+			})
+		}
+		if len(tlsServerCerts) == 0 {
+			tlsServerCerts = append(tlsServerCerts, as3TLSServerCertificates{Certificate: certName})
+		}
+		if prof.SNIDefault {
+			// BIG-IP serves the first certificate when no server name matches
+			tlsServer.Certificates = append(tlsServerCerts, tlsServer.Certificates...)
+		} else {
+			tlsServer.Certificates = append(tlsServer.Certificates, tlsServerCerts...)
+		}
 		return true
 	}
 	return false
 }
 
+// setTLSServerOptions disables the renegotiation and the protocol versions of the TLS options of the
+// profile on the TLSServer, what is disabled by any of the profiles is disabled on the TLSServer
+func setTLSServerOptions(tlsServer *as3TLSServer, prof CustomProfile) {
+	disabled := false
+	if prof.DisableRenegotiation {
+		tlsServer.RenegotiationEnabled = &disabled
+	}
+	for _, option := range prof.TLSOptions {
+		switch option {
+		case TLSOptionNoSSL:
+			tlsServer.SSLEnabled = &disabled
+		case TLSOptionNoSSLv3:
+			tlsServer.SSL3Enabled = &disabled
+		case TLSOptionNoTLSv1:
+			tlsServer.TLS1_0Enabled = &disabled
+		case TLSOptionNoTLSv1_1:
+			tlsServer.TLS1_1Enabled = &disabled
+		case TLSOptionNoTLSv1_2:
+			tlsServer.TLS1_2Enabled = &disabled
+		case TLSOptionNoTLSv1_3:
+			tlsServer.TLS1_3Enabled = false
+		case TLSOptionNoDTLS:
+			tlsServer.DTLSEnabled = &disabled
+		case TLSOptionNoDTLSv12:
+			tlsServer.DTLS1_2Enabled = &disabled
+		}
+	}
+}
+
+// createALPNHTTP2Decl attaches an HTTP/2 profile activated by ALPN, so that h2 is negotiated on the clientssl profile.
+// HTTP/2 profile referenced from the Policy takes precedence.
+func createALPNHTTP2Decl(svc *as3Service, svcName string, sharedApp as3Application) {
+	if svc.ProfileHTTP2 != nil {
+		log.Debugf("ALPN negotiation of h2 on virtual %v is governed by the referenced HTTP/2 profile", svcName)
+		return
+	}
+	http2ProfileName := fmt.Sprintf("%s_http2_alpn", svcName)
+	sharedApp[http2ProfileName] = &as3HTTP2Profile{
+		Class:          "HTTP2_Profile",
+		ActivationMode: "alpn",
+	}
+	svc.ProfileHTTP2 = &as3ResourcePointer{Use: http2ProfileName}
+}
+
+// createH2CHTTP2Decl attaches an HTTP/2 profile which is always activated, as there is no ALPN on cleartext.
+// HTTP/2 profile referenced from the Policy takes precedence.
+func createH2CHTTP2Decl(svc *as3Service, svcName string, sharedApp as3Application) {
+	if svc.ProfileHTTP2 != nil {
+		log.Debugf("HTTP/2 over cleartext on virtual %v is governed by the referenced HTTP/2 profile", svcName)
+		return
+	}
+	http2ProfileName := fmt.Sprintf("%s_http2_h2c", svcName)
+	sharedApp[http2ProfileName] = &as3HTTP2Profile{
+		Class:          "HTTP2_Profile",
+		ActivationMode: "always",
+	}
+	svc.ProfileHTTP2 = &as3ResourcePointer{Use: http2ProfileName}
+}
+
 func createCertificateDecl(prof CustomProfile, sharedApp as3Application) {
 	if "" != prof.Cert && "" != prof.Key {
 		cert := &as3Certificate{
 			Class:       "Certificate",
 			Certificate: prof.Cert,
 			PrivateKey:  prof.Key,
-			ChainCA:     prof.CAFile,
+			ChainCA:     prof.ChainCA,
 		}
 		sharedApp[prof.Name] = cert
 	}
 }
 
+// createPersistDecl creates the Persist declaration of the virtual persistence
+func createPersistDecl(cfg *ResourceConfig, svc *as3Service, sharedApp as3Application) {
+	persistName := fmt.Sprintf("%s_persist", cfg.Virtual.Name)
+	sharedApp[persistName] = &as3Persist{
+		Class:                       "Persist",
+		PersistenceMethod:           cfg.Virtual.Persistence.Type,
+		Duration:                    cfg.Virtual.Persistence.Timeout,
+		MatchAcrossVirtualPorts:     cfg.Virtual.Persistence.MatchAcrossServices,
+		MatchAcrossPools:            cfg.Virtual.Persistence.MatchAcrossPools,
+		MatchAcrossVirtualAddresses: cfg.Virtual.Persistence.MatchAcrossVirtuals,
+	}
+	svc.PersistenceMethods = &[]as3MultiTypeParam{
+		&as3ResourcePointer{
+			Use: persistName,
+		},
+	}
+}
+
+// createHTTPProfileDecl creates the HTTP profile with the HTTP profile options of the virtual,
+// X-Forwarded-For is inserted when either the options or the virtual ask for it
+func createHTTPProfileDecl(cfg *ResourceConfig, svc *as3Service, sharedApp as3Application) {
+	profileName := fmt.Sprintf("%s_http_profile", cfg.Virtual.Name)
+	sharedApp[profileName] = &as3HTTPProfile{
+		Class:             "HTTP_Profile",
+		XForwardedFor:     cfg.Virtual.HTTPProfileOptions.InsertXForwardedFor || cfg.Virtual.InsertXForwardedFor,
+		ServerHeaderValue: cfg.Virtual.HTTPProfileOptions.ServerAgentName,
+		ProxyType:         cfg.Virtual.HTTPProfileOptions.ProxyType,
+	}
+	svc.ProfileHTTP = &as3ResourcePointer{Use: profileName}
+}
+
+// createProfileL4Decl creates the L4 profile with the fastL4 options of the virtual
+func createProfileL4Decl(cfg *ResourceConfig, svc *as3Service, sharedApp as3Application) {
+	profileName := fmt.Sprintf("%s_profileL4", cfg.Virtual.Name)
+	sharedApp[profileName] = &as3ProfileL4{
+		Class:               "L4_Profile",
+		LooseInitialization: copyBool(cfg.Virtual.ProfileL4Options.LooseInitialization),
+		ResetOnTimeout:      copyBool(cfg.Virtual.ProfileL4Options.ResetOnTimeout),
+		IdleTimeout:         copyInt32(cfg.Virtual.ProfileL4Options.IdleTimeout),
+		TCPHandshakeTimeout: copyInt32(cfg.Virtual.ProfileL4Options.TCPHandshakeTimeout),
+	}
+	svc.ProfileL4 = &as3ResourcePointer{
+		Use: profileName,
+	}
+}
+
+// createClientAuthDecl requires client certificates on the TLSServer and trusts the CA bundle of the profile
+func createClientAuthDecl(prof CustomProfile, tlsServer *as3TLSServer, tlsServerName string, sharedApp as3Application) {
+	caBundleName := fmt.Sprintf("%s_ca_bundle", tlsServerName)
+	caBundle, ok := sharedApp[caBundleName].(*as3CABundle)
+	if !ok {
+		caBundle = &as3CABundle{
+			Class:  "CA_Bundle",
+			Bundle: "",
+		}
+		sharedApp[caBundleName] = caBundle
+	}
+	if !strings.Contains(caBundle.Bundle, prof.CAFile) {
+		caBundle.Bundle += "\n" + prof.CAFile
+	}
+	tlsServer.AuthenticationMode = PeerCertRequired
+	tlsServer.AuthenticationTrustCA = &as3ResourcePointer{Use: caBundleName}
+}
+
 func createUpdateCABundle(prof CustomProfile, caBundleName string, sharedApp as3Application) {
 	// For TLSClient only Cert (DestinationCACertificate) is given and key is empty string
 	if "" != prof.Cert && "" == prof.Key {
@@ -1427,6 +1975,14 @@ func createTLSClient(
 		} else {
 			tlsClient.Ciphers = prof.Ciphers
 		}
+		if prof.DisableRenegotiation {
+			renegotiation := false
+			tlsClient.RenegotiationEnabled = &renegotiation
+		}
+		// the server certificate must match the server name when it is validated
+		if !prof.IgnoreServerNameCheck {
+			tlsClient.ServerName = prof.ServerName
+		}
 		sharedApp[tlsClientName] = tlsClient
 		svc.ClientTLS = tlsClientName
 		updateVirtualToHTTPS(svc)
@@ -1436,10 +1992,56 @@ func createTLSClient(
 	return nil
 }
 
+// createServerNameTLSClients creates a TLSClient for each of the server names of the pool paths,
+// which is selected by the reencrypt iRule to present the server name in SNI to the backends,
+// the validated server certificate must also match the server name unless the check is ignored
+func createServerNameTLSClients(
+	prof CustomProfile,
+	svcName string,
+	tlsClient *as3TLSClient,
+	sharedApp as3Application,
+) {
+	if tlsClient == nil {
+		return
+	}
+	for _, serverName := range prof.PathServerNames {
+		serverNameTLSClient := *tlsClient
+		serverNameTLSClient.SendSNI = serverName
+		if !prof.IgnoreServerNameCheck {
+			serverNameTLSClient.ServerName = serverName
+		}
+		sharedApp[getServerNameTLSClientName(svcName, serverName)] = &serverNameTLSClient
+	}
+}
+
+// createClonePoolsDecl creates the clone pools of the virtual, the pools of BIG-IP are referenced
+// with bigip while the pools of the virtual are referenced with use
+func createClonePoolsDecl(clonePools *ClonePools) *as3ClonePools {
+	if clonePools == nil {
+		return nil
+	}
+	getPoolPointer := func(clonePool *ClonePool) *as3ResourcePointer {
+		if clonePool == nil {
+			return nil
+		}
+		if clonePool.Reference == BIGIP {
+			return &as3ResourcePointer{BigIP: clonePool.Name}
+		}
+		return &as3ResourcePointer{Use: clonePool.Name}
+	}
+	return &as3ClonePools{
+		Ingress: getPoolPointer(clonePools.Ingress),
+		Egress:  getPoolPointer(clonePools.Egress),
+	}
+}
+
 // Create health monitor declaration
 func createMonitorDecl(cfg *ResourceConfig, sharedApp as3Application) {
 
 	for _, v := range cfg.Monitors {
+		if isPartitionPool(cfg, v.Partition) {
+			continue
+		}
 		monitor := &as3Monitor{}
 		monitor.Class = "Monitor"
 		monitor.Interval = v.Interval
@@ -1447,8 +2049,11 @@ func createMonitorDecl(cfg *ResourceConfig, sharedApp as3Application) {
 		monitor.Timeout = v.Timeout
 		val := 0
 		monitor.TargetPort = v.TargetPort
-		targetAddressStr := ""
+		targetAddressStr := v.TargetAddress
 		monitor.TargetAddress = &targetAddressStr
+		if v.Transparent {
+			monitor.Transparent = copyBool(&v.Transparent)
+		}
 		//Monitor type
 		switch v.Type {
 		case "http":
@@ -1474,15 +2079,76 @@ func createMonitorDecl(cfg *ResourceConfig, sharedApp as3Application) {
 			monitor.Adaptive = &adaptiveFalse
 			monitor.Receive = v.Recv
 			monitor.Send = v.Send
+		case MonitorTypeHTTP2:
+			adaptiveFalse := false
+			monitor.Adaptive = &adaptiveFalse
+			monitor.Receive = v.Recv
+			monitor.Send = v.Send
+			monitor.ClientTLS = getMonitorClientTLS(cfg)
+		}
+		setAdaptiveMonitor(monitor, v)
+		// the first declaration of a monitor name in the tenant is kept, the pools referring to
+		// a different monitor of the same name would be monitored with the settings of the first
+		if existing, ok := sharedApp[v.Name].(*as3Monitor); ok && !reflect.DeepEqual(existing, monitor) {
+			log.Warningf("[AS3] Monitor %v of virtual %v conflicts with a different monitor of the same name "+
+				"in partition %v, keeping the one declared first", v.Name, cfg.Virtual.Name, v.Partition)
+			continue
 		}
 		sharedApp[v.Name] = monitor
 	}
 
 }
 
+// setAdaptiveMonitor enables adaptive response time monitoring on the monitor declaration
+func setAdaptiveMonitor(monitor *as3Monitor, v Monitor) {
+	if !v.Adaptive {
+		return
+	}
+	monitor.Adaptive = copyBool(&v.Adaptive)
+	monitor.AdaptiveDivergenceType = v.AdaptiveDivergenceType
+	if v.AdaptiveDivergenceValue != nil {
+		if v.AdaptiveDivergenceType == AdaptiveDivergenceAbsolute {
+			monitor.AdaptiveDivergenceMilliseconds = *v.AdaptiveDivergenceValue
+		} else {
+			monitor.AdaptiveDivergencePercentage = *v.AdaptiveDivergenceValue
+		}
+	}
+	if v.AdaptiveLimit != nil {
+		monitor.AdaptiveLimitMilliseconds = *v.AdaptiveLimit
+	}
+}
+
+// getMonitorClientTLS returns the serverssl profile of the virtual to be used by http2 monitors
+func getMonitorClientTLS(cfg *ResourceConfig) *as3ResourcePointer {
+	for _, prof := range cfg.Virtual.Profiles {
+		if prof.Context == CustomProfileServer && prof.BigIPProfile {
+			return &as3ResourcePointer{
+				BigIP: fmt.Sprintf("/%v/%v", prof.Partition, prof.Name),
+			}
+		}
+	}
+	for _, prof := range cfg.customProfiles {
+		if prof.Context == CustomProfileServer {
+			return &as3ResourcePointer{
+				Use: fmt.Sprintf("%s_tls_client", cfg.Virtual.Name),
+			}
+		}
+	}
+	return nil
+}
+
+// setServiceEnabled posts a disabled virtual as disabled rather than removing it
+func setServiceEnabled(cfg *ResourceConfig, svc *as3Service) {
+	if !cfg.Virtual.Enabled {
+		enable := false
+		svc.Enable = &enable
+	}
+}
+
 // Create AS3 transport Service for CRD
 func createTransportServiceDecl(cfg *ResourceConfig, sharedApp as3Application) {
 	svc := &as3Service{}
+	setServiceEnabled(cfg, svc)
 	if cfg.Virtual.Mode == "standard" {
 		if cfg.Virtual.IpProtocol == "udp" {
 			svc.Class = "Service_UDP"
@@ -1503,16 +2169,20 @@ func createTransportServiceDecl(cfg *ResourceConfig, sharedApp as3Application) {
 	}
 
 	svc.ProfileL4 = "basic"
-	if len(cfg.Virtual.ProfileL4) > 0 {
+	if cfg.Virtual.ProfileL4Options != nil {
+		createProfileL4Decl(cfg, svc, sharedApp)
+	} else if len(cfg.Virtual.ProfileL4) > 0 {
 		svc.ProfileL4 = &as3ResourcePointer{
 			BigIP: cfg.Virtual.ProfileL4,
 		}
 	}
 
-	if len(cfg.Virtual.PersistenceProfile) > 0 {
-		svc.PersistenceMethods = &[]string{cfg.Virtual.PersistenceProfile}
+	if cfg.Virtual.Persistence != nil {
+		createPersistDecl(cfg, svc, sharedApp)
+	} else if len(cfg.Virtual.PersistenceProfile) > 0 {
+		svc.PersistenceMethods = &[]as3MultiTypeParam{getPersistenceMethod(cfg)}
 		if cfg.Virtual.PersistenceProfile == "none" {
-			svc.PersistenceMethods = &[]string{}
+			svc.PersistenceMethods = &[]as3MultiTypeParam{}
 		}
 	}
 
@@ -1522,12 +2192,6 @@ func createTransportServiceDecl(cfg *ResourceConfig, sharedApp as3Application) {
 		}
 	}
 
-	if len(cfg.Virtual.ProfileBotDefense) > 0 {
-		svc.ProfileBotDefense = &as3ResourcePointer{
-			BigIP: cfg.Virtual.ProfileBotDefense,
-		}
-	}
-
 	if len(cfg.Virtual.TCP.Client) > 0 || len(cfg.Virtual.TCP.Server) > 0 {
 		if cfg.Virtual.TCP.Client == "" {
 			log.Errorf("[AS3] resetting ProfileTCP as client profile doesnt co-exist with TCP Server Profile, Please include client TCP Profile ")
@@ -1549,6 +2213,13 @@ func createTransportServiceDecl(cfg *ResourceConfig, sharedApp as3Application) {
 		}
 	}
 
+	// TCP analytics profile is not applicable to the udp and sctp virtuals
+	if cfg.Virtual.TCPAnalyticsProfile != "" && (cfg.Virtual.IpProtocol == "" || cfg.Virtual.IpProtocol == "tcp") {
+		svc.ProfileAnalyticsTcp = &as3ResourcePointer{
+			BigIP: cfg.Virtual.TCPAnalyticsProfile,
+		}
+	}
+
 	// Attaching Profiles from Policy CRD
 	for _, profile := range cfg.Virtual.Profiles {
 		_, name := getPartitionAndName(profile.Name)
@@ -1561,15 +2232,16 @@ func createTransportServiceDecl(cfg *ResourceConfig, sharedApp as3Application) {
 					BigIP: fmt.Sprintf("%v", profile.Name),
 				}
 			}
+		case "sctp":
+			// AS3 supports only references to the existing sctp profiles
+			svc.ProfileSCTP = &as3ResourcePointer{
+				BigIP: fmt.Sprintf("%v", profile.Name),
+			}
 		}
 	}
 
-	if cfg.Virtual.TranslateServerAddress == true {
-		svc.TranslateServerAddress = cfg.Virtual.TranslateServerAddress
-	}
-	if cfg.Virtual.TranslateServerPort == true {
-		svc.TranslateServerPort = cfg.Virtual.TranslateServerPort
-	}
+	svc.TranslateServerAddress = copyBool(cfg.Virtual.TranslateServerAddress)
+	svc.TranslateServerPort = copyBool(cfg.Virtual.TranslateServerPort)
 	if cfg.Virtual.Source != "" {
 		svc.Source = cfg.Virtual.Source
 	}
@@ -1591,6 +2263,8 @@ func createTransportServiceDecl(cfg *ResourceConfig, sharedApp as3Application) {
 		}
 	}
 	svc.Pool = cfg.Virtual.PoolName
+	svc.ClonePools = createClonePoolsDecl(cfg.Virtual.ClonePools)
+	svc.RateLimit = cfg.Virtual.ConnectionRateLimit
 	processCommonDecl(cfg, svc)
 	sharedApp[cfg.Virtual.Name] = svc
 }
@@ -1606,11 +2280,31 @@ func processCommonDecl(cfg *ResourceConfig, svc *as3Service) {
 		}
 	}
 
-	//Attach AllowVLANs
-	if cfg.Virtual.AllowVLANs != nil {
+	//Attach AllowVLANs, an empty list restricts the virtual to no VLAN
+	if cfg.Virtual.VLANsEnabled || len(cfg.Virtual.AllowVLANs) > 0 {
+		allowVLANs := make([]as3ResourcePointer, 0, len(cfg.Virtual.AllowVLANs))
 		for _, vlan := range cfg.Virtual.AllowVLANs {
+			allowVLANs = append(allowVLANs, as3ResourcePointer{BigIP: vlan})
+		}
+		svc.AllowVLANs = &allowVLANs
+	}
+
+	//Attach RejectVLANs
+	if cfg.Virtual.RejectVLANs != nil {
+		for _, vlan := range cfg.Virtual.RejectVLANs {
 			vlans := as3ResourcePointer{BigIP: vlan}
-			svc.AllowVLANs = append(svc.AllowVLANs, vlans)
+			svc.RejectVLANs = append(svc.RejectVLANs, vlans)
+		}
+	}
+
+	//Attach description and metadata
+	if cfg.Virtual.Description != "" {
+		svc.Remark = truncateAS3Remark(cfg.Virtual.Description)
+	}
+	if len(cfg.Virtual.Metadata) > 0 {
+		svc.Metadata = make(map[string]as3MetadataValue, len(cfg.Virtual.Metadata))
+		for key, value := range cfg.Virtual.Metadata {
+			svc.Metadata[key] = as3MetadataValue{Value: value}
 		}
 	}
 
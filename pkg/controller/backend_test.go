@@ -2,6 +2,8 @@ package controller
 
 import (
 	"encoding/json"
+	"strings"
+
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/test"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -45,6 +47,8 @@ var _ = Describe("Backend Tests", func() {
 			rsCfg.Virtual.Name = "crd_vs_172.13.14.15"
 			rsCfg.Virtual.Destination = "/test/172.13.14.5:8080"
 			rsCfg.Virtual.AllowVLANs = []string{"flannel_vxlan"}
+			rsCfg.Virtual.Description = "default/SampleVS"
+			rsCfg.Virtual.Metadata = map[string]string{"owner": "team-a"}
 			rsCfg.Virtual.Policies = []nameRef{
 				{
 					Name:      "policy1",
@@ -201,13 +205,13 @@ var _ = Describe("Backend Tests", func() {
 				Name:         "default_svc_test_com_cssl",
 				ResourceName: "crd_vs_172.13.14.15",
 			}] = CustomProfile{
-				Name:       "default_svc_test_com_cssl",
-				Partition:  "test",
-				Context:    "clientside",
-				Cert:       "crthash",
-				Key:        "keyhash",
-				ServerName: "test.com",
-				SNIDefault: false,
+				Name:           "default_svc_test_com_cssl",
+				Partition:      "test",
+				Context:        "clientside",
+				Cert:           "crthash",
+				Key:            "keyhash",
+				SNIServerNames: []string{"test.com"},
+				SNIDefault:     false,
 			}
 			rsCfg2.customProfiles[SecretKey{
 				Name:         "default_svc_test_com_sssl",
@@ -235,6 +239,17 @@ var _ = Describe("Backend Tests", func() {
 			decl := agent.createTenantAS3Declaration(config)
 
 			Expect(string(decl)).ToNot(Equal(""), "Failed to Create AS3 Declaration")
+			sharedApp := agent.incomingTenantDeclMap["default"][as3SharedApplication].(as3Application)
+			svc := sharedApp["crd_vs_172.13.14.15"].(*as3Service)
+			Expect(svc.Remark).To(Equal("default/SampleVS"), "Description not added to AS3 Service")
+			Expect(svc.Metadata).To(Equal(map[string]as3MetadataValue{"owner": {Value: "team-a"}}),
+				"Metadata not added to AS3 Service")
+		})
+		It("Truncates the remark by characters", func() {
+			remark := strings.Repeat("a", 63) + "éé"
+			Expect(truncateAS3Remark(remark)).To(Equal(strings.Repeat("a", 63)+"é"),
+				"Remark should be truncated to 64 characters without splitting one")
+			Expect(truncateAS3Remark("default/SampleVS")).To(Equal("default/SampleVS"))
 		})
 		It("TransportServer Declaration", func() {
 			rsCfg := &ResourceConfig{}
@@ -243,8 +258,9 @@ var _ = Describe("Backend Tests", func() {
 			rsCfg.Virtual.Name = "crd_vs_172.13.14.16"
 			rsCfg.Virtual.Mode = "standard"
 			rsCfg.Virtual.IpProtocol = "tcp"
-			rsCfg.Virtual.TranslateServerAddress = true
-			rsCfg.Virtual.TranslateServerPort = true
+			translate := true
+			rsCfg.Virtual.TranslateServerAddress = &translate
+			rsCfg.Virtual.TranslateServerPort = &translate
 			rsCfg.Virtual.AllowVLANs = []string{"flannel_vxlan"}
 			rsCfg.Virtual.Destination = "172.13.14.6:1600"
 			rsCfg.customProfiles = make(map[SecretKey]CustomProfile)
@@ -270,6 +286,489 @@ var _ = Describe("Backend Tests", func() {
 			Expect(string(decl)).ToNot(Equal(""), "Failed to Create AS3 Declaration")
 
 		})
+		It("Same named monitors in different partitions", func() {
+			monitorName := formatMonitorName("default", "svc1", "http", 80, "", "")
+			newRsCfg := func(partition string, interval int) *ResourceConfig {
+				rsCfg := &ResourceConfig{}
+				rsCfg.MetaData.Active = true
+				rsCfg.MetaData.ResourceType = VirtualServer
+				rsCfg.Virtual.Name = "nextgenroutes_80"
+				rsCfg.Virtual.Partition = partition
+				rsCfg.Virtual.Destination = "/" + partition + "/172.13.14.7:80"
+				rsCfg.Pools = Pools{
+					Pool{
+						Name:         "svc1_80_default",
+						Partition:    partition,
+						MonitorNames: []MonitorName{{Name: JoinBigipPath(partition, monitorName)}},
+					},
+				}
+				rsCfg.Monitors = Monitors{
+					Monitor{Name: monitorName, Partition: partition, Type: "http", Interval: interval, Send: "GET /"},
+				}
+				return rsCfg
+			}
+
+			config := ResourceConfigRequest{
+				ltmConfig:          make(LTMConfig),
+				shareNodes:         true,
+				gtmConfig:          GTMConfig{},
+				defaultRouteDomain: 1,
+			}
+			config.ltmConfig["p1"] = &PartitionConfig{make(ResourceMap), 0}
+			config.ltmConfig["p1"].ResourceMap["nextgenroutes_80"] = newRsCfg("p1", 5)
+			config.ltmConfig["p2"] = &PartitionConfig{make(ResourceMap), 0}
+			config.ltmConfig["p2"].ResourceMap["nextgenroutes_80"] = newRsCfg("p2", 10)
+
+			agent.createTenantAS3Declaration(config)
+			for partition, interval := range map[string]int{"p1": 5, "p2": 10} {
+				sharedApp := agent.incomingTenantDeclMap[partition][as3SharedApplication].(as3Application)
+				Expect(sharedApp[monitorName].(*as3Monitor).Interval).To(Equal(interval),
+					"Monitor of partition %v overridden", partition)
+				Expect(sharedApp["svc1_80_default"].(*as3Pool).Monitors).To(Equal([]as3ResourcePointer{
+					{Use: "/" + partition + "/Shared/" + monitorName},
+				}), "Pool of partition %v refers to monitor of another partition", partition)
+			}
+
+			Expect(getMonitorUsePath(monitorName, "p1")).To(Equal("/p1/Shared/" + monitorName))
+			Expect(getMonitorUsePath("/p2/"+monitorName, "p1")).To(Equal("/p2/Shared/" + monitorName))
+		})
+		It("Pool in a different partition than its virtual", func() {
+			monitorName := formatMonitorName("default", "svc1", "http", 80, "test.com", "/foo")
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.Active = true
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Name = "crd_172_13_14_7_80"
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.Virtual.Destination = "/test/172.13.14.7:80"
+			rsCfg.Virtual.Policies = []nameRef{{Name: "crd_172_13_14_7_80_test_com_policy", Partition: "test"}}
+			rsCfg.Pools = Pools{
+				Pool{
+					Name:         "svc1_80_default_test_com",
+					Partition:    "shared",
+					MonitorNames: []MonitorName{{Name: JoinBigipPath("shared", monitorName)}},
+					Members:      []PoolMember{{Address: "10.1.1.1", Port: 8080}},
+				},
+				Pool{
+					Name:      "svc2_80_default_test_com",
+					Partition: "test",
+				},
+			}
+			rsCfg.Monitors = Monitors{
+				Monitor{Name: monitorName, Partition: "shared", Type: "http", Interval: 5, Send: "GET /"},
+			}
+			rsCfg.Policies = Policies{{
+				Name:     "crd_172_13_14_7_80_test_com_policy",
+				Strategy: "first-match",
+				Rules: Rules{
+					{Name: "vs_test_com_foo", Actions: []*action{{Name: "0", Forward: true, Request: true,
+						Pool: getPoolReference("test", "shared", "svc1_80_default_test_com")}}},
+					{Name: "vs_test_com_bar", Actions: []*action{{Name: "0", Forward: true, Request: true,
+						Pool: getPoolReference("test", "test", "svc2_80_default_test_com")}}},
+				},
+			}}
+
+			rs := NewResourceStore()
+			rs.setPoolPartitions([]string{"shared"})
+			rs.getPartitionResourceMap("test")[rsCfg.Virtual.Name] = rsCfg
+			config := ResourceConfigRequest{
+				ltmConfig:          rs.getUpdatedLTMConfigDeepCopy(),
+				shareNodes:         true,
+				gtmConfig:          GTMConfig{},
+				defaultRouteDomain: 1,
+			}
+			rs.updateCaches()
+
+			agent.createTenantAS3Declaration(config)
+			sharedApp := agent.incomingTenantDeclMap["test"][as3SharedApplication].(as3Application)
+			Expect(sharedApp).NotTo(HaveKey("svc1_80_default_test_com"), "Pool should not be in the partition of the virtual")
+			Expect(sharedApp).NotTo(HaveKey(monitorName), "Monitor should not be in the partition of the virtual")
+			Expect(sharedApp).To(HaveKey("svc2_80_default_test_com"))
+			ep := sharedApp["crd_172_13_14_7_80_test_com_policy"].(*as3EndpointPolicy)
+			Expect(ep.Rules[0].Actions[0].Select.Pool).To(Equal(&as3ResourcePointer{
+				BigIP: "/shared/Shared/svc1_80_default_test_com"}))
+			Expect(ep.Rules[1].Actions[0].Select.Pool).To(Equal(&as3ResourcePointer{Use: "svc2_80_default_test_com"}))
+
+			Expect(agent.incomingTenantDeclMap).To(HaveKey("shared"))
+			poolApp := agent.incomingTenantDeclMap["shared"][as3SharedApplication].(as3Application)
+			Expect(poolApp).To(HaveKey(monitorName))
+			pool := poolApp["svc1_80_default_test_com"].(*as3Pool)
+			Expect(pool.Monitors).To(Equal([]as3ResourcePointer{{Use: "/shared/Shared/" + monitorName}}))
+			Expect(pool.Members).To(HaveLen(1))
+
+			// the tenant of the pool partition is removed with its pools
+			newRsCfg := &ResourceConfig{}
+			newRsCfg.copyConfig(rsCfg)
+			newRsCfg.Pools = newRsCfg.Pools[1:]
+			newRsCfg.Monitors = nil
+			rs.getPartitionResourceMap("test")[rsCfg.Virtual.Name] = newRsCfg
+			config.ltmConfig = rs.getUpdatedLTMConfigDeepCopy()
+			Expect(config.ltmConfig).To(HaveKey("shared"), "Pool partition should be posted with the partition of the virtual")
+			agent.createTenantAS3Declaration(config)
+			Expect(agent.incomingTenantDeclMap["shared"]).To(Equal(as3Tenant{"class": "Tenant"}))
+		})
+		It("Route domain precedence", func() {
+			newRsCfg := func(routeDomain *int32, bindAddr string) *ResourceConfig {
+				rsCfg := &ResourceConfig{}
+				rsCfg.MetaData.Active = true
+				rsCfg.MetaData.ResourceType = VirtualServer
+				rsCfg.Virtual.Name = "crd_vs_172.13.14.18"
+				rsCfg.Virtual.Partition = "test"
+				rsCfg.Virtual.PoolName = "pool1"
+				rsCfg.Virtual.RouteDomain = routeDomain
+				rsCfg.Virtual.SetVirtualAddress(bindAddr, 80)
+				rsCfg.Pools = Pools{
+					Pool{
+						Name:    "pool1",
+						Members: []PoolMember{mem1, {Address: "1.2.3.9%4", Port: 8080}},
+					},
+				}
+				return rsCfg
+			}
+			getDecl := func(rsCfg *ResourceConfig) (as3Tenant, as3Application) {
+				config := ResourceConfigRequest{
+					ltmConfig:          make(LTMConfig),
+					shareNodes:         true,
+					gtmConfig:          GTMConfig{},
+					defaultRouteDomain: 1,
+				}
+				config.ltmConfig["test"] = &PartitionConfig{make(ResourceMap), 0}
+				config.ltmConfig["test"].ResourceMap[rsCfg.Virtual.Name] = rsCfg
+				agent.createTenantAS3Declaration(config)
+				return agent.incomingTenantDeclMap["test"], agent.incomingTenantDeclMap["test"][as3SharedApplication].(as3Application)
+			}
+			routeDomain := int32(2)
+
+			// route domain embedded in the address takes precedence over the route domain of the resource
+			rsCfg := newRsCfg(&routeDomain, "172.13.14.18%3")
+			Expect(rsCfg.Virtual.Destination).To(Equal("/test/172.13.14.18%3:80"))
+			_, sharedApp := getDecl(rsCfg)
+			Expect(sharedApp["crd_vs_172.13.14.18"].(*as3Service).VirtualAddresses).To(
+				Equal([]as3MultiTypeParam{"172.13.14.18%3"}))
+			members := sharedApp["pool1"].(*as3Pool).Members
+			Expect(members[0].ServerAddresses).To(Equal([]string{"1.2.3.5%2"}))
+			Expect(members[1].ServerAddresses).To(Equal([]string{"1.2.3.9%4"}))
+
+			// route domain of the resource is used for the addresses without one
+			rsCfg = newRsCfg(&routeDomain, "172.13.14.18")
+			Expect(rsCfg.Virtual.Destination).To(Equal("/test/172.13.14.18%2:80"))
+			copyCfg := &ResourceConfig{}
+			copyCfg.copyConfig(rsCfg)
+			Expect(copyCfg.Virtual.RouteDomain).To(Equal(&routeDomain))
+			Expect(copyCfg.Virtual.RouteDomain).NotTo(BeIdenticalTo(rsCfg.Virtual.RouteDomain))
+
+			// default route domain of the controller is used for the addresses without one
+			rsCfg = newRsCfg(nil, "172.13.14.18")
+			Expect(rsCfg.Virtual.Destination).To(Equal("/test/172.13.14.18:80"))
+			tenant, sharedApp := getDecl(rsCfg)
+			Expect(tenant["defaultRouteDomain"]).To(Equal(1))
+			Expect(sharedApp["crd_vs_172.13.14.18"].(*as3Service).VirtualAddresses).To(
+				Equal([]as3MultiTypeParam{"172.13.14.18"}))
+			members = sharedApp["pool1"].(*as3Pool).Members
+			Expect(members[0].ServerAddresses).To(Equal([]string{"1.2.3.5"}))
+			Expect(members[1].ServerAddresses).To(Equal([]string{"1.2.3.9%4"}))
+		})
+		It("X-Forwarded-For HTTP profile", func() {
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.Active = true
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Name = "crd_vs_172.13.14.17"
+			rsCfg.Virtual.Destination = "/test/172.13.14.7:80"
+			rsCfg.Virtual.InsertXForwardedFor = true
+
+			sharedApp := as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc := sharedApp["crd_vs_172.13.14.17"].(*as3Service)
+			Expect(svc.Class).To(Equal("Service_HTTP"))
+			Expect(svc.ProfileHTTP).To(Equal(&as3ResourcePointer{Use: "crd_vs_172.13.14.17_http_xff"}),
+				"HTTP profile inserting X-Forwarded-For not attached")
+			Expect(sharedApp["crd_vs_172.13.14.17_http_xff"]).To(Equal(&as3HTTPProfile{
+				Class:         "HTTP_Profile",
+				XForwardedFor: true,
+			}), "HTTP profile inserting X-Forwarded-For not created")
+
+			// Passthrough virtuals are L4 and never carry the HTTP profile
+			rsCfg.Virtual.TLSTermination = TLSPassthrough
+			sharedApp = as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc = sharedApp["crd_vs_172.13.14.17"].(*as3Service)
+			Expect(svc.Class).To(Equal("Service_TCP"))
+			Expect(svc.ProfileHTTP).To(BeNil(), "HTTP profile should not be attached to L4 virtual")
+			Expect(sharedApp).NotTo(HaveKey("crd_vs_172.13.14.17_http_xff"))
+		})
+		It("TLS renegotiation", func() {
+			svcName := "crd_vs_172.13.14.18"
+			sharedApp := as3Application{svcName: &as3Service{Class: "Service_HTTP"}}
+			prof := CustomProfile{
+				Name:    "clientssl",
+				Context: CustomProfileClient,
+				Cert:    "crthash",
+				Key:     "keyhash",
+			}
+			Expect(createUpdateTLSServer(prof, svcName, sharedApp)).To(BeTrue())
+			tlsServer := sharedApp[svcName+"_tls_server"].(*as3TLSServer)
+			Expect(tlsServer.RenegotiationEnabled).To(BeNil(), "Renegotiation should be left to the AS3 default")
+
+			prof.Name = "clientssl-norenegotiation"
+			prof.DisableRenegotiation = true
+			Expect(createUpdateTLSServer(prof, svcName, sharedApp)).To(BeTrue())
+			Expect(*tlsServer.RenegotiationEnabled).To(BeFalse(), "Renegotiation not disabled on TLS server")
+
+			serverProf := CustomProfile{
+				Name:    "serverssl",
+				Context: CustomProfileServer,
+				Cert:    "cacrthash",
+			}
+			tlsClient := createTLSClient(serverProf, svcName, "serverssl_ca_bundle", sharedApp)
+			Expect(tlsClient).NotTo(BeNil())
+			Expect(tlsClient.RenegotiationEnabled).To(BeNil(), "Renegotiation should be enabled by default")
+
+			serverProf.DisableRenegotiation = true
+			tlsClient = createTLSClient(serverProf, svcName, "serverssl_ca_bundle", sharedApp)
+			Expect(*tlsClient.RenegotiationEnabled).To(BeFalse(), "Renegotiation not disabled on TLS client")
+		})
+		It("TLS client server name check", func() {
+			svcName := "crd_vs_172.13.14.20"
+			sharedApp := as3Application{svcName: &as3Service{Class: "Service_HTTP"}}
+			serverProf := CustomProfile{
+				Name:    "serverssl",
+				Context: CustomProfileServer,
+				Cert:    "cacrthash",
+			}
+			tlsClient := createTLSClient(serverProf, svcName, "serverssl_ca_bundle", sharedApp)
+			Expect(tlsClient.ServerName).To(BeEmpty())
+			Expect(tlsClient.ValidateCertificate).To(BeFalse())
+
+			serverProf.ServerName = "backend.test.com"
+			serverProf.PathServerNames = []string{"foo.backend.com"}
+			tlsClient = createTLSClient(serverProf, svcName, "serverssl_ca_bundle", sharedApp)
+			Expect(tlsClient.ServerName).To(Equal("backend.test.com"))
+			Expect(tlsClient.ValidateCertificate).To(BeFalse(), "Validation is left to the trusted CA of the profile")
+			tlsClient.ValidateCertificate = true
+			createServerNameTLSClients(serverProf, svcName, tlsClient, sharedApp)
+			Expect(sharedApp[getServerNameTLSClientName(svcName, "foo.backend.com")]).To(Equal(&as3TLSClient{
+				Class:               "TLS_Client",
+				TrustCA:             &as3ResourcePointer{Use: "serverssl_ca_bundle"},
+				ValidateCertificate: true,
+				ServerName:          "foo.backend.com",
+				SendSNI:             "foo.backend.com",
+			}), "Path server name should be sent in SNI and matched by the server certificate")
+
+			serverProf.ServerName = ""
+			serverProf.IgnoreServerNameCheck = true
+			tlsClient = createTLSClient(serverProf, svcName, "serverssl_ca_bundle", sharedApp)
+			Expect(tlsClient.ServerName).To(BeEmpty())
+			tlsClient.ValidateCertificate = true
+			createServerNameTLSClients(serverProf, svcName, tlsClient, sharedApp)
+			Expect(sharedApp[getServerNameTLSClientName(svcName, "foo.backend.com")]).To(Equal(&as3TLSClient{
+				Class:               "TLS_Client",
+				TrustCA:             &as3ResourcePointer{Use: "serverssl_ca_bundle"},
+				ValidateCertificate: true,
+				SendSNI:             "foo.backend.com",
+			}), "Path server name should be sent in SNI without checking the server certificate name")
+		})
+		It("HTTP/2 over cleartext", func() {
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.Active = true
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Name = "crd_vs_172.13.14.18"
+			rsCfg.Virtual.Destination = "/test/172.13.14.8:80"
+			rsCfg.Virtual.AllowH2C = true
+
+			sharedApp := as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc := sharedApp["crd_vs_172.13.14.18"].(*as3Service)
+			Expect(svc.Class).To(Equal("Service_HTTP"))
+			Expect(svc.ProfileHTTP2).To(Equal(&as3ResourcePointer{Use: "crd_vs_172.13.14.18_http2_h2c"}),
+				"HTTP/2 profile for h2c not attached")
+			Expect(sharedApp["crd_vs_172.13.14.18_http2_h2c"]).To(Equal(&as3HTTP2Profile{
+				Class:          "HTTP2_Profile",
+				ActivationMode: "always",
+			}))
+
+			// HTTP/2 profile from the Policy is retained
+			rsCfg.Virtual.Profiles = ProfileRefs{{Name: "/Common/http2", Context: "http2", BigIPProfile: true}}
+			sharedApp = as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc = sharedApp["crd_vs_172.13.14.18"].(*as3Service)
+			Expect(svc.ProfileHTTP2).To(Equal(&as3ResourcePointer{BigIP: "/Common/http2"}))
+			Expect(sharedApp).NotTo(HaveKey("crd_vs_172.13.14.18_http2_h2c"))
+		})
+		It("Forwarding policy order relative to WAF", func() {
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.Active = true
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Name = "crd_vs_172.13.14.18"
+			rsCfg.Virtual.Destination = "/test/172.13.14.8:80"
+			rsCfg.Virtual.WAF = "/Common/WAF_Policy"
+			rsCfg.Virtual.Policies = []nameRef{{Name: "crd_vs_172.13.14.18_policy", Partition: "test"}}
+			rsCfg.Policies = Policies{{
+				Name:     "crd_vs_172.13.14.18_policy",
+				Controls: []string{PolicyControlForward},
+				Strategy: "/Common/first-match",
+				Rules: Rules{{
+					Name: "vs_test_com_foo",
+					Conditions: []*condition{{Name: "0", Host: true, HTTPHost: true, Equals: true, Request: true,
+						Values: []string{"test.com"}}},
+					Actions: []*action{{Name: "0", Forward: true, Request: true, Pool: "test_svc1_80"}},
+				}, {
+					Name:  "vs_test_com_response_header",
+					Event: PolicyEventResponse,
+					Conditions: []*condition{{Name: "0", HTTPHeader: true, Present: true,
+						Values: []string{"X-Backend"}}},
+					Actions: []*action{{Name: "0", HTTPHeader: true, Remove: true,
+						Header: &actionHeader{Name: "X-Backend"}}},
+				}},
+			}}
+			wafAction := &as3Action{Type: "waf", Event: PolicyEventRequest,
+				Policy: &as3ResourcePointer{BigIP: "/Common/WAF_Policy"}}
+
+			// WAF policy is enforced on the virtual by default
+			sharedApp := as3Application{}
+			createPoliciesDecl(rsCfg, sharedApp)
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc := sharedApp["crd_vs_172.13.14.18"].(*as3Service)
+			Expect(svc.WAF).To(Equal(&as3ResourcePointer{BigIP: "/Common/WAF_Policy"}))
+			ep := sharedApp["crd_vs_172.13.14.18_policy"].(*as3EndpointPolicy)
+			Expect(ep.Strategy).To(Equal("first-match"))
+			Expect(ep.Rules).To(HaveLen(2))
+			Expect(ep.Rules[0].Actions).NotTo(ContainElement(wafAction))
+
+			// WAF policy is enforced by the forwarding policy after the forward actions
+			Expect(rsCfg.Virtual.setForwardingPolicyOrder(ForwardingPolicyBeforeWAF)).To(Succeed())
+			sharedApp = as3Application{}
+			createPoliciesDecl(rsCfg, sharedApp)
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc = sharedApp["crd_vs_172.13.14.18"].(*as3Service)
+			Expect(svc.WAF).To(BeNil(), "WAF policy should not be attached to the virtual")
+			ep = sharedApp["crd_vs_172.13.14.18_policy"].(*as3EndpointPolicy)
+			Expect(ep.Strategy).To(Equal("first-match"))
+			Expect(ep.Rules).To(HaveLen(3))
+			Expect(ep.Rules[0].Actions).To(HaveLen(2))
+			Expect(ep.Rules[0].Actions[0].Type).To(Equal("forward"))
+			Expect(ep.Rules[0].Actions[1]).To(Equal(wafAction))
+			Expect(ep.Rules[1].Conditions[0].Event).To(Equal(PolicyEventResponse))
+			Expect(ep.Rules[1].Actions).NotTo(ContainElement(wafAction),
+				"WAF policy should not be enforced by the response rules")
+			Expect(ep.Rules[2]).To(Equal(&as3Rule{Name: "waf_default", Actions: []*as3Action{wafAction}}),
+				"WAF policy should be enforced on the requests not forwarded")
+
+			// WAF policy is enforced on the virtual without a forwarding policy
+			rsCfg.Policies = nil
+			sharedApp = as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc = sharedApp["crd_vs_172.13.14.18"].(*as3Service)
+			Expect(svc.WAF).To(Equal(&as3ResourcePointer{BigIP: "/Common/WAF_Policy"}))
+
+			Expect(rsCfg.Virtual.setForwardingPolicyOrder("first")).To(MatchError(
+				"invalid forwardingPolicyOrder \"first\", supported values are after-waf and before-waf"))
+		})
+		It("ALPN protocols", func() {
+			svcName := "crd_vs_172.13.14.19"
+			svc := &as3Service{Class: "Service_HTTP"}
+			sharedApp := as3Application{svcName: svc}
+			prof := CustomProfile{
+				Name:          "clientssl",
+				Context:       CustomProfileClient,
+				Cert:          "crthash",
+				Key:           "keyhash",
+				ALPNProtocols: []string{ALPNHTTP11},
+			}
+			Expect(createUpdateTLSServer(prof, svcName, sharedApp)).To(BeTrue())
+			Expect(svc.ProfileHTTP2).To(BeNil(), "HTTP/2 profile should not be attached without h2")
+
+			prof.ALPNProtocols = []string{ALPNHTTP2, ALPNHTTP11}
+			Expect(createUpdateTLSServer(prof, svcName, sharedApp)).To(BeTrue())
+			Expect(svc.ProfileHTTP2).To(Equal(&as3ResourcePointer{Use: svcName + "_http2_alpn"}),
+				"HTTP/2 profile activated by ALPN not attached")
+			Expect(sharedApp[svcName+"_http2_alpn"]).To(Equal(&as3HTTP2Profile{
+				Class:          "HTTP2_Profile",
+				ActivationMode: "alpn",
+			}))
+
+			// HTTP/2 profile from the Policy is retained
+			svc = &as3Service{Class: "Service_HTTP", ProfileHTTP2: &as3ResourcePointer{BigIP: "/Common/http2"}}
+			sharedApp = as3Application{svcName: svc}
+			Expect(createUpdateTLSServer(prof, svcName, sharedApp)).To(BeTrue())
+			Expect(svc.ProfileHTTP2).To(Equal(&as3ResourcePointer{BigIP: "/Common/http2"}))
+			Expect(sharedApp).NotTo(HaveKey(svcName + "_http2_alpn"))
+		})
+		It("TLS options", func() {
+			svcName := "crd_vs_172.13.14.19"
+			svc := &as3Service{Class: "Service_HTTP"}
+			sharedApp := as3Application{svcName: svc}
+			prof := CustomProfile{
+				Name:        "clientssl",
+				Context:     CustomProfileClient,
+				Cert:        "crthash",
+				Key:         "keyhash",
+				CipherGroup: "/Common/f5-default",
+				TLSOptions:  []string{TLSOptionNoSSLv3, TLSOptionNoTLSv1},
+			}
+			Expect(createUpdateTLSServer(prof, svcName, sharedApp)).To(BeTrue())
+			tlsServer := sharedApp[svcName+"_tls_server"].(*as3TLSServer)
+			disabled := false
+			Expect(tlsServer.SSL3Enabled).To(Equal(&disabled), "SSLv3 should be disabled")
+			Expect(tlsServer.TLS1_0Enabled).To(Equal(&disabled), "TLS 1.0 should be disabled")
+			Expect(tlsServer.TLS1_1Enabled).To(BeNil(), "TLS 1.1 should be left to the default")
+			Expect(tlsServer.TLS1_3Enabled).To(BeTrue(), "TLS 1.3 should be enabled with the cipher group")
+
+			// options of another profile on the same TLSServer are added
+			prof.Name = "clientssl2"
+			prof.TLSOptions = []string{TLSOptionNoTLSv1_1, TLSOptionNoTLSv1_3}
+			Expect(createUpdateTLSServer(prof, svcName, sharedApp)).To(BeTrue())
+			Expect(tlsServer.TLS1_0Enabled).To(Equal(&disabled), "TLS 1.0 should remain disabled")
+			Expect(tlsServer.TLS1_1Enabled).To(Equal(&disabled), "TLS 1.1 should be disabled")
+			Expect(tlsServer.TLS1_3Enabled).To(BeFalse(), "TLS 1.3 should be disabled")
+		})
+		It("SNI certificates", func() {
+			svcName := "crd_vs_172.13.14.20"
+			sharedApp := as3Application{svcName: &as3Service{Class: "Service_HTTP"}}
+			prof := CustomProfile{
+				Name:           "foo-clientssl",
+				Context:        CustomProfileClient,
+				Cert:           "crthash",
+				Key:            "keyhash",
+				SNIServerNames: []string{"a.foo.com", "b.foo.com"},
+			}
+			Expect(createUpdateTLSServer(prof, svcName, sharedApp)).To(BeTrue())
+			prof.Name = "bar-clientssl"
+			prof.SNIServerNames = []string{"bar.com"}
+			prof.SNIDefault = true
+			Expect(createUpdateTLSServer(prof, svcName, sharedApp)).To(BeTrue())
+			tlsServer := sharedApp[svcName+"_tls_server"].(*as3TLSServer)
+			Expect(tlsServer.Certificates).To(Equal([]as3TLSServerCertificates{
+				{Certificate: "bar-clientssl", MatchToSNI: "bar.com"},
+				{Certificate: "foo-clientssl", MatchToSNI: "a.foo.com"},
+				{Certificate: "foo-clientssl", MatchToSNI: "b.foo.com"},
+			}), "Certificate should be matched to each of the server names with the SNI default first")
+
+			// the catch-all certificate is not matched to a server name
+			prof.Name = "default-clientssl"
+			prof.SNIServerNames = nil
+			prof.SNIDefault = false
+			Expect(createUpdateTLSServer(prof, svcName, sharedApp)).To(BeTrue())
+			Expect(tlsServer.Certificates[3]).To(Equal(as3TLSServerCertificates{Certificate: "default-clientssl"}))
+		})
+		It("Disabled virtual", func() {
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.Active = true
+			rsCfg.MetaData.ResourceType = TransportServer
+			rsCfg.Virtual.Name = "crd_ts_172.13.14.17"
+			rsCfg.Virtual.Mode = "standard"
+			rsCfg.Virtual.IpProtocol = "tcp"
+			rsCfg.Virtual.Destination = "/test/172.13.14.7:1600"
+
+			sharedApp := as3Application{}
+			createTransportServiceDecl(rsCfg, sharedApp)
+			disabled := false
+			Expect(sharedApp["crd_ts_172.13.14.17"].(*as3Service).Enable).To(Equal(&disabled),
+				"Disabled virtual not posted as disabled")
+
+			rsCfg.Virtual.Enabled = true
+			sharedApp = as3Application{}
+			createTransportServiceDecl(rsCfg, sharedApp)
+			Expect(sharedApp["crd_ts_172.13.14.17"].(*as3Service).Enable).To(BeNil())
+		})
 		It("Delete partition", func() {
 			config := ResourceConfigRequest{
 				ltmConfig:          make(LTMConfig),
@@ -291,6 +790,37 @@ var _ = Describe("Backend Tests", func() {
 			Expect(agent.incomingTenantDeclMap["default"]).To(Equal(deletedTenantDecl), "Failed to Create AS3 Declaration for deleted tenant")
 			Expect(adc["default"]).To(Equal(map[string]interface{}(deletedTenantDecl)), "Failed to Create AS3 Declaration for deleted tenant")
 		})
+		It("Tenant post order", func() {
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.Active = true
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Name = "crd_vs_172.13.14.15"
+			rsCfg.Virtual.Destination = "172.13.14.15:80"
+			rsCfg.Pools = Pools{
+				Pool{
+					Name:    "pool1",
+					Members: []PoolMember{mem1, mem2},
+				},
+			}
+
+			config := ResourceConfigRequest{
+				ltmConfig:          make(LTMConfig),
+				shareNodes:         true,
+				gtmConfig:          GTMConfig{},
+				defaultRouteDomain: 1,
+			}
+			config.ltmConfig["old"] = &PartitionConfig{make(ResourceMap), 2}
+			config.ltmConfig["new"] = &PartitionConfig{make(ResourceMap), 1}
+			config.ltmConfig["new"].ResourceMap["crd_vs_172.13.14.15"] = rsCfg
+			config.ltmConfig["high"] = &PartitionConfig{make(ResourceMap), 3}
+			config.ltmConfig["high"].ResourceMap["crd_vs_172.13.14.15"] = rsCfg
+			config.ltmConfig["default"] = &PartitionConfig{make(ResourceMap), 0}
+			config.ltmConfig["default"].ResourceMap["crd_vs_172.13.14.15"] = rsCfg
+
+			agent.createTenantAS3Declaration(config)
+			Expect(agent.getTenantPostOrder()).To(Equal([][]string{{"high"}, {"new"}, {"default"}, {"old"}}),
+				"Emptied tenant should be posted at last irrespective of its priority")
+		})
 	})
 
 	It("DNS Config", func() {
@@ -423,6 +953,60 @@ var _ = Describe("Backend Tests", func() {
 			Expect(ok).To(BeTrue())
 			Expect(val).NotTo(BeNil())
 		})
+
+		It("Persistence profile reference", func() {
+			rsCfg := &ResourceConfig{}
+			rsCfg.Virtual.Name = "crd_1_2_3_4_80"
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.Virtual.PoolName = "default_pool"
+			rsCfg.Virtual.PersistenceProfile = "/Common/my-persist"
+			sharedApp := as3Application{}
+			createTransportServiceDecl(rsCfg, sharedApp)
+			Expect(*sharedApp[rsCfg.Virtual.Name].(*as3Service).PersistenceMethods).To(Equal(
+				[]as3MultiTypeParam{&as3ResourcePointer{BigIP: "/Common/my-persist"}}),
+				"Full path of the persistence profile not honored")
+
+			// unqualified profile belongs to the partition of the virtual
+			rsCfg.Virtual.PersistenceProfile = "my-persist"
+			Expect(getPersistenceMethod(rsCfg)).To(Equal(&as3ResourcePointer{BigIP: "/test/my-persist"}))
+			// basic persistence methods are referred by name
+			rsCfg.Virtual.PersistenceProfile = "source-address"
+			Expect(getPersistenceMethod(rsCfg)).To(Equal("source-address"))
+		})
+
+		It("Network virtual Service Address declaration", func() {
+			rsCfg := &ResourceConfig{
+				ServiceAddress: []ServiceAddress{
+					{
+						ArpEnabled: true,
+						Mask:       "24",
+					},
+				},
+			}
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.Virtual.SetVirtualAddress("10.0.0.0", 0)
+			Expect(rsCfg.Virtual.SetVirtualAddressMask(rsCfg.ServiceAddress)).To(BeNil())
+			Expect(rsCfg.Virtual.VirtualAddress.PrefixLen).To(Equal(24))
+			app := as3Application{}
+			createServiceAddressDecl(rsCfg, "10.0.0.0", app)
+			Expect(app["crd_service_address_10_0_0_0"].(*as3ServiceAddress).VirtualAddress).To(Equal("10.0.0.0/24"))
+
+			// IPv4 netmask
+			rsCfg.ServiceAddress[0].Mask = "255.255.255.0"
+			Expect(rsCfg.Virtual.SetVirtualAddressMask(rsCfg.ServiceAddress)).To(BeNil())
+			Expect(rsCfg.Virtual.VirtualAddress.PrefixLen).To(Equal(24))
+			// host bits are set
+			rsCfg.Virtual.SetVirtualAddress("10.0.0.1", 0)
+			Expect(rsCfg.Virtual.SetVirtualAddressMask(rsCfg.ServiceAddress)).NotTo(BeNil())
+			// mask doesn't match the address family
+			rsCfg.Virtual.SetVirtualAddress("2001:db8::", 0)
+			Expect(rsCfg.Virtual.SetVirtualAddressMask(rsCfg.ServiceAddress)).NotTo(BeNil())
+			rsCfg.ServiceAddress[0].Mask = "64"
+			Expect(rsCfg.Virtual.SetVirtualAddressMask(rsCfg.ServiceAddress)).To(BeNil())
+			Expect(rsCfg.Virtual.VirtualAddress.PrefixLen).To(Equal(64))
+			rsCfg.Virtual.SetVirtualAddress("10.0.0.0", 0)
+			Expect(rsCfg.Virtual.SetVirtualAddressMask(rsCfg.ServiceAddress)).NotTo(BeNil())
+		})
 	})
 
 	Describe("JSON comparision of AS3 declaration", func() {
@@ -18,6 +18,7 @@ package controller
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -69,10 +70,23 @@ const (
 	ConfigMap = "ConfigMap"
 	// Route is OpenShift Route
 	Route = "Route"
+	// K8sSecret is k8s native Secret resource
+	K8sSecret = "Secret"
+	// RouteGroup is a group of OpenShift Routes sharing the virtuals
+	RouteGroup = "RouteGroup"
 
 	NodePort = "nodeport"
+	Cluster  = "cluster"
 
 	PolicyControlForward = "forwarding"
+	// PolicyControlResponse marks a policy having rules on HTTP response events
+	PolicyControlResponse = "response"
+	// Events on which the policy rules are evaluated
+	PolicyEventRequest  = "request"
+	PolicyEventResponse = "response"
+	// Evaluation order of the forwarding policy relative to the WAF policy
+	ForwardingPolicyAfterWAF  = "after-waf"
+	ForwardingPolicyBeforeWAF = "before-waf"
 	// Namespace for IPAM CRD
 	IPAMNamespace = "kube-system"
 	//Name for ipam CR
@@ -90,12 +104,29 @@ const (
 	LBServiceIPAMLabelAnnotation  = "cis.f5.com/ipamLabel"
 	HealthMonitorAnnotation       = "cis.f5.com/health"
 	LBServicePolicyNameAnnotation = "cis.f5.com/policyName"
+	// VirtualMetadataAnnotationPrefix annotations are added as metadata of the virtual
+	VirtualMetadataAnnotationPrefix = "cis.f5.com/metadata."
+	// BackendBalanceAnnotationPrefix annotation sets the balance of the route backend named by the suffix
+	BackendBalanceAnnotationPrefix = "virtual-server.f5.com/balance."
+	// PoolMemberSessionAnnotation sets the session state of the pool members keyed by their address
+	PoolMemberSessionAnnotation = "cis.f5.com/pool-member-session"
+
+	// Session states of the pool member
+	MemberSessionEnabled      = "user-enabled"
+	MemberSessionDisabled     = "user-disabled"
+	MemberSessionForceOffline = "force-offline"
 
 	//Antrea NodePortLocal support
 	NPLPodAnnotation = "nodeportlocal.antrea.io"
 	NPLSvcAnnotation = "nodeportlocal.antrea.io/enabled"
 	NodePortLocal    = "nodeportlocal"
 
+	// DefaultMaxResourceRetries is the default number of retries of a failed resource, with the
+	// exponential back-off of the resource queues a resource is retried for about an hour
+	DefaultMaxResourceRetries = 20
+	// initStateRequeueDelay is the delay of the resources deferred until the services are processed at init time
+	initStateRequeueDelay = 100 * time.Millisecond
+
 	// AS3 Related constants
 	as3SupportedVersion = 3.18
 	//Update as3Version,defaultAS3Version,defaultAS3Build while updating AS3 validation schema.
@@ -108,22 +139,38 @@ const (
 func NewController(params Params) *Controller {
 
 	ctlr := &Controller{
-		namespaces:         make(map[string]bool),
-		crInformers:        make(map[string]*CRInformer),
-		nsInformers:        make(map[string]*NSInformer),
-		resources:          NewResourceStore(),
-		Agent:              params.Agent,
-		PoolMemberType:     params.PoolMemberType,
-		UseNodeInternal:    params.UseNodeInternal,
-		Partition:          params.Partition,
-		initState:          true,
-		SSLContext:         make(map[string]*v1.Secret),
-		dgPath:             strings.Join([]string{DEFAULT_PARTITION, "Shared"}, "/"),
-		shareNodes:         params.ShareNodes,
-		eventNotifier:      apm.NewEventNotifier(nil),
-		defaultRouteDomain: params.DefaultRouteDomain,
-		mode:               params.Mode,
-		namespaceLabel:     params.NamespaceLabel,
+		namespaces:             make(map[string]bool),
+		crInformers:            make(map[string]*CRInformer),
+		nsInformers:            make(map[string]*NSInformer),
+		resources:              NewResourceStore(),
+		Agent:                  params.Agent,
+		PoolMemberType:         params.PoolMemberType,
+		UseNodeInternal:        params.UseNodeInternal,
+		Partition:              params.Partition,
+		initState:              true,
+		SSLContext:             make(map[string]*v1.Secret),
+		shareNodes:             params.ShareNodes,
+		eventNotifier:          apm.NewEventNotifier(nil),
+		defaultRouteDomain:     params.DefaultRouteDomain,
+		mode:                   params.Mode,
+		namespaceLabel:         params.NamespaceLabel,
+		poolMemberDrainTimeout: time.Duration(params.PoolMemberDrainTimeout) * time.Second,
+		routeGroupDeleteGrace:  time.Duration(params.RouteGroupDeleteGrace) * time.Second,
+		clusterName:            params.ClusterName,
+		poolMetadataLabels:     params.PoolMetadataLabels,
+		maxResourceRetries:     params.MaxResourceRetries,
+		poolPartitions:         params.PoolPartitions,
+		dryRun:                 params.DryRun,
+		tlsProfileFailOpen:     params.TLSProfileFailOpen,
+		strictCertificateHost:  params.StrictCertificateHost,
+		syncComplete:           params.SyncComplete,
+	}
+	if ctlr.maxResourceRetries <= 0 {
+		ctlr.maxResourceRetries = DefaultMaxResourceRetries
+	}
+	ctlr.resources.setPoolPartitions(ctlr.poolPartitions)
+	if ctlr.Agent != nil {
+		ctlr.Agent.setLastSyncTimeSource(ctlr.LastSyncTime)
 	}
 
 	log.Debug("Controller Created")
@@ -132,8 +179,13 @@ func NewController(params Params) *Controller {
 	case OpenShiftMode:
 		ctlr.routeSpecCMKey = params.RouteSpecConfigmap
 		ctlr.routeLabel = params.RouteLabel
+		ctlr.routerName = params.RouterName
+		if ctlr.routerName == "" {
+			ctlr.routerName = F5RouterName
+		}
 		var processedHostPath ProcessedHostPath
 		processedHostPath.processedHostPathMap = make(map[string]metaV1.Time)
+		processedHostPath.processedHostPathPriority = make(map[string]int)
 		ctlr.processedHostPath = &processedHostPath
 		fallthrough
 	case KubernetesMode:
@@ -181,6 +233,11 @@ func NewController(params Params) *Controller {
 		}
 	}
 
+	// the namespaces of the namespace label informers are looked up there
+	if len(ctlr.poolMetadataLabels) > 0 && ctlr.namespaceLabel == "" {
+		ctlr.createPoolMetadataNamespaceInformer()
+	}
+
 	if err3 := ctlr.setupInformers(); err3 != nil {
 		log.Error("Failed to Setup Informers")
 	}
@@ -387,6 +444,9 @@ func (ctlr *Controller) Start() {
 	for _, nsInf := range ctlr.nsInformers {
 		nsInf.start()
 	}
+	if ctlr.poolMetadataNsInformer != nil {
+		ctlr.poolMetadataNsInformer.start()
+	}
 
 	if ctlr.ipamCli != nil {
 		go ctlr.ipamCli.Start()
@@ -425,6 +485,9 @@ func (ctlr *Controller) Stop() {
 	for _, nsInf := range ctlr.nsInformers {
 		nsInf.stop()
 	}
+	if ctlr.poolMetadataNsInformer != nil {
+		ctlr.poolMetadataNsInformer.stop()
+	}
 
 	ctlr.nodePoller.Stop()
 	ctlr.Agent.Stop()
@@ -432,3 +495,19 @@ func (ctlr *Controller) Stop() {
 		ctlr.ipamCli.Stop()
 	}
 }
+
+// LTMConfigHandler serves the sanitized LTM configuration of the controller
+func (ctlr *Controller) LTMConfigHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := ctlr.resources.DumpLTMConfig()
+		if err != nil {
+			log.Errorf("Unable to dump LTM config: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
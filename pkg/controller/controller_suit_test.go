@@ -42,7 +42,7 @@ type (
 
 func newMockController() *mockController {
 	return &mockController{
-		Controller:    &Controller{},
+		Controller:    &Controller{resources: NewResourceStore()},
 		mockResources: make(map[string][]interface{}),
 	}
 }
@@ -4,4 +4,30 @@ type RouteAnnotation string
 
 const (
 	URLRewriteAnnotation RouteAnnotation = "virtual-server.f5.com/rewrite-target-url"
+	// IRulesAnnotation is a comma separated list of iRules appended to the iRules of the route group
+	IRulesAnnotation RouteAnnotation = "virtual-server.f5.com/irules"
+	// RoutePriorityAnnotation is an integer priority of the route, the route with the highest
+	// priority owns a host path regardless of its age
+	RoutePriorityAnnotation RouteAnnotation = "virtual-server.f5.com/route-priority"
+	// RedirectTargetAnnotation is the host and optional path to which the insecure requests of
+	// the route are redirected instead of the same host over https
+	RedirectTargetAnnotation RouteAnnotation = "virtual-server.f5.com/redirect-target"
+	// ABDeploymentModeAnnotation selects how the traffic of an A/B route is split across its backends,
+	// either by the A/B iRule and data group or by the ratio of the members of a single pool
+	ABDeploymentModeAnnotation RouteAnnotation = "virtual-server.f5.com/ab-deployment-mode"
+	// StripPathPrefixAnnotation is a prefix of the route path removed from the request path before
+	// the request is forwarded to the backends
+	StripPathPrefixAnnotation RouteAnnotation = "virtual-server.f5.com/strip-path-prefix"
+	// InsertRequestHeadersAnnotation is a JSON list of the name and value of the request headers
+	// inserted before the request is forwarded to the backends
+	InsertRequestHeadersAnnotation RouteAnnotation = "virtual-server.f5.com/insert-request-headers"
+	// RemoveRequestHeadersAnnotation is a JSON list of the names of the request headers removed before
+	// the request is forwarded to the backends
+	RemoveRequestHeadersAnnotation RouteAnnotation = "virtual-server.f5.com/remove-request-headers"
+)
+
+// A/B deployment modes of the routes
+const (
+	ABDeploymentModeDataGroup = "data-group"
+	ABDeploymentModeRatio     = "ratio"
 )
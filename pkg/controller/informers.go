@@ -102,6 +102,10 @@ func (crInfr *CRInformer) start() {
 		go crInfr.podInformer.Run(crInfr.stopCh)
 		cacheSyncs = append(cacheSyncs, crInfr.podInformer.HasSynced)
 	}
+	if crInfr.secretInformer != nil {
+		go crInfr.secretInformer.Run(crInfr.stopCh)
+		cacheSyncs = append(cacheSyncs, crInfr.secretInformer.HasSynced)
+	}
 	cache.WaitForNamedCacheSync(
 		"F5 CIS CRD Controller",
 		crInfr.stopCh,
@@ -121,6 +125,10 @@ func (nrInfr *NRInformer) start() {
 		cacheSyncs = append(cacheSyncs, nrInfr.routeInformer.HasSynced)
 		cacheSyncs = append(cacheSyncs, nrInfr.cmInformer.HasSynced)
 	}
+	if nrInfr.secretInformer != nil {
+		go nrInfr.secretInformer.Run(nrInfr.stopCh)
+		cacheSyncs = append(cacheSyncs, nrInfr.secretInformer.HasSynced)
+	}
 	cache.WaitForNamedCacheSync(
 		"F5 CIS Ingress Controller",
 		nrInfr.stopCh,
@@ -363,6 +371,17 @@ func (ctlr *Controller) newNamespacedCustomResourceInformer(
 			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
 		)
 	}
+	crInf.secretInformer = cache.NewSharedIndexInformer(
+		cache.NewFilteredListWatchFromClient(
+			restClientv1,
+			"secrets",
+			namespace,
+			everything,
+		),
+		&corev1.Secret{},
+		resyncPeriod,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
 	return crInf
 }
 
@@ -416,6 +435,20 @@ func (ctlr *Controller) newNamespacedNativeResourceInformer(
 			resyncPeriod,
 			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
 		)
+
+		nrInformer.secretInformer = cache.NewSharedIndexInformer(
+			cache.NewFilteredListWatchFromClient(
+				restClientv1,
+				"secrets",
+				namespace,
+				func(options *metav1.ListOptions) {
+					options.LabelSelector = ""
+				},
+			),
+			&corev1.Secret{},
+			resyncPeriod,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+		)
 	}
 
 	return nrInformer
@@ -549,6 +582,15 @@ func (ctlr *Controller) addCustomResourceEventHandlers(crInf *CRInformer) {
 			},
 		)
 	}
+
+	if crInf.secretInformer != nil {
+		crInf.secretInformer.AddEventHandler(
+			&cache.ResourceEventHandlerFuncs{
+				UpdateFunc: func(old, cur interface{}) { ctlr.enqueueUpdatedSecret(old, cur) },
+				DeleteFunc: func(obj interface{}) { ctlr.enqueueDeletedSecret(obj) },
+			},
+		)
+	}
 }
 
 func (ctlr *Controller) addEssentialResourceEventHandlers(esInf *EssentialInformer) {
@@ -595,6 +637,15 @@ func (ctlr *Controller) addNativeResourceEventHandlers(nrInf *NRInformer) {
 			},
 		)
 	}
+
+	if nrInf.secretInformer != nil {
+		nrInf.secretInformer.AddEventHandler(
+			&cache.ResourceEventHandlerFuncs{
+				UpdateFunc: func(old, cur interface{}) { ctlr.enqueueUpdatedSecret(old, cur) },
+				DeleteFunc: func(obj interface{}) { ctlr.enqueueDeletedSecret(obj) },
+			},
+		)
+	}
 }
 
 func (ctlr *Controller) getEventHandlerForIPAM() *cache.ResourceEventHandlerFuncs {
@@ -1057,6 +1108,57 @@ func (ctlr *Controller) enqueueEndpoints(obj interface{}, event string) {
 	}
 }
 
+func (ctlr *Controller) enqueueDeletedSecret(obj interface{}) {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return
+		}
+		if secret, ok = tombstone.Obj.(*corev1.Secret); !ok {
+			return
+		}
+	}
+	log.Debugf("Enqueueing Deleted Secret: %v/%v", secret.Namespace, secret.Name)
+	key := &rqKey{
+		namespace: secret.ObjectMeta.Namespace,
+		kind:      K8sSecret,
+		rscName:   secret.ObjectMeta.Name,
+		rsc:       secret,
+		event:     Delete,
+	}
+
+	switch ctlr.mode {
+	case KubernetesMode, OpenShiftMode:
+		ctlr.nativeResourceQueue.Add(key)
+	case CustomResourceMode:
+		ctlr.rscQueue.Add(key)
+	}
+}
+
+func (ctlr *Controller) enqueueUpdatedSecret(old, cur interface{}) {
+	oldSecret := old.(*corev1.Secret)
+	newSecret := cur.(*corev1.Secret)
+	if reflect.DeepEqual(oldSecret.Data, newSecret.Data) {
+		return
+	}
+	log.Debugf("Enqueueing Secret: %v/%v", newSecret.Namespace, newSecret.Name)
+	key := &rqKey{
+		namespace: newSecret.ObjectMeta.Namespace,
+		kind:      K8sSecret,
+		rscName:   newSecret.ObjectMeta.Name,
+		rsc:       cur,
+		event:     Update,
+	}
+
+	switch ctlr.mode {
+	case KubernetesMode, OpenShiftMode:
+		ctlr.nativeResourceQueue.Add(key)
+	case CustomResourceMode:
+		ctlr.rscQueue.Add(key)
+	}
+}
+
 func (ctlr *Controller) enqueueRoute(obj interface{}, event string) {
 	rt := obj.(*routeapi.Route)
 	log.Debugf("Enqueueing Route: %v", rt)
@@ -1088,6 +1190,18 @@ func (ctlr *Controller) enqueueUpdatedRoute(old, cur interface{}) {
 	ctlr.nativeResourceQueue.Add(key)
 }
 
+// enqueueRouteGroup enqueues the route group to process its routes
+func (ctlr *Controller) enqueueRouteGroup(routeGroup string) {
+	log.Debugf("Enqueueing RouteGroup: %v", routeGroup)
+	key := &rqKey{
+		kind:    RouteGroup,
+		rscName: routeGroup,
+		rsc:     routeGroup,
+		event:   Update,
+	}
+	ctlr.nativeResourceQueue.Add(key)
+}
+
 func (ctlr *Controller) enqueueConfigmap(obj interface{}, event string) {
 	cm := obj.(*corev1.ConfigMap)
 
@@ -1217,6 +1331,7 @@ func (ctlr *Controller) createNamespaceLabeledInformer(label string) error {
 	ctlr.nsInformers[label].nsInformer.AddEventHandlerWithResyncPeriod(
 		&cache.ResourceEventHandlerFuncs{
 			AddFunc:    func(obj interface{}) { ctlr.enqueueNamespace(obj) },
+			UpdateFunc: func(oldObj, newObj interface{}) { ctlr.enqueueUpdatedNamespace(oldObj, newObj) },
 			DeleteFunc: func(obj interface{}) { ctlr.enqueueDeletedNamespace(obj) },
 		},
 		resyncPeriod,
@@ -1225,6 +1340,26 @@ func (ctlr *Controller) createNamespaceLabeledInformer(label string) error {
 	return nil
 }
 
+// createPoolMetadataNamespaceInformer creates the informer of all the namespaces for the pool metadata,
+// it has no event handlers as the namespaces are only looked up from its cache
+func (ctlr *Controller) createPoolMetadataNamespaceInformer() {
+	restClientv1 := ctlr.kubeClient.CoreV1().RESTClient()
+	ctlr.poolMetadataNsInformer = &NSInformer{
+		stopCh: make(chan struct{}),
+		nsInformer: cache.NewSharedIndexInformer(
+			cache.NewFilteredListWatchFromClient(
+				restClientv1,
+				"namespaces",
+				"",
+				func(options *metav1.ListOptions) {},
+			),
+			&corev1.Namespace{},
+			0*time.Second,
+			cache.Indexers{},
+		),
+	}
+}
+
 func (ctlr *Controller) enqueueNamespace(obj interface{}) {
 	ns := obj.(*corev1.Namespace)
 	log.Infof("Enqueueing Namespace: %v", ns)
@@ -1243,6 +1378,27 @@ func (ctlr *Controller) enqueueNamespace(obj interface{}) {
 	}
 }
 
+// enqueueUpdatedNamespace enqueues the namespace when its labels change, the labels place
+// the virtuals of its routes in the partitions mapped to the partition labels of the route group
+func (ctlr *Controller) enqueueUpdatedNamespace(oldObj, newObj interface{}) {
+	oldNs := oldObj.(*corev1.Namespace)
+	newNs := newObj.(*corev1.Namespace)
+	if reflect.DeepEqual(oldNs.Labels, newNs.Labels) {
+		return
+	}
+	switch ctlr.mode {
+	case KubernetesMode, OpenShiftMode:
+		log.Infof("Enqueueing Namespace: %v on Update", newNs)
+		ctlr.nativeResourceQueue.Add(&rqKey{
+			namespace: newNs.ObjectMeta.Namespace,
+			kind:      Namespace,
+			rscName:   newNs.ObjectMeta.Name,
+			rsc:       newObj,
+			event:     Update,
+		})
+	}
+}
+
 func (ctlr *Controller) enqueueDeletedNamespace(obj interface{}) {
 	ns := obj.(*corev1.Namespace)
 	log.Infof("Enqueueing Namespace: %v on Delete", ns)
@@ -2,12 +2,13 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -53,7 +54,8 @@ func (ctlr *Controller) processNativeResource() bool {
 	// During Init time, just accumulate all the poolMembers by processing only services
 	if ctlr.initState && rKey.kind != Namespace {
 		if rKey.kind != Service {
-			ctlr.nativeResourceQueue.AddRateLimited(key)
+			// deferring is not a failure, so it does not count towards the retries of the resource
+			ctlr.nativeResourceQueue.AddAfter(key, initStateRequeueDelay)
 			return true
 		}
 		ctlr.initialSvcCount--
@@ -68,6 +70,7 @@ func (ctlr *Controller) processNativeResource() bool {
 	}
 
 	// Check the type of resource and process accordingly.
+	ctlr.resources.cacheMutex.Lock()
 	switch rKey.kind {
 
 	case Route:
@@ -90,10 +93,18 @@ func (ctlr *Controller) processNativeResource() bool {
 				namespace: route.Namespace,
 				name:      route.Name,
 			})
+			ctlr.deleteSecretResource(resourceRef{
+				kind:      Route,
+				namespace: route.Namespace,
+				name:      route.Name,
+			})
 			// Delete the route entry from hostPath Map
 			ctlr.deleteHostPathMapEntry(route)
 		}
 		if routeGroup, ok := ctlr.resources.invertedNamespaceLabelMap[route.Namespace]; ok {
+			if rscDelete {
+				ctlr.pruneRouteDataGroupRecords(routeGroup, route)
+			}
 			err := ctlr.processRoutes(routeGroup, false)
 			if err != nil {
 				// TODO
@@ -102,6 +113,17 @@ func (ctlr *Controller) processNativeResource() bool {
 			}
 		}
 
+	case RouteGroup:
+		routeGroup := rKey.rsc.(string)
+		if _, ok := ctlr.resources.extdSpecMap[routeGroup]; !ok {
+			break
+		}
+		err := ctlr.processRoutes(routeGroup, false)
+		if err != nil {
+			utilruntime.HandleError(fmt.Errorf("Sync %v failed with %v", key, err))
+			isRetryableError = true
+		}
+
 	case ConfigMap:
 		cm := rKey.rsc.(*v1.ConfigMap)
 		err, ok := ctlr.processConfigMap(cm, rscDelete)
@@ -132,6 +154,9 @@ func (ctlr *Controller) processNativeResource() bool {
 			break
 		}
 		ctlr.updatePoolMembersForRoutes(svc.Namespace)
+	case K8sSecret:
+		secret := rKey.rsc.(*v1.Secret)
+		ctlr.processSecret(secret, rscDelete)
 	case Endpoints:
 		ep := rKey.rsc.(*v1.Endpoints)
 		svc := ctlr.getServiceForEndpoints(ep)
@@ -189,8 +214,13 @@ func (ctlr *Controller) processNativeResource() bool {
 	default:
 		log.Errorf("Unknown resource Kind: %v", rKey.kind)
 	}
+	if rscDelete {
+		// names of the deleted resources don't disambiguate the names of other resources anymore
+		ctlr.resources.pruneAS3NameSources()
+	}
+	ctlr.resources.cacheMutex.Unlock()
 	if isRetryableError {
-		ctlr.nativeResourceQueue.AddRateLimited(key)
+		ctlr.requeueFailedResource(ctlr.nativeResourceQueue, key)
 	} else {
 		ctlr.nativeResourceQueue.Forget(key)
 	}
@@ -203,8 +233,18 @@ func (ctlr *Controller) processNativeResource() bool {
 
 func (ctlr *Controller) postResourceConfigRequest() {
 	if ctlr.resources.isConfigUpdated() {
+		if ctlr.dryRun {
+			// The caches are updated as if the config was posted, so that only
+			// the next changes are logged and nothing is retried. Nothing is synced
+			// with BIG-IP, so the last sync time isn't updated
+			ctlr.logConfigDiff()
+			ctlr.initState = false
+			ctlr.resources.updateCaches()
+			return
+		}
+		// Only the updated partitions are posted, the Agent retains the declarations of the others
 		config := ResourceConfigRequest{
-			ltmConfig:          ctlr.resources.getLTMConfigDeepCopy(),
+			ltmConfig:          ctlr.resources.getUpdatedLTMConfigDeepCopy(),
 			shareNodes:         ctlr.shareNodes,
 			gtmConfig:          ctlr.resources.getGTMConfigCopy(),
 			defaultRouteDomain: ctlr.defaultRouteDomain,
@@ -212,12 +252,43 @@ func (ctlr *Controller) postResourceConfigRequest() {
 		go ctlr.TeemData.PostTeemsData()
 		config.reqId = ctlr.enqueueReq(config)
 		ctlr.Agent.PostConfig(config)
+		ctlr.updateLastSyncTime()
 		ctlr.initState = false
 		ctlr.resources.updateCaches()
 	}
 
 }
 
+// logConfigDiff logs the changes of the LTM config compared to the config last posted
+func (ctlr *Controller) logConfigDiff() {
+	diff := ctlr.resources.getLTMConfigCacheDiff()
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		log.Errorf("[dry-run] Unable to marshal the LTM config diff: %v", err)
+		return
+	}
+	log.Infof("[dry-run] Skipped posting the config, LTM config diff: %s", diffJSON)
+}
+
+// LastSyncTime returns the time the last config batch was posted to the Agent,
+// it is the zero time until the first config batch is posted
+func (ctlr *Controller) LastSyncTime() time.Time {
+	ctlr.syncMutex.RLock()
+	defer ctlr.syncMutex.RUnlock()
+	return ctlr.lastSyncTime
+}
+
+// updateLastSyncTime records the completion of a config batch and notifies the sync complete callback
+func (ctlr *Controller) updateLastSyncTime() {
+	syncTime := time.Now()
+	ctlr.syncMutex.Lock()
+	ctlr.lastSyncTime = syncTime
+	ctlr.syncMutex.Unlock()
+	if ctlr.syncComplete != nil {
+		ctlr.syncComplete(syncTime)
+	}
+}
+
 func (ctlr *Controller) processRoutes(routeGroup string, triggerDelete bool) error {
 	startTime := time.Now()
 	defer func() {
@@ -226,27 +297,124 @@ func (ctlr *Controller) processRoutes(routeGroup string, triggerDelete bool) err
 			routeGroup, endTime.Sub(startTime))
 	}()
 
-	extdSpec, partition := ctlr.resources.getExtendedRouteSpec(routeGroup)
+	extdSpec, _ := ctlr.resources.getExtendedRouteSpec(routeGroup)
 
 	if extdSpec == nil {
 		return fmt.Errorf("extended Route Spec not available for RouteGroup/Namespace: %v", routeGroup)
 	}
 
+	if triggerDelete {
+		ctlr.cancelRouteGroupDelete(routeGroup)
+		// Delete all possible virtuals for this route group without validating its
+		// routes, so that they neither claim host paths nor get their status updated
+		for _, rgPartition := range ctlr.resources.getRouteGroupPartitions(routeGroup) {
+			ctlr.deleteRouteGroupVirtuals(routeGroup, rgPartition, extdSpec)
+		}
+		return nil
+	}
+
 	routes := ctlr.getGroupedRoutes(routeGroup, extdSpec)
 
-	if triggerDelete || len(routes) == 0 {
+	if len(routes) == 0 {
+		if ctlr.deferRouteGroupDelete(routeGroup, extdSpec) {
+			return nil
+		}
 		// Delete all possible virtuals for this route group
+		for _, rgPartition := range ctlr.resources.getRouteGroupPartitions(routeGroup) {
+			ctlr.deleteRouteGroupVirtuals(routeGroup, rgPartition, extdSpec)
+		}
+		return nil
+	}
+	ctlr.cancelRouteGroupDelete(routeGroup)
+
+	// routes are placed in the partition mapped to the labels of their namespace
+	partitionRoutes := make(map[string][]*routeapi.Route)
+	for _, rt := range routes {
+		rtPartition := ctlr.resources.getNamespacePartition(routeGroup, rt.Namespace)
+		partitionRoutes[rtPartition] = append(partitionRoutes[rtPartition], rt)
+	}
+	for _, rgPartition := range ctlr.resources.getRouteGroupPartitions(routeGroup) {
+		if _, ok := partitionRoutes[rgPartition]; !ok {
+			ctlr.deleteRouteGroupVirtuals(routeGroup, rgPartition, extdSpec)
+			continue
+		}
+		ctlr.processRoutesForPartition(routeGroup, rgPartition, extdSpec, partitionRoutes[rgPartition])
+	}
+	return nil
+}
+
+// deferRouteGroupDelete defers the delete of the virtuals of a route group without routes until
+// it has been without routes for the delete grace period, returns whether the delete is deferred
+func (ctlr *Controller) deferRouteGroupDelete(routeGroup string, extdSpec *ExtendedRouteGroupSpec) bool {
+	if ctlr.routeGroupDeleteGrace <= 0 {
+		return false
+	}
+	if pending, ok := ctlr.routeGroupDeletes[routeGroup]; ok {
+		if time.Now().Before(pending.expiry) {
+			return true
+		}
+		delete(ctlr.routeGroupDeletes, routeGroup)
+		return false
+	}
+	if !ctlr.hasRouteGroupVirtuals(routeGroup, extdSpec) {
+		return false
+	}
+	if ctlr.routeGroupDeletes == nil {
+		ctlr.routeGroupDeletes = make(map[string]routeGroupDelete)
+	}
+	log.Debugf("Deferring the delete of the virtuals of RouteGroup %v for %v", routeGroup,
+		ctlr.routeGroupDeleteGrace)
+	ctlr.routeGroupDeletes[routeGroup] = routeGroupDelete{
+		expiry: time.Now().Add(ctlr.routeGroupDeleteGrace),
+		timer: time.AfterFunc(ctlr.routeGroupDeleteGrace, func() {
+			// Requeue the route group to delete its virtuals if it is still without routes
+			ctlr.enqueueRouteGroup(routeGroup)
+		}),
+	}
+	return true
+}
+
+// cancelRouteGroupDelete cancels the pending delete of the virtuals of a route group
+func (ctlr *Controller) cancelRouteGroupDelete(routeGroup string) {
+	if pending, ok := ctlr.routeGroupDeletes[routeGroup]; ok {
+		log.Debugf("Cancelling the delete of the virtuals of RouteGroup %v", routeGroup)
+		pending.timer.Stop()
+		delete(ctlr.routeGroupDeletes, routeGroup)
+	}
+}
+
+// hasRouteGroupVirtuals checks whether any virtual of the route group exists
+func (ctlr *Controller) hasRouteGroupVirtuals(routeGroup string, extdSpec *ExtendedRouteGroupSpec) bool {
+	for _, rgPartition := range ctlr.resources.getRouteGroupPartitions(routeGroup) {
 		for _, portStruct := range getBasicVirtualPorts() {
 			rsName := frameRouteVSName(extdSpec.VServerName, extdSpec.VServerAddr, portStruct)
-			if ctlr.getVirtualServer(partition, rsName) != nil {
-				log.Debugf("Removing virtual %v belongs to RouteGroup: %v",
-					rsName, routeGroup)
-				ctlr.deleteVirtualServer(partition, rsName)
+			if ctlr.getVirtualServer(rgPartition, rsName) != nil {
+				return true
 			}
 		}
-		return nil
 	}
+	return false
+}
+
+// deleteRouteGroupVirtuals deletes all possible virtuals of the route group in the partition
+func (ctlr *Controller) deleteRouteGroupVirtuals(routeGroup, partition string, extdSpec *ExtendedRouteGroupSpec) {
+	for _, portStruct := range getBasicVirtualPorts() {
+		rsName := frameRouteVSName(extdSpec.VServerName, extdSpec.VServerAddr, portStruct)
+		if ctlr.getVirtualServer(partition, rsName) != nil {
+			log.Debugf("Removing virtual %v belongs to RouteGroup: %v",
+				rsName, routeGroup)
+			ctlr.deleteVirtualServer(partition, rsName)
+		}
+	}
+}
 
+// processRoutesForPartition prepares the virtuals of the route group routes placed in the partition
+func (ctlr *Controller) processRoutesForPartition(
+	routeGroup string,
+	partition string,
+	extdSpec *ExtendedRouteGroupSpec,
+	routes []*routeapi.Route,
+) {
 	portStructs := getVirtualPortsForRoutes(routes)
 	vsMap := make(ResourceMap)
 	processingError := false
@@ -255,7 +423,10 @@ func (ctlr *Controller) processRoutes(routeGroup string, triggerDelete bool) err
 		rsName := frameRouteVSName(extdSpec.VServerName, extdSpec.VServerAddr, portStruct)
 
 		// Delete rsCfg if it is HTTP port and the Route does not handle HTTPTraffic
-		if portStruct.protocol == "http" && !doRoutesHandleHTTP(routes) {
+		if portStruct.protocol == "http" && (extdSpec.HTTPSOnly || !doRoutesHandleHTTP(routes)) {
+			if extdSpec.HTTPSOnly {
+				logHTTPSOnlyIgnoredRoutes(routeGroup, routes)
+			}
 			ctlr.deleteVirtualServer(partition, rsName)
 			continue
 		}
@@ -263,7 +434,7 @@ func (ctlr *Controller) processRoutes(routeGroup string, triggerDelete bool) err
 		rsCfg := &ResourceConfig{}
 		rsCfg.Virtual.Partition = partition
 		rsCfg.MetaData.ResourceType = VirtualServer
-		rsCfg.Virtual.Enabled = true
+		rsCfg.Virtual.Enabled = isVirtualEnabled(extdSpec.Enabled)
 		rsCfg.Virtual.Name = rsName
 		rsCfg.MetaData.Protocol = portStruct.protocol
 		rsCfg.Virtual.SetVirtualAddress(
@@ -287,6 +458,7 @@ func (ctlr *Controller) processRoutes(routeGroup string, triggerDelete bool) err
 			log.Errorf("%v", err)
 			break
 		}
+		ctlr.handleDefaultPassthroughPool(rsCfg, routeGroup, extdSpec)
 
 		for _, rt := range routes {
 			rsCfg.MetaData.baseResources[rt.Namespace+"/"+rt.Name] = Route
@@ -320,6 +492,13 @@ func (ctlr *Controller) processRoutes(routeGroup string, triggerDelete bool) err
 		}
 		ctlr.removeUnusedHealthMonitors(rsCfg)
 
+		if !processingError {
+			if err = rsCfg.validateHTTP2Monitors(); err != nil {
+				processingError = true
+				log.Errorf("%v", err)
+			}
+		}
+
 		if processingError {
 			log.Errorf("Unable to Process Route Group %s", routeGroup)
 			break
@@ -328,6 +507,9 @@ func (ctlr *Controller) processRoutes(routeGroup string, triggerDelete bool) err
 		// Save ResourceConfig in temporary Map
 		vsMap[rsName] = rsCfg
 		for _, namespace := range ctlr.resources.extdSpecMap[routeGroup].namespaces {
+			if ctlr.resources.getNamespacePartition(routeGroup, namespace) != partition {
+				continue
+			}
 			if ctlr.PoolMemberType == NodePort {
 				ctlr.updatePoolMembersForNodePort(rsCfg, namespace)
 			} else {
@@ -342,8 +524,50 @@ func (ctlr *Controller) processRoutes(routeGroup string, triggerDelete bool) err
 			rsMap[name] = rscfg
 		}
 	}
+}
 
-	return nil
+// pruneRouteDataGroupRecords removes the host records of a deleted route from the data groups
+// of the route group virtuals, so that they do not outlive the route when its route group
+// fails to be reprocessed
+func (ctlr *Controller) pruneRouteDataGroupRecords(routeGroup string, route *routeapi.Route) {
+	extdSpec, _ := ctlr.resources.getExtendedRouteSpec(routeGroup)
+	if extdSpec == nil {
+		return
+	}
+	partition := ctlr.resources.getNamespacePartition(routeGroup, route.Namespace)
+	host := strings.TrimPrefix(route.Spec.Host, "*")
+	path := route.Spec.Path
+	if path == "" {
+		path = "/"
+	}
+	// records are keyed by the host for passthrough and by host and path otherwise
+	recordNames := []string{host, host + path, strings.TrimSuffix(host+path, "/")}
+	for _, portStruct := range getBasicVirtualPorts() {
+		rsName := frameRouteVSName(extdSpec.VServerName, extdSpec.VServerAddr, portStruct)
+		rsCfg := ctlr.getVirtualServer(partition, rsName)
+		if rsCfg == nil {
+			continue
+		}
+		freshRsCfg := &ResourceConfig{}
+		freshRsCfg.copyConfig(rsCfg)
+		pruned := false
+		for _, nsDg := range freshRsCfg.IntDgMap {
+			dg, ok := nsDg[route.Namespace]
+			if !ok || dg.Type == DataGroupAllowSourceRangeType {
+				continue
+			}
+			for _, name := range recordNames {
+				if dg.RemoveRecord(name) {
+					pruned = true
+				}
+			}
+		}
+		if pruned {
+			log.Debugf("Pruned data group records of deleted route %v/%v from virtual %v",
+				route.Namespace, route.Name, rsName)
+			_ = ctlr.resources.setResourceConfig(partition, rsName, freshRsCfg)
+		}
+	}
 }
 
 func (ctlr *Controller) removeUnusedHealthMonitors(rsCfg *ResourceConfig) {
@@ -381,7 +605,7 @@ func (ctlr *Controller) getGroupedRoutes(routeGroup string, extdSpec *ExtendedRo
 				} else {
 					key = route.Spec.Host + route.Spec.Path
 				}
-				ctlr.updateHostPathMap(route.ObjectMeta.CreationTimestamp, key)
+				ctlr.updateHostPathMap(route.ObjectMeta.CreationTimestamp, getRoutePriority(route), key)
 				assocRoutes = append(assocRoutes, route)
 			}
 		}
@@ -389,6 +613,9 @@ func (ctlr *Controller) getGroupedRoutes(routeGroup string, extdSpec *ExtendedRo
 	return assocRoutes
 }
 
+// cookie name allowed for abPersistence in extended spec
+var abCookieNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
 func (ctlr *Controller) handleRouteGroupExtendedSpec(rsCfg *ResourceConfig, extdSpec *ExtendedRouteGroupSpec) error {
 	if extdSpec.SNAT == "" {
 		rsCfg.Virtual.SNAT = DEFAULT_SNAT
@@ -396,28 +623,113 @@ func (ctlr *Controller) handleRouteGroupExtendedSpec(rsCfg *ResourceConfig, extd
 		rsCfg.Virtual.SNAT = extdSpec.SNAT
 	}
 	rsCfg.Virtual.WAF = extdSpec.WAF
-	rsCfg.Virtual.IRules = extdSpec.IRules
+	// copy the iRules of the route group as the routes append their iRules to the virtual,
+	// mandatory iRules are attached first so that they are always evaluated ahead of the others
+	rsCfg.Virtual.IRules = nil
+	for _, iRule := range extdSpec.MandatoryIRules {
+		rsCfg.Virtual.AddIRule(iRule)
+	}
+	for _, iRule := range extdSpec.IRules {
+		rsCfg.Virtual.AddIRule(iRule)
+	}
+	if extdSpec.ABPersistence != nil {
+		if extdSpec.ABPersistence.CookieName != "" && !abCookieNameRegex.MatchString(extdSpec.ABPersistence.CookieName) {
+			return fmt.Errorf("invalid cookie name %v for abPersistence", extdSpec.ABPersistence.CookieName)
+		}
+		if extdSpec.ABPersistence.TTL < 0 {
+			return fmt.Errorf("invalid ttl %v for abPersistence", extdSpec.ABPersistence.TTL)
+		}
+	}
+	rsCfg.Virtual.ABPersistence = extdSpec.ABPersistence
+	if !isValidHttpRedirectCode(extdSpec.HTTPRedirectCode) {
+		return fmt.Errorf("invalid httpRedirectCode %v, supported codes are 301, 302, 307 and 308", extdSpec.HTTPRedirectCode)
+	}
 
 	for _, hm := range extdSpec.HealthMonitors {
 		if hm.Type == "" {
 			hm.Type = "http"
 		}
-		rsCfg.Monitors = append(
-			rsCfg.Monitors,
-			Monitor{
-				Name:      AS3NameFormatter(hm.Path) + "_monitor",
-				Partition: rsCfg.Virtual.Partition,
-				Interval:  hm.Interval,
-				Type:      hm.Type,
-				Send:      hm.Send,
-				Recv:      hm.Recv,
-				Timeout:   hm.Timeout,
-				Path:      hm.Path,
-			})
+		monitor := Monitor{
+			Name:           AS3NameFormatter(hm.Path) + "_monitor",
+			Partition:      rsCfg.Virtual.Partition,
+			Interval:       hm.Interval,
+			Type:           hm.Type,
+			Send:           hm.Send,
+			Recv:           hm.Recv,
+			Timeout:        hm.Timeout,
+			Path:           hm.Path,
+			AutoHostHeader: hm.AutoHostHeader,
+		}
+		setHTTP2MonitorDefaults(&monitor)
+		// health monitor path is the host followed by the uri path of the route
+		host, uriPath := hm.Path, "/"
+		if idx := strings.Index(hm.Path, "/"); idx != -1 {
+			host, uriPath = hm.Path[:idx], hm.Path[idx:]
+		}
+		if err := setMonitorHostHeaderSend(&monitor, host, uriPath); err != nil {
+			return err
+		}
+		rsCfg.Monitors = append(rsCfg.Monitors, monitor)
 	}
 	return nil
 }
 
+// handleDefaultPassthroughPool adds the default passthrough pool of the route group to its https virtual,
+// the pool is skipped when its service is not found in the route group namespaces of the partition
+func (ctlr *Controller) handleDefaultPassthroughPool(
+	rsCfg *ResourceConfig,
+	routeGroup string,
+	extdSpec *ExtendedRouteGroupSpec,
+) {
+	dpp := extdSpec.DefaultPassthroughPool
+	if dpp == nil || rsCfg.MetaData.Protocol != HTTPS {
+		return
+	}
+	if dpp.Service == "" || dpp.ServiceNamespace == "" {
+		log.Errorf("Ignoring default passthrough pool of route group %v: service and serviceNamespace are required",
+			routeGroup)
+		return
+	}
+	found := false
+	for _, ns := range ctlr.getNamespacesForRouteGroup(routeGroup) {
+		if ns == dpp.ServiceNamespace && ctlr.resources.getNamespacePartition(routeGroup, ns) == rsCfg.Virtual.Partition {
+			found = true
+			break
+		}
+	}
+	if !found {
+		log.Errorf("Ignoring default passthrough pool of route group %v: namespace %v is not in the route group",
+			routeGroup, dpp.ServiceNamespace)
+		return
+	}
+	esInf, ok := ctlr.getNamespacedEssentialInformer(dpp.ServiceNamespace)
+	if !ok {
+		log.Errorf("Ignoring default passthrough pool of route group %v: informer not found for namespace %v",
+			routeGroup, dpp.ServiceNamespace)
+		return
+	}
+	port, err := resource.GetServicePort(dpp.ServiceNamespace, dpp.Service, esInf.svcInformer.GetIndexer(), "",
+		resource.ResourceTypeRoute)
+	if err != nil {
+		log.Errorf("Ignoring default passthrough pool of route group %v: %v", routeGroup, err)
+		return
+	}
+	if dpp.ServicePort != 0 {
+		port = dpp.ServicePort
+	}
+	servicePort := intstr.IntOrString{IntVal: port}
+	pool := Pool{
+		Name:             formatPoolName(dpp.ServiceNamespace, dpp.Service, servicePort, "", ""),
+		Partition:        rsCfg.Virtual.Partition,
+		ServiceName:      dpp.Service,
+		ServiceNamespace: dpp.ServiceNamespace,
+		ServicePort:      servicePort,
+		Metadata:         ctlr.getPoolMetadata(dpp.ServiceNamespace, dpp.Service),
+	}
+	rsCfg.Pools = append(rsCfg.Pools, pool)
+	rsCfg.Virtual.DefaultPassthroughPool = pool.Name
+}
+
 // gets the target port for the route
 // if targetPort is set to IntVal, it's used directly
 // otherwise the port is fetched from the associated service
@@ -470,8 +782,25 @@ func (ctlr *Controller) prepareResourceConfigFromRoute(
 	}
 
 	rsCfg.MetaData.hosts = append(rsCfg.MetaData.hosts, route.Spec.Host)
+	rsCfg.Virtual.SetDescription(route.Namespace, route.Name, route.Annotations)
+	// iRules of the route are attached after the iRules of the route group
+	if iRules, ok := route.Annotations[string(IRulesAnnotation)]; ok {
+		for _, iRule := range strings.Split(iRules, ",") {
+			if iRule = strings.TrimSpace(iRule); iRule != "" {
+				rsCfg.Virtual.AddIRule(iRule)
+			}
+		}
+	}
 
 	backendSvcs := GetRouteBackends(route)
+	// traffic of the A/B route is split by the ratio of the members of a single pool
+	abRatio := isRouteABRatioDeployment(route)
+	var weightedBackends []WeightedBackend
+	if abRatio {
+		weightedBackends, _ = getRouteRatioBackends(route)
+		backendSvcs = backendSvcs[:1]
+		backendSvcs[0].Balance = "ratio-member"
+	}
 
 	for _, bs := range backendSvcs {
 		pool := Pool{
@@ -487,22 +816,24 @@ func (ctlr *Controller) prepareResourceConfigFromRoute(
 			ServiceNamespace: route.Namespace,
 			ServicePort:      servicePort,
 			NodeMemberLabel:  "",
-			Balance:          route.ObjectMeta.Annotations[resource.F5VsBalanceAnnotation],
+			Balance:          bs.Balance,
+			WeightedBackends: weightedBackends,
+			Metadata:         ctlr.getPoolMetadata(route.Namespace, bs.Name),
 		}
 
 		for index, monitor := range rsCfg.Monitors {
 			if strings.HasPrefix(monitor.Path, route.Spec.Host+route.Spec.Path) {
 				// Remove unused health monitors
 				rsCfg.Monitors[index].InUse = true
-				pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: monitor.Name})
+				pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: JoinBigipPath(rsCfg.Virtual.Partition, monitor.Name)})
 				break
 			}
 		}
 
 		rsCfg.Pools = append(rsCfg.Pools, pool)
 		// skip the policy creation for passthrough termination
-		// skip the policy creation for A/B Deployment
-		if !isPassthroughRoute(route) && !IsRouteABDeployment(route) {
+		// skip the policy creation for A/B Deployment unless it is split by the pool member ratio
+		if !isPassthroughRoute(route) && (!IsRouteABDeployment(route) || abRatio) {
 			rules := ctlr.prepareRouteLTMRules(route, pool.Name, rsCfg.Virtual.AllowSourceRange)
 			if rules == nil {
 				return fmt.Errorf("failed to create LTM Rules")
@@ -550,35 +881,47 @@ func (ctlr *Controller) prepareRouteLTMRules(
 		rl.Actions = append(rl.Actions, rewriteActions...)
 	}
 
-	if strings.HasPrefix(uri, "*.") == true {
-		wildcards[uri] = rl
-	} else {
-		rlMap[uri] = rl
+	if prefix, ok := route.Annotations[string(StripPathPrefixAnnotation)]; ok {
+		if _, ok := route.Annotations[string(URLRewriteAnnotation)]; ok {
+			log.Errorf("Error configuring rule: %v and %v annotations are mutually exclusive",
+				URLRewriteAnnotation, StripPathPrefixAnnotation)
+			return nil
+		}
+		stripActions, err := getStripPathPrefixActions(
+			path,
+			prefix,
+			len(rl.Actions),
+		)
+		if nil != err {
+			log.Errorf("Error configuring rule: %v", err)
+			return nil
+		}
+		rl.Actions = append(rl.Actions, stripActions...)
 	}
 
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	sortrules := func(r ruleMap, rls *Rules, ordinal int) {
-		for _, v := range r {
-			*rls = append(*rls, v)
+	insertHeaders := route.Annotations[string(InsertRequestHeadersAnnotation)]
+	removeHeaders := route.Annotations[string(RemoveRequestHeadersAnnotation)]
+	if insertHeaders != "" || removeHeaders != "" {
+		headerActions, err := getRequestHeaderActions(insertHeaders, removeHeaders)
+		if nil != err {
+			log.Errorf("Error configuring rule: %v", err)
+			return nil
 		}
-		//sort.Sort(sort.Reverse(*rls))
-		for _, v := range *rls {
-			v.Ordinal = ordinal
-			ordinal++
+		// the headers are manipulated ahead of forwarding the request
+		rl.Actions = append(headerActions, rl.Actions...)
+		for i, a := range rl.Actions {
+			a.Name = fmt.Sprintf("%d", i)
 		}
-		wg.Done()
 	}
 
-	rls := Rules{}
-	go sortrules(rlMap, &rls, 0)
-
-	w := Rules{}
-	go sortrules(wildcards, &w, len(rlMap))
-
-	wg.Wait()
+	if strings.HasPrefix(uri, "*.") == true {
+		wildcards[uri] = rl
+	} else {
+		rlMap[uri] = rl
+	}
 
+	rls := assignRuleOrdinals(rlMap, 0)
+	w := assignRuleOrdinals(wildcards, len(rlMap))
 	rls = append(rls, w...)
 	sort.Sort(rls)
 
@@ -591,24 +934,32 @@ func (ctlr *Controller) updatePoolMembersForRoutes(namespace string) {
 		if !ok {
 			continue
 		}
-		extdSpec, partition := ctlr.resources.getExtendedRouteSpec(routeGroup)
+		extdSpec, _ := ctlr.resources.getExtendedRouteSpec(routeGroup)
 		if extdSpec == nil {
 			continue
 		}
+		partition := ctlr.resources.getNamespacePartition(routeGroup, namespace)
 		rsName := frameRouteVSName(extdSpec.VServerName, extdSpec.VServerAddr, portStruct)
 		rsCfg := ctlr.getVirtualServer(partition, rsName)
 		if rsCfg == nil {
 			continue
 		}
-		freshRsCfg := &ResourceConfig{}
-		freshRsCfg.copyConfig(rsCfg)
+		// Only the pool members change with the endpoints, so the rules and policies
+		// of the stored config are shared instead of copying the whole route group config
+		freshRsCfg := rsCfg.copyPoolsConfig()
 		for _, ns := range ctlr.getNamespacesForRouteGroup(routeGroup) {
+			if ctlr.resources.getNamespacePartition(routeGroup, ns) != partition {
+				continue
+			}
 			if ctlr.PoolMemberType == NodePort {
 				ctlr.updatePoolMembersForNodePort(freshRsCfg, ns)
 			} else {
 				ctlr.updatePoolMembersForCluster(freshRsCfg, ns)
 			}
 		}
+		if freshRsCfg.MetaData.Active == rsCfg.MetaData.Active && reflect.DeepEqual(freshRsCfg.Pools, rsCfg.Pools) {
+			continue
+		}
 		_ = ctlr.resources.setResourceConfig(partition, rsName, freshRsCfg)
 	}
 }
@@ -691,6 +1042,118 @@ func (ctlr *Controller) setNamespaceLabelMode(cm *v1.ConfigMap) error {
 	return nil
 }
 
+var partitionNameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*$`)
+
+// validateExtendedSpec checks every route group of the extended spec and returns
+// an error for each route group and field at fault
+func validateExtendedSpec(es extendedSpec, isGlobal bool) []string {
+	var errs []string
+	for i, ergc := range es.ExtendedRouteGroupConfigs {
+		routeGroup := ergc.Namespace
+		if len(ergc.NamespaceLabel) > 0 {
+			routeGroup = ergc.NamespaceLabel
+		}
+		if routeGroup == "" {
+			errs = append(errs, fmt.Sprintf("extendedRouteSpec[%d]: namespace or namespaceLabel is required", i))
+			routeGroup = strconv.Itoa(i)
+		}
+		// vserverAddr may only be left to the local configmap when overriding is allowed
+		allowOverride, _ := strconv.ParseBool(ergc.AllowOverride)
+		if isGlobal && ergc.VServerAddr == "" && !allowOverride {
+			errs = append(errs, fmt.Sprintf("route group %v: vserverAddr is required", routeGroup))
+		}
+		if !isGlobal && ergc.HTTPSOnly {
+			errs = append(errs, fmt.Sprintf("route group %v: httpsOnly can only be set in the global configmap", routeGroup))
+		}
+		if ergc.BigIpPartition != "" && !partitionNameRegex.MatchString(ergc.BigIpPartition) {
+			errs = append(errs, fmt.Sprintf("route group %v: bigIpPartition %v has invalid characters",
+				routeGroup, ergc.BigIpPartition))
+		}
+		if len(ergc.PartitionLabels) > 0 && ergc.NamespaceLabel == "" {
+			errs = append(errs, fmt.Sprintf("route group %v: partitionLabels requires namespaceLabel", routeGroup))
+		}
+		labels := make([]string, 0, len(ergc.PartitionLabels))
+		for label := range ergc.PartitionLabels {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		for _, label := range labels {
+			if partition := ergc.PartitionLabels[label]; !partitionNameRegex.MatchString(partition) {
+				errs = append(errs, fmt.Sprintf("route group %v: partition %v of partitionLabels %v has invalid characters",
+					routeGroup, partition, label))
+			}
+		}
+		tls := ergc.TLS
+		if tls.Reference != "" && tls.Reference != BIGIP && tls.Reference != Secret {
+			errs = append(errs, fmt.Sprintf("route group %v: tls.reference %v must be %v or %v",
+				routeGroup, tls.Reference, BIGIP, Secret))
+		}
+		if (tls.ClientSSL != "" || tls.ServerSSL != "") && tls.Reference == "" {
+			errs = append(errs, fmt.Sprintf("route group %v: tls.reference is required with tls profiles", routeGroup))
+		}
+		// reencrypt termination needs the clientSSL along with the serverSSL
+		if tls.ServerSSL != "" && tls.ClientSSL == "" {
+			errs = append(errs, fmt.Sprintf("route group %v: tls.clientSSL is required with tls.serverSSL", routeGroup))
+		}
+	}
+	if len(es.PoolMemberAddressMaps) > 0 {
+		if !isGlobal {
+			errs = append(errs, "poolMemberAddressMaps can only be set in the global configmap")
+		} else if _, err := newCIDRAddressTranslator(es.PoolMemberAddressMaps); err != nil {
+			errs = append(errs, fmt.Sprintf("poolMemberAddressMaps: %v", err))
+		}
+	}
+	return errs
+}
+
+// setPoolMemberAddressMaps sets the address translator of the pool members from the
+// address maps of the global configmap and returns whether the address maps changed
+func (ctlr *Controller) setPoolMemberAddressMaps(addressMaps []PoolMemberAddressMap) bool {
+	if reflect.DeepEqual(ctlr.resources.poolMemberAddressMaps, addressMaps) {
+		return false
+	}
+	ctlr.resources.poolMemberAddressMaps = addressMaps
+	ctlr.translateMemberAddress = nil
+	if len(addressMaps) > 0 {
+		// the address maps are validated along with the extended spec
+		ctlr.translateMemberAddress, _ = newCIDRAddressTranslator(addressMaps)
+	}
+	return true
+}
+
+// recordConfigMapEvent reports a warning event on the extended spec configmap
+func (ctlr *Controller) recordConfigMapEvent(cm *v1.ConfigMap, reason, message string) {
+	if ctlr.kubeClient == nil {
+		return
+	}
+	now := metav1.Now()
+	event := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: cm.Name + ".",
+			Namespace:    cm.Namespace,
+		},
+		InvolvedObject: v1.ObjectReference{
+			Kind:            ConfigMap,
+			Namespace:       cm.Namespace,
+			Name:            cm.Name,
+			UID:             cm.UID,
+			APIVersion:      "v1",
+			ResourceVersion: cm.ResourceVersion,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           v1.EventTypeWarning,
+		Source:         v1.EventSource{Component: "k8s-bigip-ctlr"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	_, err := ctlr.kubeClient.CoreV1().Events(cm.Namespace).Create(context.TODO(), event, metav1.CreateOptions{})
+	if err != nil {
+		log.Debugf("Unable to record event on configmap %v/%v: %v", cm.Namespace, cm.Name, err)
+	}
+}
+
 func (ctlr *Controller) processConfigMap(cm *v1.ConfigMap, isDelete bool) (error, bool) {
 	startTime := time.Now()
 	defer func() {
@@ -699,20 +1162,33 @@ func (ctlr *Controller) processConfigMap(cm *v1.ConfigMap, isDelete bool) (error
 			cm.Namespace, cm.Name, endTime.Sub(startTime))
 	}()
 
+	// base route config fragments are merged when the global configmap is processed
+	if ctlr.isBaseRouteConfigMap(cm) {
+		return ctlr.processGlobalConfigMapForBaseRouteConfig(cm)
+	}
+
 	ersData := cm.Data
 	es := extendedSpec{}
 	//log.Debugf("GCM: %v", cm.Data)
 	err := yaml.UnmarshalStrict([]byte(ersData["extendedSpec"]), &es)
 	if err != nil {
+		ctlr.recordConfigMapEvent(cm, "InvalidExtendedSpec", err.Error())
 		return fmt.Errorf("invalid extended route spec in configmap: %v/%v error: %v", cm.Namespace, cm.Name, err), false
 	}
 
+	if errs := validateExtendedSpec(es, ctlr.isGlobalExtendedRouteSpec(cm)); len(errs) > 0 {
+		ctlr.recordConfigMapEvent(cm, "InvalidExtendedSpec", strings.Join(errs, "; "))
+		return fmt.Errorf("invalid extended route spec in configmap: %v/%v errors: %v",
+			cm.Namespace, cm.Name, strings.Join(errs, "; ")), false
+	}
+
 	newExtdSpecMap := make(extendedSpecMap, len(ctlr.resources.extdSpecMap))
 
 	if ctlr.isGlobalExtendedRouteSpec(cm) {
 
 		// Get the base route config from the Global ConfigMap
-		ctlr.readBaseRouteConfigFromGlobalCM(es.BaseRouteConfig)
+		ctlr.readBaseRouteConfigFromGlobalCM(cm, es)
+		addressMapsChanged := ctlr.setPoolMemberAddressMaps(es.PoolMemberAddressMaps)
 
 		for rg := range es.ExtendedRouteGroupConfigs {
 			// ergc needs to be created at every iteration, as we are using address inside this container
@@ -743,12 +1219,19 @@ func (ctlr *Controller) processConfigMap(cm *v1.ConfigMap, isDelete bool) (error
 				partition = ctlr.Partition
 			}
 
+			nsPartitions, err := ctlr.getNamespacePartitionsForRouteGroup(routeGroup, ergc.PartitionLabels)
+			if err != nil {
+				ctlr.recordConfigMapEvent(cm, "InvalidExtendedSpec", err.Error())
+				return fmt.Errorf("invalid extended route spec in configmap: %v/%v error: %v", cm.Namespace, cm.Name, err), false
+			}
+
 			newExtdSpecMap[routeGroup] = &extendedParsedSpec{
-				override:   allowOverride,
-				local:      nil,
-				global:     &ergc.ExtendedRouteGroupSpec,
-				namespaces: ctlr.getNamespacesForRouteGroup(routeGroup),
-				partition:  partition,
+				override:            allowOverride,
+				local:               nil,
+				global:              &ergc.ExtendedRouteGroupSpec,
+				namespaces:          ctlr.getNamespacesForRouteGroup(routeGroup),
+				partition:           partition,
+				namespacePartitions: nsPartitions,
 			}
 			if len(newExtdSpecMap[routeGroup].namespaces) > 0 {
 				ctlr.TeemData.Lock()
@@ -767,6 +1250,10 @@ func (ctlr *Controller) processConfigMap(cm *v1.ConfigMap, isDelete bool) (error
 			ctlr.resources.extdSpecMap, newExtdSpecMap, isDelete,
 		)
 
+		// Recompute the host path ownership for the new set of route groups before
+		// reprocessing them, so that stale entries do not reject the rightful owners
+		claimedSpecs := ctlr.rebuildHostPathMap(newExtdSpecMap)
+
 		for _, routeGroupKey := range deletedSpecs {
 			_ = ctlr.processRoutes(routeGroupKey, true)
 			if ctlr.resources.extdSpecMap[routeGroupKey].local == nil {
@@ -785,6 +1272,7 @@ func (ctlr *Controller) processConfigMap(cm *v1.ConfigMap, isDelete bool) (error
 				ctlr.resources.extdSpecMap[routeGroupKey].override = false
 				ctlr.resources.extdSpecMap[routeGroupKey].partition = ""
 				ctlr.resources.extdSpecMap[routeGroupKey].namespaces = []string{}
+				ctlr.resources.extdSpecMap[routeGroupKey].namespacePartitions = nil
 
 			}
 
@@ -792,20 +1280,22 @@ func (ctlr *Controller) processConfigMap(cm *v1.ConfigMap, isDelete bool) (error
 
 		for _, routeGroupKey := range modifiedSpecs {
 			_ = ctlr.processRoutes(routeGroupKey, true)
-			// deleting the bigip partition when partition is changes
-			if ctlr.resources.extdSpecMap[routeGroupKey].partition != newExtdSpecMap[routeGroupKey].partition {
-				if _, ok := ctlr.resources.ltmConfig[ctlr.resources.extdSpecMap[routeGroupKey].partition]; ok {
-					ctlr.resources.updatePartitionPriority(ctlr.resources.extdSpecMap[routeGroupKey].partition, 1)
-				}
-			}
+			oldPartition := ctlr.resources.extdSpecMap[routeGroupKey].partition
 			ctlr.resources.extdSpecMap[routeGroupKey].override = newExtdSpecMap[routeGroupKey].override
 			ctlr.resources.extdSpecMap[routeGroupKey].global = newExtdSpecMap[routeGroupKey].global
 			ctlr.resources.extdSpecMap[routeGroupKey].partition = newExtdSpecMap[routeGroupKey].partition
 			ctlr.resources.extdSpecMap[routeGroupKey].namespaces = newExtdSpecMap[routeGroupKey].namespaces
+			ctlr.resources.extdSpecMap[routeGroupKey].namespacePartitions = newExtdSpecMap[routeGroupKey].namespacePartitions
 			err := ctlr.processRoutes(routeGroupKey, false)
 			if err != nil {
 				log.Errorf("Failed to process RouteGroup: %v with modified extended spec", routeGroupKey)
 			}
+			// when partition changes, prioritize the new partition so that it gets posted before
+			// the old partition, which gets emptied and is posted at last
+			if oldPartition != newExtdSpecMap[routeGroupKey].partition {
+				ctlr.resources.updatePartitionPriority(oldPartition, 0)
+				ctlr.resources.updatePartitionPriority(newExtdSpecMap[routeGroupKey].partition, 1)
+			}
 		}
 
 		for _, routeGroupKey := range updatedSpecs {
@@ -813,6 +1303,7 @@ func (ctlr *Controller) processConfigMap(cm *v1.ConfigMap, isDelete bool) (error
 			ctlr.resources.extdSpecMap[routeGroupKey].global = newExtdSpecMap[routeGroupKey].global
 			ctlr.resources.extdSpecMap[routeGroupKey].partition = newExtdSpecMap[routeGroupKey].partition
 			ctlr.resources.extdSpecMap[routeGroupKey].namespaces = newExtdSpecMap[routeGroupKey].namespaces
+			ctlr.resources.extdSpecMap[routeGroupKey].namespacePartitions = newExtdSpecMap[routeGroupKey].namespacePartitions
 			err := ctlr.processRoutes(routeGroupKey, false)
 			if err != nil {
 				log.Errorf("Failed to process RouteGroup: %v with updated extended spec", routeGroupKey)
@@ -825,12 +1316,38 @@ func (ctlr *Controller) processConfigMap(cm *v1.ConfigMap, isDelete bool) (error
 			ctlr.resources.extdSpecMap[routeGroupKey].global = newExtdSpecMap[routeGroupKey].global
 			ctlr.resources.extdSpecMap[routeGroupKey].partition = newExtdSpecMap[routeGroupKey].partition
 			ctlr.resources.extdSpecMap[routeGroupKey].namespaces = newExtdSpecMap[routeGroupKey].namespaces
+			ctlr.resources.extdSpecMap[routeGroupKey].namespacePartitions = newExtdSpecMap[routeGroupKey].namespacePartitions
 			err := ctlr.processRoutes(routeGroupKey, false)
 			if err != nil {
 				log.Errorf("Failed to process RouteGroup: %v on addition of extended spec", routeGroupKey)
 			}
 		}
 
+		// route groups left unchanged need to be processed as well, when their routes
+		// have taken over the host paths of the routes from removed route groups
+		for _, routeGroupKey := range claimedSpecs {
+			if !containsString(modifiedSpecs, routeGroupKey) && !containsString(updatedSpecs, routeGroupKey) &&
+				!containsString(createdSpecs, routeGroupKey) {
+				err := ctlr.processRoutes(routeGroupKey, false)
+				if err != nil {
+					log.Errorf("Failed to process RouteGroup: %v with claimed host paths", routeGroupKey)
+				}
+			}
+		}
+
+		// the pool members of the unchanged route groups are remapped on a change of the address maps
+		if addressMapsChanged {
+			for routeGroupKey := range newExtdSpecMap {
+				if !containsString(modifiedSpecs, routeGroupKey) && !containsString(updatedSpecs, routeGroupKey) &&
+					!containsString(createdSpecs, routeGroupKey) && !containsString(claimedSpecs, routeGroupKey) {
+					err := ctlr.processRoutes(routeGroupKey, false)
+					if err != nil {
+						log.Errorf("Failed to process RouteGroup: %v with remapped pool member addresses", routeGroupKey)
+					}
+				}
+			}
+		}
+
 	} else if len(es.ExtendedRouteGroupConfigs) > 0 && !ctlr.nativeResourceContext.namespaceLabelMode {
 		ergc := es.ExtendedRouteGroupConfigs[0]
 		if ergc.Namespace != cm.Namespace {
@@ -915,16 +1432,40 @@ func (ctlr *Controller) processConfigMap(cm *v1.ConfigMap, isDelete bool) (error
 	return nil, true
 }
 
-func (ctlr *Controller) readBaseRouteConfigFromGlobalCM(baseRouteConfig BaseRouteConfig) {
+func (ctlr *Controller) readBaseRouteConfigFromGlobalCM(cm *v1.ConfigMap, es extendedSpec) {
+	cmKey := cm.Namespace + "/" + cm.Name
+	fragments := []baseRouteConfigFragment{{source: cmKey, config: es.BaseRouteConfig}}
+	ctlr.resources.baseRouteCMKeys = nil
+	for _, key := range es.BaseRouteConfigMaps {
+		if !strings.Contains(key, "/") {
+			key = cm.Namespace + "/" + key
+		}
+		if key == cmKey {
+			continue
+		}
+		ctlr.resources.baseRouteCMKeys = append(ctlr.resources.baseRouteCMKeys, key)
+		fragment, err := ctlr.getBaseRouteConfigFragment(key)
+		if err != nil {
+			log.Errorf("Ignoring the base route config of configmap %v: %v", key, err)
+			ctlr.recordConfigMapEvent(cm, "InvalidBaseRouteSpec", fmt.Sprintf("configmap %v: %v", key, err))
+			continue
+		}
+		fragments = append(fragments, baseRouteConfigFragment{source: key, config: fragment})
+	}
+	baseRouteConfig, conflicts := mergeBaseRouteConfigs(fragments)
+	if len(conflicts) > 0 {
+		log.Warningf("Conflicting base route config in configmap %v: %v", cmKey, strings.Join(conflicts, "; "))
+		ctlr.recordConfigMapEvent(cm, "ConflictingBaseRouteSpec", strings.Join(conflicts, "; "))
+	}
 
 	//declare default configuration for TLS Ciphers
 	ctlr.resources.baseRouteConfig.TLSCipher = TLSCipher{
-		"1.2",
-		"DEFAULT",
-		"/Common/f5-default",
+		TLSVersion:  "1.2",
+		Ciphers:     "DEFAULT",
+		CipherGroup: "/Common/f5-default",
 	}
 
-	if (baseRouteConfig != BaseRouteConfig{}) {
+	if !reflect.DeepEqual(baseRouteConfig, BaseRouteConfig{}) {
 		if baseRouteConfig.TLSCipher.TLSVersion != "" {
 			ctlr.resources.baseRouteConfig.TLSCipher.TLSVersion = baseRouteConfig.TLSCipher.TLSVersion
 		}
@@ -935,10 +1476,93 @@ func (ctlr *Controller) readBaseRouteConfigFromGlobalCM(baseRouteConfig BaseRout
 		if baseRouteConfig.TLSCipher.CipherGroup != "" {
 			ctlr.resources.baseRouteConfig.TLSCipher.CipherGroup = baseRouteConfig.TLSCipher.CipherGroup
 		}
+		if len(baseRouteConfig.TLSCipher.TLSOptions) > 0 {
+			if err := validateTLSOptions(baseRouteConfig.TLSCipher.TLSOptions); err != nil {
+				log.Errorf("Ignoring tlsOptions of the base route config: %v", err)
+			} else {
+				ctlr.resources.baseRouteConfig.TLSCipher.TLSOptions = baseRouteConfig.TLSCipher.TLSOptions
+			}
+		}
 	}
 
 }
 
+// baseRouteConfigFragment is the base route config given in a configmap
+type baseRouteConfigFragment struct {
+	source string
+	config BaseRouteConfig
+}
+
+// mergeBaseRouteConfigs merges the base route config fragments in order, the keys of a fragment
+// override the ones of the earlier fragments and are reported as conflicts when their values differ
+func mergeBaseRouteConfigs(fragments []baseRouteConfigFragment) (BaseRouteConfig, []string) {
+	var merged BaseRouteConfig
+	var conflicts []string
+	sources := make(map[string]string)
+	mergeKey := func(key, source string, isSet, differs bool, set func()) {
+		if !isSet {
+			return
+		}
+		if prevSource, ok := sources[key]; ok && differs {
+			conflicts = append(conflicts, fmt.Sprintf("%v of configmap %v overrides the one of configmap %v",
+				key, source, prevSource))
+		}
+		sources[key] = source
+		set()
+	}
+	for _, f := range fragments {
+		tlsCipher := f.config.TLSCipher
+		mergeKey("tlsCipher.tlsVersion", f.source, tlsCipher.TLSVersion != "",
+			tlsCipher.TLSVersion != merged.TLSCipher.TLSVersion,
+			func() { merged.TLSCipher.TLSVersion = tlsCipher.TLSVersion })
+		mergeKey("tlsCipher.ciphers", f.source, tlsCipher.Ciphers != "",
+			tlsCipher.Ciphers != merged.TLSCipher.Ciphers,
+			func() { merged.TLSCipher.Ciphers = tlsCipher.Ciphers })
+		mergeKey("tlsCipher.cipherGroup", f.source, tlsCipher.CipherGroup != "",
+			tlsCipher.CipherGroup != merged.TLSCipher.CipherGroup,
+			func() { merged.TLSCipher.CipherGroup = tlsCipher.CipherGroup })
+		mergeKey("tlsCipher.tlsOptions", f.source, len(tlsCipher.TLSOptions) > 0,
+			!reflect.DeepEqual(tlsCipher.TLSOptions, merged.TLSCipher.TLSOptions),
+			func() { merged.TLSCipher.TLSOptions = tlsCipher.TLSOptions })
+	}
+	return merged, conflicts
+}
+
+// getBaseRouteConfigFragment returns the base route config of the configmap referenced by the global configmap
+func (ctlr *Controller) getBaseRouteConfigFragment(cmKey string) (BaseRouteConfig, error) {
+	splits := strings.SplitN(cmKey, "/", 2)
+	cm, err := ctlr.kubeClient.CoreV1().ConfigMaps(splits[0]).Get(context.TODO(), splits[1], metav1.GetOptions{})
+	if err != nil {
+		return BaseRouteConfig{}, err
+	}
+	es := extendedSpec{}
+	if err := yaml.UnmarshalStrict([]byte(cm.Data["extendedSpec"]), &es); err != nil {
+		return BaseRouteConfig{}, err
+	}
+	if len(es.ExtendedRouteGroupConfigs) > 0 || len(es.BaseRouteConfigMaps) > 0 {
+		return BaseRouteConfig{}, fmt.Errorf("only baseRouteSpec is allowed")
+	}
+	return es.BaseRouteConfig, nil
+}
+
+// isBaseRouteConfigMap returns whether the configmap contributes to the base route config of the global configmap
+func (ctlr *Controller) isBaseRouteConfigMap(cm *v1.ConfigMap) bool {
+	return !ctlr.isGlobalExtendedRouteSpec(cm) && containsString(ctlr.resources.baseRouteCMKeys, cm.Namespace+"/"+cm.Name)
+}
+
+// processGlobalConfigMapForBaseRouteConfig processes the global configmap again to merge the updated
+// base route config of the configmap
+func (ctlr *Controller) processGlobalConfigMapForBaseRouteConfig(cm *v1.ConfigMap) (error, bool) {
+	log.Debugf("Base route config of configmap %v/%v changed, processing the global configmap %v",
+		cm.Namespace, cm.Name, ctlr.routeSpecCMKey)
+	splits := strings.Split(ctlr.routeSpecCMKey, "/")
+	globalCM, err := ctlr.kubeClient.CoreV1().ConfigMaps(splits[0]).Get(context.TODO(), splits[1], metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to get the global configmap %v: %v", ctlr.routeSpecCMKey, err), false
+	}
+	return ctlr.processConfigMap(globalCM, false)
+}
+
 func (ctlr *Controller) isGlobalExtendedRouteSpec(cm *v1.ConfigMap) bool {
 	cmKey := cm.Namespace + "/" + cm.Name
 
@@ -1000,7 +1624,8 @@ func getOperationalExtendedConfigMapSpecs(
 			continue
 		}
 		if !reflect.DeepEqual(spec, newMap[routeGroupKey]) {
-			if spec.global.VServerName != newSpec.global.VServerName || spec.override != newSpec.override || spec.partition != newSpec.partition {
+			if spec.global.VServerName != newSpec.global.VServerName || spec.override != newSpec.override || spec.partition != newSpec.partition ||
+				!reflect.DeepEqual(spec.namespacePartitions, newSpec.namespacePartitions) {
 				// Update to VServerName or override should trigger delete and recreation of object
 				modifiedSpecs = append(modifiedSpecs, routeGroupKey)
 			} else {
@@ -1056,13 +1681,15 @@ func (ctlr *Controller) getOrderedRoutes(namespace string) []*routeapi.Route {
 	sort.Slice(allRoutes, func(i, j int) bool {
 		if allRoutes[i].Spec.Host == allRoutes[j].Spec.Host {
 			if (len(allRoutes[i].Spec.Path) == 0 || len(allRoutes[j].Spec.Path) == 0) && (allRoutes[i].Spec.Path == "/" || allRoutes[j].Spec.Path == "/") {
-				return allRoutes[i].CreationTimestamp.Before(&allRoutes[j].CreationTimestamp)
+				return isRoutePreferred(getRoutePriority(allRoutes[i]), allRoutes[i].CreationTimestamp,
+					getRoutePriority(allRoutes[j]), allRoutes[j].CreationTimestamp)
 			}
 		}
 		return (allRoutes[i].Spec.Host < allRoutes[j].Spec.Host) ||
 			(allRoutes[i].Spec.Host == allRoutes[j].Spec.Host &&
 				allRoutes[i].Spec.Path == allRoutes[j].Spec.Path &&
-				allRoutes[i].CreationTimestamp.Before(&allRoutes[j].CreationTimestamp)) ||
+				isRoutePreferred(getRoutePriority(allRoutes[i]), allRoutes[i].CreationTimestamp,
+					getRoutePriority(allRoutes[j]), allRoutes[j].CreationTimestamp)) ||
 			(allRoutes[i].Spec.Host == allRoutes[j].Spec.Host &&
 				allRoutes[i].Spec.Path < allRoutes[j].Spec.Path)
 	})
@@ -1070,6 +1697,30 @@ func (ctlr *Controller) getOrderedRoutes(namespace string) []*routeapi.Route {
 	return allRoutes
 }
 
+// getRoutePriority returns the priority of the route set with the route priority annotation,
+// routes without a valid priority have the priority 0
+func getRoutePriority(route *routeapi.Route) int {
+	value, ok := route.Annotations[string(RoutePriorityAnnotation)]
+	if !ok {
+		return 0
+	}
+	priority, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		log.Warningf("[CORE] Ignoring invalid priority %v of route %v/%v", value, route.Namespace, route.Name)
+		return 0
+	}
+	return priority
+}
+
+// isRoutePreferred checks whether the first route takes precedence over the second route for a host path,
+// the route with the higher priority is preferred and the older route is preferred among the equal priorities
+func isRoutePreferred(priority int, timestamp metav1.Time, otherPriority int, otherTimestamp metav1.Time) bool {
+	if priority != otherPriority {
+		return priority > otherPriority
+	}
+	return timestamp.Before(&otherTimestamp)
+}
+
 func doRoutesHandleHTTP(routes []*routeapi.Route) bool {
 	for _, route := range routes {
 		if !isSecureRoute(route) {
@@ -1087,6 +1738,22 @@ func doRoutesHandleHTTP(routes []*routeapi.Route) bool {
 	return false
 }
 
+// logHTTPSOnlyIgnoredRoutes warns about the routes whose HTTP traffic isn't handled as the route group is HTTPS only
+func logHTTPSOnlyIgnoredRoutes(routeGroup string, routes []*routeapi.Route) {
+	for _, route := range routes {
+		if !isSecureRoute(route) {
+			log.Warningf("Ignoring Route %v/%v without TLS as RouteGroup %v is HTTPS only",
+				route.Namespace, route.Name, routeGroup)
+			continue
+		}
+		if route.Spec.TLS.InsecureEdgeTerminationPolicy == routeapi.InsecureEdgeTerminationPolicyAllow ||
+			route.Spec.TLS.InsecureEdgeTerminationPolicy == routeapi.InsecureEdgeTerminationPolicyRedirect {
+			log.Warningf("Ignoring insecureEdgeTerminationPolicy %v of Route %v/%v as RouteGroup %v is HTTPS only",
+				route.Spec.TLS.InsecureEdgeTerminationPolicy, route.Namespace, route.Name, routeGroup)
+		}
+	}
+}
+
 func isSecureRoute(route *routeapi.Route) bool {
 	return route.Spec.TLS != nil
 }
@@ -1161,7 +1828,7 @@ func (ctlr *Controller) updateRouteAdmitStatus(
 		Admitted := false
 		now := metaV1.Now().Rfc3339Copy()
 		for _, routeIngress := range route.Status.Ingress {
-			if routeIngress.RouterName == F5RouterName {
+			if routeIngress.RouterName == ctlr.routerName {
 				for _, condition := range routeIngress.Conditions {
 					if condition.Status == status {
 						Admitted = true
@@ -1176,7 +1843,7 @@ func (ctlr *Controller) updateRouteAdmitStatus(
 			return
 		}
 		route.Status.Ingress = append(route.Status.Ingress, routeapi.RouteIngress{
-			RouterName: F5RouterName,
+			RouterName: ctlr.routerName,
 			Host:       route.Spec.Host,
 			Conditions: []routeapi.RouteIngressCondition{{
 				Type:               routeapi.RouteAdmitted,
@@ -1223,6 +1890,7 @@ func (ctlr *Controller) eraseAllRouteAdmitStatus() {
 		ctlr.processedHostPath.Lock()
 		if timestamp, ok := ctlr.processedHostPath.processedHostPathMap[key]; ok && timestamp == route.ObjectMeta.CreationTimestamp {
 			delete(ctlr.processedHostPath.processedHostPathMap, key)
+			delete(ctlr.processedHostPath.processedHostPathPriority, key)
 		}
 		ctlr.processedHostPath.Unlock()
 	}
@@ -1235,7 +1903,7 @@ func (ctlr *Controller) eraseRouteAdmitStatus(rscKey string) {
 		return
 	}
 	for i := 0; i < len(route.Status.Ingress); i++ {
-		if route.Status.Ingress[i].RouterName == F5RouterName {
+		if route.Status.Ingress[i].RouterName == ctlr.routerName {
 			route.Status.Ingress = append(route.Status.Ingress[:i], route.Status.Ingress[i+1:]...)
 			erased := false
 			retryCount := 0
@@ -1289,8 +1957,9 @@ func (ctlr *Controller) checkValidRoute(route *routeapi.Route, extdSpec *Extende
 	}
 	if processedRouteTimestamp, found := ctlr.processedHostPath.processedHostPathMap[key]; found {
 		// update the status if different route
-		if processedRouteTimestamp.Before(&route.ObjectMeta.CreationTimestamp) {
-			message := fmt.Sprintf("Discarding route %v as other route already exposes URI %v%v and is older ", route.Name, route.Spec.Host, route.Spec.Path)
+		if processedRouteTimestamp != route.ObjectMeta.CreationTimestamp && isRoutePreferred(ctlr.processedHostPath.processedHostPathPriority[key], processedRouteTimestamp,
+			getRoutePriority(route), route.ObjectMeta.CreationTimestamp) {
+			message := fmt.Sprintf("Discarding route %v as other route already exposes URI %v%v and is older or has a higher priority", route.Name, route.Spec.Host, route.Spec.Path)
 			log.Errorf(message)
 			go ctlr.updateRouteAdmitStatus(fmt.Sprintf("%v/%v", route.Namespace, route.Name), "HostAlreadyClaimed", message, v1.ConditionFalse)
 			return false
@@ -1318,6 +1987,11 @@ func (ctlr *Controller) checkValidRoute(route *routeapi.Route, extdSpec *Extende
 			go ctlr.updateRouteAdmitStatus(fmt.Sprintf("%v/%v", route.Namespace, route.Name), "ExtendedValidationFailed", message, v1.ConditionFalse)
 			return false
 		}
+		if ctlr.strictCertificateHost && !certificateCoversHost(route.Spec.Host, []byte(route.Spec.TLS.Certificate)) {
+			message := fmt.Sprintf("Certificate of route %v does not match host %v", route.ObjectMeta.Name, route.Spec.Host)
+			go ctlr.updateRouteAdmitStatus(fmt.Sprintf("%v/%v", route.Namespace, route.Name), "HostnameMismatch", message, v1.ConditionFalse)
+			return false
+		}
 	}
 	// Validate the route service exists or not
 	err, _ := ctlr.getServicePort(route)
@@ -1332,7 +2006,7 @@ func (ctlr *Controller) checkValidRoute(route *routeapi.Route, extdSpec *Extende
 	return true
 }
 
-func (ctlr *Controller) updateHostPathMap(timestamp metav1.Time, key string) {
+func (ctlr *Controller) updateHostPathMap(timestamp metav1.Time, priority int, key string) {
 	// This function updates the processedHostPathMap
 	ctlr.processedHostPath.Lock()
 	defer ctlr.processedHostPath.Unlock()
@@ -1340,10 +2014,58 @@ func (ctlr *Controller) updateHostPathMap(timestamp metav1.Time, key string) {
 		if routeTimestamp == timestamp && hostPath != key {
 			// Deleting the ProcessedHostPath map if route's path is changed
 			delete(ctlr.processedHostPath.processedHostPathMap, hostPath)
+			delete(ctlr.processedHostPath.processedHostPathPriority, hostPath)
 		}
 	}
 	// adding the ProcessedHostPath map entry
 	ctlr.processedHostPath.processedHostPathMap[key] = timestamp
+	ctlr.processedHostPath.processedHostPathPriority[key] = priority
+}
+
+// rebuildHostPathMap recomputes the processedHostPathMap from the routes of the route groups,
+// where the route with the highest priority or else the oldest route of a host path owns it,
+// and swaps the new map in at once.
+// It returns the route groups whose routes have taken over a host path.
+func (ctlr *Controller) rebuildHostPathMap(specMap extendedSpecMap) []string {
+	hostPathMap := make(map[string]metav1.Time)
+	hostPathPriority := make(map[string]int)
+	hostPathOwners := make(map[string]string)
+	for routeGroup, spec := range specMap {
+		for _, namespace := range spec.namespaces {
+			for _, route := range ctlr.getOrderedRoutes(namespace) {
+				// routes without a service are discarded and hence do not own the host path
+				if err, _ := ctlr.getServicePort(route); err != nil {
+					continue
+				}
+				var key string
+				if route.Spec.Path == "/" || len(route.Spec.Path) == 0 {
+					key = route.Spec.Host + "/"
+				} else {
+					key = route.Spec.Host + route.Spec.Path
+				}
+				priority := getRoutePriority(route)
+				if timestamp, found := hostPathMap[key]; found &&
+					!isRoutePreferred(priority, route.CreationTimestamp, hostPathPriority[key], timestamp) {
+					continue
+				}
+				hostPathMap[key] = route.CreationTimestamp
+				hostPathPriority[key] = priority
+				hostPathOwners[key] = routeGroup
+			}
+		}
+	}
+	ctlr.processedHostPath.Lock()
+	defer ctlr.processedHostPath.Unlock()
+	var routeGroups []string
+	for key, timestamp := range hostPathMap {
+		if oldTimestamp, found := ctlr.processedHostPath.processedHostPathMap[key]; found && oldTimestamp != timestamp &&
+			!containsString(routeGroups, hostPathOwners[key]) {
+			routeGroups = append(routeGroups, hostPathOwners[key])
+		}
+	}
+	ctlr.processedHostPath.processedHostPathMap = hostPathMap
+	ctlr.processedHostPath.processedHostPathPriority = hostPathPriority
+	return routeGroups
 }
 
 func (ctlr *Controller) deleteHostPathMapEntry(route *routeapi.Route) {
@@ -1360,6 +2082,7 @@ func (ctlr *Controller) deleteHostPathMapEntry(route *routeapi.Route) {
 		if routeTimestamp == route.CreationTimestamp && hostPath == key {
 			// Deleting the ProcessedHostPath map if route's path is changed
 			delete(ctlr.processedHostPath.processedHostPathMap, hostPath)
+			delete(ctlr.processedHostPath.processedHostPathPriority, hostPath)
 		}
 	}
 }
@@ -1383,3 +2106,36 @@ func (ctlr *Controller) getNamespacesForRouteGroup(namespaceGroup string) []stri
 	}
 	return namespaces
 }
+
+// getNamespacePartitionsForRouteGroup maps the namespaces of a namespace label route group
+// to the partitions of the partition labels they carry
+func (ctlr *Controller) getNamespacePartitionsForRouteGroup(
+	namespaceGroup string,
+	partitionLabels map[string]string,
+) (map[string]string, error) {
+	nsPartitions := make(map[string]string)
+	if !ctlr.namespaceLabelMode || len(partitionLabels) == 0 {
+		return nsPartitions, nil
+	}
+	labels := make([]string, 0, len(partitionLabels))
+	for label := range partitionLabels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		partition := partitionLabels[label]
+		nsLabel := fmt.Sprintf("%v,%v,%v", ctlr.namespaceLabel, namespaceGroup, label)
+		nss, err := ctlr.kubeClient.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{LabelSelector: nsLabel})
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch namespaces for partition label %v: %v", label, err)
+		}
+		for _, ns := range nss.Items {
+			if mapped, ok := nsPartitions[ns.Name]; ok && mapped != partition {
+				return nil, fmt.Errorf("namespace %v maps to partitions %v and %v in route group %v",
+					ns.Name, mapped, partition, namespaceGroup)
+			}
+			nsPartitions[ns.Name] = partition
+		}
+	}
+	return nsPartitions, nil
+}
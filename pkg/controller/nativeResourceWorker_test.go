@@ -1,8 +1,13 @@
 package controller
 
 import (
+	"container/list"
+	"context"
 	"fmt"
 	"reflect"
+	"sort"
+	"sync"
+	"testing"
 	"time"
 
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/teem"
@@ -14,8 +19,10 @@ import (
 	. "github.com/onsi/gomega"
 	routeapi "github.com/openshift/api/route/v1"
 	fakeRouteClient "github.com/openshift/client-go/route/clientset/versioned/fake"
+	"gopkg.in/yaml.v2"
 	v1 "k8s.io/api/core/v1"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
 )
 
 var _ = Describe("Routes", func() {
@@ -24,6 +31,7 @@ var _ = Describe("Routes", func() {
 		mockCtlr = newMockController()
 		mockCtlr.mode = OpenShiftMode
 		mockCtlr.routeClientV1 = fakeRouteClient.NewSimpleClientset().RouteV1()
+		mockCtlr.routerName = F5RouterName
 		mockCtlr.namespaces = make(map[string]bool)
 		mockCtlr.namespaces["default"] = true
 		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
@@ -37,6 +45,7 @@ var _ = Describe("Routes", func() {
 		mockCtlr.esInformers["default"] = mockCtlr.newNamespacedEssentialResourceInformer("default")
 		var processedHostPath ProcessedHostPath
 		processedHostPath.processedHostPathMap = make(map[string]metav1.Time)
+		processedHostPath.processedHostPathPriority = make(map[string]int)
 		mockCtlr.processedHostPath = &processedHostPath
 		mockCtlr.TeemData = &teem.TeemsData{
 			ResourceType: teem.ResourceTypes{
@@ -140,6 +149,63 @@ var _ = Describe("Routes", func() {
 			Expect(dg[ns].Records[0].Data).To(BeEquivalentTo("foo_80_default"), "Invalid hostname in datagroup")
 		})
 
+		It("Passthrough Route with default passthrough pool", func() {
+			mockCtlr.resources = NewResourceStore()
+			mockCtlr.resources.extdSpecMap[ns] = &extendedParsedSpec{
+				global: &ExtendedRouteGroupSpec{
+					VServerName:   "samplevs",
+					VServerAddr:   "10.10.10.10",
+					AllowOverride: "False",
+					SNAT:          "auto",
+					DefaultPassthroughPool: &DefaultPassthroughPool{
+						Service:          "dflt",
+						ServiceNamespace: ns,
+					},
+				},
+				namespaces: []string{ns},
+				partition:  "test",
+			}
+			spec1 := routeapi.RouteSpec{
+				Host: "foo.com",
+				To: routeapi.RouteTargetReference{
+					Kind: "Service",
+					Name: "foo",
+				},
+				TLS: &routeapi.TLSConfig{Termination: TLSPassthrough},
+			}
+			mockCtlr.addRoute(test.NewRoute("route1", "1", ns, spec1, nil))
+			for _, name := range []string{"foo", "dflt"} {
+				ports := []v1.ServicePort{{Port: 443, NodePort: 30443}}
+				mockCtlr.addService(test.NewService(name, "1", ns, "NodePort", ports))
+				mockCtlr.addEndpoints(test.NewEndpoints(name, "1", "node0", ns, []string{"10.1.1.1"}, []string{},
+					convertSvcPortsToEndpointPorts(ports)))
+			}
+			mockCtlr.resources.invertedNamespaceLabelMap[ns] = ns
+
+			Expect(mockCtlr.processRoutes(ns, false)).To(BeNil(), "Failed to process routes")
+			rsCfg := mockCtlr.resources.ltmConfig["test"].ResourceMap["samplevs_443"]
+			Expect(rsCfg.Virtual.DefaultPassthroughPool).To(Equal("dflt_443_default"))
+			var poolNames []string
+			for _, pl := range rsCfg.Pools {
+				poolNames = append(poolNames, pl.Name)
+			}
+			Expect(poolNames).To(ConsistOf("dflt_443_default", "foo_443_default"))
+			// unknown server names land on the default pool unless they are edge or reencrypt hosts
+			tlsIRule := rsCfg.IRulesMap[NameRef{Name: getRSCfgResName("samplevs_443", TLSIRuleName), Partition: "test"}]
+			Expect(tlsIRule).NotTo(BeNil())
+			Expect(tlsIRule.Code).To(ContainSubstring(`set dflt_pool_passthrough "dflt_443_default"`))
+			Expect(tlsIRule.Code).To(ContainSubstring(`class names $tls_class "$tls_host/*"`))
+
+			// default pool of a service which doesn't exist is ignored
+			mockCtlr.resources.extdSpecMap[ns].global.DefaultPassthroughPool.Service = "unknown"
+			Expect(mockCtlr.processRoutes(ns, false)).To(BeNil(), "Failed to process routes")
+			rsCfg = mockCtlr.resources.ltmConfig["test"].ResourceMap["samplevs_443"]
+			Expect(rsCfg.Virtual.DefaultPassthroughPool).To(BeEmpty())
+			Expect(rsCfg.Pools).To(HaveLen(1))
+			tlsIRule = rsCfg.IRulesMap[NameRef{Name: getRSCfgResName("samplevs_443", TLSIRuleName), Partition: "test"}]
+			Expect(tlsIRule.Code).NotTo(ContainSubstring("tls_host_found"))
+		})
+
 		It("Route Admit Status", func() {
 			spec1 := routeapi.RouteSpec{
 				Host: "foo.com",
@@ -166,6 +232,48 @@ var _ = Describe("Routes", func() {
 			Expect(mockCtlr.fetchRoute(fmt.Sprintf("%v-invalid", rskey))).To(BeNil(), "We should not be able to fetch the route")
 
 		})
+		It("Route Admit Status of another router", func() {
+			spec1 := routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To: routeapi.RouteTargetReference{
+					Kind: "Service",
+					Name: "foo",
+				},
+			}
+			route1 := test.NewRoute("route1", "1", "default", spec1, nil)
+			route1.Status.Ingress = []routeapi.RouteIngress{{
+				RouterName: "F5 BIG-IP green",
+				Host:       spec1.Host,
+				Conditions: []routeapi.RouteIngressCondition{{
+					Type:   routeapi.RouteAdmitted,
+					Status: v1.ConditionTrue,
+				}},
+			}}
+			mockCtlr.addRoute(route1)
+			_, err := mockCtlr.routeClientV1.Routes(route1.Namespace).Create(context.TODO(), route1, metav1.CreateOptions{})
+			Expect(err).To(BeNil())
+			mockCtlr.routerName = "F5 BIG-IP blue"
+			rskey := fmt.Sprintf("%v/%v", route1.Namespace, route1.Name)
+
+			mockCtlr.updateRouteAdmitStatus(rskey, "HostAlreadyClaimed", "Testing", v1.ConditionFalse)
+			route, err := mockCtlr.routeClientV1.Routes(route1.Namespace).Get(context.TODO(), route1.Name, metav1.GetOptions{})
+			Expect(err).To(BeNil())
+			Expect(route.Status.Ingress).To(HaveLen(2), "Status of both routers should be present")
+			Expect(route.Status.Ingress[0].RouterName).To(Equal("F5 BIG-IP green"))
+			Expect(route.Status.Ingress[0].Conditions[0].Status).To(Equal(v1.ConditionTrue),
+				"Status of the other router should not be changed")
+			Expect(route.Status.Ingress[1].RouterName).To(Equal("F5 BIG-IP blue"))
+			Expect(route.Status.Ingress[1].Conditions[0].Status).To(Equal(v1.ConditionFalse))
+
+			// only the status of this router is erased
+			mockCtlr.addRoute(route)
+			mockCtlr.eraseRouteAdmitStatus(rskey)
+			route, err = mockCtlr.routeClientV1.Routes(route1.Namespace).Get(context.TODO(), route1.Name, metav1.GetOptions{})
+			Expect(err).To(BeNil())
+			Expect(route.Status.Ingress).To(HaveLen(1), "Status of the other router should not be erased")
+			Expect(route.Status.Ingress[0].RouterName).To(Equal("F5 BIG-IP green"))
+		})
 		It("Check Valid Route", func() {
 			spec1 := routeapi.RouteSpec{
 				Host: "foo.com",
@@ -217,6 +325,52 @@ var _ = Describe("Routes", func() {
 			Expect(route3.Status.Ingress[0].Conditions[0].Status).To(BeEquivalentTo(v1.ConditionFalse), "Incorrect route admit status")
 			Expect(route3.Status.Ingress[0].Conditions[0].Reason).To(BeEquivalentTo("ServiceNotFound"), "Incorrect route admit reason")
 		})
+		It("Route priority overrides the oldest route", func() {
+			spec := routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To: routeapi.RouteTargetReference{
+					Kind: "Service",
+					Name: "foo",
+				},
+			}
+			fooPorts := []v1.ServicePort{{Port: 80, NodePort: 30001}}
+			mockCtlr.addService(test.NewService("foo", "1", "default", "NodePort", fooPorts))
+			oldRoute := test.NewRoute("oldroute", "1", "default", spec, nil)
+			oldRoute.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+			newRoute := test.NewRoute("newroute", "1", "default", spec,
+				map[string]string{string(RoutePriorityAnnotation): "10"})
+			mockCtlr.addRoute(oldRoute)
+			mockCtlr.addRoute(newRoute)
+			Expect(getRoutePriority(oldRoute)).To(BeZero())
+			Expect(getRoutePriority(newRoute)).To(Equal(10))
+
+			routes := mockCtlr.getOrderedRoutes("default")
+			Expect(len(routes)).To(Equal(2))
+			Expect(routes[0].Name).To(Equal("newroute"), "Route with higher priority should be ordered first")
+
+			key := spec.Host + spec.Path
+			Expect(mockCtlr.checkValidRoute(newRoute, nil)).To(BeTrue())
+			mockCtlr.updateHostPathMap(newRoute.CreationTimestamp, getRoutePriority(newRoute), key)
+			Expect(mockCtlr.checkValidRoute(oldRoute, nil)).To(BeFalse(),
+				"Older route should be discarded for the route with higher priority")
+			Expect(mockCtlr.checkValidRoute(newRoute, nil)).To(BeTrue(), "Owner route should not be rejected")
+
+			// without priorities the oldest route wins
+			newRoute.Annotations = nil
+			routes = mockCtlr.getOrderedRoutes("default")
+			Expect(routes[0].Name).To(Equal("oldroute"))
+			mockCtlr.rebuildHostPathMap(extendedSpecMap{"default": {namespaces: []string{"default"}}})
+			Expect(mockCtlr.processedHostPath.processedHostPathMap[key]).To(Equal(oldRoute.CreationTimestamp))
+			Expect(mockCtlr.checkValidRoute(oldRoute, nil)).To(BeTrue())
+			Expect(mockCtlr.checkValidRoute(newRoute, nil)).To(BeFalse())
+
+			// host path map rebuild honors the priority
+			newRoute.Annotations = map[string]string{string(RoutePriorityAnnotation): "1"}
+			mockCtlr.rebuildHostPathMap(extendedSpecMap{"default": {namespaces: []string{"default"}}})
+			Expect(mockCtlr.processedHostPath.processedHostPathMap[key]).To(Equal(newRoute.CreationTimestamp))
+			Expect(mockCtlr.processedHostPath.processedHostPathPriority[key]).To(Equal(1))
+		})
 		It("Check Host-Path Map functions", func() {
 			spec1 := routeapi.RouteSpec{
 				Host: "foo.com",
@@ -239,7 +393,7 @@ var _ = Describe("Routes", func() {
 			route1.Spec.Path = "/test"
 			newURI := route1.Spec.Host + route1.Spec.Path
 			mockCtlr.updateRoute(route1)
-			mockCtlr.updateHostPathMap(route1.ObjectMeta.CreationTimestamp, route1.Spec.Host+route1.Spec.Path)
+			mockCtlr.updateHostPathMap(route1.ObjectMeta.CreationTimestamp, 0, route1.Spec.Host+route1.Spec.Path)
 			_, found := mockCtlr.processedHostPath.processedHostPathMap[oldURI]
 			Expect(found).To(BeFalse())
 			_, found = mockCtlr.processedHostPath.processedHostPathMap[newURI]
@@ -455,6 +609,289 @@ var _ = Describe("Routes", func() {
 				intstr.IntOrString{IntVal: 443}, extdSpec2)).To(BeFalse())
 		})
 
+		It("Check Route TLS redirect target", func() {
+			extdSpec := &ExtendedRouteGroupSpec{
+				VServerName: "defaultServer",
+				VServerAddr: "10.8.3.11",
+				TLS: TLS{
+					ClientSSL: "/Common/clientssl",
+					Reference: "bigip",
+				},
+				AllowOverride: "0",
+			}
+			newRouteSpec := func(host string) routeapi.RouteSpec {
+				return routeapi.RouteSpec{
+					Host: host,
+					Path: "/foo",
+					To: routeapi.RouteTargetReference{
+						Kind: "Service",
+						Name: "foo",
+					},
+					TLS: &routeapi.TLSConfig{
+						Termination:                   "edge",
+						InsecureEdgeTerminationPolicy: routeapi.InsecureEdgeTerminationPolicyRedirect,
+					},
+				}
+			}
+			route1 := test.NewRoute("route1", "1", "default", newRouteSpec("foo.com"), nil)
+			route2 := test.NewRoute("route2", "1", "default", newRouteSpec("old.foo.com"),
+				map[string]string{string(RedirectTargetAnnotation): "www.foo.com/home"})
+			rsCfg := &ResourceConfig{}
+			rsCfg.Virtual.Partition = "default"
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Enabled = true
+			rsCfg.Virtual.Name = "newroutes_80"
+			rsCfg.MetaData.Protocol = HTTP
+			rsCfg.Virtual.SetVirtualAddress("10.8.3.11", DEFAULT_HTTP_PORT)
+			rsCfg.IntDgMap = make(InternalDataGroupMap)
+			rsCfg.IRulesMap = make(IRulesMap)
+			ps := portStruct{HTTP, DEFAULT_HTTP_PORT}
+			iRuleKey := NameRef{
+				Name:      getHttpRedirectIRuleName(rsCfg.Virtual.Name, HttpRedirectIRuleName, DEFAULT_HTTPS_PORT, 0),
+				Partition: rsCfg.Virtual.Partition,
+			}
+			targetDgKey := NameRef{
+				Name:      getHttpsRedirectTargetDgName(rsCfg.Virtual.Name, DEFAULT_HTTPS_PORT),
+				Partition: rsCfg.Virtual.Partition,
+			}
+
+			// route without redirect target is redirected to the same host
+			Expect(mockCtlr.prepareResourceConfigFromRoute(rsCfg, route1, intstr.IntOrString{IntVal: 80}, ps)).To(BeNil())
+			Expect(mockCtlr.handleRouteTLS(rsCfg, route1, extdSpec.VServerAddr, intstr.IntOrString{IntVal: 80}, extdSpec)).To(BeTrue())
+			Expect(rsCfg.IRulesMap).To(HaveKey(iRuleKey))
+			Expect(rsCfg.IRulesMap[iRuleKey].Code).To(ContainSubstring(
+				`HTTP::redirect https://[getfield [HTTP::host] ":" 1]:443[HTTP::uri]`))
+			Expect(rsCfg.IRulesMap[iRuleKey].Code).NotTo(ContainSubstring(targetDgKey.Name))
+			Expect(rsCfg.IntDgMap).NotTo(HaveKey(targetDgKey))
+
+			// route with redirect target is redirected to the target
+			Expect(mockCtlr.prepareResourceConfigFromRoute(rsCfg, route2, intstr.IntOrString{IntVal: 80}, ps)).To(BeNil())
+			Expect(mockCtlr.handleRouteTLS(rsCfg, route2, extdSpec.VServerAddr, intstr.IntOrString{IntVal: 80}, extdSpec)).To(BeTrue())
+			Expect(rsCfg.IntDgMap).To(HaveKey(targetDgKey))
+			Expect(rsCfg.IntDgMap[targetDgKey]["default"].Records).To(Equal(InternalDataGroupRecords{
+				{Name: "old.foo.com", Data: "www.foo.com/home"},
+			}))
+			Expect(len(rsCfg.Virtual.IRules)).To(Equal(1))
+			Expect(rsCfg.IRulesMap[iRuleKey].Code).To(ContainSubstring("/default/Shared/" + targetDgKey.Name))
+			Expect(rsCfg.IRulesMap[iRuleKey].Code).To(ContainSubstring(`HTTP::redirect "https://$target"`))
+			Expect(rsCfg.IRulesMap[iRuleKey].Code).To(ContainSubstring(
+				`HTTP::redirect https://[getfield [HTTP::host] ":" 1]:443[HTTP::uri]`),
+				"Hosts without target should be redirected to the same host")
+
+			// invalid redirect targets are ignored
+			route2.Annotations[string(RedirectTargetAnnotation)] = "https://www.foo.com"
+			Expect(getRouteRedirectTarget(route2)).To(BeEmpty())
+			route2.Annotations[string(RedirectTargetAnnotation)] = " www.foo.com:8443 "
+			Expect(getRouteRedirectTarget(route2)).To(Equal("www.foo.com:8443"))
+		})
+
+		It("Route with path prefix stripping", func() {
+			spec := routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/app/v1",
+				To: routeapi.RouteTargetReference{
+					Kind: "Service",
+					Name: "foo",
+				},
+			}
+			route := test.NewRoute("route1", "1", "default", spec,
+				map[string]string{string(StripPathPrefixAnnotation): "/app"})
+			rules := mockCtlr.prepareRouteLTMRules(route, "foo_80_default", nil)
+			Expect(rules).NotTo(BeNil())
+			Expect(len(*rules)).To(Equal(1))
+			Expect((*rules)[0].Actions).To(HaveLen(2))
+			Expect((*rules)[0].Actions[1].HTTPPath).To(BeTrue())
+			Expect((*rules)[0].Actions[1].Value).To(Equal(
+				`tcl:[expr {[string length [HTTP::path]] > 4 ? [string range [HTTP::path] 4 end] : "/"}]`))
+
+			// prefix which is not a prefix of the route path
+			route.Annotations[string(StripPathPrefixAnnotation)] = "/v1"
+			Expect(mockCtlr.prepareRouteLTMRules(route, "foo_80_default", nil)).To(BeNil())
+			route.Annotations[string(StripPathPrefixAnnotation)] = "app"
+			Expect(mockCtlr.prepareRouteLTMRules(route, "foo_80_default", nil)).To(BeNil())
+			// stripping is exclusive of rewrite
+			route.Annotations[string(StripPathPrefixAnnotation)] = "/app"
+			route.Annotations[string(URLRewriteAnnotation)] = "/bar"
+			Expect(mockCtlr.prepareRouteLTMRules(route, "foo_80_default", nil)).To(BeNil())
+		})
+
+		It("Route with request header insertion and removal", func() {
+			spec := routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/app",
+				To: routeapi.RouteTargetReference{
+					Kind: "Service",
+					Name: "foo",
+				},
+			}
+			route := test.NewRoute("route1", "1", "default", spec,
+				map[string]string{string(InsertRequestHeadersAnnotation): `[{"name": "X-Forwarded-Host", "value": "tcl:[HTTP::host]"}]`})
+			rules := mockCtlr.prepareRouteLTMRules(route, "foo_80_default", nil)
+			Expect(rules).NotTo(BeNil())
+			Expect((*rules)[0].Actions).To(HaveLen(2))
+			Expect(*(*rules)[0].Actions[0]).To(Equal(action{Name: "0", HTTPHeader: true, Insert: true,
+				Request: true, Header: &actionHeader{Name: "X-Forwarded-Host", Value: "tcl:[HTTP::host]"}}))
+			Expect((*rules)[0].Actions[1].Forward).To(BeTrue())
+			Expect((*rules)[0].Actions[1].Name).To(Equal("1"))
+
+			route.Annotations = map[string]string{string(RemoveRequestHeadersAnnotation): `["X-Debug"]`}
+			rules = mockCtlr.prepareRouteLTMRules(route, "foo_80_default", nil)
+			Expect(rules).NotTo(BeNil())
+			Expect((*rules)[0].Actions).To(HaveLen(2))
+			Expect(*(*rules)[0].Actions[0]).To(Equal(action{Name: "0", HTTPHeader: true, Remove: true,
+				Request: true, Header: &actionHeader{Name: "X-Debug"}}))
+			Expect((*rules)[0].Actions[1].Forward).To(BeTrue())
+
+			// values may contain commas
+			route.Annotations = map[string]string{string(InsertRequestHeadersAnnotation): `[{"name": "X-Client", "value": "tcl:[string map {, ;} [HTTP::header X-Client]]"}]`}
+			rules = mockCtlr.prepareRouteLTMRules(route, "foo_80_default", nil)
+			Expect(rules).NotTo(BeNil())
+			Expect((*rules)[0].Actions).To(HaveLen(2))
+			Expect((*rules)[0].Actions[0].Header.Value).To(Equal("tcl:[string map {, ;} [HTTP::header X-Client]]"))
+
+			// header to insert without a value
+			route.Annotations[string(InsertRequestHeadersAnnotation)] = `[{"name": "X-Forwarded-Host"}]`
+			Expect(mockCtlr.prepareRouteLTMRules(route, "foo_80_default", nil)).To(BeNil())
+			// not a list
+			route.Annotations[string(InsertRequestHeadersAnnotation)] = "X-Forwarded-Host:foo"
+			Expect(mockCtlr.prepareRouteLTMRules(route, "foo_80_default", nil)).To(BeNil())
+		})
+
+		It("Last sync time", func() {
+			mockCtlr.Agent = newMockAgent(nil)
+			mockCtlr.requestQueue = &requestQueue{sync.Mutex{}, list.New()}
+			var syncTimes []time.Time
+			mockCtlr.syncComplete = func(syncTime time.Time) {
+				syncTimes = append(syncTimes, syncTime)
+			}
+			mockCtlr.Agent.setLastSyncTimeSource(mockCtlr.LastSyncTime)
+
+			// no config update
+			mockCtlr.postResourceConfigRequest()
+			Expect(mockCtlr.LastSyncTime().IsZero()).To(BeTrue(), "Sync time should not be set without config update")
+			Expect(syncTimes).To(BeEmpty())
+
+			rsCfg := &ResourceConfig{}
+			rsCfg.Virtual.Name = "newroutes_80"
+			rsCfg.Virtual.Partition = "test"
+			mockCtlr.resources.getPartitionResourceMap("test")[rsCfg.Virtual.Name] = rsCfg
+			Expect(mockCtlr.resources.isConfigUpdated()).To(BeTrue())
+			mockCtlr.postResourceConfigRequest()
+			lastSyncTime := mockCtlr.LastSyncTime()
+			Expect(lastSyncTime.IsZero()).To(BeFalse(), "Sync time should be set after config update")
+			Expect(syncTimes).To(Equal([]time.Time{lastSyncTime}), "Sync complete not notified")
+			Expect(mockCtlr.Agent.getLastSyncTime()).To(Equal(lastSyncTime), "Sync time not exposed to the health endpoint")
+
+			// posting again without config update does not advance the sync time
+			mockCtlr.postResourceConfigRequest()
+			Expect(mockCtlr.LastSyncTime()).To(Equal(lastSyncTime))
+			Expect(len(syncTimes)).To(Equal(1))
+
+			rsCfg = &ResourceConfig{}
+			rsCfg.Virtual.Name = "newroutes_443"
+			rsCfg.Virtual.Partition = "test"
+			mockCtlr.resources.getPartitionResourceMap("test")[rsCfg.Virtual.Name] = rsCfg
+			mockCtlr.postResourceConfigRequest()
+			Expect(mockCtlr.LastSyncTime().After(lastSyncTime)).To(BeTrue(), "Sync time should advance after config update")
+			Expect(len(syncTimes)).To(Equal(2))
+		})
+
+		It("Posts only the updated partitions", func() {
+			mockCtlr.Agent = newMockAgent(nil)
+			mockCtlr.requestQueue = &requestQueue{sync.Mutex{}, list.New()}
+			addVirtual := func(partition, name string) *ResourceConfig {
+				rsCfg := &ResourceConfig{}
+				rsCfg.Virtual.Name = name
+				rsCfg.Virtual.Partition = partition
+				mockCtlr.resources.getPartitionResourceMap(partition)[name] = rsCfg
+				return rsCfg
+			}
+			postedPartitions := func() []string {
+				var partitions []string
+				config := <-mockCtlr.Agent.postChan
+				for partition := range config.ltmConfig {
+					partitions = append(partitions, partition)
+				}
+				sort.Strings(partitions)
+				return partitions
+			}
+
+			addVirtual("test", "newroutes_80")
+			addVirtual("test2", "newroutes_80")
+			mockCtlr.postResourceConfigRequest()
+			Expect(postedPartitions()).To(Equal([]string{"test", "test2"}), "All partitions should be posted initially")
+
+			addVirtual("test2", "newroutes_443")
+			mockCtlr.postResourceConfigRequest()
+			Expect(postedPartitions()).To(Equal([]string{"test2"}), "Only the updated partition should be posted")
+
+			// partitions sharing pools are posted together
+			rsCfg := addVirtual("test", "newroutes_8080")
+			rsCfg.Pools = Pools{{Name: "svc1_80_default", Partition: "test2"}}
+			mockCtlr.postResourceConfigRequest()
+			Expect(postedPartitions()).To(Equal([]string{"test", "test2"}), "Pool partition should be posted")
+
+			// emptied partition is posted to remove its config
+			delete(mockCtlr.resources.getPartitionResourceMap("test"), "newroutes_80")
+			delete(mockCtlr.resources.getPartitionResourceMap("test"), "newroutes_8080")
+			mockCtlr.postResourceConfigRequest()
+			config := <-mockCtlr.Agent.postChan
+			Expect(config.ltmConfig).To(HaveKey("test"))
+			Expect(config.ltmConfig["test"].ResourceMap).To(BeEmpty(), "Emptied partition should be posted")
+			Expect(config.ltmConfig).To(HaveKey("test2"), "Partition no longer sharing pools should be posted")
+
+			// a superseded request not yet processed by the Agent is merged into the latest one
+			addVirtual("test", "newroutes_80")
+			mockCtlr.postResourceConfigRequest()
+			addVirtual("test2", "newroutes_8080")
+			mockCtlr.postResourceConfigRequest()
+			Expect(postedPartitions()).To(Equal([]string{"test", "test2"}), "Pending partitions should not be dropped")
+
+			mockCtlr.Agent.updateLTMConfig(config)
+			Expect(mockCtlr.Agent.ltmConfig).NotTo(HaveKey("test"), "Emptied partition should be removed")
+			Expect(mockCtlr.Agent.ltmConfig).To(HaveKey("test2"))
+		})
+
+		It("Dry run", func() {
+			mockCtlr.Agent = newMockAgent(nil)
+			mockCtlr.requestQueue = &requestQueue{sync.Mutex{}, list.New()}
+			mockCtlr.dryRun = true
+			var syncTimes []time.Time
+			mockCtlr.syncComplete = func(syncTime time.Time) {
+				syncTimes = append(syncTimes, syncTime)
+			}
+
+			rsCfg := &ResourceConfig{}
+			rsCfg.Virtual.Name = "newroutes_80"
+			rsCfg.Virtual.Partition = "test"
+			mockCtlr.resources.getPartitionResourceMap("test")[rsCfg.Virtual.Name] = rsCfg
+			Expect(mockCtlr.resources.getLTMConfigCacheDiff().Virtuals).To(Equal(ConfigDiff{
+				Added: []string{"/test/newroutes_80"},
+			}), "Diff of the config not produced")
+
+			mockCtlr.postResourceConfigRequest()
+			Expect(mockCtlr.Agent.postChan).To(BeEmpty(), "Config should not be posted in dry run")
+			Expect(mockCtlr.requestQueue.Len()).To(BeZero(), "Request should not be queued in dry run")
+			Expect(mockCtlr.resources.isConfigUpdated()).To(BeFalse(), "Caches should be updated in dry run")
+			Expect(mockCtlr.LastSyncTime().IsZero()).To(BeTrue(), "Sync time should not be set in dry run")
+			Expect(syncTimes).To(BeEmpty(), "Sync complete should not be notified in dry run")
+
+			// only the next changes are in the diff
+			rsCfg = &ResourceConfig{}
+			rsCfg.Virtual.Name = "newroutes_443"
+			rsCfg.Virtual.Partition = "test"
+			mockCtlr.resources.getPartitionResourceMap("test")[rsCfg.Virtual.Name] = rsCfg
+			Expect(mockCtlr.resources.getLTMConfigCacheDiff().Virtuals).To(Equal(ConfigDiff{
+				Added: []string{"/test/newroutes_443"},
+			}))
+			mockCtlr.postResourceConfigRequest()
+			Expect(mockCtlr.Agent.postChan).To(BeEmpty(), "Config should not be posted in dry run")
+
+			mockCtlr.dryRun = false
+			delete(mockCtlr.resources.getPartitionResourceMap("test"), "newroutes_443")
+			mockCtlr.postResourceConfigRequest()
+			Expect(mockCtlr.Agent.postChan).To(HaveLen(1), "Config should be posted without dry run")
+		})
 	})
 
 	Describe("Extended Spec ConfigMap", func() {
@@ -518,6 +955,33 @@ extendedRouteSpec:
 			Expect(ok).To(BeTrue())
 		})
 
+		It("Extended Route Spec with multiple validation errors", func() {
+			data["extendedSpec"] = `
+extendedRouteSpec:
+    - namespace: default
+      vserverName: nextgenroutes
+      bigIpPartition: "dev/team"
+    - namespace: new
+      vserverAddr: 10.8.3.12
+      tls:
+        serverSSL: /Common/serverssl
+        reference: local
+`
+			err, ok := mockCtlr.processConfigMap(cm, false)
+			Expect(err).ToNot(BeNil())
+			Expect(ok).To(BeFalse())
+			Expect(err.Error()).To(ContainSubstring("route group default: vserverAddr is required"))
+			Expect(err.Error()).To(ContainSubstring("route group default: bigIpPartition dev/team has invalid characters"))
+			Expect(err.Error()).To(ContainSubstring("route group new: tls.reference local must be bigip or secret"))
+			Expect(err.Error()).To(ContainSubstring("route group new: tls.clientSSL is required with tls.serverSSL"))
+			Expect(mockCtlr.resources.extdSpecMap).To(BeEmpty(), "Invalid extended spec should not be applied")
+
+			events, _ := mockCtlr.kubeClient.CoreV1().Events(cm.Namespace).List(context.TODO(), metav1.ListOptions{})
+			Expect(events.Items).To(HaveLen(1), "Validation errors should be reported on the configmap")
+			Expect(events.Items[0].InvolvedObject.Name).To(Equal(cm.Name))
+			Expect(events.Items[0].Message).To(ContainSubstring("route group new: tls.clientSSL is required"))
+		})
+
 		It("Extended Route Spec Allow local", func() {
 			data["extendedSpec"] = `
 extendedRouteSpec:
@@ -889,30 +1353,139 @@ extendedRouteSpec:
 			Expect(ok).To(BeTrue())
 
 		})
-		It("Allow Source Range", func() {
+		It("Global ConfigMap with pool member address maps", func() {
+			data["extendedSpec"] = `
+poolMemberAddressMaps:
+    - from: 10.244.0.0/16
+      to: 172.16.0.0/16
+extendedRouteSpec:
+    - namespace: default
+      vserverAddr: 10.8.3.11
+      allowOverride: true
+`
+			err, ok := mockCtlr.processConfigMap(cm, false)
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeTrue())
+			Expect(mockCtlr.translateMemberAddress).NotTo(BeNil(), "Address translator not set")
+			Expect(mockCtlr.translateMemberAddress("10.244.1.5")).To(Equal("172.16.1.5"))
+			Expect(mockCtlr.translateMemberAddress("10.245.1.5")).To(Equal("10.245.1.5"),
+				"Addresses out of the CIDR should be left unchanged")
+
+			members := mockCtlr.translatePoolMembers([]PoolMember{
+				{Address: "10.244.1.5", Port: 8080},
+				{Address: "10.244.2.6", Port: 8080},
+			})
+			Expect(members).To(Equal([]PoolMember{
+				{Address: "172.16.1.5", Port: 8080},
+				{Address: "172.16.2.6", Port: 8080},
+			}), "Pool member addresses not remapped")
+
+			// CIDRs of different prefix lengths can't be mapped
+			data["extendedSpec"] = `
+poolMemberAddressMaps:
+    - from: 10.244.0.0/16
+      to: 172.16.0.0/24
+extendedRouteSpec:
+    - namespace: default
+      vserverAddr: 10.8.3.11
+      allowOverride: true
+`
+			err, _ = mockCtlr.processConfigMap(cm, false)
+			Expect(err).NotTo(BeNil(), "Mismatching prefix lengths should be rejected")
 
-			routeGroup := "default"
-			mockCtlr.resources = NewResourceStore()
-			mockCtlr.resources.extdSpecMap[routeGroup] = &extendedParsedSpec{
-				override: true,
-				global: &ExtendedRouteGroupSpec{
-					VServerName:      "nextgenroutes",
-					VServerAddr:      "10.10.10.10",
-					AllowOverride:    "False",
-					SNAT:             "auto",
-					WAF:              "/Common/WAFPolicy",
-					IRules:           []string{"/Common/iRule1"},
-					AllowSourceRange: []string{"10.1.0.0/16", "10.2.0.0/16"},
-					TLS: TLS{
-						ClientSSL: "/Common/clientssl",
-						ServerSSL: "/Common/serverssl",
-						Reference: "bigip",
-					},
-				},
-				namespaces: []string{routeGroup},
-				partition:  "test",
+			// the address translator is removed along with the address maps
+			data["extendedSpec"] = `
+extendedRouteSpec:
+    - namespace: default
+      vserverAddr: 10.8.3.11
+      allowOverride: true
+`
+			err, _ = mockCtlr.processConfigMap(cm, false)
+			Expect(err).To(BeNil())
+			Expect(mockCtlr.translateMemberAddress).To(BeNil(), "Address translator not removed")
+		})
+
+		It("Global ConfigMap with base route config fragments", func() {
+			ciphersCM := test.NewConfigMap("ciphers", "v1", "system", map[string]string{"extendedSpec": `
+baseRouteSpec:
+    tlsCipher:
+      ciphers: ECDHE-RSA-AES256-GCM-SHA384
+      tlsOptions: [no-tlsv1]
+`})
+			groupCM := test.NewConfigMap("cipher-group", "v1", "team", map[string]string{"extendedSpec": `
+baseRouteSpec:
+    tlsCipher:
+      tlsVersion: 1.3
+      cipherGroup: /Common/team-ciphers
+`})
+			for _, c := range []*v1.ConfigMap{ciphersCM, groupCM} {
+				_, err := mockCtlr.kubeClient.CoreV1().ConfigMaps(c.Namespace).Create(context.TODO(), c, metav1.CreateOptions{})
+				Expect(err).To(BeNil())
 			}
+			data["extendedSpec"] = `
+baseRouteSpec:
+    tlsCipher:
+      tlsVersion: 1.2
+baseRouteSpecConfigMaps: [ciphers, team/cipher-group]
+extendedRouteSpec:
+    - namespace: default
+      vserverAddr: 10.8.3.11
+      allowOverride: true
+`
+			_, err := mockCtlr.kubeClient.CoreV1().ConfigMaps(cm.Namespace).Create(context.TODO(), cm, metav1.CreateOptions{})
+			Expect(err).To(BeNil())
+			err, ok := mockCtlr.processConfigMap(cm, false)
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeTrue())
+			Expect(mockCtlr.resources.baseRouteConfig.TLSCipher).To(Equal(TLSCipher{
+				TLSVersion:  "1.3",
+				Ciphers:     "ECDHE-RSA-AES256-GCM-SHA384",
+				CipherGroup: "/Common/team-ciphers",
+				TLSOptions:  []string{"no-tlsv1"},
+			}), "Later base route config fragments should override the earlier ones")
+
+			events, _ := mockCtlr.kubeClient.CoreV1().Events(cm.Namespace).List(context.TODO(), metav1.ListOptions{})
+			Expect(events.Items).To(HaveLen(1), "Conflicting keys should be reported on the configmap")
+			Expect(events.Items[0].Reason).To(Equal("ConflictingBaseRouteSpec"))
+			Expect(events.Items[0].Message).To(Equal(
+				"tlsCipher.tlsVersion of configmap team/cipher-group overrides the one of configmap system/escm"))
+
+			// update of a fragment is merged into the base route config
+			ciphersCM.Data["extendedSpec"] = `
+baseRouteSpec:
+    tlsCipher:
+      ciphers: DEFAULT:!RC4
+`
+			_, err = mockCtlr.kubeClient.CoreV1().ConfigMaps("system").Update(context.TODO(), ciphersCM, metav1.UpdateOptions{})
+			Expect(err).To(BeNil())
+			err, ok = mockCtlr.processConfigMap(ciphersCM, false)
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeTrue())
+			Expect(mockCtlr.resources.baseRouteConfig.TLSCipher.Ciphers).To(Equal("DEFAULT:!RC4"))
+			Expect(mockCtlr.resources.baseRouteConfig.TLSCipher.TLSOptions).To(BeEmpty())
+
+			merged, conflicts := mergeBaseRouteConfigs([]baseRouteConfigFragment{
+				{source: "a/x", config: BaseRouteConfig{TLSCipher: TLSCipher{Ciphers: "DEFAULT"}}},
+				{source: "a/y", config: BaseRouteConfig{TLSCipher: TLSCipher{Ciphers: "DEFAULT"}}},
+			})
+			Expect(merged.TLSCipher.Ciphers).To(Equal("DEFAULT"))
+			Expect(conflicts).To(BeEmpty(), "Same values should not conflict")
+		})
+
+		It("RouteGroup migrating between partitions", func() {
+			data["extendedSpec"] = `
+extendedRouteSpec:
+    - namespace: default
+      vserverAddr: 10.8.3.11
+      vserverName: nextgenroutes
+      allowOverride: true
+      bigIpPartition: test
+`
+			err, ok := mockCtlr.processConfigMap(cm, false)
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeTrue())
 
+			routeGroup := "default"
 			spec1 := routeapi.RouteSpec{
 				Host: "foo.com",
 				Path: "/foo",
@@ -920,75 +1493,911 @@ extendedRouteSpec:
 					Kind: "Service",
 					Name: "foo",
 				},
-				TLS: &routeapi.TLSConfig{Termination: "reencrypt"},
 			}
-			fooPorts := []v1.ServicePort{{Port: 80, NodePort: 30001},
-				{Port: 8080, NodePort: 38001},
-				{Port: 9090, NodePort: 39001}}
+			fooPorts := []v1.ServicePort{{Port: 80, NodePort: 30001}}
 			foo := test.NewService("foo", "1", routeGroup, "NodePort", fooPorts)
 			mockCtlr.addService(foo)
-			fooIps := []string{"10.1.1.1"}
 			fooEndpts := test.NewEndpoints(
-				"foo", "1", "node0", routeGroup, fooIps, []string{},
+				"foo", "1", "node0", routeGroup, []string{"10.1.1.1"}, []string{},
 				convertSvcPortsToEndpointPorts(fooPorts))
 			mockCtlr.addEndpoints(fooEndpts)
 			route1 := test.NewRoute("route1", "1", routeGroup, spec1, nil)
-
 			mockCtlr.addRoute(route1)
 			mockCtlr.resources.invertedNamespaceLabelMap[routeGroup] = routeGroup
-			err := mockCtlr.processRoutes(routeGroup, false)
-
-			Expect(err).To(BeNil())
-			Expect(reflect.DeepEqual(mockCtlr.resources.ltmConfig["test"].ResourceMap["nextgenroutes_443"].Virtual.AllowSourceRange, []string{"10.1.0.0/16", "10.2.0.0/16"})).To(BeTrue())
-
-			mockCtlr.resources.extdSpecMap[routeGroup] = &extendedParsedSpec{
-				override: true,
-				local: &ExtendedRouteGroupSpec{
-					VServerName:      "nextgenroutes",
-					VServerAddr:      "10.10.10.10",
-					AllowOverride:    "True",
-					SNAT:             "auto",
-					WAF:              "/Common/WAFPolicy",
-					IRules:           []string{"/Common/iRule1"},
-					AllowSourceRange: []string{"10.3.0.0/16", "10.4.0.0/16"},
-					TLS: TLS{
-						ClientSSL: "/Common/clientssl",
-						ServerSSL: "/Common/serverssl",
-						Reference: "bigip",
-					},
-				},
-				global: &ExtendedRouteGroupSpec{
-					VServerName:      "nextgenroutes",
-					VServerAddr:      "10.10.10.10",
-					AllowOverride:    "True",
-					SNAT:             "auto",
-					WAF:              "/Common/WAFPolicy",
-					IRules:           []string{"/Common/iRule1"},
-					AllowSourceRange: []string{"10.1.0.0/16", "10.2.0.0/16"},
-					TLS: TLS{
-						ClientSSL: "/Common/clientssl",
-						ServerSSL: "/Common/serverssl",
-						Reference: "bigip",
-					},
-				},
-				namespaces: []string{routeGroup},
-				partition:  "test",
-			}
 			err = mockCtlr.processRoutes(routeGroup, false)
 			Expect(err).To(BeNil())
-			Expect(reflect.DeepEqual(mockCtlr.resources.ltmConfig["test"].ResourceMap["nextgenroutes_443"].Virtual.AllowSourceRange, []string{"10.3.0.0/16", "10.4.0.0/16"})).To(BeTrue())
-
-		})
-
-	})
-})
+			Expect(mockCtlr.resources.ltmConfig["test"].ResourceMap["nextgenroutes_80"]).NotTo(BeNil())
 
-var _ = Describe("With NamespaceLabel parameter in deployment", func() {
-	var mockCtlr *mockController
-	BeforeEach(func() {
-		mockCtlr = newMockController()
+			data["extendedSpec"] = `
+extendedRouteSpec:
+    - namespace: default
+      vserverAddr: 10.8.3.11
+      vserverName: nextgenroutes
+      allowOverride: true
+      bigIpPartition: dev
+`
+			err, ok = mockCtlr.processConfigMap(cm, false)
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeTrue())
+			Expect(len(mockCtlr.resources.ltmConfig["test"].ResourceMap)).To(BeZero())
+			Expect(mockCtlr.resources.ltmConfig["test"].Priority).To(BeZero())
+			Expect(mockCtlr.resources.ltmConfig["dev"].ResourceMap["nextgenroutes_80"]).NotTo(BeNil())
+			Expect(mockCtlr.resources.ltmConfig["dev"].Priority).To(Equal(1))
+
+			// new partition is posted before the emptied old partition
+			agent := newMockAgent(nil)
+			agent.createTenantAS3Declaration(ResourceConfigRequest{
+				ltmConfig:  mockCtlr.resources.getLTMConfigDeepCopy(),
+				shareNodes: true,
+				gtmConfig:  GTMConfig{},
+			})
+			Expect(agent.getTenantPostOrder()).To(Equal([][]string{{"dev"}, {"test"}}))
+		})
+		It("Host path ownership across global ConfigMap reloads", func() {
+			data["extendedSpec"] = `
+extendedRouteSpec:
+    - namespace: default
+      vserverAddr: 10.8.3.11
+      vserverName: defaultroutes
+      allowOverride: true
+      bigIpPartition: test
+    - namespace: test
+      vserverAddr: 10.8.3.12
+      vserverName: testroutes
+      allowOverride: true
+      bigIpPartition: test
+`
+			spec := routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To: routeapi.RouteTargetReference{
+					Kind: "Service",
+					Name: "foo",
+				},
+			}
+			fooPorts := []v1.ServicePort{{Port: 80, NodePort: 30001}}
+			for _, ns := range []string{"default", "test"} {
+				mockCtlr.addService(test.NewService("foo", "1", ns, "NodePort", fooPorts))
+				mockCtlr.addEndpoints(test.NewEndpoints(
+					"foo", "1", "node0", ns, []string{"10.1.1.1"}, []string{},
+					convertSvcPortsToEndpointPorts(fooPorts)))
+			}
+			oldRoute := test.NewRoute("oldroute", "1", "test", spec, nil)
+			oldRoute.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+			newRoute := test.NewRoute("newroute", "1", "default", spec, nil)
+			mockCtlr.addRoute(oldRoute)
+			mockCtlr.addRoute(newRoute)
+			key := spec.Host + spec.Path
+
+			err, ok := mockCtlr.processConfigMap(cm, false)
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeTrue())
+			Expect(mockCtlr.processedHostPath.processedHostPathMap[key]).To(Equal(oldRoute.CreationTimestamp),
+				"Older route should own the host path")
+			Expect(mockCtlr.resources.ltmConfig["test"].ResourceMap["testroutes_80"]).NotTo(BeNil())
+			Expect(mockCtlr.resources.ltmConfig["test"].ResourceMap["defaultroutes_80"]).To(BeNil())
+
+			// reload with an updated route group keeps the older route as the owner
+			data["extendedSpec"] = `
+extendedRouteSpec:
+    - namespace: default
+      vserverAddr: 10.8.3.21
+      vserverName: defaultroutes
+      allowOverride: true
+      bigIpPartition: test
+    - namespace: test
+      vserverAddr: 10.8.3.12
+      vserverName: testroutes
+      allowOverride: true
+      bigIpPartition: test
+`
+			err, ok = mockCtlr.processConfigMap(cm, false)
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeTrue())
+			Expect(mockCtlr.processedHostPath.processedHostPathMap[key]).To(Equal(oldRoute.CreationTimestamp),
+				"Older route should own the host path")
+			Expect(mockCtlr.checkValidRoute(oldRoute, nil)).To(BeTrue(), "Owner route should not be rejected")
+			Expect(mockCtlr.resources.ltmConfig["test"].ResourceMap["testroutes_80"]).NotTo(BeNil())
+
+			// once the route group of the older route is removed, the host path goes to the newer route
+			data["extendedSpec"] = `
+extendedRouteSpec:
+    - namespace: default
+      vserverAddr: 10.8.3.21
+      vserverName: defaultroutes
+      allowOverride: true
+      bigIpPartition: test
+`
+			err, ok = mockCtlr.processConfigMap(cm, false)
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeTrue())
+			Expect(mockCtlr.processedHostPath.processedHostPathMap[key]).To(Equal(newRoute.CreationTimestamp),
+				"Newer route should own the host path")
+			Expect(mockCtlr.resources.ltmConfig["test"].ResourceMap["testroutes_80"]).To(BeNil())
+			rsCfg := mockCtlr.resources.ltmConfig["test"].ResourceMap["defaultroutes_80"]
+			Expect(rsCfg).NotTo(BeNil())
+			Expect(rsCfg.MetaData.baseResources).To(HaveKey("default/newroute"))
+		})
+		It("Custom partition route group", func() {
+			routeGroup := "default"
+			mockCtlr.resources = NewResourceStore()
+			mockCtlr.resources.extdSpecMap[routeGroup] = &extendedParsedSpec{
+				override: true,
+				global: &ExtendedRouteGroupSpec{
+					VServerName:      "nextgenroutes",
+					VServerAddr:      "10.10.10.10",
+					AllowOverride:    "False",
+					AllowSourceRange: []string{"10.1.0.0/16"},
+					HealthMonitors: Monitors{
+						{Path: "foo.com/foo", Interval: 10, Timeout: 31},
+					},
+					TLS: TLS{
+						ClientSSL: "/Common/clientssl",
+						ServerSSL: "/Common/serverssl",
+						Reference: "bigip",
+					},
+				},
+				namespaces: []string{routeGroup},
+				partition:  "test",
+			}
+
+			spec1 := routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To: routeapi.RouteTargetReference{
+					Kind: "Service",
+					Name: "foo",
+				},
+				TLS: &routeapi.TLSConfig{Termination: "reencrypt"},
+			}
+			fooPorts := []v1.ServicePort{{Port: 80, NodePort: 30001}}
+			foo := test.NewService("foo", "1", routeGroup, "NodePort", fooPorts)
+			mockCtlr.addService(foo)
+			fooEndpts := test.NewEndpoints(
+				"foo", "1", "node0", routeGroup, []string{"10.1.1.1"}, []string{},
+				convertSvcPortsToEndpointPorts(fooPorts))
+			mockCtlr.addEndpoints(fooEndpts)
+			route1 := test.NewRoute("route1", "1", routeGroup, spec1, nil)
+			mockCtlr.addRoute(route1)
+			mockCtlr.resources.invertedNamespaceLabelMap[routeGroup] = routeGroup
+			err := mockCtlr.processRoutes(routeGroup, false)
+			Expect(err).To(BeNil())
+
+			rsCfg := mockCtlr.resources.ltmConfig["test"].ResourceMap["nextgenroutes_443"]
+			Expect(rsCfg).NotTo(BeNil())
+			Expect(rsCfg.IRulesMap).NotTo(BeEmpty())
+			for key, iRule := range rsCfg.IRulesMap {
+				Expect(key.Partition).To(Equal("test"), "iRule %v created outside the route group partition", key.Name)
+				Expect(iRule.Code).NotTo(ContainSubstring("/Common/Shared/"), "iRule %v refers to data groups in Common", key.Name)
+			}
+			Expect(rsCfg.Virtual.IRules).To(ContainElement(JoinBigipPath("test", getRSCfgResName(rsCfg.Virtual.Name, TLSIRuleName))))
+			Expect(rsCfg.IntDgMap).NotTo(BeEmpty())
+			for key, nsDg := range rsCfg.IntDgMap {
+				Expect(key.Partition).To(Equal("test"), "data group %v created outside the route group partition", key.Name)
+				for _, dg := range nsDg {
+					Expect(dg.Partition).To(Equal("test"), "data group %v created outside the route group partition", dg.Name)
+				}
+			}
+			Expect(len(rsCfg.Monitors)).To(Equal(1))
+			Expect(rsCfg.Monitors[0].Partition).To(Equal("test"))
+			Expect(rsCfg.Pools[0].MonitorNames).To(Equal([]MonitorName{{Name: JoinBigipPath("test", rsCfg.Monitors[0].Name)}}),
+				"pool should refer to the monitor in the route group partition")
+		})
+
+		It("Allow Source Range", func() {
+
+			routeGroup := "default"
+			mockCtlr.resources = NewResourceStore()
+			mockCtlr.resources.extdSpecMap[routeGroup] = &extendedParsedSpec{
+				override: true,
+				global: &ExtendedRouteGroupSpec{
+					VServerName:      "nextgenroutes",
+					VServerAddr:      "10.10.10.10",
+					AllowOverride:    "False",
+					SNAT:             "auto",
+					WAF:              "/Common/WAFPolicy",
+					IRules:           []string{"/Common/iRule1"},
+					AllowSourceRange: []string{"10.1.0.0/16", "10.2.0.0/16"},
+					TLS: TLS{
+						ClientSSL: "/Common/clientssl",
+						ServerSSL: "/Common/serverssl",
+						Reference: "bigip",
+					},
+				},
+				namespaces: []string{routeGroup},
+				partition:  "test",
+			}
+
+			spec1 := routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To: routeapi.RouteTargetReference{
+					Kind: "Service",
+					Name: "foo",
+				},
+				TLS: &routeapi.TLSConfig{Termination: "reencrypt"},
+			}
+			fooPorts := []v1.ServicePort{{Port: 80, NodePort: 30001},
+				{Port: 8080, NodePort: 38001},
+				{Port: 9090, NodePort: 39001}}
+			foo := test.NewService("foo", "1", routeGroup, "NodePort", fooPorts)
+			mockCtlr.addService(foo)
+			fooIps := []string{"10.1.1.1"}
+			fooEndpts := test.NewEndpoints(
+				"foo", "1", "node0", routeGroup, fooIps, []string{},
+				convertSvcPortsToEndpointPorts(fooPorts))
+			mockCtlr.addEndpoints(fooEndpts)
+			route1 := test.NewRoute("route1", "1", routeGroup, spec1,
+				map[string]string{VirtualMetadataAnnotationPrefix + "app": "foo"})
+
+			mockCtlr.addRoute(route1)
+			mockCtlr.resources.invertedNamespaceLabelMap[routeGroup] = routeGroup
+			err := mockCtlr.processRoutes(routeGroup, false)
+
+			Expect(err).To(BeNil())
+			Expect(reflect.DeepEqual(mockCtlr.resources.ltmConfig["test"].ResourceMap["nextgenroutes_443"].Virtual.AllowSourceRange, []string{"10.1.0.0/16", "10.2.0.0/16"})).To(BeTrue())
+			Expect(mockCtlr.resources.ltmConfig["test"].ResourceMap["nextgenroutes_443"].Virtual.Description).To(Equal(routeGroup+"/route1"),
+				"Description of route group virtual not set")
+			Expect(mockCtlr.resources.ltmConfig["test"].ResourceMap["nextgenroutes_443"].Virtual.Metadata).To(Equal(map[string]string{"app": "foo"}),
+				"Metadata of route group virtual not set")
+
+			mockCtlr.resources.extdSpecMap[routeGroup] = &extendedParsedSpec{
+				override: true,
+				local: &ExtendedRouteGroupSpec{
+					VServerName:      "nextgenroutes",
+					VServerAddr:      "10.10.10.10",
+					AllowOverride:    "True",
+					SNAT:             "auto",
+					WAF:              "/Common/WAFPolicy",
+					IRules:           []string{"/Common/iRule1"},
+					AllowSourceRange: []string{"10.3.0.0/16", "10.4.0.0/16"},
+					TLS: TLS{
+						ClientSSL: "/Common/clientssl",
+						ServerSSL: "/Common/serverssl",
+						Reference: "bigip",
+					},
+				},
+				global: &ExtendedRouteGroupSpec{
+					VServerName:      "nextgenroutes",
+					VServerAddr:      "10.10.10.10",
+					AllowOverride:    "True",
+					SNAT:             "auto",
+					WAF:              "/Common/WAFPolicy",
+					IRules:           []string{"/Common/iRule1"},
+					AllowSourceRange: []string{"10.1.0.0/16", "10.2.0.0/16"},
+					TLS: TLS{
+						ClientSSL: "/Common/clientssl",
+						ServerSSL: "/Common/serverssl",
+						Reference: "bigip",
+					},
+				},
+				namespaces: []string{routeGroup},
+				partition:  "test",
+			}
+			err = mockCtlr.processRoutes(routeGroup, false)
+			Expect(err).To(BeNil())
+			Expect(reflect.DeepEqual(mockCtlr.resources.ltmConfig["test"].ResourceMap["nextgenroutes_443"].Virtual.AllowSourceRange, []string{"10.3.0.0/16", "10.4.0.0/16"})).To(BeTrue())
+
+		})
+
+		It("Disabled route group virtual", func() {
+			routeGroup := "default"
+			disabled := false
+			enabled := true
+			mockCtlr.resources = NewResourceStore()
+			mockCtlr.resources.extdSpecMap[routeGroup] = &extendedParsedSpec{
+				override: true,
+				global: &ExtendedRouteGroupSpec{
+					VServerName:   "nextgenroutes",
+					VServerAddr:   "10.10.10.10",
+					AllowOverride: "True",
+					Enabled:       &disabled,
+				},
+				namespaces: []string{routeGroup},
+				partition:  "test",
+			}
+
+			spec1 := routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To: routeapi.RouteTargetReference{
+					Kind: "Service",
+					Name: "foo",
+				},
+			}
+			fooPorts := []v1.ServicePort{{Port: 80, NodePort: 30001}}
+			foo := test.NewService("foo", "1", routeGroup, "NodePort", fooPorts)
+			mockCtlr.addService(foo)
+			fooEndpts := test.NewEndpoints(
+				"foo", "1", "node0", routeGroup, []string{"10.1.1.1"}, []string{},
+				convertSvcPortsToEndpointPorts(fooPorts))
+			mockCtlr.addEndpoints(fooEndpts)
+			route1 := test.NewRoute("route1", "1", routeGroup, spec1, nil)
+			mockCtlr.addRoute(route1)
+			mockCtlr.resources.invertedNamespaceLabelMap[routeGroup] = routeGroup
+
+			err := mockCtlr.processRoutes(routeGroup, false)
+			Expect(err).To(BeNil())
+			rsCfg := mockCtlr.resources.ltmConfig["test"].ResourceMap["nextgenroutes_80"]
+			Expect(rsCfg).NotTo(BeNil(), "Disabled virtual should not be removed")
+			Expect(rsCfg.Virtual.Enabled).To(BeFalse(), "Virtual should be disabled")
+			sharedApp := as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			Expect(sharedApp["nextgenroutes_80"].(*as3Service).Enable).To(Equal(&disabled),
+				"Virtual should be posted as disabled")
+
+			// local spec overrides the global enabled state
+			mockCtlr.resources.extdSpecMap[routeGroup].local = &ExtendedRouteGroupSpec{
+				VServerName: "nextgenroutes",
+				VServerAddr: "10.10.10.10",
+				Enabled:     &enabled,
+			}
+			err = mockCtlr.processRoutes(routeGroup, false)
+			Expect(err).To(BeNil())
+			rsCfg = mockCtlr.resources.ltmConfig["test"].ResourceMap["nextgenroutes_80"]
+			Expect(rsCfg.Virtual.Enabled).To(BeTrue(), "Virtual should be enabled")
+			sharedApp = as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			Expect(sharedApp["nextgenroutes_80"].(*as3Service).Enable).To(BeNil())
+		})
+
+		It("HTTPS only route group", func() {
+			routeGroup := "default"
+			mockCtlr.resources = NewResourceStore()
+			mockCtlr.resources.extdSpecMap[routeGroup] = &extendedParsedSpec{
+				override: true,
+				global: &ExtendedRouteGroupSpec{
+					VServerName:   "nextgenroutes",
+					VServerAddr:   "10.10.10.10",
+					AllowOverride: "True",
+					HTTPSOnly:     true,
+					TLS: TLS{
+						ClientSSL: "/Common/clientssl",
+						Reference: BIGIP,
+					},
+				},
+				namespaces: []string{routeGroup},
+				partition:  "test",
+			}
+
+			spec1 := routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To: routeapi.RouteTargetReference{
+					Kind: "Service",
+					Name: "foo",
+				},
+				TLS: &routeapi.TLSConfig{
+					Termination:                   routeapi.TLSTerminationEdge,
+					InsecureEdgeTerminationPolicy: routeapi.InsecureEdgeTerminationPolicyAllow,
+				},
+			}
+			fooPorts := []v1.ServicePort{{Port: 80, NodePort: 30001}}
+			foo := test.NewService("foo", "1", routeGroup, "NodePort", fooPorts)
+			mockCtlr.addService(foo)
+			fooEndpts := test.NewEndpoints(
+				"foo", "1", "node0", routeGroup, []string{"10.1.1.1"}, []string{},
+				convertSvcPortsToEndpointPorts(fooPorts))
+			mockCtlr.addEndpoints(fooEndpts)
+			route1 := test.NewRoute("route1", "1", routeGroup, spec1, nil)
+			mockCtlr.addRoute(route1)
+			mockCtlr.resources.invertedNamespaceLabelMap[routeGroup] = routeGroup
+
+			err := mockCtlr.processRoutes(routeGroup, false)
+			Expect(err).To(BeNil())
+			Expect(mockCtlr.resources.ltmConfig["test"].ResourceMap).To(HaveKey("nextgenroutes_443"))
+			Expect(mockCtlr.resources.ltmConfig["test"].ResourceMap).NotTo(HaveKey("nextgenroutes_80"),
+				"HTTP virtual should not be created for an HTTPS only route group")
+
+			// httpsOnly is not allowed in the local configmap
+			errs := validateExtendedSpec(extendedSpec{
+				ExtendedRouteGroupConfigs: []ExtendedRouteGroupConfig{{
+					Namespace: routeGroup,
+					ExtendedRouteGroupSpec: ExtendedRouteGroupSpec{
+						VServerName: "nextgenroutes",
+						VServerAddr: "10.10.10.10",
+						HTTPSOnly:   true,
+					},
+				}},
+			}, false)
+			Expect(errs).To(ContainElement(ContainSubstring("httpsOnly can only be set in the global configmap")))
+		})
+
+		It("Route iRules merged with route group iRules", func() {
+			routeGroup := "default"
+			mockCtlr.resources = NewResourceStore()
+			groupIRules := []string{"/Common/group_irule"}
+			mockCtlr.resources.extdSpecMap[routeGroup] = &extendedParsedSpec{
+				override: true,
+				global: &ExtendedRouteGroupSpec{
+					VServerName:   "nextgenroutes",
+					VServerAddr:   "10.10.10.10",
+					AllowOverride: "True",
+					IRules:        groupIRules,
+				},
+				namespaces: []string{routeGroup},
+				partition:  "test",
+			}
+
+			for _, name := range []string{"foo", "bar"} {
+				ports := []v1.ServicePort{{Port: 80, NodePort: 30001}}
+				mockCtlr.addService(test.NewService(name, "1", routeGroup, "NodePort", ports))
+				mockCtlr.addEndpoints(test.NewEndpoints(
+					name, "1", "node0", routeGroup, []string{"10.1.1.1"}, []string{},
+					convertSvcPortsToEndpointPorts(ports)))
+			}
+			route1 := test.NewRoute("route1", "1", routeGroup, routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To:   routeapi.RouteTargetReference{Kind: "Service", Name: "foo"},
+			}, map[string]string{string(IRulesAnnotation): "/Common/foo_irule, /Common/group_irule"})
+			route2 := test.NewRoute("route2", "1", routeGroup, routeapi.RouteSpec{
+				Host: "bar.com",
+				Path: "/bar",
+				To:   routeapi.RouteTargetReference{Kind: "Service", Name: "bar"},
+			}, map[string]string{string(IRulesAnnotation): "/Common/bar_irule"})
+			mockCtlr.addRoute(route1)
+			mockCtlr.addRoute(route2)
+			mockCtlr.resources.invertedNamespaceLabelMap[routeGroup] = routeGroup
+
+			err := mockCtlr.processRoutes(routeGroup, false)
+			Expect(err).To(BeNil())
+			rsCfg := mockCtlr.resources.ltmConfig["test"].ResourceMap["nextgenroutes_80"]
+			Expect(rsCfg).NotTo(BeNil())
+			Expect(len(rsCfg.Virtual.IRules)).To(Equal(3), "iRules should be deduplicated")
+			Expect(rsCfg.Virtual.IRules[0]).To(Equal("/Common/group_irule"),
+				"Route group iRules should be attached first")
+			Expect(rsCfg.Virtual.IRules).To(ContainElements("/Common/foo_irule", "/Common/bar_irule"))
+			Expect(groupIRules).To(Equal([]string{"/Common/group_irule"}),
+				"Route group iRules should not be modified")
+		})
+
+		It("Mandatory route group iRules are attached ahead of other iRules", func() {
+			routeGroup := "default"
+			mockCtlr.resources = NewResourceStore()
+			mockCtlr.resources.extdSpecMap[routeGroup] = &extendedParsedSpec{
+				override: true,
+				global: &ExtendedRouteGroupSpec{
+					VServerName:     "nextgenroutes",
+					VServerAddr:     "10.10.10.10",
+					AllowOverride:   "True",
+					IRules:          []string{"/Common/group_irule"},
+					MandatoryIRules: []string{"/Common/mandatory_irule"},
+				},
+				local: &ExtendedRouteGroupSpec{
+					VServerName:     "nextgenroutes",
+					VServerAddr:     "10.10.10.10",
+					IRules:          []string{"/Common/local_irule"},
+					MandatoryIRules: []string{"/Common/local_mandatory_irule"},
+				},
+				namespaces: []string{routeGroup},
+				partition:  "test",
+			}
+
+			extdSpec, _ := mockCtlr.resources.getExtendedRouteSpec(routeGroup)
+			Expect(extdSpec.MandatoryIRules).To(Equal([]string{"/Common/mandatory_irule"}),
+				"Mandatory iRules should not be overridden by the local configMap")
+			Expect(extdSpec.IRules).To(Equal([]string{"/Common/local_irule"}))
+
+			ports := []v1.ServicePort{{Port: 80, NodePort: 30001}}
+			mockCtlr.addService(test.NewService("foo", "1", routeGroup, "NodePort", ports))
+			mockCtlr.addEndpoints(test.NewEndpoints(
+				"foo", "1", "node0", routeGroup, []string{"10.1.1.1"}, []string{},
+				convertSvcPortsToEndpointPorts(ports)))
+			route1 := test.NewRoute("route1", "1", routeGroup, routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To:   routeapi.RouteTargetReference{Kind: "Service", Name: "foo"},
+			}, map[string]string{string(IRulesAnnotation): "/Common/foo_irule, /Common/mandatory_irule"})
+			mockCtlr.addRoute(route1)
+			mockCtlr.resources.invertedNamespaceLabelMap[routeGroup] = routeGroup
+
+			err := mockCtlr.processRoutes(routeGroup, false)
+			Expect(err).To(BeNil())
+			rsCfg := mockCtlr.resources.ltmConfig["test"].ResourceMap["nextgenroutes_80"]
+			Expect(rsCfg).NotTo(BeNil())
+			Expect(rsCfg.Virtual.IRules).To(Equal([]string{
+				"/Common/mandatory_irule",
+				"/Common/local_irule",
+				"/Common/foo_irule",
+			}), "Mandatory iRules should be attached first and deduplicated")
+		})
+
+		It("Route group virtuals are deleted after the delete grace period", func() {
+			routeGroup := "default"
+			mockCtlr.routeGroupDeleteGrace = 200 * time.Millisecond
+			mockCtlr.nativeResourceQueue = workqueue.NewNamedRateLimitingQueue(
+				workqueue.DefaultControllerRateLimiter(), "native-resource-controller")
+			mockCtlr.resources = NewResourceStore()
+			mockCtlr.resources.extdSpecMap[routeGroup] = &extendedParsedSpec{
+				override: true,
+				global: &ExtendedRouteGroupSpec{
+					VServerName:   "nextgenroutes",
+					VServerAddr:   "10.10.10.10",
+					AllowOverride: "False",
+				},
+				namespaces: []string{routeGroup},
+				partition:  "test",
+			}
+			ports := []v1.ServicePort{{Port: 80, NodePort: 30001}}
+			mockCtlr.addService(test.NewService("foo", "1", routeGroup, "NodePort", ports))
+			route := test.NewRoute("route1", "1", routeGroup, routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To:   routeapi.RouteTargetReference{Kind: "Service", Name: "foo"},
+			}, nil)
+			mockCtlr.addRoute(route)
+			mockCtlr.resources.invertedNamespaceLabelMap[routeGroup] = routeGroup
+			Expect(mockCtlr.processRoutes(routeGroup, false)).To(BeNil())
+			Expect(mockCtlr.getVirtualServer("test", "nextgenroutes_80")).NotTo(BeNil())
+
+			// route recreated within the grace period keeps the virtual
+			mockCtlr.deleteRoute(route)
+			Expect(mockCtlr.processRoutes(routeGroup, false)).To(BeNil())
+			Expect(mockCtlr.getVirtualServer("test", "nextgenroutes_80")).NotTo(BeNil(),
+				"virtual should not be deleted within the grace period")
+			mockCtlr.addRoute(route)
+			Expect(mockCtlr.processRoutes(routeGroup, false)).To(BeNil())
+			Expect(mockCtlr.routeGroupDeletes).NotTo(HaveKey(routeGroup), "pending delete should be cancelled")
+			Consistently(mockCtlr.nativeResourceQueue.Len, 400*time.Millisecond).Should(Equal(0))
+			Expect(mockCtlr.getVirtualServer("test", "nextgenroutes_80")).NotTo(BeNil())
+
+			// route group is requeued on expiry of the grace period and the virtual is deleted
+			mockCtlr.deleteRoute(route)
+			Expect(mockCtlr.processRoutes(routeGroup, false)).To(BeNil())
+			Expect(mockCtlr.getVirtualServer("test", "nextgenroutes_80")).NotTo(BeNil())
+			Eventually(mockCtlr.nativeResourceQueue.Len, time.Second).Should(Equal(1))
+			key, _ := mockCtlr.nativeResourceQueue.Get()
+			Expect(key.(*rqKey).kind).To(Equal(RouteGroup))
+			Expect(mockCtlr.processRoutes(key.(*rqKey).rsc.(string), false)).To(BeNil())
+			Expect(mockCtlr.getVirtualServer("test", "nextgenroutes_80")).To(BeNil(),
+				"virtual should be deleted after the grace period")
+			Expect(mockCtlr.routeGroupDeletes).NotTo(HaveKey(routeGroup))
+		})
+
+		It("Deleting a route prunes its data group records", func() {
+			routeGroup := "default"
+			mockCtlr.resources = NewResourceStore()
+			mockCtlr.resources.extdSpecMap[routeGroup] = &extendedParsedSpec{
+				override: true,
+				global: &ExtendedRouteGroupSpec{
+					VServerName:   "nextgenroutes",
+					VServerAddr:   "10.10.10.10",
+					AllowOverride: "False",
+					TLS: TLS{
+						ClientSSL: "/Common/clientssl",
+						Reference: "bigip",
+					},
+				},
+				namespaces: []string{routeGroup},
+				partition:  "test",
+			}
+
+			fooPorts := []v1.ServicePort{{Port: 80, NodePort: 30001}}
+			mockCtlr.addService(test.NewService("foo", "1", routeGroup, "NodePort", fooPorts))
+			mockCtlr.addEndpoints(test.NewEndpoints(
+				"foo", "1", "node0", routeGroup, []string{"10.1.1.1"}, []string{},
+				convertSvcPortsToEndpointPorts(fooPorts)))
+			var routes []*routeapi.Route
+			for i, host := range []string{"foo.com", "bar.com"} {
+				route := test.NewRoute(fmt.Sprintf("route%d", i+1), "1", routeGroup, routeapi.RouteSpec{
+					Host: host,
+					Path: "/app",
+					To: routeapi.RouteTargetReference{
+						Kind: "Service",
+						Name: "foo",
+					},
+					TLS: &routeapi.TLSConfig{
+						Termination:                   "edge",
+						InsecureEdgeTerminationPolicy: routeapi.InsecureEdgeTerminationPolicyRedirect,
+					},
+				}, nil)
+				mockCtlr.addRoute(route)
+				routes = append(routes, route)
+			}
+			mockCtlr.resources.invertedNamespaceLabelMap[routeGroup] = routeGroup
+			err := mockCtlr.processRoutes(routeGroup, false)
+			Expect(err).To(BeNil())
+
+			edgeDgKey := NameRef{Name: getRSCfgResName("nextgenroutes_443", EdgeHostsDgName), Partition: "test"}
+			edgeRecords := func() []string {
+				var names []string
+				rsCfg := mockCtlr.getVirtualServer("test", "nextgenroutes_443")
+				for _, dg := range rsCfg.IntDgMap[edgeDgKey] {
+					for _, rec := range dg.Records {
+						names = append(names, rec.Name)
+					}
+				}
+				return names
+			}
+			redirectRecords := func() []string {
+				var names []string
+				rsCfg := mockCtlr.getVirtualServer("test", "nextgenroutes_80")
+				for _, nsDg := range rsCfg.IntDgMap {
+					for _, dg := range nsDg {
+						for _, rec := range dg.Records {
+							names = append(names, rec.Name)
+						}
+					}
+				}
+				return names
+			}
+			Expect(edgeRecords()).To(ConsistOf("foo.com/app", "bar.com/app"))
+			Expect(redirectRecords()).To(ContainElement(ContainSubstring("bar.com")))
+
+			mockCtlr.deleteRoute(routes[1])
+			mockCtlr.deleteHostPathMapEntry(routes[1])
+			mockCtlr.pruneRouteDataGroupRecords(routeGroup, routes[1])
+			Expect(edgeRecords()).To(ConsistOf("foo.com/app"), "record of the deleted route should be pruned")
+			serverSslDgKey := NameRef{Name: getRSCfgResName("nextgenroutes_443", EdgeServerSslDgName), Partition: "test"}
+			Expect(mockCtlr.getVirtualServer("test", "nextgenroutes_443").IntDgMap[serverSslDgKey][routeGroup].Records).To(
+				Equal(InternalDataGroupRecords{{Name: "foo.com/app", Data: "false"}}), "only the deleted route server name record should be pruned")
+
+			err = mockCtlr.processRoutes(routeGroup, false)
+			Expect(err).To(BeNil())
+			Expect(edgeRecords()).To(ConsistOf("foo.com/app"), "record of the deleted route should be pruned")
+			Expect(redirectRecords()).NotTo(ContainElement(ContainSubstring("bar.com")),
+				"redirect record of the deleted route should be pruned")
+			Expect(redirectRecords()).To(ContainElement(ContainSubstring("foo.com")))
+		})
+
+		It("A/B Persistence", func() {
+			rsCfg := &ResourceConfig{}
+			rsCfg.Virtual.SetVirtualAddress("10.10.10.10", DEFAULT_HTTPS_PORT)
+			extdSpec := &ExtendedRouteGroupSpec{
+				VServerName: "nextgenroutes",
+				VServerAddr: "10.10.10.10",
+				ABPersistence: &ABPersistence{
+					CookieName: "ab_cookie",
+					TTL:        3600,
+				},
+			}
+			err := mockCtlr.handleRouteGroupExtendedSpec(rsCfg, extdSpec)
+			Expect(err).To(BeNil())
+			Expect(rsCfg.Virtual.ABPersistence).NotTo(BeNil())
+
+			iRule := mockCtlr.getTLSIRule("nextgenroutes_443", "test", nil, rsCfg.Virtual.ABPersistence, "")
+			Expect(iRule).To(ContainSubstring("persisted_pool"))
+			Expect(iRule).To(ContainSubstring(`HTTP::cookie value "ab_cookie"`))
+			Expect(iRule).To(ContainSubstring(`HTTP::cookie expires "ab_cookie" 3600 relative`))
+
+			iRule = mockCtlr.getTLSIRule("nextgenroutes_443", "test", nil, nil, "")
+			Expect(iRule).NotTo(ContainSubstring("persisted_pool"))
+			Expect(iRule).NotTo(ContainSubstring("HTTP::cookie"))
+
+			extdSpec.ABPersistence = &ABPersistence{CookieName: "ab;cookie"}
+			err = mockCtlr.handleRouteGroupExtendedSpec(rsCfg, extdSpec)
+			Expect(err).NotTo(BeNil())
+
+			extdSpec.ABPersistence = &ABPersistence{TTL: -1}
+			err = mockCtlr.handleRouteGroupExtendedSpec(rsCfg, extdSpec)
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("Health monitor send string with host header", func() {
+			var extdSpec ExtendedRouteGroupSpec
+			Expect(yaml.Unmarshal([]byte(`
+vserverAddr: 10.10.10.10
+healthMonitors:
+- path: "foo.com/app/health"
+  autoHostHeader: true
+  recv: "ok"
+  interval: 5
+- path: "bar.com"
+  type: https
+  autoHostHeader: true
+- path: "baz.com/"
+  send: "GET / HTTP/1.1\r\nHost: baz.com\r\nConnection: Close\r\n\r\n"
+`), &extdSpec)).To(BeNil())
+			rsCfg := &ResourceConfig{}
+			rsCfg.Virtual.SetVirtualAddress("10.10.10.10", DEFAULT_HTTP_PORT)
+			Expect(mockCtlr.handleRouteGroupExtendedSpec(rsCfg, &extdSpec)).To(BeNil())
+			Expect(len(rsCfg.Monitors)).To(Equal(3))
+			Expect(rsCfg.Monitors[0].Send).To(Equal("GET /app/health HTTP/1.1\r\nHost: foo.com\r\nConnection: Close\r\n\r\n"))
+			Expect(rsCfg.Monitors[1].Send).To(Equal("GET / HTTP/1.1\r\nHost: bar.com\r\nConnection: Close\r\n\r\n"))
+			// generated send string is identical to the explicit one
+			explicit := rsCfg.Monitors[2]
+			Expect(explicit.AutoHostHeader).To(BeFalse())
+			rsCfg = &ResourceConfig{}
+			extdSpec.HealthMonitors = Monitors{{Path: "baz.com/", AutoHostHeader: true}}
+			Expect(mockCtlr.handleRouteGroupExtendedSpec(rsCfg, &extdSpec)).To(BeNil())
+			Expect(rsCfg.Monitors[0].Send).To(Equal(explicit.Send))
+
+			// send string and autoHostHeader are mutually exclusive
+			extdSpec.HealthMonitors = Monitors{{Path: "baz.com/", Send: "GET /", AutoHostHeader: true}}
+			Expect(mockCtlr.handleRouteGroupExtendedSpec(&ResourceConfig{}, &extdSpec)).NotTo(BeNil())
+			// host header is not applicable to tcp monitors
+			extdSpec.HealthMonitors = Monitors{{Path: "baz.com/", Type: "tcp", AutoHostHeader: true}}
+			Expect(mockCtlr.handleRouteGroupExtendedSpec(&ResourceConfig{}, &extdSpec)).NotTo(BeNil())
+		})
+
+		It("A/B Deployment with balance per backend", func() {
+			fooWeight := int32(80)
+			barWeight := int32(20)
+			spec := routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To: routeapi.RouteTargetReference{
+					Kind:   "Service",
+					Name:   "foo",
+					Weight: &fooWeight,
+				},
+				AlternateBackends: []routeapi.RouteTargetReference{
+					{Kind: "Service", Name: "bar", Weight: &barWeight},
+				},
+			}
+			route := test.NewRoute("route1", "1", "default", spec, map[string]string{
+				BackendBalanceAnnotationPrefix + "foo": "least-connections-member",
+				BackendBalanceAnnotationPrefix + "bar": "ratio-member",
+			})
+			rsCfg := &ResourceConfig{}
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.Virtual.SetVirtualAddress("10.10.10.10", DEFAULT_HTTP_PORT)
+			err := mockCtlr.prepareResourceConfigFromRoute(rsCfg, route, intstr.IntOrString{IntVal: 80},
+				portStruct{protocol: HTTP, port: DEFAULT_HTTP_PORT})
+			Expect(err).To(BeNil())
+			Expect(len(rsCfg.Pools)).To(Equal(2))
+			Expect(rsCfg.Pools[0].ServiceName).To(Equal("foo"))
+			Expect(rsCfg.Pools[0].Balance).To(Equal("least-connections-member"), "Invalid balance of primary backend")
+			Expect(rsCfg.Pools[1].ServiceName).To(Equal("bar"))
+			Expect(rsCfg.Pools[1].Balance).To(Equal("ratio-member"), "Invalid balance of alternate backend")
+
+			// backends without balance annotation fall back to the route balance and then the default balance
+			route.Annotations = map[string]string{
+				"virtual-server.f5.com/balance":        "least-connections-node",
+				BackendBalanceAnnotationPrefix + "bar": "ratio-member",
+			}
+			backends := GetRouteBackends(route)
+			Expect(backends[0].Balance).To(Equal("least-connections-node"))
+			Expect(backends[1].Balance).To(Equal("ratio-member"))
+			route.Annotations = nil
+			backends = GetRouteBackends(route)
+			Expect(backends[0].Balance).To(Equal(DEFAULT_BALANCE))
+			Expect(backends[1].Balance).To(Equal(DEFAULT_BALANCE))
+		})
+
+		It("A/B Deployment with data group and pool member ratio", func() {
+			routeGroup := "default"
+			mockCtlr.resources = NewResourceStore()
+			mockCtlr.resources.extdSpecMap[routeGroup] = &extendedParsedSpec{
+				override: true,
+				global: &ExtendedRouteGroupSpec{
+					VServerName:   "nextgenroutes",
+					VServerAddr:   "10.10.10.10",
+					AllowOverride: "False",
+					TLS: TLS{
+						ClientSSL: "/Common/clientssl",
+						Reference: "bigip",
+					},
+				},
+				namespaces: []string{routeGroup},
+				partition:  "test",
+			}
+			backendAddrs := map[string][]string{"foo": {"10.1.1.1", "10.1.1.3"}, "bar": {"10.1.1.2"}}
+			for _, name := range []string{"foo", "bar"} {
+				ports := []v1.ServicePort{{Port: 80, NodePort: 30001}}
+				svc := test.NewService(name, "1", routeGroup, "NodePort", ports)
+				svc.Spec.ClusterIP = "None"
+				eps := test.NewEndpoints(
+					name, "1", "node0", routeGroup, backendAddrs[name], []string{},
+					convertSvcPortsToEndpointPorts(ports))
+				mockCtlr.addService(svc)
+				mockCtlr.addEndpoints(eps)
+				Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+			}
+			fooWeight := int32(60)
+			barWeight := int32(20)
+			route := test.NewRoute("route1", "1", routeGroup, routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To:   routeapi.RouteTargetReference{Kind: "Service", Name: "foo", Weight: &fooWeight},
+				AlternateBackends: []routeapi.RouteTargetReference{
+					{Kind: "Service", Name: "bar", Weight: &barWeight},
+				},
+				TLS: &routeapi.TLSConfig{Termination: "edge"},
+			}, nil)
+			mockCtlr.addRoute(route)
+			mockCtlr.resources.invertedNamespaceLabelMap[routeGroup] = routeGroup
+			abDgKey := NameRef{Name: getRSCfgResName("nextgenroutes_443", AbDeploymentDgName), Partition: "test"}
+			fooPool := formatPoolName(routeGroup, "foo", intstr.IntOrString{IntVal: 80}, "", "")
+			barPool := formatPoolName(routeGroup, "bar", intstr.IntOrString{IntVal: 80}, "", "")
+
+			// data group splits the traffic across a pool per backend
+			Expect(mockCtlr.processRoutes(routeGroup, false)).To(BeNil())
+			rsCfg := mockCtlr.getVirtualServer("test", "nextgenroutes_443")
+			Expect(rsCfg).NotTo(BeNil())
+			Expect(len(rsCfg.Pools)).To(Equal(2))
+			Expect(rsCfg.Pools[0].WeightedBackends).To(BeNil())
+			Expect(rsCfg.IntDgMap).To(HaveKey(abDgKey))
+			Expect(rsCfg.IntDgMap[abDgKey][routeGroup].Records[0].Data).To(Equal(
+				fmt.Sprintf("%s,0.750;%s,1.000", fooPool, barPool)))
+			Expect(rsCfg.Policies).To(BeEmpty(), "A/B deployment should not have a policy rule")
+
+			// single pool with members weighted by ratio
+			route.Annotations = map[string]string{string(ABDeploymentModeAnnotation): ABDeploymentModeRatio}
+			mockCtlr.addRoute(route)
+			Expect(mockCtlr.processRoutes(routeGroup, false)).To(BeNil())
+			rsCfg = mockCtlr.getVirtualServer("test", "nextgenroutes_443")
+			Expect(len(rsCfg.Pools)).To(Equal(1))
+			Expect(rsCfg.Pools[0].Name).To(Equal(fooPool))
+			Expect(rsCfg.Pools[0].Balance).To(Equal("ratio-member"))
+			Expect(rsCfg.Pools[0].WeightedBackends).To(Equal([]WeightedBackend{
+				{ServiceName: "foo", Ratio: 75},
+				{ServiceName: "bar", Ratio: 25},
+			}))
+			ratios := make(map[string]int)
+			for _, mem := range rsCfg.Pools[0].Members {
+				ratios[mem.Address] = mem.Ratio
+			}
+			// the ratio of foo is divided across its two members
+			Expect(ratios).To(Equal(map[string]int{"10.1.1.1": 100, "10.1.1.3": 100, "10.1.1.2": 67}),
+				"Invalid pool member ratios")
+			if dgs, ok := rsCfg.IntDgMap[abDgKey]; ok {
+				Expect(dgs[routeGroup].Records).To(BeEmpty(), "ratio A/B deployment should not use the data group")
+			}
+			Expect(rsCfg.Policies).NotTo(BeEmpty(), "ratio A/B deployment should have a policy rule")
+			sharedApp := as3Application{}
+			createPoolDecl(rsCfg, sharedApp, false, "test")
+			Expect(sharedApp[fooPool].(*as3Pool).LoadBalancingMode).To(Equal("ratio-member"))
+			for _, mem := range sharedApp[fooPool].(*as3Pool).Members {
+				Expect(mem.Ratio).To(Equal(ratios[mem.ServerAddresses[0]]))
+			}
+
+			// backends with 0 weight are skipped and invalid weights fall back to the data group
+			zeroWeight := int32(0)
+			route.Spec.AlternateBackends[0].Weight = &zeroWeight
+			backends, err := getRouteRatioBackends(route)
+			Expect(err).To(BeNil())
+			Expect(backends).To(Equal([]WeightedBackend{{ServiceName: "foo", Ratio: 100}}))
+			route.Spec.To.Weight = &zeroWeight
+			_, err = getRouteRatioBackends(route)
+			Expect(err).NotTo(BeNil(), "weights summing to 0 should not be allowed")
+			Expect(isRouteABRatioDeployment(route)).To(BeFalse())
+			route.Annotations[string(ABDeploymentModeAnnotation)] = "invalid"
+			route.Spec.To.Weight = &fooWeight
+			Expect(isRouteABRatioDeployment(route)).To(BeFalse())
+		})
+
+	})
+	Describe("Pool member updates", func() {
+		It("Updates only the pool members of the route group config", func() {
+			rsCfg := newPoolMembersRouteGroup(mockCtlr.Controller, 2)
+			rsCfg.Policies = Policies{{Name: "nextgenroutes_443_policy", Partition: "test"}}
+			rsName := rsCfg.Virtual.Name
+
+			mockCtlr.resources.poolMemCache["default/svc-1"].memberMap[portRef{port: 8080}] = []PoolMember{
+				{Address: "10.1.1.1", Port: 8080}, {Address: "10.1.1.2", Port: 8080},
+			}
+			mockCtlr.updatePoolMembersForRoutes("default")
+
+			freshRsCfg := mockCtlr.getVirtualServer("test", rsName)
+			Expect(freshRsCfg).NotTo(BeIdenticalTo(rsCfg), "Stored config should be replaced")
+			Expect(freshRsCfg.Pools[1].Members).To(Equal([]PoolMember{
+				{Address: "10.1.1.1", Port: 8080}, {Address: "10.1.1.2", Port: 8080},
+			}), "Pool members not updated")
+			Expect(rsCfg.Pools[1].Members).To(Equal([]PoolMember{{Address: "10.1.1.1", Port: 8080}}),
+				"Pool members of the previous config should not be changed")
+			Expect(freshRsCfg.Pools[0]).To(Equal(rsCfg.Pools[0]), "Other pools should not be changed")
+			Expect(&freshRsCfg.Policies[0]).To(BeIdenticalTo(&rsCfg.Policies[0]),
+				"Policies should be shared with the previous config")
+			Expect(reflect.ValueOf(freshRsCfg.IntDgMap).Pointer()).To(Equal(reflect.ValueOf(rsCfg.IntDgMap).Pointer()),
+				"Data groups should be shared with the previous config")
+
+			// the stored config is kept without any member change
+			mockCtlr.updatePoolMembersForRoutes("default")
+			Expect(mockCtlr.getVirtualServer("test", rsName)).To(BeIdenticalTo(freshRsCfg),
+				"Stored config should be kept when the members are unchanged")
+		})
+	})
+})
+
+var _ = Describe("With NamespaceLabel parameter in deployment", func() {
+	var mockCtlr *mockController
+	BeforeEach(func() {
+		mockCtlr = newMockController()
 		mockCtlr.mode = OpenShiftMode
 		mockCtlr.routeClientV1 = fakeRouteClient.NewSimpleClientset().RouteV1()
+		mockCtlr.routerName = F5RouterName
 		mockCtlr.namespaces = make(map[string]bool)
 		mockCtlr.namespaces["default"] = true
 		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
@@ -1004,6 +2413,7 @@ var _ = Describe("With NamespaceLabel parameter in deployment", func() {
 		mockCtlr.namespaceLabel = "environment=dev"
 		var processedHostPath ProcessedHostPath
 		processedHostPath.processedHostPathMap = make(map[string]metav1.Time)
+		processedHostPath.processedHostPathPriority = make(map[string]int)
 		mockCtlr.processedHostPath = &processedHostPath
 		mockCtlr.TeemData = &teem.TeemsData{
 			ResourceType: teem.ResourceTypes{
@@ -1060,6 +2470,93 @@ extendedRouteSpec:
 			Expect(err).To(BeNil())
 			Expect(ok).To(BeTrue())
 		})
+		It("with namespaceLabel resolving to namespaces in different partitions", func() {
+			for ns, team := range map[string]string{"default": "a", "test": "b"} {
+				_, _ = mockCtlr.kubeClient.CoreV1().Namespaces().Create(context.TODO(),
+					test.NewNamespace(ns, "1", map[string]string{"environment": "dev", "foo": "true", "team": team}),
+					metav1.CreateOptions{})
+				fooPorts := []v1.ServicePort{{Port: 80, NodePort: 30001}}
+				mockCtlr.addService(test.NewService("foo", "1", ns, "NodePort", fooPorts))
+				mockCtlr.addEndpoints(test.NewEndpoints(
+					"foo", "1", "node0", ns, []string{"10.1.1.1"}, []string{},
+					convertSvcPortsToEndpointPorts(fooPorts)))
+				mockCtlr.addRoute(test.NewRoute("route1", "1", ns, routeapi.RouteSpec{
+					Host: ns + ".foo.com",
+					To: routeapi.RouteTargetReference{
+						Kind: "Service",
+						Name: "foo",
+					},
+				}, nil))
+			}
+			data["extendedSpec"] = `
+extendedRouteSpec:
+    - namespaceLabel: foo=true
+      vserverAddr: 10.8.3.11
+      vserverName: nextgenroutes
+      bigIpPartition: foo
+      partitionLabels:
+        team=a: teama
+        team=b: teamb
+`
+			mockCtlr.namespaceLabelMode = true
+			err, ok := mockCtlr.processConfigMap(cm, false)
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeTrue())
+			Expect(mockCtlr.resources.extdSpecMap["foo=true"].namespacePartitions).To(Equal(
+				map[string]string{"default": "teama", "test": "teamb"}))
+			Expect(mockCtlr.resources.getRouteGroupPartitions("foo=true")).To(Equal([]string{"foo", "teama", "teamb"}))
+
+			for ns, partition := range map[string]string{"default": "teama", "test": "teamb"} {
+				rsCfg := mockCtlr.getVirtualServer(partition, "nextgenroutes_80")
+				Expect(rsCfg).NotTo(BeNil(), "virtual of namespace %v not created in partition %v", ns, partition)
+				Expect(len(rsCfg.Pools)).To(Equal(1))
+				Expect(rsCfg.Pools[0].ServiceNamespace).To(Equal(ns))
+				Expect(rsCfg.Pools[0].Partition).To(Equal(partition))
+			}
+			Expect(mockCtlr.getVirtualServer("foo", "nextgenroutes_80")).To(BeNil(),
+				"no virtual expected in the route group partition")
+
+			// relabeling a namespace moves its virtual to the partition of its new partition label
+			mockCtlr.nativeResourceQueue = workqueue.NewNamedRateLimitingQueue(
+				workqueue.DefaultControllerRateLimiter(), "native-resource-controller")
+			mockCtlr.Agent = newMockAgent(nil)
+			mockCtlr.requestQueue = &requestQueue{sync.Mutex{}, list.New()}
+			_, _ = mockCtlr.kubeClient.CoreV1().ConfigMaps(cm.Namespace).Create(context.TODO(), cm, metav1.CreateOptions{})
+			// the namespace label informer of the route group isn't started against the fake client
+			mockCtlr.nsInformers[mockCtlr.namespaceLabel+",foo=true"] = &NSInformer{}
+			oldNs, _ := mockCtlr.kubeClient.CoreV1().Namespaces().Get(context.TODO(), "test", metav1.GetOptions{})
+			newNs := oldNs.DeepCopy()
+			newNs.Labels["team"] = "a"
+			_, _ = mockCtlr.kubeClient.CoreV1().Namespaces().Update(context.TODO(), newNs, metav1.UpdateOptions{})
+			mockCtlr.enqueueUpdatedNamespace(oldNs, oldNs.DeepCopy())
+			Expect(mockCtlr.nativeResourceQueue.Len()).To(BeZero(), "namespace without label changes enqueued")
+			mockCtlr.enqueueUpdatedNamespace(oldNs, newNs)
+			Expect(mockCtlr.nativeResourceQueue.Len()).To(Equal(1), "relabeled namespace not enqueued")
+			Expect(mockCtlr.processNativeResource()).To(BeTrue())
+			Expect(mockCtlr.resources.extdSpecMap["foo=true"].namespacePartitions).To(Equal(
+				map[string]string{"default": "teama", "test": "teama"}))
+			rsCfg := mockCtlr.getVirtualServer("teama", "nextgenroutes_80")
+			Expect(rsCfg).NotTo(BeNil())
+			Expect(len(rsCfg.Pools)).To(Equal(2), "pool of the relabeled namespace not moved")
+			Expect(mockCtlr.getVirtualServer("teamb", "nextgenroutes_80")).To(BeNil(),
+				"virtual of the previous partition of the namespace not deleted")
+
+			// namespace matching partition labels of two partitions is invalid
+			data["extendedSpec"] = `
+extendedRouteSpec:
+    - namespaceLabel: foo=true
+      vserverAddr: 10.8.3.11
+      vserverName: nextgenroutes
+      bigIpPartition: foo
+      partitionLabels:
+        team=a: teama
+        environment=dev: teamb
+`
+			err, ok = mockCtlr.processConfigMap(cm, false)
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("namespace default maps to partitions"))
+			Expect(ok).To(BeFalse())
+		})
 	})
 })
 
@@ -1101,3 +2598,47 @@ extendedRouteSpec:
 		})
 	})
 })
+
+// newPoolMembersRouteGroup stores the route group config of the default namespace with pools
+// of the services svc-0 to svc-n, each of them having a single endpoint
+func newPoolMembersRouteGroup(ctlr *Controller, pools int) *ResourceConfig {
+	ctlr.resources.invertedNamespaceLabelMap["default"] = "default"
+	ctlr.resources.extdSpecMap["default"] = &extendedParsedSpec{
+		global:    &ExtendedRouteGroupSpec{VServerName: "nextgenroutes", VServerAddr: "10.8.3.11"},
+		partition: "test",
+	}
+	rsCfg := &ResourceConfig{}
+	rsCfg.Virtual.Name = frameRouteVSName("nextgenroutes", "10.8.3.11", portStruct{HTTPS, DEFAULT_HTTPS_PORT})
+	rsCfg.IntDgMap = make(InternalDataGroupMap)
+	rsCfg.IRulesMap = make(IRulesMap)
+	for i := 0; i < pools; i++ {
+		svcName := fmt.Sprintf("svc-%d", i)
+		members := []PoolMember{{Address: "10.1.1.1", Port: 8080}}
+		ctlr.resources.poolMemCache["default/"+svcName] = poolMembersInfo{
+			svcType:   v1.ServiceTypeClusterIP,
+			memberMap: map[portRef][]PoolMember{{port: 8080}: members},
+		}
+		rsCfg.Pools = append(rsCfg.Pools, Pool{
+			Name:             svcName + "_8080_default",
+			Partition:        "test",
+			ServiceName:      svcName,
+			ServiceNamespace: "default",
+			ServicePort:      intstr.FromInt(8080),
+			Members:          members,
+		})
+	}
+	rsCfg.MetaData.Active = true
+	ctlr.resources.getPartitionResourceMap("test")[rsCfg.Virtual.Name] = rsCfg
+	return rsCfg
+}
+
+func BenchmarkUpdatePoolMembersForRoutes(b *testing.B) {
+	ctlr := &Controller{resources: NewResourceStore()}
+	newPoolMembersRouteGroup(ctlr, 500)
+	memberMap := ctlr.resources.poolMemCache["default/svc-0"].memberMap
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		memberMap[portRef{port: 8080}] = []PoolMember{{Address: fmt.Sprintf("10.2.%d.%d", i/250%250, i%250), Port: 8080}}
+		ctlr.updatePoolMembersForRoutes("default")
+	}
+}
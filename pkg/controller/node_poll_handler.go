@@ -13,6 +13,7 @@ import (
 
 	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 func (ctlr *Controller) SetupNodePolling(
@@ -85,65 +86,71 @@ func (ctlr *Controller) ProcessNodeUpdate(
 		// Compare last set of nodes with new one
 		if !reflect.DeepEqual(newNodes, ctlr.oldNodes) {
 			log.Debugf("Processing Node Updates")
-			// Handle NodeLabelUpdates
-			if ctlr.PoolMemberType == NodePort {
-				if ctlr.watchingAllNamespaces() {
-					crInf, _ := ctlr.getNamespacedInformer("")
-					virtuals := crInf.vsInformer.GetIndexer().List()
-					if len(virtuals) != 0 {
-						for _, virtual := range virtuals {
-							vs := virtual.(*cisapiv1.VirtualServer)
-							qKey := &rqKey{
-								vs.ObjectMeta.Namespace,
-								VirtualServer,
-								vs.ObjectMeta.Name,
-								vs,
-								Update,
-							}
-							ctlr.rscQueue.Add(qKey)
-						}
+			// Handle NodeLabelUpdates for the virtuals with NodePort pool members
+			if ctlr.watchingAllNamespaces() {
+				crInf, _ := ctlr.getNamespacedInformer("")
+				virtuals := crInf.vsInformer.GetIndexer().List()
+				for _, virtual := range virtuals {
+					vs := virtual.(*cisapiv1.VirtualServer)
+					if ctlr.getPoolMemberType(vs.Spec.PoolMemberType) != NodePort {
+						continue
 					}
-					transportVirtuals := crInf.tsInformer.GetIndexer().List()
-					if len(transportVirtuals) != 0 {
-						for _, virtual := range transportVirtuals {
-							vs := virtual.(*cisapiv1.TransportServer)
-							qKey := &rqKey{
-								vs.ObjectMeta.Namespace,
-								TransportServer,
-								vs.ObjectMeta.Name,
-								vs,
-								Update,
-							}
-							ctlr.rscQueue.Add(qKey)
-						}
+					qKey := &rqKey{
+						vs.ObjectMeta.Namespace,
+						VirtualServer,
+						vs.ObjectMeta.Name,
+						vs,
+						Update,
+					}
+					ctlr.rscQueue.Add(qKey)
+				}
+				transportVirtuals := crInf.tsInformer.GetIndexer().List()
+				for _, virtual := range transportVirtuals {
+					vs := virtual.(*cisapiv1.TransportServer)
+					if ctlr.getPoolMemberType(vs.Spec.PoolMemberType) != NodePort {
+						continue
+					}
+					qKey := &rqKey{
+						vs.ObjectMeta.Namespace,
+						TransportServer,
+						vs.ObjectMeta.Name,
+						vs,
+						Update,
 					}
+					ctlr.rscQueue.Add(qKey)
+				}
 
-				} else {
-					ctlr.namespacesMutex.Lock()
-					defer ctlr.namespacesMutex.Unlock()
-					for ns, _ := range ctlr.namespaces {
-						virtuals := ctlr.getAllVirtualServers(ns)
-						transportVirtuals := ctlr.getAllTransportServers(ns)
-						for _, virtual := range virtuals {
-							qKey := &rqKey{
-								ns,
-								VirtualServer,
-								virtual.ObjectMeta.Name,
-								virtual,
-								Update,
-							}
-							ctlr.rscQueue.Add(qKey)
+			} else {
+				ctlr.namespacesMutex.Lock()
+				defer ctlr.namespacesMutex.Unlock()
+				for ns, _ := range ctlr.namespaces {
+					virtuals := ctlr.getAllVirtualServers(ns)
+					transportVirtuals := ctlr.getAllTransportServers(ns)
+					for _, virtual := range virtuals {
+						if ctlr.getPoolMemberType(virtual.Spec.PoolMemberType) != NodePort {
+							continue
+						}
+						qKey := &rqKey{
+							ns,
+							VirtualServer,
+							virtual.ObjectMeta.Name,
+							virtual,
+							Update,
+						}
+						ctlr.rscQueue.Add(qKey)
+					}
+					for _, virtual := range transportVirtuals {
+						if ctlr.getPoolMemberType(virtual.Spec.PoolMemberType) != NodePort {
+							continue
 						}
-						for _, virtual := range transportVirtuals {
-							qKey := &rqKey{
-								ns,
-								TransportServer,
-								virtual.ObjectMeta.Name,
-								virtual,
-								Update,
-							}
-							ctlr.rscQueue.Add(qKey)
+						qKey := &rqKey{
+							ns,
+							TransportServer,
+							virtual.ObjectMeta.Name,
+							virtual,
+							Update,
 						}
+						ctlr.rscQueue.Add(qKey)
 					}
 				}
 			}
@@ -210,16 +217,16 @@ func (ctlr *Controller) getNodesWithLabel(
 ) []Node {
 	allNodes := ctlr.getNodesFromCache()
 
-	label := strings.Split(nodeMemberLabel, "=")
-	if len(label) != 2 {
+	// NodeMemberLabel is a full label selector, so set-based
+	// requirements like "key in (a,b)" are supported along with "key=value"
+	selector, err := labels.Parse(nodeMemberLabel)
+	if err != nil || selector.Empty() {
 		log.Warningf("Invalid NodeMemberLabel: %v", nodeMemberLabel)
 		return nil
 	}
-	labelKey := label[0]
-	labelValue := label[1]
 	var nodes []Node
 	for _, node := range allNodes {
-		if node.Labels[labelKey] == labelValue {
+		if selector.Matches(labels.Set(node.Labels)) {
 			nodes = append(nodes, node)
 		}
 	}
@@ -69,7 +69,27 @@ var _ = Describe("Node Poller Handler", func() {
 		nodes = mockCtlr.getNodesWithLabel("app=test")
 		Expect(nodes).ToNot(BeNil(), "Failed to get Nodes with Label")
 
-		nodes = mockCtlr.getNodesWithLabel("app")
+		nodes = mockCtlr.getNodesWithLabel("app in (")
 		Expect(nodes).To(BeNil(), "Failed to Validate Nodes with Label")
 	})
+
+	It("Nodes with set-based label selector", func() {
+		mockCtlr.oldNodes = []Node{
+			{Name: "worker1", Addr: "1.2.3.4", Labels: map[string]string{"zone": "a"}},
+			{Name: "worker2", Addr: "1.2.3.5", Labels: map[string]string{"zone": "b"}},
+			{Name: "worker3", Addr: "1.2.3.6", Labels: map[string]string{"zone": "c"}},
+		}
+
+		nodes := mockCtlr.getNodesWithLabel("zone in (a,b)")
+		Expect(len(nodes)).To(Equal(2), "Failed to get Nodes with set-based Label")
+		Expect(nodes[0].Name).To(Equal("worker1"))
+		Expect(nodes[1].Name).To(Equal("worker2"))
+
+		nodes = mockCtlr.getNodesWithLabel("zone notin (a,b)")
+		Expect(len(nodes)).To(Equal(1), "Failed to get Nodes with set-based Label")
+		Expect(nodes[0].Name).To(Equal("worker3"))
+
+		members := mockCtlr.getEndpointsForNodePort(30000, "zone in (a,b)")
+		Expect(len(members)).To(Equal(2), "Failed to get members with set-based Label")
+	})
 })
@@ -1,9 +1,14 @@
 package controller
 
 import (
+	"context"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // Creates a new ClientSSL profile from a Secret
@@ -12,6 +17,10 @@ func (ctlr *Controller) createSecretClientSSLProfile(
 	secret *v1.Secret,
 	tlsCipher TLSCipher,
 	context string,
+	peerCertMode string,
+	caFile string,
+	renegotiation bool,
+	alpnProtocols []string,
 ) (error, bool) {
 
 	if _, ok := secret.Data["tls.key"]; !ok {
@@ -26,7 +35,7 @@ func (ctlr *Controller) createSecretClientSSLProfile(
 		return err, false
 	}
 
-	return ctlr.createClientSSLProfile(rsCfg, string(secret.Data["tls.key"]), string(secret.Data["tls.crt"]), secret.ObjectMeta.Name, secret.ObjectMeta.Namespace, tlsCipher, context)
+	return ctlr.createClientSSLProfile(rsCfg, string(secret.Data["tls.key"]), string(secret.Data["tls.crt"]), secret.ObjectMeta.Name, secret.ObjectMeta.Namespace, tlsCipher, context, peerCertMode, caFile, renegotiation, alpnProtocols)
 }
 
 // Creates a new ClientSSL profile from a Secret
@@ -38,8 +47,16 @@ func (ctlr *Controller) createClientSSLProfile(
 	namespace string,
 	tlsCipher TLSCipher,
 	context string,
+	peerCertMode string,
+	caFile string,
+	renegotiation bool,
+	alpnProtocols []string,
 ) (error, bool) {
 
+	if peerCertMode == PeerCertRequired && caFile == "" {
+		return fmt.Errorf("Invalid clientssl profile '%v': CA certificate is required to authenticate clients", name), false
+	}
+
 	// Create Default for SNI profile
 	skey := SecretKey{
 		Name:         fmt.Sprintf("default-%s-%s", context, rsCfg.GetName()),
@@ -52,7 +69,7 @@ func (ctlr *Controller) createClientSSLProfile(
 	}
 	if _, ok := rsCfg.customProfiles[skey]; !ok {
 		// This is just a basic profile, so we don't need all the fields
-		cp := NewCustomProfile(sni, "", "", "", true, "", "", "", tlsCipher)
+		cp := NewCustomProfile(sni, "", "", "", true, "", "", "", tlsCipher, renegotiation)
 		rsCfg.customProfiles[skey] = cp
 	}
 
@@ -72,17 +89,23 @@ func (ctlr *Controller) createClientSSLProfile(
 		key,
 		"",    // serverName
 		false, // sni
-		"",    // peerCertMode
-		"",    // caFile
-		"",    // chainCA,
+		peerCertMode,
+		caFile,
+		"", // chainCA,
 		tlsCipher,
+		renegotiation,
 	)
+	cp.ALPNProtocols = alpnProtocols
+	cp.TLSOptions = tlsCipher.TLSOptions
 	skey = SecretKey{
 		Name:         cp.Name,
 		ResourceName: rsCfg.GetName(),
 	}
 	if prof, ok := rsCfg.customProfiles[skey]; ok {
-		if prof != cp {
+		// server names and sni default are derived from the hosts of the TLS contexts
+		cp.SNIServerNames = prof.SNIServerNames
+		cp.SNIDefault = prof.SNIDefault
+		if !reflect.DeepEqual(prof, cp) {
 			rsCfg.customProfiles[skey] = cp
 			rsCfg.Virtual.AddOrUpdateProfile(profRef)
 			return nil, true
@@ -95,12 +118,179 @@ func (ctlr *Controller) createClientSSLProfile(
 	return nil, false
 }
 
+// createPairedClientSSLProfile creates the clientssl profile of the paired secret of the TLS context,
+// which shares the server names of the profile of the primary secret
+func (ctlr *Controller) createPairedClientSSLProfile(
+	rsCfg *ResourceConfig,
+	primary *v1.Secret,
+	tlsContext TLSContext,
+	tlsCipher TLSCipher,
+	peerCertMode string,
+	caFile string,
+	renegotiation bool,
+) error {
+	name := tlsContext.bigIPSSLProfiles.pairedClientSSL
+	secret, ok := ctlr.SSLContext[name]
+	if !ok {
+		var err error
+		secret, err = ctlr.kubeClient.CoreV1().Secrets(tlsContext.namespace).
+			Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("secret %s not found", name)
+		}
+		ctlr.SSLContext[name] = secret
+	}
+	if err := validateCertificatePair(primary, secret); err != nil {
+		return err
+	}
+	err, _ := ctlr.createSecretClientSSLProfile(rsCfg, secret, tlsCipher, CustomProfileClient,
+		peerCertMode, caFile, renegotiation, tlsContext.bigIPSSLProfiles.alpnProtocols)
+	if err != nil {
+		return err
+	}
+	skey := SecretKey{Name: secret.ObjectMeta.Name, ResourceName: rsCfg.GetName()}
+	prof := rsCfg.customProfiles[skey]
+	prof.PairedProfile = primary.ObjectMeta.Name
+	rsCfg.customProfiles[skey] = prof
+	rsCfg.updateSNIProfiles()
+	return nil
+}
+
+// validateCertificatePair checks that the certificates of the secrets are of different key types,
+// so that BIG-IP selects one of them by the signature algorithms supported by the client
+func validateCertificatePair(primary, paired *v1.Secret) error {
+	primaryType, err := getCertificateKeyType(primary.Data["tls.crt"], primary.Data["tls.key"])
+	if err != nil {
+		return fmt.Errorf("invalid certificate of secret '%v': %v", primary.ObjectMeta.Name, err)
+	}
+	pairedType, err := getCertificateKeyType(paired.Data["tls.crt"], paired.Data["tls.key"])
+	if err != nil {
+		return fmt.Errorf("invalid certificate of secret '%v': %v", paired.ObjectMeta.Name, err)
+	}
+	if primaryType == pairedType {
+		return fmt.Errorf("certificates of secrets '%v' and '%v' are both of key type %v, expected %v and %v",
+			primary.ObjectMeta.Name, paired.ObjectMeta.Name, primaryType, CertificateKeyRSA, CertificateKeyECDSA)
+	}
+	return nil
+}
+
+// addSNIServerNames adds the host names to the server names the clientssl profile is selected for with SNI
+func (rsCfg *ResourceConfig) addSNIServerNames(hostnames []string, profName string) {
+	key := SecretKey{Name: profName, ResourceName: rsCfg.GetName()}
+	prof, ok := rsCfg.customProfiles[key]
+	if !ok {
+		return
+	}
+	// a new slice is framed as the profile may share it with the cached config
+	sniServerNames := append([]string{}, prof.SNIServerNames...)
+	for _, hostname := range hostnames {
+		if hostname != "" && !containsString(sniServerNames, hostname) {
+			sniServerNames = append(sniServerNames, hostname)
+		}
+	}
+	// the catch-all profile is left without server names
+	if len(sniServerNames) > 0 {
+		sort.Strings(sniServerNames)
+		prof.SNIServerNames = sniServerNames
+		rsCfg.customProfiles[key] = prof
+	}
+	rsCfg.updateSNIProfiles()
+}
+
+// getPathServerNames returns the distinct server names presented in SNI to the backends of the pool paths
+func (tlsContext TLSContext) getPathServerNames() []string {
+	var serverNames []string
+	for _, poolPathRef := range tlsContext.poolPathRefs {
+		if poolPathRef.serverName != "" && !containsString(serverNames, poolPathRef.serverName) {
+			serverNames = append(serverNames, poolPathRef.serverName)
+		}
+	}
+	return serverNames
+}
+
+// addPathServerNames adds the server names of the pool paths to the serverssl profiles of the virtual,
+// the virtual shared by multiple resources gets the server names of all of them
+func (rsCfg *ResourceConfig) addPathServerNames(serverNames []string) {
+	for key, prof := range rsCfg.customProfiles {
+		if prof.Context != CustomProfileServer || key.ResourceName != rsCfg.GetName() {
+			continue
+		}
+		// a new slice is framed as the profile may share it with the cached config
+		pathServerNames := append([]string{}, prof.PathServerNames...)
+		for _, serverName := range serverNames {
+			if !containsString(pathServerNames, serverName) {
+				pathServerNames = append(pathServerNames, serverName)
+			}
+		}
+		sort.Strings(pathServerNames)
+		prof.PathServerNames = pathServerNames
+		rsCfg.customProfiles[key] = prof
+	}
+}
+
+// getServerNameTLSClientName returns the name of the serverssl profile presenting the server name in SNI
+func getServerNameTLSClientName(svcName, serverName string) string {
+	return AS3NameFormatter(fmt.Sprintf("%s_%s_tls_client", svcName, serverName))
+}
+
+// getServerNames returns the host names served by the clientssl profile of the TLS context
+func (tlsContext TLSContext) getServerNames() []string {
+	if len(tlsContext.serverNames) > 0 {
+		return tlsContext.serverNames
+	}
+	return []string{tlsContext.hostname}
+}
+
+// updateSNIProfiles sets SNIDefault only on one clientssl profile of the virtual, the catch-all profile
+// without server names is preferred, else the first profile also serves the hosts matching no profile
+func (rsCfg *ResourceConfig) updateSNIProfiles() {
+	var sniProfiles, pairedProfiles []SecretKey
+	for _, key := range getSortedCustomProfileKeys(rsCfg.customProfiles) {
+		prof := rsCfg.customProfiles[key]
+		if prof.Context != CustomProfileClient || prof.Cert == "" || prof.Key == "" {
+			continue
+		}
+		if prof.PairedProfile != "" {
+			pairedProfiles = append(pairedProfiles, key)
+			continue
+		}
+		sniProfiles = append(sniProfiles, key)
+	}
+	if len(sniProfiles) == 0 {
+		return
+	}
+
+	sniDefault := sniProfiles[0]
+	for _, key := range sniProfiles {
+		if len(rsCfg.customProfiles[key].SNIServerNames) == 0 {
+			sniDefault = key
+			break
+		}
+	}
+	for key, prof := range rsCfg.customProfiles {
+		if prof.Context != CustomProfileClient {
+			continue
+		}
+		prof.SNIDefault = key == sniDefault
+		rsCfg.customProfiles[key] = prof
+	}
+	// paired profiles are selected by the key type for the server names of their primary profile
+	for _, key := range pairedProfiles {
+		prof := rsCfg.customProfiles[key]
+		prof.SNIServerNames = rsCfg.customProfiles[SecretKey{Name: prof.PairedProfile, ResourceName: key.ResourceName}].SNIServerNames
+		rsCfg.customProfiles[key] = prof
+	}
+}
+
 // Creates a new ServerSSL profile from a Secret
 func (ctlr *Controller) createSecretServerSSLProfile(
 	rsCfg *ResourceConfig,
 	secret *v1.Secret,
 	tlsCipher TLSCipher,
 	context string,
+	renegotiation bool,
+	serverName string,
+	ignoreServerNameCheck bool,
 ) (error, bool) {
 
 	// tls.key is not mandatory for ServerSSL Profile
@@ -109,10 +299,12 @@ func (ctlr *Controller) createSecretServerSSLProfile(
 			secret.ObjectMeta.Name)
 		return err, false
 	}
-	return ctlr.createServerSSLProfile(rsCfg, string(secret.Data["tls.crt"]), "", secret.ObjectMeta.Name, secret.ObjectMeta.Namespace, tlsCipher, context)
+	return ctlr.createServerSSLProfile(rsCfg, string(secret.Data["tls.crt"]), "", secret.ObjectMeta.Name, secret.ObjectMeta.Namespace, tlsCipher, context,
+		renegotiation, serverName, ignoreServerNameCheck)
 }
 
-// Creates a new ServerSSL profile from a Secret
+// Creates a new ServerSSL profile from a Secret, the server certificate of the backends
+// must match the serverName unless the server name check is ignored
 func (ctlr *Controller) createServerSSLProfile(
 	rsCfg *ResourceConfig,
 	cert string,
@@ -121,6 +313,9 @@ func (ctlr *Controller) createServerSSLProfile(
 	namespace string,
 	tlsCipher TLSCipher,
 	context string,
+	renegotiation bool,
+	serverName string,
+	ignoreServerNameCheck bool,
 ) (error, bool) {
 
 	// Create Default for SNI profile
@@ -135,7 +330,7 @@ func (ctlr *Controller) createServerSSLProfile(
 	}
 	if _, ok := rsCfg.customProfiles[skey]; !ok {
 		// This is just a basic profile, so we don't need all the fields
-		cp := NewCustomProfile(sni, "", "", "", true, "", "", "", tlsCipher)
+		cp := NewCustomProfile(sni, "", "", "", true, "", "", "", tlsCipher, renegotiation)
 		rsCfg.customProfiles[skey] = cp
 	}
 	// TODO
@@ -158,13 +353,21 @@ func (ctlr *Controller) createServerSSLProfile(
 		"",        // caFile
 		certchain, // certchain,
 		tlsCipher,
+		renegotiation,
 	)
+	if ignoreServerNameCheck {
+		cp.IgnoreServerNameCheck = true
+	} else {
+		cp.ServerName = serverName
+	}
 	skey = SecretKey{
 		Name:         cp.Name,
 		ResourceName: rsCfg.GetName(),
 	}
 	if prof, ok := rsCfg.customProfiles[skey]; ok {
-		if prof != cp {
+		// server names of the pool paths are added by the resources sharing the virtual
+		cp.PathServerNames = prof.PathServerNames
+		if !reflect.DeepEqual(prof, cp) {
 			rsCfg.customProfiles[skey] = cp
 			rsCfg.Virtual.AddOrUpdateProfile(profRef)
 			return nil, true
@@ -176,3 +379,46 @@ func (ctlr *Controller) createServerSSLProfile(
 	rsCfg.Virtual.AddOrUpdateProfile(profRef)
 	return nil, false
 }
+
+// isRenegotiationEnabled returns whether TLS renegotiation is enabled on the SSL profiles, it is enabled unless disabled explicitly
+func (sslProfiles BigIPSSLProfiles) isRenegotiationEnabled() bool {
+	return sslProfiles.renegotiation == nil || *sslProfiles.renegotiation
+}
+
+// getClientAuthCA returns the CA bundle used to authenticate the client certificates of the TLS context
+func (ctlr *Controller) getClientAuthCA(tlsContext TLSContext) (string, error) {
+	caSecret := tlsContext.bigIPSSLProfiles.clientCASecret
+	if tlsContext.bigIPSSLProfiles.peerCertMode != PeerCertRequired || caSecret == "" {
+		return "", nil
+	}
+	secret, ok := ctlr.SSLContext[caSecret]
+	if !ok {
+		var err error
+		secret, err = ctlr.kubeClient.CoreV1().Secrets(tlsContext.namespace).
+			Get(context.TODO(), caSecret, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("CA secret %s not found: %v", caSecret, err)
+		}
+		ctlr.SSLContext[caSecret] = secret
+	}
+	caFile, ok := secret.Data["ca.crt"]
+	if !ok || len(caFile) == 0 {
+		return "", fmt.Errorf("Invalid Secret '%v': 'ca.crt' field not specified.", caSecret)
+	}
+	return string(caFile), nil
+}
+
+// validateTLSOptions checks that the TLS options are known protocol versions disabled on the clientssl profile
+func validateTLSOptions(options []string) error {
+	for _, option := range options {
+		switch option {
+		case TLSOptionNoSSL, TLSOptionNoSSLv3, TLSOptionNoTLSv1, TLSOptionNoTLSv1_1,
+			TLSOptionNoTLSv1_2, TLSOptionNoTLSv1_3, TLSOptionNoDTLS, TLSOptionNoDTLSv12:
+		default:
+			return fmt.Errorf("invalid TLS option %v, allowed options are %v", option, strings.Join([]string{
+				TLSOptionNoSSL, TLSOptionNoSSLv3, TLSOptionNoTLSv1, TLSOptionNoTLSv1_1,
+				TLSOptionNoTLSv1_2, TLSOptionNoTLSv1_3, TLSOptionNoDTLS, TLSOptionNoDTLSv12}, ", "))
+		}
+	}
+	return nil
+}
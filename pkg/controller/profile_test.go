@@ -14,9 +14,7 @@ var _ = Describe("Profile", func() {
 		mockCtlr.resources = NewResourceStore()
 		mockCtlr.mode = CustomResourceMode
 		mockCtlr.resources.supplementContextCache.baseRouteConfig.TLSCipher = TLSCipher{
-			"1.2",
-			"",
-			"",
+			TLSVersion: "1.2",
 		}
 
 	})
@@ -52,27 +50,53 @@ var _ = Describe("Profile", func() {
 
 		tlsCipher := mockCtlr.resources.supplementContextCache.baseRouteConfig.TLSCipher
 
-		err, updated := mockCtlr.createSecretClientSSLProfile(rsCfg, secret, tlsCipher, "clientside")
+		err, updated := mockCtlr.createSecretClientSSLProfile(rsCfg, secret, tlsCipher, "clientside", "", "", true, nil)
 		Expect(err).To(BeNil(), "Failed to Create Client SSL")
 		Expect(updated).To(BeFalse(), "Failed to Create Client SSL")
 
-		err, updated = mockCtlr.createSecretClientSSLProfile(rsCfg, secret, tlsCipher, "clientside")
+		err, updated = mockCtlr.createSecretClientSSLProfile(rsCfg, secret, tlsCipher, "clientside", "", "", true, nil)
 		Expect(err).To(BeNil(), "Failed to Create Client SSL")
 		Expect(updated).To(BeFalse(), "Failed to Create Client SSL")
 
 		secret.Data["tls.crt"] = []byte("dfaf")
-		err, updated = mockCtlr.createSecretClientSSLProfile(rsCfg, secret, tlsCipher, "clientside")
+		err, updated = mockCtlr.createSecretClientSSLProfile(rsCfg, secret, tlsCipher, "clientside", "", "", true, nil)
 		Expect(err).To(BeNil(), "Failed to Update Client SSL")
 		Expect(updated).To(BeTrue(), "Failed to Update Client SSL")
+		Expect(rsCfg.customProfiles[SecretKey{Name: "SampleSecret", ResourceName: "crd_virtual_server"}].DisableRenegotiation).
+			To(BeFalse(), "Renegotiation should be enabled on Client SSL")
+
+		err, updated = mockCtlr.createSecretClientSSLProfile(rsCfg, secret, tlsCipher, "clientside", "", "", false, nil)
+		Expect(err).To(BeNil(), "Failed to Update Client SSL")
+		Expect(updated).To(BeTrue(), "Failed to disable renegotiation on Client SSL")
+		Expect(rsCfg.customProfiles[SecretKey{Name: "SampleSecret", ResourceName: "crd_virtual_server"}].DisableRenegotiation).
+			To(BeTrue(), "Renegotiation should be disabled on Client SSL")
+
+		alpnProtocols := []string{ALPNHTTP2, ALPNHTTP11}
+		err, updated = mockCtlr.createSecretClientSSLProfile(rsCfg, secret, tlsCipher, "clientside", "", "", false, alpnProtocols)
+		Expect(err).To(BeNil(), "Failed to Update Client SSL")
+		Expect(updated).To(BeTrue(), "Failed to set ALPN protocols on Client SSL")
+		Expect(rsCfg.customProfiles[SecretKey{Name: "SampleSecret", ResourceName: "crd_virtual_server"}].ALPNProtocols).
+			To(Equal(alpnProtocols), "ALPN protocols not set on Client SSL")
+
+		tlsCipher.TLSOptions = []string{TLSOptionNoTLSv1, TLSOptionNoTLSv1_1}
+		err, updated = mockCtlr.createSecretClientSSLProfile(rsCfg, secret, tlsCipher, "clientside", "", "", false, alpnProtocols)
+		Expect(err).To(BeNil(), "Failed to Update Client SSL")
+		Expect(updated).To(BeTrue(), "Failed to set TLS options on Client SSL")
+		Expect(rsCfg.customProfiles[SecretKey{Name: "SampleSecret", ResourceName: "crd_virtual_server"}].TLSOptions).
+			To(Equal([]string{TLSOptionNoTLSv1, TLSOptionNoTLSv1_1}), "TLS options not set on Client SSL")
+		tlsCipher.TLSOptions = nil
+
+		Expect(validateTLSOptions([]string{TLSOptionNoSSLv3, TLSOptionNoTLSv1_3})).To(BeNil())
+		Expect(validateTLSOptions([]string{"no-tlsv2"})).NotTo(BeNil(), "Unknown TLS option should be invalid")
 
 		// Negative Cases
 		delete(secret.Data, "tls.crt")
-		err, updated = mockCtlr.createSecretClientSSLProfile(rsCfg, secret, tlsCipher, "clientside")
+		err, updated = mockCtlr.createSecretClientSSLProfile(rsCfg, secret, tlsCipher, "clientside", "", "", true, nil)
 		Expect(err).ToNot(BeNil(), "Failed to Validate Client SSL")
 		Expect(updated).To(BeFalse(), "Failed to Validate Client SSL")
 
 		delete(secret.Data, "tls.key")
-		err, updated = mockCtlr.createSecretClientSSLProfile(rsCfg, secret, tlsCipher, "clientside")
+		err, updated = mockCtlr.createSecretClientSSLProfile(rsCfg, secret, tlsCipher, "clientside", "", "", true, nil)
 		Expect(err).ToNot(BeNil(), "Failed to Validate Client SSL")
 		Expect(updated).To(BeFalse(), "Failed to Validate Client SSL")
 
@@ -106,25 +130,126 @@ var _ = Describe("Profile", func() {
 		}
 		secret.Data["tls.crt"] = []byte("ahfa;osejfn;kahse;ha")
 		tlsCipher := mockCtlr.resources.supplementContextCache.baseRouteConfig.TLSCipher
-		err, updated := mockCtlr.createSecretServerSSLProfile(rsCfg, secret, tlsCipher, "clientside")
+		err, updated := mockCtlr.createSecretServerSSLProfile(rsCfg, secret, tlsCipher, "clientside", true, "", false)
 		Expect(err).To(BeNil(), "Failed to Create Server SSL")
 		Expect(updated).To(BeFalse(), "Failed to Create Server SSL")
 
-		err, updated = mockCtlr.createSecretServerSSLProfile(rsCfg, secret, tlsCipher, "clientside")
+		err, updated = mockCtlr.createSecretServerSSLProfile(rsCfg, secret, tlsCipher, "clientside", true, "", false)
 		Expect(err).To(BeNil(), "Failed to Create Server SSL")
 		Expect(updated).To(BeFalse(), "Failed to Create Server SSL")
 
 		secret.Data["tls.crt"] = []byte("dfaf")
-		err, updated = mockCtlr.createSecretServerSSLProfile(rsCfg, secret, tlsCipher, "clientside")
+		err, updated = mockCtlr.createSecretServerSSLProfile(rsCfg, secret, tlsCipher, "clientside", true, "", false)
 		Expect(err).To(BeNil(), "Failed to Update Server SSL")
 		Expect(updated).To(BeTrue(), "Failed to Update Server SSL")
 
+		err, updated = mockCtlr.createSecretServerSSLProfile(rsCfg, secret, tlsCipher, "clientside", false, "", false)
+		Expect(err).To(BeNil(), "Failed to Update Server SSL")
+		Expect(updated).To(BeTrue(), "Failed to disable renegotiation on Server SSL")
+		Expect(rsCfg.customProfiles[SecretKey{Name: "SampleSecret", ResourceName: "crd_virtual_server"}].DisableRenegotiation).
+			To(BeTrue(), "Renegotiation should be disabled on Server SSL")
+
+		// explicit server name
+		err, updated = mockCtlr.createSecretServerSSLProfile(rsCfg, secret, tlsCipher, "clientside", true, "backend.test.com", false)
+		Expect(err).To(BeNil(), "Failed to Update Server SSL")
+		Expect(updated).To(BeTrue(), "Failed to set server name on Server SSL")
+		prof := rsCfg.customProfiles[SecretKey{Name: "SampleSecret", ResourceName: "crd_virtual_server"}]
+		Expect(prof.ServerName).To(Equal("backend.test.com"))
+		Expect(prof.IgnoreServerNameCheck).To(BeFalse())
+
+		// ignore the server name check
+		err, updated = mockCtlr.createSecretServerSSLProfile(rsCfg, secret, tlsCipher, "clientside", true, "backend.test.com", true)
+		Expect(err).To(BeNil(), "Failed to Update Server SSL")
+		Expect(updated).To(BeTrue(), "Failed to ignore server name check on Server SSL")
+		prof = rsCfg.customProfiles[SecretKey{Name: "SampleSecret", ResourceName: "crd_virtual_server"}]
+		Expect(prof.ServerName).To(BeEmpty(), "Server name should not be set when the check is ignored")
+		Expect(prof.IgnoreServerNameCheck).To(BeTrue())
+
 		// Negative Cases
 		delete(secret.Data, "tls.crt")
-		err, updated = mockCtlr.createSecretServerSSLProfile(rsCfg, secret, tlsCipher, "clientside")
+		err, updated = mockCtlr.createSecretServerSSLProfile(rsCfg, secret, tlsCipher, "clientside", true, "", false)
 		Expect(err).ToNot(BeNil(), "Failed to Validate Server SSL")
 		Expect(updated).To(BeFalse(), "Failed to Validate Server SSL")
 
 	})
 
+	It("Client SSL with SNI", func() {
+		rsCfg := &ResourceConfig{
+			MetaData: metaData{
+				ResourceType: VirtualServer,
+			},
+			Virtual: Virtual{
+				Name:      "crd_virtual_server",
+				Partition: "test",
+				Profiles:  ProfileRefs{},
+			},
+			customProfiles: make(map[SecretKey]CustomProfile),
+			IntDgMap:       make(InternalDataGroupMap),
+		}
+		tlsCipher := mockCtlr.resources.supplementContextCache.baseRouteConfig.TLSCipher
+		newSecret := func(name, namespace string) *v1.Secret {
+			return &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+				},
+				Data: map[string][]byte{
+					"tls.key": []byte("key-" + name),
+					"tls.crt": []byte("crt-" + name),
+				},
+			}
+		}
+
+		// Two VirtualServers sharing the same IP contribute certificates to the same virtual
+		for _, host := range []struct {
+			hostnames []string
+			secret    *v1.Secret
+		}{
+			{[]string{"foo.com"}, newSecret("foo-secret", "default")},
+			{[]string{"bar.com"}, newSecret("bar-secret", "other")},
+			{[]string{"b.shared.com", "a.shared.com"}, newSecret("shared-secret", "default")},
+			{nil, newSecret("zoo-secret", "default")},
+		} {
+			err, _ := mockCtlr.createSecretClientSSLProfile(rsCfg, host.secret, tlsCipher, CustomProfileClient, "", "", true, nil)
+			Expect(err).To(BeNil(), "Failed to Create Client SSL")
+			rsCfg.addSNIServerNames(host.hostnames, host.secret.Name)
+		}
+
+		profKey := func(name string) SecretKey {
+			return SecretKey{Name: name, ResourceName: rsCfg.GetName()}
+		}
+		Expect(rsCfg.customProfiles[profKey("foo-secret")].SNIServerNames).
+			To(Equal([]string{"foo.com"}), "Invalid server names")
+		Expect(rsCfg.customProfiles[profKey("shared-secret")].SNIServerNames).
+			To(Equal([]string{"a.shared.com", "b.shared.com"}), "Invalid server names")
+		Expect(rsCfg.customProfiles[profKey("zoo-secret")].SNIServerNames).
+			To(BeEmpty(), "Catch-all profile should not have server names")
+
+		sniDefaults := 0
+		for _, prof := range rsCfg.customProfiles {
+			if prof.SNIDefault {
+				sniDefaults++
+				Expect(prof.Name).To(Equal("zoo-secret"), "Catch-all profile should be SNI default")
+			}
+		}
+		Expect(sniDefaults).To(Equal(1), "Only one profile should be SNI default")
+
+		// Reprocessing the secret retains the SNI settings
+		err, updated := mockCtlr.createSecretClientSSLProfile(rsCfg, newSecret("foo-secret", "default"), tlsCipher, CustomProfileClient, "", "", true, nil)
+		Expect(err).To(BeNil(), "Failed to Create Client SSL")
+		Expect(updated).To(BeFalse(), "Client SSL should not be updated")
+		Expect(rsCfg.customProfiles[profKey("foo-secret")].SNIServerNames).
+			To(Equal([]string{"foo.com"}), "Server names should be retained")
+
+		// Without a catch-all, the first profile is SNI default
+		delete(rsCfg.customProfiles, profKey("zoo-secret"))
+		rsCfg.updateSNIProfiles()
+		Expect(rsCfg.customProfiles[profKey("bar-secret")].SNIDefault).
+			To(BeTrue(), "First profile should be SNI default")
+		Expect(rsCfg.customProfiles[profKey("foo-secret")].SNIDefault).
+			To(BeFalse(), "Only one profile should be SNI default")
+		Expect(rsCfg.customProfiles[profKey("shared-secret")].SNIDefault).
+			To(BeFalse(), "Profile of multiple hosts should not be SNI default")
+	})
+
 })
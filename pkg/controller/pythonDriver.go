@@ -201,7 +201,8 @@ func (agent *Agent) healthCheckPythonDriver() {
 	http.Handle("/metrics", promhttp.Handler())
 	// Add health check to track whether Python process still alive
 	hc := &health.HealthChecker{
-		SubPID: agent.PythonDriverPID,
+		SubPID:       agent.PythonDriverPID,
+		LastSyncTime: agent.getLastSyncTime,
 	}
 	http.Handle("/health", hc.HealthCheckHandler())
 	bigIPPrometheus.RegisterMetrics()
@@ -21,12 +21,15 @@ import (
 	"encoding/json"
 	"fmt"
 	ficV1 "github.com/F5Networks/f5-ipam-controller/pkg/ipamapis/apis/fic/v1"
+	"hash/fnv"
 	"net"
 	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/tools/cache"
 
@@ -57,8 +60,10 @@ func (rs *ResourceStore) Init() {
 	rs.extdSpecMap = make(extendedSpecMap)
 	rs.invertedNamespaceLabelMap = make(map[string]string)
 	rs.svcResourceCache = make(map[string]map[string]struct{})
+	rs.secretResourceCache = make(map[string]map[resourceRef]struct{})
 	rs.ipamContext = make(map[string]ficV1.IPSpec)
 	rs.processedNativeResources = make(map[resourceRef]struct{})
+	rs.as3NameSources = make(map[string]map[string]map[string]struct{})
 }
 
 const (
@@ -67,6 +72,9 @@ const (
 	DEFAULT_HTTP_PORT         int32  = 80
 	DEFAULT_HTTPS_PORT        int32  = 443
 	DEFAULT_SNAT              string = "auto"
+	DEFAULT_IRULE_PRIORITY    int    = 500
+	DEFAULT_UDP_PROFILE       string = "/Common/udp"
+	DEFAULT_SCTP_PROFILE      string = "/Common/sctp"
 	urlRewriteRulePrefix             = "url-rewrite-rule-"
 	appRootForwardRulePrefix         = "app-root-forward-rule-"
 	appRootRedirectRulePrefix        = "app-root-redirect-rule-"
@@ -84,6 +92,39 @@ const (
 	PeerCertIgnored  = "ignore"
 	PeerCertDefault  = PeerCertIgnored
 
+	// Constants for Pool.ServiceDownAction
+	ServiceDownActionNone     = "none"
+	ServiceDownActionReset    = "reset"
+	ServiceDownActionDrop     = "drop"
+	ServiceDownActionReselect = "reselect"
+
+	// Hashing algorithms of Pool.Balance persisting the connections by the hash key
+	BalanceCARP = "carp"
+	BalanceHash = "hash"
+	// Constants for HashKey.Source
+	HashKeySourceIP       = "source-ip"
+	HashKeyHeader         = "header"
+	HashKeyCookie         = "cookie"
+	DefaultHashKeyTimeout = 180
+
+	// Constants for CustomProfile.ALPNProtocols
+	ALPNHTTP2  = "h2"
+	ALPNHTTP11 = "http/1.1"
+
+	// Constants for CustomProfile.TLSOptions
+	TLSOptionNoSSL     = "no-ssl"
+	TLSOptionNoSSLv3   = "no-sslv3"
+	TLSOptionNoTLSv1   = "no-tlsv1"
+	TLSOptionNoTLSv1_1 = "no-tlsv1.1"
+	TLSOptionNoTLSv1_2 = "no-tlsv1.2"
+	TLSOptionNoTLSv1_3 = "no-tlsv1.3"
+	TLSOptionNoDTLS    = "no-dtls"
+	TLSOptionNoDTLSv12 = "no-dtlsv1.2"
+
+	// Key types of the certificates of the clientSSLs of a TLSProfile
+	CertificateKeyRSA   = "RSA"
+	CertificateKeyECDSA = "ECDSA"
+
 	// Constants
 	HttpRedirectIRuleName = "http_redirect_irule"
 	// Constants
@@ -91,6 +132,54 @@ const (
 	// Internal data group for https redirect
 	HttpsRedirectDgName = "https_redirect_dg"
 	TLSIRuleName        = "tls_irule"
+	// iRule serving the sorry page of a VirtualServer
+	SorryPageIRuleName = "sorry_page_irule"
+	// iRule overriding the SNAT of the virtual for the pools with own SNAT
+	PoolSNATIRuleName = "pool_snat_irule"
+	// Internal data group for the custom https redirect targets of the hosts
+	HttpsRedirectTargetDgName = "https_redirect_target_dg"
+	// Status code of the http redirect when not specified
+	DefaultHTTPRedirectCode = 302
+	// Status code and content type of the sorry page when not specified
+	DefaultSorryPageStatusCode  = 503
+	DefaultSorryPageContentType = "text/html"
+)
+
+// constants for http2 health monitor of gRPC backends
+const (
+	MonitorTypeHTTP2      = "http2"
+	DefaultGRPCHealthSend = "POST /grpc.health.v1.Health/Check HTTP/2.0\r\nContent-Type: application/grpc\r\nTE: trailers\r\n\r\n"
+	DefaultGRPCHealthRecv = "200"
+)
+
+// AS3 does not provide a sctp monitor, sctp TransportServers are monitored with icmp by default
+const MonitorTypeICMP = "icmp"
+
+// constants for adaptive monitor divergence types
+const (
+	AdaptiveDivergenceAbsolute = "absolute"
+	AdaptiveDivergenceRelative = "relative"
+)
+
+// constants for proxy types of the HTTP profile
+const (
+	HTTPProxyReverse     = "reverse"
+	HTTPProxyTransparent = "transparent"
+	HTTPProxyExplicit    = "explicit"
+)
+
+// constants for header match operators
+const (
+	HeaderMatchEquals   = "equals"
+	HeaderMatchContains = "contains"
+)
+
+// constants for persistence types
+const (
+	SourceAddressPersistence      = "source-address"
+	DestinationAddressPersistence = "destination-address"
+	UniversalPersistence          = "universal"
+	CookiePersistence             = "cookie"
 )
 
 // constants for TLS references
@@ -113,17 +202,19 @@ func NewCustomProfile(
 	caFile string,
 	chainCA string,
 	tlsCipher TLSCipher,
+	renegotiation bool,
 ) CustomProfile {
 	cp := CustomProfile{
-		Name:         profile.Name,
-		Partition:    profile.Partition,
-		Context:      profile.Context,
-		Cert:         cert,
-		Key:          key,
-		ServerName:   serverName,
-		SNIDefault:   sni,
-		PeerCertMode: peerCertMode,
-		ChainCA:      chainCA,
+		Name:                 profile.Name,
+		Partition:            profile.Partition,
+		Context:              profile.Context,
+		Cert:                 cert,
+		Key:                  key,
+		ServerName:           serverName,
+		SNIDefault:           sni,
+		PeerCertMode:         peerCertMode,
+		ChainCA:              chainCA,
+		DisableRenegotiation: !renegotiation,
 	}
 	if peerCertMode == PeerCertRequired {
 		cp.CAFile = caFile
@@ -197,6 +288,59 @@ func (v *Virtual) AddIRule(ruleName string) bool {
 	return true
 }
 
+// setVLANs sets the VLANs the virtual listens on, an empty allowVlans with vlansEnabled
+// means the virtual listens on no VLAN while vlansEnabled false means all VLANs
+func (v *Virtual) setVLANs(allowVLANs, rejectVLANs []string, vlansEnabled *bool) error {
+	if len(allowVLANs) > 0 && len(rejectVLANs) > 0 {
+		return fmt.Errorf("allowVlans and rejectVlans are mutually exclusive")
+	}
+	if vlansEnabled != nil {
+		if !*vlansEnabled && len(allowVLANs) > 0 {
+			return fmt.Errorf("allowVlans requires vlansEnabled")
+		}
+		if *vlansEnabled && len(rejectVLANs) > 0 {
+			return fmt.Errorf("rejectVlans can't be used with vlansEnabled")
+		}
+	}
+	v.AllowVLANs = allowVLANs
+	v.RejectVLANs = rejectVLANs
+	v.VLANsEnabled = len(allowVLANs) > 0 || (vlansEnabled != nil && *vlansEnabled)
+	return nil
+}
+
+// addPriorityIRules attaches iRules with priorities to the virtual and orders the iRules by priority
+func (rsCfg *ResourceConfig) addPriorityIRules(iRules []cisapiv1.IRulePriority) {
+	if len(iRules) == 0 {
+		return
+	}
+	if rsCfg.MetaData.iRulePriorities == nil {
+		rsCfg.MetaData.iRulePriorities = make(map[string]int)
+	}
+	for _, iRule := range iRules {
+		rsCfg.Virtual.AddIRule(iRule.Name)
+		rsCfg.MetaData.iRulePriorities[iRule.Name] = iRule.Priority
+	}
+	rsCfg.sortIRules()
+}
+
+// sortIRules orders the iRules of the virtual in ascending order of priority
+// iRules without a priority are considered to be of DEFAULT_IRULE_PRIORITY,
+// iRules of same priority retain the order in which they are attached
+func (rsCfg *ResourceConfig) sortIRules() {
+	if len(rsCfg.MetaData.iRulePriorities) == 0 {
+		return
+	}
+	priority := func(iRule string) int {
+		if p, ok := rsCfg.MetaData.iRulePriorities[iRule]; ok {
+			return p
+		}
+		return DEFAULT_IRULE_PRIORITY
+	}
+	sort.SliceStable(rsCfg.Virtual.IRules, func(i, j int) bool {
+		return priority(rsCfg.Virtual.IRules[i]) < priority(rsCfg.Virtual.IRules[j])
+	})
+}
+
 func (slice ProfileRefs) Less(i, j int) bool {
 	return ((slice[i].Partition < slice[j].Partition) ||
 		(slice[i].Partition == slice[j].Partition &&
@@ -239,7 +383,7 @@ func (ctlr *Controller) virtualPorts(input interface{}) []portStruct {
 
 		ports = append(ports, http)
 
-		if len(vs.Spec.TLSProfileName) != 0 {
+		if isTLSVirtualServer(vs) {
 			ports = append(ports, https)
 		}
 	}
@@ -264,13 +408,59 @@ func formatCustomVirtualServerName(name string, port int32) string {
 	return fmt.Sprintf("%s_%d", name, port)
 }
 
-func framePoolName(ns string, pool cisapiv1.Pool, port intstr.IntOrString, host string) string {
-	poolName := pool.Name
-	if poolName == "" {
-		poolName = formatPoolName(ns, pool.Service, port, pool.NodeMemberLabel, host)
+func (ctlr *Controller) framePoolName(
+	partition string,
+	ns string,
+	pool cisapiv1.Pool,
+	port intstr.IntOrString,
+	host string,
+) string {
+	if pool.Name != "" {
+		return ctlr.resources.getUniqueAS3Name(partition, pool.Name, AS3NameFormatter(pool.Name))
+	}
+	source := fmt.Sprintf("%s/%s/%s/%s/%s", ns, pool.Service, fetchPortString(port), host, pool.NodeMemberLabel)
+	return ctlr.resources.getUniqueAS3Name(partition, source,
+		formatPoolName(ns, pool.Service, port, pool.NodeMemberLabel, host))
+}
+
+// framePoolMonitorName returns the name of the monitor of the VirtualServer pool. The virtuals of several
+// partitions may place monitors of the same name in a pool partition, such a monitor is named uniquely
+// in the pool partition by the partition of its virtual through getUniqueAS3Name, so its name changes
+// when the virtuals of other partitions start or stop sharing it.
+func (ctlr *Controller) framePoolMonitorName(rsCfg *ResourceConfig, poolPartition, monitorName string) string {
+	if poolPartition == rsCfg.Virtual.Partition {
+		return monitorName
 	}
+	return ctlr.resources.getUniqueAS3Name(poolPartition, rsCfg.Virtual.Partition+"/"+monitorName, monitorName)
+}
 
-	return poolName
+// getPoolPartition returns the partition of the VirtualServer pool, which is the partition of the
+// virtual unless the pool is placed in one of the pool partitions
+func (ctlr *Controller) getPoolPartition(rsCfg *ResourceConfig, pool cisapiv1.Pool) string {
+	if pool.Partition != "" {
+		return pool.Partition
+	}
+	return rsCfg.Virtual.Partition
+}
+
+// validatePoolPartition checks that the partition of the VirtualServer pool is managed by the controller
+func (ctlr *Controller) validatePoolPartition(rsCfg *ResourceConfig, pool cisapiv1.Pool) error {
+	if pool.Partition == "" || pool.Partition == rsCfg.Virtual.Partition {
+		return nil
+	}
+	if !containsString(ctlr.poolPartitions, pool.Partition) {
+		return fmt.Errorf("partition %v of pool %v is not managed by the controller", pool.Partition, pool.Service)
+	}
+	return nil
+}
+
+// getPoolReference returns the name by which the virtual of the partition refers to the pool,
+// a pool of another partition is referred to with its full path
+func getPoolReference(virtualPartition, poolPartition, poolName string) string {
+	if poolPartition == virtualPartition {
+		return poolName
+	}
+	return fmt.Sprintf("/%s/%s/%s", poolPartition, as3SharedApplication, poolName)
 }
 
 // format the pool name for an VirtualServer
@@ -281,12 +471,300 @@ func formatPoolName(namespace, svc string, port intstr.IntOrString, nodeMemberLa
 		poolName = fmt.Sprintf("%s_%s", poolName, host)
 	}
 	if nodeMemberLabel != "" {
-		nodeMemberLabel = strings.ReplaceAll(nodeMemberLabel, "=", "_")
-		poolName = fmt.Sprintf("%s_%s", poolName, nodeMemberLabel)
+		poolName = fmt.Sprintf("%s_%s", poolName, formatNodeMemberLabel(nodeMemberLabel))
 	}
 	return AS3NameFormatter(poolName)
 }
 
+// formatNodeMemberLabel returns the pool name suffix for a NodeMemberLabel.
+// Simple "key=value" labels keep their readable form, while set-based
+// selectors are hashed so the pool name stays short and stable. The canonical
+// form of the selector is hashed, so equivalent selectors share the pool name.
+func formatNodeMemberLabel(nodeMemberLabel string) string {
+	label := strings.Split(nodeMemberLabel, "=")
+	if len(label) == 2 && !strings.ContainsAny(nodeMemberLabel, "!(), ") {
+		return strings.ReplaceAll(nodeMemberLabel, "=", "_")
+	}
+	if selector, err := labels.Parse(nodeMemberLabel); err == nil {
+		nodeMemberLabel = selector.String()
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(nodeMemberLabel))
+	return fmt.Sprintf("nodes_%08x", hash.Sum32())
+}
+
+// addPolicyMonitors attaches the monitors of the Policy to the VirtualServer pool without own monitors
+func (rsCfg *ResourceConfig) addPolicyMonitors(pool *Pool, pl cisapiv1.Pool, host string) error {
+	for _, plcMonitor := range rsCfg.MetaData.policyMonitors {
+		if plcMonitor.Name != "" && plcMonitor.Reference == BIGIP {
+			pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: plcMonitor.Name, Reference: plcMonitor.Reference})
+			continue
+		}
+		formatPort := plcMonitor.TargetPort
+		if formatPort == 0 {
+			formatPort = pl.ServicePort
+		}
+		monitorName := formatPolicyMonitorName(rsCfg.MetaData.policyName, pool.Name, plcMonitor.Type, formatPort)
+		pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: JoinBigipPath(pool.Partition, monitorName)})
+		monitor := Monitor{
+			Name:                    monitorName,
+			Partition:               pool.Partition,
+			Type:                    plcMonitor.Type,
+			Interval:                plcMonitor.Interval,
+			Send:                    plcMonitor.Send,
+			Recv:                    plcMonitor.Recv,
+			Timeout:                 plcMonitor.Timeout,
+			TargetPort:              plcMonitor.TargetPort,
+			AutoHostHeader:          plcMonitor.AutoHostHeader,
+			TargetAddress:           plcMonitor.TargetAddress,
+			Transparent:             plcMonitor.Transparent,
+			Adaptive:                plcMonitor.Adaptive,
+			AdaptiveDivergenceType:  plcMonitor.AdaptiveDivergenceType,
+			AdaptiveDivergenceValue: plcMonitor.AdaptiveDivergenceValue,
+			AdaptiveLimit:           plcMonitor.AdaptiveLimit,
+		}
+		setHTTP2MonitorDefaults(&monitor)
+		if err := setMonitorHostHeaderSend(&monitor, host, pl.Path); err != nil {
+			return err
+		}
+		if err := validateMonitorTarget(monitor); err != nil {
+			return err
+		}
+		if err := validateAdaptiveMonitor(monitor); err != nil {
+			return err
+		}
+		rsCfg.Monitors = append(rsCfg.Monitors, monitor)
+	}
+	return nil
+}
+
+// validateMonitorTarget checks the target address of the monitor and that a
+// transparent monitor probes a fixed destination
+func validateMonitorTarget(monitor Monitor) error {
+	if monitor.TargetAddress != "" && net.ParseIP(monitor.TargetAddress) == nil {
+		return fmt.Errorf("invalid monitor targetAddress %v", monitor.TargetAddress)
+	}
+	if !monitor.Transparent {
+		return nil
+	}
+	switch monitor.Type {
+	case "http", "tcp", "udp", MonitorTypeICMP:
+	default:
+		return fmt.Errorf("transparent is not supported for %v monitor", monitor.Type)
+	}
+	if monitor.TargetAddress == "" || (monitor.TargetPort == 0 && monitor.Type != MonitorTypeICMP) {
+		return fmt.Errorf("transparent %v monitor requires targetAddress and targetPort", monitor.Type)
+	}
+	return nil
+}
+
+// validateMinimumMonitors checks that the minimum monitors of the pool can be met by the monitors of all its members
+func validateMinimumMonitors(pool Pool) error {
+	if pool.MinimumMonitors == 0 {
+		return nil
+	}
+	if poolMonitors := len(pool.getPoolMonitorNames()); pool.MinimumMonitors < 0 || pool.MinimumMonitors > poolMonitors {
+		return fmt.Errorf("invalid minimumMonitors %v of pool %v, expected 1 to %v for the monitors of all its members",
+			pool.MinimumMonitors, pool.Name, poolMonitors)
+	}
+	return nil
+}
+
+// getPoolMonitorNames returns the monitors of the pool which aren't restricted to member addresses
+func (pool Pool) getPoolMonitorNames() []MonitorName {
+	var monitorNames []MonitorName
+	for _, monitorName := range pool.MonitorNames {
+		if len(monitorName.MemberAddresses) == 0 {
+			monitorNames = append(monitorNames, monitorName)
+		}
+	}
+	return monitorNames
+}
+
+// getMonitoredMembers returns the members of the pool, the members with monitors restricted to their
+// addresses get these monitors along with the ones of the pool and the minimum monitors of the pool
+func (pool Pool) getMonitoredMembers() []PoolMember {
+	members := make([]PoolMember, 0, len(pool.Members))
+	for _, member := range pool.Members {
+		var memberMonitorNames []MonitorName
+		for _, monitorName := range pool.MonitorNames {
+			if containsString(monitorName.MemberAddresses, member.Address) {
+				memberMonitorNames = append(memberMonitorNames, monitorName)
+			}
+		}
+		if len(memberMonitorNames) > 0 {
+			member.MonitorNames = append(pool.getPoolMonitorNames(), memberMonitorNames...)
+			member.MinimumMonitors = pool.MinimumMonitors
+		}
+		members = append(members, member)
+	}
+	return members
+}
+
+// getPoolMetadata returns the poolMetadataLabels of the namespace and the service of a pool as its
+// metadata, the labels of the service take precedence over the ones of the namespace
+func (ctlr *Controller) getPoolMetadata(namespace, svcName string) map[string]string {
+	if len(ctlr.poolMetadataLabels) == 0 {
+		return nil
+	}
+	var metadata map[string]string
+	addLabels := func(labels map[string]string) {
+		for _, label := range ctlr.poolMetadataLabels {
+			if value, ok := labels[label]; ok {
+				if metadata == nil {
+					metadata = make(map[string]string)
+				}
+				metadata[label] = value
+			}
+		}
+	}
+	if ns := ctlr.getPoolNamespace(namespace); ns != nil {
+		addLabels(ns.Labels)
+	}
+	if svc := ctlr.getPoolService(namespace, svcName); svc != nil {
+		addLabels(svc.Labels)
+	}
+	return metadata
+}
+
+// getPoolNamespace returns the namespace from the cache of the namespace informers
+func (ctlr *Controller) getPoolNamespace(namespace string) *v1.Namespace {
+	nsInformers := make([]*NSInformer, 0, len(ctlr.nsInformers)+1)
+	for _, nsInf := range ctlr.nsInformers {
+		nsInformers = append(nsInformers, nsInf)
+	}
+	if ctlr.poolMetadataNsInformer != nil {
+		nsInformers = append(nsInformers, ctlr.poolMetadataNsInformer)
+	}
+	for _, nsInf := range nsInformers {
+		if obj, found, _ := nsInf.nsInformer.GetIndexer().GetByKey(namespace); found {
+			return obj.(*v1.Namespace)
+		}
+	}
+	log.Debugf("Namespace %v of pool metadata not found", namespace)
+	return nil
+}
+
+// getPoolService returns the service of a pool from the informer of its namespace
+func (ctlr *Controller) getPoolService(namespace, svcName string) *v1.Service {
+	var svcIndexer cache.Indexer
+	if crInf, ok := ctlr.getNamespacedInformer(namespace); ok {
+		svcIndexer = crInf.svcInformer.GetIndexer()
+	} else if esInf, ok := ctlr.getNamespacedEssentialInformer(namespace); ok {
+		svcIndexer = esInf.svcInformer.GetIndexer()
+	} else {
+		return nil
+	}
+	obj, found, _ := svcIndexer.GetByKey(namespace + "/" + svcName)
+	if !found {
+		return nil
+	}
+	return obj.(*v1.Service)
+}
+
+// validateAdaptiveMonitor validates the adaptive response time settings of a monitor
+func validateAdaptiveMonitor(monitor Monitor) error {
+	if !monitor.Adaptive {
+		return nil
+	}
+	switch monitor.Type {
+	case "http", "https", "tcp":
+	default:
+		return fmt.Errorf("adaptive is not supported for %v monitor", monitor.Type)
+	}
+	switch monitor.AdaptiveDivergenceType {
+	case "", AdaptiveDivergenceRelative:
+		if v := monitor.AdaptiveDivergenceValue; v != nil && (*v < 1 || *v > 500) {
+			return fmt.Errorf("invalid adaptiveDivergenceValue %v, relative divergence must be between 1 and 500 percent", *v)
+		}
+	case AdaptiveDivergenceAbsolute:
+		if v := monitor.AdaptiveDivergenceValue; v != nil && (*v < 1 || *v > 10000) {
+			return fmt.Errorf("invalid adaptiveDivergenceValue %v, absolute divergence must be between 1 and 10000 milliseconds", *v)
+		}
+	default:
+		return fmt.Errorf("invalid adaptiveDivergenceType %v, supported values are %v and %v",
+			monitor.AdaptiveDivergenceType, AdaptiveDivergenceAbsolute, AdaptiveDivergenceRelative)
+	}
+	if v := monitor.AdaptiveLimit; v != nil && (*v < 1 || *v > 10000) {
+		return fmt.Errorf("invalid adaptiveLimit %v, must be between 1 and 10000 milliseconds", *v)
+	}
+	return nil
+}
+
+// setHTTP2MonitorDefaults sets the gRPC health check send and receive strings
+// for an http2 monitor if not provided
+func setHTTP2MonitorDefaults(monitor *Monitor) {
+	if monitor.Type != MonitorTypeHTTP2 {
+		return
+	}
+	if monitor.Send == "" {
+		monitor.Send = DefaultGRPCHealthSend
+	}
+	if monitor.Recv == "" {
+		monitor.Recv = DefaultGRPCHealthRecv
+	}
+}
+
+// setMonitorHostHeaderSend builds the send string of an http or https monitor
+// with autoHostHeader from the host and path the monitor is checking
+func setMonitorHostHeaderSend(monitor *Monitor, host, path string) error {
+	if !monitor.AutoHostHeader {
+		return nil
+	}
+	if monitor.Type != "http" && monitor.Type != "https" {
+		return fmt.Errorf("autoHostHeader is not supported for %v monitor", monitor.Type)
+	}
+	if monitor.Send != "" {
+		return fmt.Errorf("send and autoHostHeader are mutually exclusive for monitor %v", monitor.Name)
+	}
+	if host == "" {
+		return fmt.Errorf("autoHostHeader requires a host for monitor %v", monitor.Name)
+	}
+	if path == "" {
+		path = "/"
+	}
+	monitor.Send = fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nConnection: Close\r\n\r\n", path, host)
+	return nil
+}
+
+// validateHTTP2Monitors verifies that a serverssl profile is available for the
+// http2 monitors to establish TLS connections with the backends
+func (rsCfg *ResourceConfig) validateHTTP2Monitors() error {
+	for _, monitor := range rsCfg.Monitors {
+		if monitor.Type == MonitorTypeHTTP2 && !rsCfg.hasServerSSLProfile() {
+			return fmt.Errorf("http2 monitor %v requires a serverSSL profile on virtual %v",
+				monitor.Name, rsCfg.Virtual.Name)
+		}
+	}
+	return nil
+}
+
+// hasServerSSLProfile checks whether the virtual has a BIG-IP referenced or a custom serverssl profile
+func (rsCfg *ResourceConfig) hasServerSSLProfile() bool {
+	for _, prof := range rsCfg.Virtual.Profiles {
+		if prof.Context == CustomProfileServer {
+			return true
+		}
+	}
+	for _, prof := range rsCfg.customProfiles {
+		if prof.Context == CustomProfileServer {
+			return true
+		}
+	}
+	return false
+}
+
+// format the monitor name for a pool with name overridden in the pool spec
+func formatPoolMonitorName(poolName, monitorType string, port int32) string {
+	return AS3NameFormatter(fmt.Sprintf("%s_%s_%d", poolName, monitorType, port)) + "-monitor"
+}
+
+// format the monitor name of the Policy monitor for an VirtualServer pool, the policy name
+// keeps it apart from the monitors of the pool
+func formatPolicyMonitorName(policyName, poolName, monitorType string, port int32) string {
+	return AS3NameFormatter(fmt.Sprintf("%s_%s_%s_%d", poolName, policyName, monitorType, port)) + "-monitor"
+}
+
 // format the monitor name for an VirtualServer pool
 func formatMonitorName(namespace, svc string, monitorType string, port int32, hostName string, path string) string {
 	monitorName := fmt.Sprintf("%s_%s", svc, namespace)
@@ -356,6 +834,40 @@ func (ctlr *Controller) deleteSvcDepResource(rsName string, rsCfg *ResourceConfi
 	}
 }
 
+// getResourcesForSecret returns the resources which consume the secret
+func (ctlr *Controller) getResourcesForSecret(name string) []resourceRef {
+	var rscRefs []resourceRef
+	for rscRef := range ctlr.resources.secretResourceCache[name] {
+		rscRefs = append(rscRefs, rscRef)
+	}
+	sort.Slice(rscRefs, func(i, j int) bool {
+		if rscRefs[i].kind != rscRefs[j].kind {
+			return rscRefs[i].kind < rscRefs[j].kind
+		}
+		if rscRefs[i].namespace != rscRefs[j].namespace {
+			return rscRefs[i].namespace < rscRefs[j].namespace
+		}
+		return rscRefs[i].name < rscRefs[j].name
+	})
+	return rscRefs
+}
+
+func (ctlr *Controller) updateSecretResources(name string, rscRef resourceRef) {
+	if _, found := ctlr.resources.secretResourceCache[name]; !found {
+		ctlr.resources.secretResourceCache[name] = make(map[resourceRef]struct{})
+	}
+	ctlr.resources.secretResourceCache[name][rscRef] = struct{}{}
+}
+
+func (ctlr *Controller) deleteSecretResource(rscRef resourceRef) {
+	for name, resources := range ctlr.resources.secretResourceCache {
+		delete(resources, rscRef)
+		if len(resources) == 0 {
+			delete(ctlr.resources.secretResourceCache, name)
+		}
+	}
+}
+
 // fetch target port from service
 func (ctlr *Controller) fetchTargetPort(namespace, svcName string, servicePort int32) intstr.IntOrString {
 	var targetPort intstr.IntOrString
@@ -384,6 +896,44 @@ func (ctlr *Controller) fetchTargetPort(namespace, svcName string, servicePort i
 	return targetPort
 }
 
+// resolveNamedTargetPort resolves the named target port of the service port to the
+// port number of the endpoints, endpoint ports carry the name of the service port
+func (ctlr *Controller) resolveNamedTargetPort(svc *v1.Service, svcPort v1.ServicePort) (intstr.IntOrString, error) {
+	var epInf cache.SharedIndexInformer
+	switch ctlr.mode {
+	case OpenShiftMode, KubernetesMode:
+		if esInf, ok := ctlr.getNamespacedEssentialInformer(svc.Namespace); ok {
+			epInf = esInf.epsInformer
+		}
+	default:
+		if crInf, ok := ctlr.getNamespacedInformer(svc.Namespace); ok {
+			epInf = crInf.epsInformer
+		}
+	}
+	if epInf == nil {
+		return intstr.IntOrString{}, fmt.Errorf("informer not found for namespace %v", svc.Namespace)
+	}
+	item, found, _ := epInf.GetIndexer().GetByKey(svc.Namespace + "/" + svc.Name)
+	if !found {
+		return intstr.IntOrString{}, fmt.Errorf("unable to resolve target port %q, endpoints not found",
+			svcPort.TargetPort.StrVal)
+	}
+	eps := item.(*v1.Endpoints)
+	// the service has no members until its endpoints are ready, so the target port is left unresolved
+	if len(eps.Subsets) == 0 {
+		return svcPort.TargetPort, nil
+	}
+	for _, subset := range eps.Subsets {
+		for _, p := range subset.Ports {
+			if p.Name == svcPort.Name {
+				return intstr.FromInt(int(p.Port)), nil
+			}
+		}
+	}
+	return intstr.IntOrString{}, fmt.Errorf("unable to resolve target port %q, no endpoint port found",
+		svcPort.TargetPort.StrVal)
+}
+
 // Prepares resource config based on VirtualServer resource config
 func (ctlr *Controller) prepareRSConfigFromVirtualServer(
 	rsCfg *ResourceConfig,
@@ -400,7 +950,10 @@ func (ctlr *Controller) prepareRSConfigFromVirtualServer(
 	var monitors []Monitor
 	var targetPort intstr.IntOrString
 
-	framedPools := make(map[string]struct{})
+	// framedPools holds the backend key of every framed pool
+	framedPools := make(map[string]string)
+	// namedPools holds the reference of the pools named in the spec
+	namedPools := make(map[string]string)
 	for _, pl := range vs.Spec.Pools {
 		svcNamespace := vs.Namespace
 		if pl.ServiceNamespace != "" {
@@ -411,54 +964,104 @@ func (ctlr *Controller) prepareRSConfigFromVirtualServer(
 		if (intstr.IntOrString{}) == targetPort {
 			targetPort = intstr.IntOrString{IntVal: pl.ServicePort}
 		}
-		poolName := framePoolName(vs.ObjectMeta.Namespace, pl, targetPort, vs.Spec.Host)
+		if err := ctlr.validatePoolPartition(rsCfg, pl); err != nil {
+			return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
+		}
+		poolPartition := ctlr.getPoolPartition(rsCfg, pl)
+		poolName := ctlr.framePoolName(poolPartition, vs.ObjectMeta.Namespace, pl, targetPort, vs.Spec.Host)
+		if pl.Name != "" {
+			namedPools[pl.Name] = getPoolReference(rsCfg.Virtual.Partition, poolPartition, poolName)
+		}
 		//check for custom monitor
 		var monitorName string
 		if pl.Monitor.Name != "" && pl.Monitor.Reference == BIGIP {
 			monitorName = pl.Monitor.Name
 		} else {
-			monitorName = pl.Name + "-monitor"
+			monitorName = poolName + "-monitor"
 		}
 
-		if _, ok := framedPools[poolName]; ok {
+		poolKey := fmt.Sprintf("%s/%s/%s/%s", svcNamespace, pl.Service, targetPort.String(), pl.NodeMemberLabel)
+		if framedPoolKey, ok := framedPools[poolName]; ok {
+			if pl.Name != "" && framedPoolKey != poolKey {
+				return fmt.Errorf("pool name %v is used by multiple pools with different backends in VirtualServer %v/%v",
+					poolName, vs.Namespace, vs.Name)
+			}
 			// Pool with same name framed earlier, so skipping this pool
 			log.Debugf("Duplicate pool name: %v in Virtual Server: %v/%v", poolName, vs.Namespace, vs.Name)
 			continue
 		}
-		framedPools[poolName] = struct{}{}
+		framedPools[poolName] = poolKey
 
 		pool := Pool{
 			Name:             poolName,
-			Partition:        rsCfg.Virtual.Partition,
+			Partition:        poolPartition,
 			ServiceName:      pl.Service,
 			ServiceNamespace: svcNamespace,
 			ServicePort:      targetPort,
 
-			NodeMemberLabel: pl.NodeMemberLabel,
-			Balance:         pl.Balance,
+			NodeMemberLabel:   pl.NodeMemberLabel,
+			Balance:           pl.Balance,
+			ServiceDownAction: pl.ServiceDownAction,
+			ReselectTries:     pl.ReselectTries,
+			SNAT:              pl.SNAT,
+			MinimumMonitors:   pl.MinimumMonitors,
+			Metadata:          ctlr.getPoolMetadata(svcNamespace, pl.Service),
+		}
+		if err := validateServiceDownAction(pool); err != nil {
+			return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
+		}
+		if err := validateHashKey(pl, poolName); err != nil {
+			return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
+		}
+		if isHashBalance(pl.Balance) {
+			// members are selected by the persist action of the forwarding rule
+			pool.Balance = ""
+		}
+		if pool.SNAT != "" && pool.SNAT != "none" {
+			return fmt.Errorf("invalid snat %v for pool %v in VirtualServer %v/%v, only none is supported",
+				pool.SNAT, poolName, vs.Namespace, vs.Name)
 		}
 		if pl.Monitor.Name != "" && pl.Monitor.Reference == "bigip" {
 			pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: pl.Monitor.Name, Reference: pl.Monitor.Reference})
-		} else if pl.Monitor.Send != "" && pl.Monitor.Type != "" {
+		} else if (pl.Monitor.Send != "" || pl.Monitor.AutoHostHeader || pl.Monitor.Type == MonitorTypeHTTP2) && pl.Monitor.Type != "" {
 			if pl.Name == "" {
 				monitorName = formatMonitorName(vs.ObjectMeta.Namespace, pl.Service, pl.Monitor.Type, pl.ServicePort, vs.Spec.Host, pl.Path)
 			}
-			pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: JoinBigipPath(rsCfg.Virtual.Partition, monitorName)})
+			monitorName = ctlr.framePoolMonitorName(rsCfg, poolPartition, monitorName)
+			pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: JoinBigipPath(poolPartition, monitorName)})
 			monitor := Monitor{
-				Name:       monitorName,
-				Partition:  rsCfg.Virtual.Partition,
-				Type:       pl.Monitor.Type,
-				Interval:   pl.Monitor.Interval,
-				Send:       pl.Monitor.Send,
-				Recv:       pl.Monitor.Recv,
-				Timeout:    pl.Monitor.Timeout,
-				TargetPort: pl.Monitor.TargetPort,
+				Name:                    monitorName,
+				Partition:               poolPartition,
+				Type:                    pl.Monitor.Type,
+				Interval:                pl.Monitor.Interval,
+				Send:                    pl.Monitor.Send,
+				Recv:                    pl.Monitor.Recv,
+				Timeout:                 pl.Monitor.Timeout,
+				TargetPort:              pl.Monitor.TargetPort,
+				AutoHostHeader:          pl.Monitor.AutoHostHeader,
+				TargetAddress:           pl.Monitor.TargetAddress,
+				Transparent:             pl.Monitor.Transparent,
+				Adaptive:                pl.Monitor.Adaptive,
+				AdaptiveDivergenceType:  pl.Monitor.AdaptiveDivergenceType,
+				AdaptiveDivergenceValue: pl.Monitor.AdaptiveDivergenceValue,
+				AdaptiveLimit:           pl.Monitor.AdaptiveLimit,
+			}
+			setHTTP2MonitorDefaults(&monitor)
+			if err := setMonitorHostHeaderSend(&monitor, vs.Spec.Host, pl.Path); err != nil {
+				return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
+			}
+			if err := validateMonitorTarget(monitor); err != nil {
+				return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
+			}
+			if err := validateAdaptiveMonitor(monitor); err != nil {
+				return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
 			}
 			monitors = append(monitors, monitor)
 		} else if pl.Monitors != nil {
 			for _, monitor := range pl.Monitors {
 				if monitor.Name != "" && monitor.Reference == BIGIP {
-					pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: monitor.Name, Reference: monitor.Reference})
+					pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: monitor.Name, Reference: monitor.Reference,
+						MemberAddresses: monitor.MemberAddresses})
 				} else {
 					var formatPort int32
 					if monitor.TargetPort != 0 {
@@ -466,28 +1069,62 @@ func (ctlr *Controller) prepareRSConfigFromVirtualServer(
 					} else {
 						formatPort = pl.ServicePort
 					}
-					if monitor.Name == "" {
+					if pl.Name != "" {
+						monitorName = formatPoolMonitorName(poolName, monitor.Type, formatPort)
+					} else if monitor.Name == "" {
 						monitorName = formatMonitorName(vs.ObjectMeta.Namespace, pl.Service, monitor.Type, formatPort, vs.Spec.Host, pl.Path)
 					}
-					pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: JoinBigipPath(rsCfg.Virtual.Partition, monitorName)})
+					monitorName = ctlr.framePoolMonitorName(rsCfg, poolPartition, monitorName)
+					pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: JoinBigipPath(poolPartition, monitorName),
+						MemberAddresses: monitor.MemberAddresses})
 					monitor := Monitor{
-						Name:       monitorName,
-						Partition:  rsCfg.Virtual.Partition,
-						Type:       monitor.Type,
-						Interval:   monitor.Interval,
-						Send:       monitor.Send,
-						Recv:       monitor.Recv,
-						Timeout:    monitor.Timeout,
-						TargetPort: monitor.TargetPort,
+						Name:                    monitorName,
+						Partition:               poolPartition,
+						Type:                    monitor.Type,
+						Interval:                monitor.Interval,
+						Send:                    monitor.Send,
+						Recv:                    monitor.Recv,
+						Timeout:                 monitor.Timeout,
+						TargetPort:              monitor.TargetPort,
+						AutoHostHeader:          monitor.AutoHostHeader,
+						TargetAddress:           monitor.TargetAddress,
+						Transparent:             monitor.Transparent,
+						Adaptive:                monitor.Adaptive,
+						AdaptiveDivergenceType:  monitor.AdaptiveDivergenceType,
+						AdaptiveDivergenceValue: monitor.AdaptiveDivergenceValue,
+						AdaptiveLimit:           monitor.AdaptiveLimit,
+					}
+					setHTTP2MonitorDefaults(&monitor)
+					if err := setMonitorHostHeaderSend(&monitor, vs.Spec.Host, pl.Path); err != nil {
+						return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
+					}
+					if err := validateMonitorTarget(monitor); err != nil {
+						return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
+					}
+					if err := validateAdaptiveMonitor(monitor); err != nil {
+						return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
 					}
 					rsCfg.Monitors = append(rsCfg.Monitors, monitor)
 				}
 			}
+		} else if len(rsCfg.MetaData.policyMonitors) > 0 {
+			if err := rsCfg.addPolicyMonitors(&pool, pl, vs.Spec.Host); err != nil {
+				return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
+			}
+		}
+		if err := validateMinimumMonitors(pool); err != nil {
+			return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
 		}
 		pools = append(pools, pool)
 	}
 	rsCfg.Pools = append(rsCfg.Pools, pools...)
 	rsCfg.Monitors = append(rsCfg.Monitors, monitors...)
+	if err := rsCfg.setClonePools(vs.Spec.ClonePools, namedPools); err != nil {
+		return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
+	}
+	if err := rsCfg.setConnectionRateLimit(vs.Spec.ConnectionRateLimit); err != nil {
+		return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
+	}
 
 	// set the SNAT policy to auto if it's not defined by end user
 	if vs.Spec.SNAT == "" {
@@ -497,25 +1134,60 @@ func (ctlr *Controller) prepareRSConfigFromVirtualServer(
 	} else {
 		rsCfg.Virtual.SNAT = vs.Spec.SNAT
 	}
+	rsCfg.handlePoolSNATIRule()
 
 	if len(rsCfg.ServiceAddress) == 0 {
 		for _, sa := range vs.Spec.ServiceIPAddress {
 			rsCfg.ServiceAddress = append(rsCfg.ServiceAddress, ServiceAddress(sa))
 		}
 	}
+	if err := rsCfg.Virtual.SetVirtualAddressMask(rsCfg.ServiceAddress); err != nil {
+		return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
+	}
 
 	// set the WAF policy
 	if vs.Spec.WAF != "" {
 		rsCfg.Virtual.WAF = vs.Spec.WAF
 	}
+	if vs.Spec.ForwardingPolicyOrder != "" {
+		if err := rsCfg.Virtual.setForwardingPolicyOrder(vs.Spec.ForwardingPolicyOrder); err != nil {
+			return fmt.Errorf("VirtualServer %v/%v: %v", vs.Namespace, vs.Name, err)
+		}
+	}
 
-	//Attach allowVlans.
-	rsCfg.Virtual.AllowVLANs = vs.Spec.AllowVLANs
+	// VirtualServers sharing the virtual should use the same pool member type
+	poolMemberType := ctlr.getPoolMemberType(vs.Spec.PoolMemberType)
+	if rsCfg.MetaData.poolMemberType != "" && rsCfg.MetaData.poolMemberType != poolMemberType {
+		return fmt.Errorf("poolMemberType %v of VirtualServer %v/%v conflicts with poolMemberType %v of the virtual %v",
+			poolMemberType, vs.Namespace, vs.Name, rsCfg.MetaData.poolMemberType, rsCfg.Virtual.Name)
+	}
+	rsCfg.MetaData.poolMemberType = poolMemberType
+
+	//Attach allowVlans or rejectVlans.
+	if err := rsCfg.Virtual.setVLANs(vs.Spec.AllowVLANs, vs.Spec.RejectVLANs, vs.Spec.VLANsEnabled); err != nil {
+		return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
+	}
+	rsCfg.Virtual.SetDescription(vs.Namespace, vs.Name, vs.Annotations)
 
 	if vs.Spec.PersistenceProfile != "" {
 		rsCfg.Virtual.PersistenceProfile = vs.Spec.PersistenceProfile
 	}
 
+	if vs.Spec.Persistence != nil {
+		if vs.Spec.PersistenceProfile != "" {
+			return fmt.Errorf("persistenceProfile and persistence are mutually exclusive in VirtualServer %v/%v",
+				vs.Namespace, vs.Name)
+		}
+		err := validatePersistence(vs.Spec.Persistence, SourceAddressPersistence, DestinationAddressPersistence,
+			UniversalPersistence, CookiePersistence)
+		if err != nil {
+			return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
+		}
+		// persistence defined in VS overrides the persistence profile from policy CR
+		rsCfg.Virtual.PersistenceProfile = ""
+		rsCfg.Virtual.Persistence = newPersistenceProfile(vs.Spec.Persistence)
+	}
+
 	if len(vs.Spec.Profiles.TCP.Client) > 0 || len(vs.Spec.Profiles.TCP.Server) > 0 {
 		rsCfg.Virtual.TCP.Client = vs.Spec.Profiles.TCP.Client
 		rsCfg.Virtual.TCP.Server = vs.Spec.Profiles.TCP.Server
@@ -529,6 +1201,10 @@ func (ctlr *Controller) prepareRSConfigFromVirtualServer(
 		rsCfg.Virtual.AllowSourceRange = vs.Spec.AllowSourceRange
 	}
 
+	if len(vs.Spec.DenySourceRange) > 0 {
+		rsCfg.Virtual.DenySourceRange = vs.Spec.DenySourceRange
+	}
+
 	if vs.Spec.BotDefense != "" {
 		rsCfg.Virtual.ProfileBotDefense = vs.Spec.BotDefense
 	}
@@ -537,15 +1213,38 @@ func (ctlr *Controller) prepareRSConfigFromVirtualServer(
 		rsCfg.Virtual.ProfileMultiplex = vs.Spec.ProfileMultiplex
 	}
 
+	if vs.Spec.InsertXForwardedFor && isHTTPProtocol(rsCfg.MetaData.Protocol) {
+		rsCfg.Virtual.InsertXForwardedFor = true
+	}
+
+	if vs.Spec.Profiles.AllowH2C && rsCfg.MetaData.Protocol == HTTP {
+		rsCfg.Virtual.AllowH2C = true
+	}
+	// OneConnect multiplexes the HTTP/1.1 server connections and can't be combined with HTTP/2
+	if rsCfg.Virtual.AllowH2C && rsCfg.Virtual.ProfileMultiplex != "" {
+		return fmt.Errorf("allowH2C on VirtualServer %v/%v can't be combined with profileMultiplex %v",
+			vs.Namespace, vs.Name, rsCfg.Virtual.ProfileMultiplex)
+	}
+
 	// Do not Create Virtual Server L7 Forwarding policies if HTTPTraffic is set to None or Redirect
-	if len(vs.Spec.TLSProfileName) > 0 &&
+	if isTLSVirtualServer(vs) &&
 		rsCfg.Virtual.VirtualAddress.Port == httpPort &&
 		(vs.Spec.HTTPTraffic == TLSNoInsecure || vs.Spec.HTTPTraffic == TLSRedirectInsecure) {
 		return nil
 	}
 
 	// skip the policy creation for passthrough termination
-	if !passthroughVS {
+	if !passthroughVS && vs.Spec.SorryPage != nil {
+		// the sorry page is served instead of forwarding the requests to the pools
+		if err := rsCfg.addSorryPageIRule(vs.Spec.Host, vs.Spec.SorryPage); err != nil {
+			return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
+		}
+	} else if !passthroughVS && vs.Spec.SkipForwardingPolicy {
+		// the requests are routed by the iRules of the VirtualServer and the default pool
+		if err := ctlr.setDefaultPoolWithoutPolicy(rsCfg, vs); err != nil {
+			return fmt.Errorf("%v in VirtualServer %v/%v", err, vs.Namespace, vs.Name)
+		}
+	} else if !passthroughVS {
 		rules = ctlr.prepareVirtualServerRules(vs, rsCfg)
 		if rules == nil {
 			return fmt.Errorf("failed to create LTM Rules")
@@ -559,10 +1258,152 @@ func (ctlr *Controller) prepareRSConfigFromVirtualServer(
 	// Attach user specified iRules
 	if len(vs.Spec.IRules) > 0 {
 		rsCfg.Virtual.IRules = append(rsCfg.Virtual.IRules, vs.Spec.IRules...)
+		rsCfg.sortIRules()
+	}
+	return nil
+}
+
+// setDefaultPoolWithoutPolicy sets the pool with the path / as the default pool of the virtual
+// without a forwarding policy, either a default pool or an iRule is required to route the requests
+func (ctlr *Controller) setDefaultPoolWithoutPolicy(rsCfg *ResourceConfig, vs *cisapiv1.VirtualServer) error {
+	for _, pl := range vs.Spec.Pools {
+		if pl.Service == "" || (pl.Path != "" && pl.Path != "/") {
+			continue
+		}
+		poolPartition := ctlr.getPoolPartition(rsCfg, pl)
+		poolName := ctlr.framePoolName(
+			poolPartition,
+			vs.ObjectMeta.Namespace,
+			pl,
+			intstr.IntOrString{IntVal: pl.ServicePort},
+			vs.Spec.Host,
+		)
+		rsCfg.Virtual.PoolName = getPoolReference(rsCfg.Virtual.Partition, poolPartition, poolName)
+		return nil
+	}
+	if len(vs.Spec.IRules) == 0 {
+		return fmt.Errorf("skipForwardingPolicy requires a pool with the path / or iRules")
 	}
 	return nil
 }
 
+// addSorryPageIRule creates the iRule responding with the sorry page to the requests of the host
+// and attaches it ahead of the other iRules of the virtual
+func (rsCfg *ResourceConfig) addSorryPageIRule(host string, page *cisapiv1.SorryPage) error {
+	statusCode := page.StatusCode
+	if statusCode == 0 {
+		statusCode = DefaultSorryPageStatusCode
+	}
+	if statusCode < 100 || statusCode > 599 {
+		return fmt.Errorf("invalid sorryPage statusCode %v", page.StatusCode)
+	}
+	contentType := page.ContentType
+	if contentType == "" {
+		contentType = DefaultSorryPageContentType
+	}
+	ruleName := getRSCfgResName(rsCfg.Virtual.Name, SorryPageIRuleName)
+	if host != "" {
+		ruleName = getRSCfgResName(rsCfg.Virtual.Name, AS3NameFormatter(host)+"_"+SorryPageIRuleName)
+	}
+	rsCfg.removeIRule(ruleName, rsCfg.Virtual.Partition)
+	rsCfg.addIRule(ruleName, rsCfg.Virtual.Partition, sorryPageIRule(host, statusCode, contentType, page.Body))
+	ruleName = JoinBigipPath(rsCfg.Virtual.Partition, ruleName)
+	for _, iRule := range rsCfg.Virtual.IRules {
+		if iRule == ruleName {
+			return nil
+		}
+	}
+	rsCfg.Virtual.IRules = append([]string{ruleName}, rsCfg.Virtual.IRules...)
+	return nil
+}
+
+// setConnectionRateLimit sets the connection rate limit of the virtual unless it is already
+// limited by the Policy or another resource sharing the virtual
+func (rsCfg *ResourceConfig) setConnectionRateLimit(limit int32) error {
+	if limit < 0 {
+		return fmt.Errorf("invalid connectionRateLimit %v, the limit can't be negative", limit)
+	}
+	if limit == 0 {
+		return nil
+	}
+	if rsCfg.Virtual.ConnectionRateLimit != 0 {
+		if rsCfg.Virtual.ConnectionRateLimit != limit {
+			log.Debugf("Retaining connectionRateLimit %v of virtual %v instead of %v",
+				rsCfg.Virtual.ConnectionRateLimit, rsCfg.Virtual.Name, limit)
+		}
+		return nil
+	}
+	rsCfg.Virtual.ConnectionRateLimit = limit
+	return nil
+}
+
+// setClonePools attaches the clone pools to the virtual, a pool which is not referenced from BIG-IP
+// must be one of the pools named in the spec of the resource, namedPools maps them to their references
+func (rsCfg *ResourceConfig) setClonePools(clonePools *cisapiv1.ClonePools, namedPools map[string]string) error {
+	if clonePools == nil {
+		return nil
+	}
+	if clonePools.Ingress == nil && clonePools.Egress == nil {
+		return fmt.Errorf("clonePools requires an ingress or egress pool")
+	}
+	getClonePool := func(clonePool *cisapiv1.ClonePool) (*ClonePool, error) {
+		if clonePool == nil {
+			return nil, nil
+		}
+		if clonePool.Reference == BIGIP {
+			if len(strings.Split(clonePool.Name, "/")) < 3 || !strings.HasPrefix(clonePool.Name, "/") {
+				return nil, fmt.Errorf("clone pool %v is not a path of BIG-IP pool", clonePool.Name)
+			}
+			return &ClonePool{Name: clonePool.Name, Reference: BIGIP}, nil
+		}
+		poolRef, ok := namedPools[clonePool.Name]
+		if !ok {
+			return nil, fmt.Errorf("clone pool %v is not a pool of the resource", clonePool.Name)
+		}
+		return &ClonePool{Name: poolRef}, nil
+	}
+	var virtualClonePools ClonePools
+	var err error
+	if virtualClonePools.Ingress, err = getClonePool(clonePools.Ingress); err != nil {
+		return err
+	}
+	if virtualClonePools.Egress, err = getClonePool(clonePools.Egress); err != nil {
+		return err
+	}
+	// resources sharing the virtual should use the same clone pools
+	if rsCfg.Virtual.ClonePools != nil && !reflect.DeepEqual(*rsCfg.Virtual.ClonePools, virtualClonePools) {
+		return fmt.Errorf("clonePools conflict with the clonePools of the virtual %v", rsCfg.Virtual.Name)
+	}
+	rsCfg.Virtual.ClonePools = &virtualClonePools
+	return nil
+}
+
+// handlePoolSNATIRule creates the iRule disabling SNAT for the connections to the pools
+// preserving the client IP. The override is only honored when the virtual uses SNAT.
+func (rsCfg *ResourceConfig) handlePoolSNATIRule() {
+	ruleName := getRSCfgResName(rsCfg.Virtual.Name, PoolSNATIRuleName)
+	var poolPaths []string
+	for _, pool := range rsCfg.Pools {
+		if pool.SNAT == "" {
+			continue
+		}
+		if rsCfg.Virtual.SNAT == "none" {
+			log.Warningf("Ignoring snat %v of pool %v as SNAT is disabled on the virtual %v",
+				pool.SNAT, pool.Name, rsCfg.Virtual.Name)
+			continue
+		}
+		poolPaths = append(poolPaths, fmt.Sprintf("/%s/%s/%s", pool.Partition, as3SharedApplication, pool.Name))
+	}
+	if len(poolPaths) == 0 {
+		return
+	}
+	sort.Strings(poolPaths)
+	// the virtual shared by VirtualServers gets the iRule regenerated with the pools of all of them
+	rsCfg.removeIRule(ruleName, rsCfg.Virtual.Partition)
+	rsCfg.addIRule(ruleName, rsCfg.Virtual.Partition, poolSNATIRule(poolPaths))
+	rsCfg.Virtual.AddIRule(JoinBigipPath(rsCfg.Virtual.Partition, ruleName))
+}
+
 func (rsCfg *ResourceConfig) AddRuleToPolicy(policyName, partition string, rules *Rules) {
 	// Update the existing policy with rules
 	// Otherwise create new policy and set
@@ -607,19 +1448,46 @@ func (ctlr *Controller) handleTLS(
 				log.Debugf("Updated BIGIP referenced profiles for '%s' '%s'/'%s'",
 					tlsContext.resourceType, tlsContext.namespace, tlsContext.name)
 			case Secret:
+				// Track the secrets consumed by the resource to reprocess it when they are updated
+				rscRef := resourceRef{
+					kind:      tlsContext.resourceType,
+					name:      tlsContext.name,
+					namespace: tlsContext.namespace,
+				}
+				for _, secretName := range []string{clientSSL, serverSSL, tlsContext.bigIPSSLProfiles.clientCASecret,
+					tlsContext.bigIPSSLProfiles.pairedClientSSL} {
+					if secretName != "" {
+						ctlr.updateSecretResources(secretName, rscRef)
+					}
+				}
+				renegotiation := tlsContext.bigIPSSLProfiles.isRenegotiationEnabled()
 				// Prepare SSL Transient Context
 				// Check if TLS Secret already exists
 				// Process ClientSSL stored as kubernetes secret
 				if clientSSL != "" {
+					caFile, err := ctlr.getClientAuthCA(tlsContext)
+					if err != nil {
+						log.Errorf("error %v encountered while processing client authentication for '%s' '%s'/'%s'",
+							err, tlsContext.resourceType, tlsContext.namespace, tlsContext.name)
+						return false
+					}
+					peerCertMode := tlsContext.bigIPSSLProfiles.peerCertMode
+					// TLS options of the TLSProfile take precedence over the ones of the base route config
+					clientTLSCipher := ctlr.resources.baseRouteConfig.TLSCipher
+					if len(tlsContext.bigIPSSLProfiles.tlsOptions) > 0 {
+						clientTLSCipher.TLSOptions = tlsContext.bigIPSSLProfiles.tlsOptions
+					}
 					if secret, ok := ctlr.SSLContext[clientSSL]; ok {
 						log.Debugf("clientSSL secret %s for '%s'/'%s' is already available with CIS in "+
 							"SSLContext as clientSSL", secret.ObjectMeta.Name, tlsContext.namespace, tlsContext.name)
-						err, _ := ctlr.createSecretClientSSLProfile(rsCfg, secret, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileClient)
+						err, _ := ctlr.createSecretClientSSLProfile(rsCfg, secret, clientTLSCipher, CustomProfileClient,
+							peerCertMode, caFile, renegotiation, tlsContext.bigIPSSLProfiles.alpnProtocols)
 						if err != nil {
 							log.Debugf("error %v encountered while creating clientssl profile  for '%s' '%s'/'%s' using secret '%s'",
 								err, tlsContext.resourceType, tlsContext.namespace, tlsContext.name, secret.ObjectMeta.Name)
 							return false
 						}
+						rsCfg.addSNIServerNames(tlsContext.getServerNames(), secret.ObjectMeta.Name)
 					} else {
 						// Check if profile is contained in a Secret
 						// Update the SSL Context if secret found, This is used to avoid api calls
@@ -632,12 +1500,23 @@ func (ctlr *Controller) handleTLS(
 							return false
 						}
 						ctlr.SSLContext[clientSSL] = secret
-						err, _ = ctlr.createSecretClientSSLProfile(rsCfg, secret, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileClient)
+						err, _ = ctlr.createSecretClientSSLProfile(rsCfg, secret, clientTLSCipher, CustomProfileClient,
+							peerCertMode, caFile, renegotiation, tlsContext.bigIPSSLProfiles.alpnProtocols)
 						if err != nil {
 							log.Errorf("error %v encountered while creating clientssl profile for '%s' '%s'/'%s'",
 								err, tlsContext.resourceType, tlsContext.namespace, tlsContext.name)
 							return false
 						}
+						rsCfg.addSNIServerNames(tlsContext.getServerNames(), secret.ObjectMeta.Name)
+					}
+					if tlsContext.bigIPSSLProfiles.pairedClientSSL != "" {
+						err := ctlr.createPairedClientSSLProfile(rsCfg, ctlr.SSLContext[clientSSL], tlsContext,
+							clientTLSCipher, peerCertMode, caFile, renegotiation)
+						if err != nil {
+							log.Errorf("error %v encountered while creating paired clientssl profile for '%s' '%s'/'%s'",
+								err, tlsContext.resourceType, tlsContext.namespace, tlsContext.name)
+							return false
+						}
 					}
 				}
 				// Process ServerSSL stored as kubernetes secret
@@ -645,7 +1524,8 @@ func (ctlr *Controller) handleTLS(
 					if secret, ok := ctlr.SSLContext[serverSSL]; ok {
 						log.Debugf("serverSSL secret %s for '%s'/'%s' is already available with CIS in "+
 							"SSLContext as serverSSL", secret.ObjectMeta.Name, tlsContext.namespace, tlsContext.name)
-						err, _ := ctlr.createSecretServerSSLProfile(rsCfg, secret, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileServer)
+						err, _ := ctlr.createSecretServerSSLProfile(rsCfg, secret, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileServer,
+							renegotiation, tlsContext.bigIPSSLProfiles.serverName, tlsContext.bigIPSSLProfiles.ignoreServerNameCheck)
 						if err != nil {
 							log.Debugf("error %v encountered while creating serverssl profile for '%s' '%s'/'%s' using secret '%s'",
 								err, tlsContext.resourceType, tlsContext.namespace, tlsContext.name, secret.ObjectMeta.Name)
@@ -663,7 +1543,8 @@ func (ctlr *Controller) handleTLS(
 							return false
 						}
 						ctlr.SSLContext[serverSSL] = secret
-						err, _ = ctlr.createSecretServerSSLProfile(rsCfg, secret, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileServer)
+						err, _ = ctlr.createSecretServerSSLProfile(rsCfg, secret, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileServer,
+							renegotiation, tlsContext.bigIPSSLProfiles.serverName, tlsContext.bigIPSSLProfiles.ignoreServerNameCheck)
 						if err != nil {
 							log.Errorf("error %v encountered while creating serverssl profile for '%s' '%s'/'%s'",
 								err, tlsContext.resourceType, tlsContext.namespace, tlsContext.name)
@@ -676,22 +1557,24 @@ func (ctlr *Controller) handleTLS(
 				// Prepare SSL Transient Context
 				if tlsContext.bigIPSSLProfiles.key != "" && tlsContext.bigIPSSLProfiles.certificate != "" {
 					err, _ := ctlr.createClientSSLProfile(rsCfg, tlsContext.bigIPSSLProfiles.key, tlsContext.bigIPSSLProfiles.certificate,
-						fmt.Sprintf("%s-clientssl", tlsContext.name), tlsContext.namespace, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileClient)
+						fmt.Sprintf("%s-clientssl", tlsContext.name), tlsContext.namespace, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileClient,
+						"", "", true, nil)
 					if err != nil {
 						log.Debugf("error %v encountered while creating clientssl profile  for '%s' '%s'/'%s'",
 							err, tlsContext.resourceType, tlsContext.namespace, tlsContext.name)
 						return false
 					}
+					rsCfg.addSNIServerNames(tlsContext.getServerNames(), fmt.Sprintf("%s-clientssl", tlsContext.name))
 				}
 				// Create Server SSL profile for bigip
 				if tlsContext.bigIPSSLProfiles.destinationCACertificate != "" {
 					var err error
 					if tlsContext.bigIPSSLProfiles.caCertificate != "" {
 						err, _ = ctlr.createServerSSLProfile(rsCfg, tlsContext.bigIPSSLProfiles.destinationCACertificate,
-							tlsContext.bigIPSSLProfiles.caCertificate, tlsContext.name, tlsContext.namespace, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileServer)
+							tlsContext.bigIPSSLProfiles.caCertificate, tlsContext.name, tlsContext.namespace, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileServer, true, "", false)
 					} else {
 						err, _ = ctlr.createServerSSLProfile(rsCfg, tlsContext.bigIPSSLProfiles.destinationCACertificate,
-							"", fmt.Sprintf("%s-serverssl", tlsContext.name), tlsContext.namespace, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileServer)
+							"", fmt.Sprintf("%s-serverssl", tlsContext.name), tlsContext.namespace, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileServer, true, "", false)
 					}
 					if err != nil {
 						log.Debugf("error %v encountered while creating serverssl profile  for '%s' '%s'/'%s'",
@@ -704,6 +1587,15 @@ func (ctlr *Controller) handleTLS(
 					tlsContext.resourceType, tlsContext.namespace, tlsContext.name)
 				return false
 			}
+			pathServerNames := tlsContext.getPathServerNames()
+			if tlsContext.termination == TLSReencrypt && len(pathServerNames) > 0 {
+				if tlsContext.referenceType == BIGIP {
+					log.Warningf("Ignoring the server names of the pools for '%s' '%s'/'%s' as they are not supported "+
+						"with BIG-IP referenced serverssl profile", tlsContext.resourceType, tlsContext.namespace, tlsContext.name)
+				} else {
+					rsCfg.addPathServerNames(pathServerNames)
+				}
+			}
 			// TLS Cert/Key
 			for _, poolPathRef := range tlsContext.poolPathRefs {
 				switch tlsContext.termination {
@@ -718,6 +1610,9 @@ func (ctlr *Controller) handleTLS(
 					sslPath := tlsContext.hostname + poolPathRef.path
 					sslPath = strings.TrimSuffix(sslPath, "/")
 					serverSsl := AS3NameFormatter("crd_" + tlsContext.ipAddress + "_tls_client")
+					if poolPathRef.serverName != "" && tlsContext.referenceType != BIGIP {
+						serverSsl = getServerNameTLSClientName(rsCfg.Virtual.Name, poolPathRef.serverName)
+					}
 					if "" != serverSSL {
 						updateDataGroup(rsCfg.IntDgMap, getRSCfgResName(rsCfg.Virtual.Name, ReencryptServerSslDgName),
 							rsCfg.Virtual.Partition, tlsContext.namespace, sslPath, serverSsl, DataGroupType)
@@ -799,23 +1694,44 @@ func (ctlr *Controller) handleTLS(
 			log.Debugf("Redirect HTTP(insecure) requests for VirtualServer %s", tlsContext.name)
 			var ruleName string
 			if tlsContext.hostname == "" {
-				ruleName = fmt.Sprintf("%s_%d", getRSCfgResName(rsCfg.Virtual.Name, HttpRedirectNoHostIRuleName), tlsContext.httpsPort)
-				rsCfg.addIRule(ruleName, rsCfg.Virtual.Partition, httpRedirectIRuleNoHost(tlsContext.httpsPort))
+				ruleName = getHttpRedirectIRuleName(rsCfg.Virtual.Name, HttpRedirectNoHostIRuleName, tlsContext.httpsPort, tlsContext.redirectCode)
+				rsCfg.addIRule(ruleName, rsCfg.Virtual.Partition, httpRedirectIRuleNoHost(tlsContext.httpsPort, tlsContext.redirectCode))
 			} else {
-				ruleName = fmt.Sprintf("%s_%d", getRSCfgResName(rsCfg.Virtual.Name, HttpRedirectIRuleName), tlsContext.httpsPort)
-				rsCfg.addIRule(ruleName, rsCfg.Virtual.Partition, httpRedirectIRule(tlsContext.httpsPort, rsCfg.Virtual.Name, rsCfg.Virtual.Partition))
+				ruleName = getHttpRedirectIRuleName(rsCfg.Virtual.Name, HttpRedirectIRuleName, tlsContext.httpsPort, tlsContext.redirectCode)
+				var targetDgName string
+				if tlsContext.redirectTarget != "" {
+					updateHttpsRedirectTargetDataGroup(
+						rsCfg.IntDgMap,
+						rsCfg.Virtual.Name,
+						tlsContext.hostname,
+						tlsContext.namespace,
+						rsCfg.Virtual.Partition,
+						tlsContext.httpsPort,
+						tlsContext.redirectTarget,
+					)
+				}
+				// The redirect iRule is regenerated to look up the targets once any host on the port has a target
+				targetDgKey := NameRef{
+					Name:      getHttpsRedirectTargetDgName(rsCfg.Virtual.Name, tlsContext.httpsPort),
+					Partition: rsCfg.Virtual.Partition,
+				}
+				if _, ok := rsCfg.IntDgMap[targetDgKey]; ok {
+					targetDgName = targetDgKey.Name
+					rsCfg.removeIRule(ruleName, rsCfg.Virtual.Partition)
+				}
+				rsCfg.addIRule(ruleName, rsCfg.Virtual.Partition,
+					httpRedirectIRule(tlsContext.httpsPort, rsCfg.Virtual.Name, rsCfg.Virtual.Partition, tlsContext.redirectCode, targetDgName))
 			}
 			ruleName = JoinBigipPath(rsCfg.Virtual.Partition, ruleName)
 			rsCfg.Virtual.AddIRule(ruleName)
-			updateDataGroupOfDgName(
+			updateHttpsRedirectDataGroup(
 				rsCfg.IntDgMap,
 				tlsContext.poolPathRefs,
 				rsCfg.Virtual.Name,
-				HttpsRedirectDgName,
 				tlsContext.hostname,
 				tlsContext.namespace,
 				rsCfg.Virtual.Partition,
-				[]string{},
+				tlsContext.httpsPort,
 			)
 		case TLSAllowInsecure:
 			// State 3, do not apply any policy
@@ -838,7 +1754,7 @@ func (ctlr *Controller) handleVirtualServerTLS(
 	tls *cisapiv1.TLSProfile,
 	ip string,
 ) bool {
-	if 0 == len(vs.Spec.TLSProfileName) {
+	if !isTLSVirtualServer(vs) {
 		// Probably this is a non-tls Virtual Server, nothing to do w.r.t TLS
 		return false
 	}
@@ -858,20 +1774,41 @@ func (ctlr *Controller) handleVirtualServerTLS(
 	if tls.Spec.TLS.ClientSSL != "" {
 		bigIPSSLProfiles.clientSSL = tls.Spec.TLS.ClientSSL
 	}
+	if len(tls.Spec.TLS.ClientSSLs) == 2 {
+		bigIPSSLProfiles.clientSSL = tls.Spec.TLS.ClientSSLs[0]
+		bigIPSSLProfiles.pairedClientSSL = tls.Spec.TLS.ClientSSLs[1]
+	}
 	if tls.Spec.TLS.ServerSSL != "" {
 		bigIPSSLProfiles.serverSSL = tls.Spec.TLS.ServerSSL
 	}
+	if tls.Spec.TLS.ClientAuth != nil {
+		bigIPSSLProfiles.peerCertMode = tls.Spec.TLS.ClientAuth.Mode
+		bigIPSSLProfiles.clientCASecret = tls.Spec.TLS.ClientAuth.CACertificate
+	}
+	bigIPSSLProfiles.renegotiation = tls.Spec.TLS.Renegotiation
+	bigIPSSLProfiles.alpnProtocols = tls.Spec.TLS.ALPNProtocols
+	bigIPSSLProfiles.tlsOptions = tls.Spec.TLS.TLSOptions
+	bigIPSSLProfiles.serverName = tls.Spec.TLS.ServerName
+	bigIPSSLProfiles.ignoreServerNameCheck = tls.Spec.TLS.IgnoreServerNameCheck
 	var poolPathRefs []poolPathRef
 	for _, pl := range vs.Spec.Pools {
 
-		poolName := framePoolName(
+		poolPartition := ctlr.getPoolPartition(rsCfg, pl)
+		poolName := ctlr.framePoolName(
+			poolPartition,
 			vs.ObjectMeta.Namespace,
 			pl,
 			intstr.IntOrString{IntVal: pl.ServicePort},
 			vs.Spec.Host,
 		)
 
-		poolPathRefs = append(poolPathRefs, poolPathRef{pl.Path, poolName})
+		poolPathRefs = append(poolPathRefs, poolPathRef{pl.Path,
+			getPoolReference(rsCfg.Virtual.Partition, poolPartition, poolName), pl.ServerName})
+	}
+	// With multiple TLSProfiles the clientssl profile is selected with SNI for the hosts of the profile
+	var serverNames []string
+	if len(getTLSProfileNames(vs)) > 1 {
+		serverNames = getTLSProfileServerNames(vs, tls)
 	}
 	return ctlr.handleTLS(rsCfg, TLSContext{vs.ObjectMeta.Name,
 		vs.ObjectMeta.Namespace,
@@ -884,23 +1821,27 @@ func (ctlr *Controller) handleVirtualServerTLS(
 		vs.Spec.HTTPTraffic,
 		poolPathRefs,
 		bigIPSSLProfiles,
+		vs.Spec.HTTPRedirectCode,
+		serverNames,
+		"",
 	})
 }
 
 // validate TLSProfile
 // validation includes valid parameters for the type of termination(edge, re-encrypt and Pass-through)
 func validateTLSProfile(tls *cisapiv1.TLSProfile) bool {
+	hasClientSSL := tls.Spec.TLS.ClientSSL != "" || len(tls.Spec.TLS.ClientSSLs) > 0
 	//validation for re-encrypt termination
 	if tls.Spec.TLS.Termination == "reencrypt" {
 		// Should contain both client and server SSL profiles
-		if (tls.Spec.TLS.ClientSSL == "") || (tls.Spec.TLS.ServerSSL == "") {
+		if !hasClientSSL || (tls.Spec.TLS.ServerSSL == "") {
 			log.Errorf("TLSProfile %s of type re-encrypt termination should contain both "+
 				"ClientSSL and ServerSSL", tls.ObjectMeta.Name)
 			return false
 		}
 	} else if tls.Spec.TLS.Termination == "edge" {
 		// Should contain only client SSL
-		if tls.Spec.TLS.ClientSSL == "" {
+		if !hasClientSSL {
 			log.Errorf("TLSProfile %s of type edge termination should contain Client SSL",
 				tls.ObjectMeta.Name)
 			return false
@@ -912,15 +1853,137 @@ func validateTLSProfile(tls *cisapiv1.TLSProfile) bool {
 		}
 	} else {
 		// Pass-through
-		if (tls.Spec.TLS.ClientSSL != "") || (tls.Spec.TLS.ServerSSL != "") {
+		if hasClientSSL || (tls.Spec.TLS.ServerSSL != "") {
 			log.Errorf("TLSProfile %s of type Pass-through termination should NOT contain either "+
 				"ClientSSL or ServerSSL", tls.ObjectMeta.Name)
 			return false
 		}
 	}
+	if len(tls.Spec.TLS.ClientSSLs) > 0 {
+		if tls.Spec.TLS.Reference != Secret {
+			log.Errorf("TLSProfile %s with clientSSLs should refer to them as secrets",
+				tls.ObjectMeta.Name)
+			return false
+		}
+		if tls.Spec.TLS.ClientSSL != "" {
+			log.Errorf("TLSProfile %s should NOT contain both clientSSL and clientSSLs",
+				tls.ObjectMeta.Name)
+			return false
+		}
+		if len(tls.Spec.TLS.ClientSSLs) != 2 || tls.Spec.TLS.ClientSSLs[0] == tls.Spec.TLS.ClientSSLs[1] {
+			log.Errorf("TLSProfile %s should contain the secrets of an RSA and an ECDSA certificate in clientSSLs",
+				tls.ObjectMeta.Name)
+			return false
+		}
+	}
+	if clientAuth := tls.Spec.TLS.ClientAuth; clientAuth != nil {
+		if tls.Spec.TLS.Termination == TLSPassthrough {
+			log.Errorf("TLSProfile %s of type Pass-through termination should NOT contain clientAuth",
+				tls.ObjectMeta.Name)
+			return false
+		}
+		if clientAuth.Mode == PeerCertRequired {
+			if tls.Spec.TLS.Reference != Secret {
+				log.Errorf("TLSProfile %s with clientAuth mode require should refer to ClientSSL as secret",
+					tls.ObjectMeta.Name)
+				return false
+			}
+			if clientAuth.CACertificate == "" {
+				log.Errorf("TLSProfile %s with clientAuth mode require should contain caCertificate",
+					tls.ObjectMeta.Name)
+				return false
+			}
+		}
+	}
+	if len(tls.Spec.TLS.ALPNProtocols) > 0 {
+		if tls.Spec.TLS.Termination == TLSPassthrough || tls.Spec.TLS.Reference != Secret {
+			log.Errorf("TLSProfile %s with alpnProtocols should refer to ClientSSL as secret",
+				tls.ObjectMeta.Name)
+			return false
+		}
+		for _, protocol := range tls.Spec.TLS.ALPNProtocols {
+			if protocol != ALPNHTTP2 && protocol != ALPNHTTP11 {
+				log.Errorf("TLSProfile %s contains invalid ALPN protocol %v, allowed protocols are %v and %v",
+					tls.ObjectMeta.Name, protocol, ALPNHTTP2, ALPNHTTP11)
+				return false
+			}
+		}
+	}
+	if len(tls.Spec.TLS.TLSOptions) > 0 {
+		if tls.Spec.TLS.Termination == TLSPassthrough || tls.Spec.TLS.Reference != Secret {
+			log.Errorf("TLSProfile %s with tlsOptions should refer to ClientSSL as secret",
+				tls.ObjectMeta.Name)
+			return false
+		}
+		if err := validateTLSOptions(tls.Spec.TLS.TLSOptions); err != nil {
+			log.Errorf("TLSProfile %s contains %v", tls.ObjectMeta.Name, err)
+			return false
+		}
+	}
+	if tls.Spec.TLS.ServerName != "" || tls.Spec.TLS.IgnoreServerNameCheck {
+		if tls.Spec.TLS.Termination != TLSReencrypt || tls.Spec.TLS.Reference != Secret {
+			log.Errorf("TLSProfile %s with serverName or ignoreServerNameCheck should be of type re-encrypt "+
+				"termination and refer to ServerSSL as secret", tls.ObjectMeta.Name)
+			return false
+		}
+		if tls.Spec.TLS.ServerName != "" && tls.Spec.TLS.IgnoreServerNameCheck {
+			log.Errorf("TLSProfile %s should NOT contain both serverName and ignoreServerNameCheck",
+				tls.ObjectMeta.Name)
+			return false
+		}
+		if tls.Spec.TLS.IgnoreServerNameCheck {
+			log.Warningf("TLSProfile %s ignores the server name check of the backend certificates",
+				tls.ObjectMeta.Name)
+		}
+	}
 	return true
 }
 
+// validate TransportServer
+// validation includes valid type, mode allowed for the type and profiles allowed for the mode
+func validateTransportServer(ts *cisapiv1.TransportServer) error {
+	tsType := ts.Spec.Type
+	if tsType == "" {
+		tsType = "tcp"
+	}
+	// supported modes for each type
+	var modes []string
+	switch tsType {
+	case "tcp", "udp":
+		modes = []string{"standard", "performance"}
+	case "sctp":
+		// performance mode(fastL4) does not support sctp
+		modes = []string{"standard"}
+	default:
+		return fmt.Errorf("TransportServer %s/%s has invalid type %q, supported types are tcp, udp and sctp",
+			ts.Namespace, ts.Name, ts.Spec.Type)
+	}
+	validMode := false
+	for _, mode := range modes {
+		if ts.Spec.Mode == mode {
+			validMode = true
+			break
+		}
+	}
+	if !validMode {
+		return fmt.Errorf("TransportServer %s/%s has invalid mode %q for type %s, supported modes are %s",
+			ts.Namespace, ts.Name, ts.Spec.Mode, tsType, strings.Join(modes, ", "))
+	}
+	// profileL4 is attached only to the performance mode virtual
+	if ts.Spec.ProfileL4 != nil && ts.Spec.Mode != "performance" {
+		return fmt.Errorf("TransportServer %s/%s with profileL4 should be of performance mode",
+			ts.Namespace, ts.Name)
+	}
+	// tcp profiles are attached only to the standard mode tcp virtual
+	if ts.Spec.Profiles.TCP.Client != "" || ts.Spec.Profiles.TCP.Server != "" {
+		if tsType != "tcp" || ts.Spec.Mode != "standard" {
+			return fmt.Errorf("TransportServer %s/%s with tcp profiles should be of type tcp and standard mode",
+				ts.Namespace, ts.Name)
+		}
+	}
+	return nil
+}
+
 // ConvertStringToProfileRef converts strings to profile references
 func ConvertStringToProfileRef(profileName, context, ns string) ProfileRef {
 	profName := strings.TrimSpace(strings.TrimPrefix(profileName, "/"))
@@ -971,6 +2034,30 @@ func (v *Virtual) AddOrUpdateProfile(prof ProfileRef) bool {
 	return true
 }
 
+// SetDescription records the kubernetes resource owning the virtual as its description
+// and the metadata annotations of the resource as its metadata.
+// The first resource processed for a shared virtual is recorded.
+func (v *Virtual) SetDescription(namespace, name string, annotations map[string]string) {
+	if v.Description == "" {
+		v.Description = namespace + "/" + name
+	}
+	for key, value := range annotations {
+		if !strings.HasPrefix(key, VirtualMetadataAnnotationPrefix) {
+			continue
+		}
+		metaKey := strings.TrimPrefix(key, VirtualMetadataAnnotationPrefix)
+		if metaKey == "" {
+			continue
+		}
+		if v.Metadata == nil {
+			v.Metadata = make(map[string]string)
+		}
+		if _, ok := v.Metadata[metaKey]; !ok {
+			v.Metadata[metaKey] = value
+		}
+	}
+}
+
 // SetVirtualAddress sets a VirtualAddress
 func (v *Virtual) SetVirtualAddress(bindAddr string, port int32) {
 	v.Destination = ""
@@ -982,7 +2069,7 @@ func (v *Virtual) SetVirtualAddress(bindAddr string, port int32) {
 			Port:     port,
 		}
 		// Validate the IP address, and create the destination
-		ip, rd := split_ip_with_route_domain(bindAddr)
+		ip, rd := split_ip_with_route_domain(getAddressWithRouteDomain(bindAddr, v.RouteDomain))
 		if len(rd) > 0 {
 			rd = "%" + rd
 		}
@@ -999,6 +2086,73 @@ func (v *Virtual) SetVirtualAddress(bindAddr string, port int32) {
 	}
 }
 
+// SetVirtualAddressMask turns the virtual address into a network address with the netmask
+// or the prefix length of the first service address that sets one
+func (v *Virtual) SetVirtualAddressMask(serviceAddresses []ServiceAddress) error {
+	var mask string
+	for _, sa := range serviceAddresses {
+		if sa.Mask != "" {
+			mask = sa.Mask
+			break
+		}
+	}
+	if mask == "" {
+		return nil
+	}
+	if v.VirtualAddress == nil || v.VirtualAddress.BindAddr == "" {
+		return fmt.Errorf("mask %v requires a virtual address", mask)
+	}
+	ip, _ := split_ip_with_route_domain(v.VirtualAddress.BindAddr)
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return fmt.Errorf("mask %v requires a valid virtual address", mask)
+	}
+	prefixLen, err := parseVirtualAddressMask(addr, mask)
+	if err != nil {
+		return err
+	}
+	bits := 8 * net.IPv6len
+	if addr.To4() != nil {
+		addr = addr.To4()
+		bits = 8 * net.IPv4len
+	}
+	if !addr.Mask(net.CIDRMask(prefixLen, bits)).Equal(addr) {
+		return fmt.Errorf("virtual address %v is not a network address of the mask %v", ip, mask)
+	}
+	v.VirtualAddress.PrefixLen = prefixLen
+	return nil
+}
+
+// parseVirtualAddressMask returns the prefix length of a prefix length, an IPv4 netmask
+// or an IPv6 mask, which must match the address family of the virtual address
+func parseVirtualAddressMask(addr net.IP, mask string) (int, error) {
+	bits := 8 * net.IPv6len
+	if addr.To4() != nil {
+		bits = 8 * net.IPv4len
+	}
+	if prefixLen, err := strconv.Atoi(mask); err == nil {
+		if prefixLen < 1 || prefixLen > bits {
+			return 0, fmt.Errorf("invalid prefix length %v for the virtual address %v", mask, addr)
+		}
+		return prefixLen, nil
+	}
+	netmask := net.ParseIP(mask)
+	if netmask == nil {
+		return 0, fmt.Errorf("invalid mask %v", mask)
+	}
+	if (netmask.To4() != nil) != (addr.To4() != nil) {
+		return 0, fmt.Errorf("mask %v doesn't match the address family of the virtual address %v", mask, addr)
+	}
+	if bits == 8*net.IPv4len {
+		netmask = netmask.To4()
+	}
+	prefixLen, size := net.IPMask(netmask).Size()
+	if size == 0 || prefixLen == 0 {
+		return 0, fmt.Errorf("invalid mask %v", mask)
+	}
+	return prefixLen, nil
+}
+
 // SetPolicy sets a policy
 func (rc *ResourceConfig) SetPolicy(policy Policy) {
 	toFind := nameRef{
@@ -1067,13 +2221,22 @@ func (rs *ResourceStore) setResourceConfig(partition, name string, rsCfg *Resour
 	return nil
 }
 
+// setPoolPartitions sets the partitions of the pools, which are managed whether or not they have virtuals,
+// so that their tenants are declared empty once the last pool placed in them is removed
+func (rs *ResourceStore) setPoolPartitions(partitions []string) {
+	rs.poolPartitions = partitions
+	for _, prtn := range partitions {
+		rs.getPartitionResourceMap(prtn)
+	}
+}
+
 // getSanitizedLTMConfigCopy is a Resource reference copy of LTMConfig
 func (rs *ResourceStore) getSanitizedLTMConfigCopy() LTMConfig {
 	ltmConfig := make(LTMConfig)
 	var deletePartitions []string
 	for prtn, partitionConfig := range rs.ltmConfig {
 		// copy only those partitions where virtual server exists otherwise remove from ltmConfig
-		if len(partitionConfig.ResourceMap) > 0 {
+		if len(partitionConfig.ResourceMap) > 0 || containsString(rs.poolPartitions, prtn) {
 			ltmConfig[prtn] = &PartitionConfig{make(ResourceMap), partitionConfig.Priority}
 			for rsName, res := range partitionConfig.ResourceMap {
 				ltmConfig[prtn].ResourceMap[rsName] = res
@@ -1093,16 +2256,82 @@ func (rs *ResourceStore) getSanitizedLTMConfigCopy() LTMConfig {
 func (rs *ResourceStore) getLTMConfigDeepCopy() LTMConfig {
 	ltmConfig := make(LTMConfig)
 	for prtn, partitionConfig := range rs.ltmConfig {
-		ltmConfig[prtn] = &PartitionConfig{make(ResourceMap), partitionConfig.Priority}
-		for rsName, res := range partitionConfig.ResourceMap {
-			copyRes := &ResourceConfig{}
-			copyRes.copyConfig(res)
-			ltmConfig[prtn].ResourceMap[rsName] = copyRes
+		ltmConfig[prtn] = partitionConfig.deepCopy()
+	}
+	return ltmConfig
+}
+
+// getUpdatedLTMConfigDeepCopy is a deep copy of the partitions of LTMConfig updated since the config
+// last posted. Partitions sharing pools with an updated partition, now or in the config last posted,
+// are included as well, as the tenant declarations of both are framed from the resources of the virtual.
+func (rs *ResourceStore) getUpdatedLTMConfigDeepCopy() LTMConfig {
+	// linkedPartitions holds the partitions sharing pools with each other
+	linkedPartitions := make(map[string]map[string]struct{})
+	linkPartitions := func(prtn1, prtn2 string) {
+		for _, prtns := range [][2]string{{prtn1, prtn2}, {prtn2, prtn1}} {
+			if _, ok := linkedPartitions[prtns[0]]; !ok {
+				linkedPartitions[prtns[0]] = make(map[string]struct{})
+			}
+			linkedPartitions[prtns[0]][prtns[1]] = struct{}{}
+		}
+	}
+	linkPoolPartitions := func(ltmConfig LTMConfig) {
+		for _, partitionConfig := range ltmConfig {
+			for _, cfg := range partitionConfig.ResourceMap {
+				for _, pool := range cfg.Pools {
+					if isPartitionPool(cfg, pool.Partition) {
+						linkPartitions(cfg.Virtual.Partition, pool.Partition)
+					}
+				}
+				for _, monitor := range cfg.Monitors {
+					if isPartitionPool(cfg, monitor.Partition) {
+						linkPartitions(cfg.Virtual.Partition, monitor.Partition)
+					}
+				}
+			}
+		}
+	}
+
+	var updatedPartitions []string
+	rs.cacheMutex.RLock()
+	for prtn, partitionConfig := range rs.ltmConfig {
+		if !reflect.DeepEqual(partitionConfig, rs.ltmConfigCache[prtn]) {
+			updatedPartitions = append(updatedPartitions, prtn)
+		}
+	}
+	// the pools removed from a partition are to be removed from the tenant of the pool partition too
+	linkPoolPartitions(rs.ltmConfigCache)
+	rs.cacheMutex.RUnlock()
+	linkPoolPartitions(rs.ltmConfig)
+
+	ltmConfig := make(LTMConfig)
+	for len(updatedPartitions) > 0 {
+		prtn := updatedPartitions[0]
+		updatedPartitions = updatedPartitions[1:]
+		if _, ok := ltmConfig[prtn]; ok {
+			continue
+		}
+		if partitionConfig, ok := rs.ltmConfig[prtn]; ok {
+			ltmConfig[prtn] = partitionConfig.deepCopy()
+		}
+		for linkedPrtn := range linkedPartitions[prtn] {
+			updatedPartitions = append(updatedPartitions, linkedPrtn)
 		}
 	}
 	return ltmConfig
 }
 
+// deepCopy is a Resource reference copy of the PartitionConfig
+func (pc *PartitionConfig) deepCopy() *PartitionConfig {
+	partitionConfig := &PartitionConfig{make(ResourceMap), pc.Priority}
+	for rsName, res := range pc.ResourceMap {
+		copyRes := &ResourceConfig{}
+		copyRes.copyConfig(res)
+		partitionConfig.ResourceMap[rsName] = copyRes
+	}
+	return partitionConfig
+}
+
 // getGTMConfigCopy is a WideIP reference copy of GTMConfig
 func (rs *ResourceStore) getGTMConfigCopy() GTMConfig {
 	gtmConfig := make(GTMConfig)
@@ -1117,10 +2346,82 @@ func (rs *ResourceStore) getGTMConfigCopy() GTMConfig {
 
 func (rs *ResourceStore) updateCaches() {
 	// No need to deep copy as each RsCfg will be framed in a fresh memory block while creating live ltmConfig
+	rs.cacheMutex.Lock()
 	rs.ltmConfigCache = rs.getSanitizedLTMConfigCopy()
+	rs.cacheMutex.Unlock()
 	rs.gtmConfigCache = rs.getGTMConfigCopy()
 }
 
+// getLTMConfigCacheDiff returns the changes of the LTM config compared to the config last posted
+func (rs *ResourceStore) getLTMConfigCacheDiff() LTMConfigDiff {
+	rs.cacheMutex.RLock()
+	defer rs.cacheMutex.RUnlock()
+	return DiffLTMConfig(rs.ltmConfigCache, rs.ltmConfig)
+}
+
+// DumpLTMConfig marshals a deep copy of the current LTM configuration of the controller.
+// Certificate and key material of custom profiles is redacted.
+func (rs *ResourceStore) DumpLTMConfig() ([]byte, error) {
+	rs.cacheMutex.RLock()
+	ltmConfig := make(LTMConfig, len(rs.ltmConfig))
+	for prtn, partitionConfig := range rs.ltmConfig {
+		ltmConfig[prtn] = &PartitionConfig{make(ResourceMap), partitionConfig.Priority}
+		for rsName, res := range partitionConfig.ResourceMap {
+			copyRes := &ResourceConfig{}
+			copyRes.copyConfig(res)
+			ltmConfig[prtn].ResourceMap[rsName] = copyRes
+		}
+	}
+	rs.cacheMutex.RUnlock()
+
+	dump := make(ltmConfigDump, len(ltmConfig))
+	for prtn, partitionConfig := range ltmConfig {
+		prtnDump := partitionConfigDump{
+			Priority:  partitionConfig.Priority,
+			Resources: make(map[string]resourceConfigDump, len(partitionConfig.ResourceMap)),
+		}
+		for rsName, rsCfg := range partitionConfig.ResourceMap {
+			prtnDump.Resources[rsName] = rsCfg.dump()
+		}
+		dump[prtn] = prtnDump
+	}
+	return json.Marshal(dump)
+}
+
+// dump prepares a marshallable copy of ResourceConfig with secrets redacted
+func (rc *ResourceConfig) dump() resourceConfigDump {
+	rcDump := resourceConfigDump{
+		Virtual:        rc.Virtual,
+		Pools:          rc.Pools,
+		Policies:       rc.Policies,
+		Monitors:       rc.Monitors,
+		ServiceAddress: rc.ServiceAddress,
+	}
+	for _, irl := range rc.IRulesMap {
+		rcDump.IRules = append(rcDump.IRules, *irl)
+	}
+	sort.Slice(rcDump.IRules, func(i, j int) bool {
+		return rcDump.IRules[i].Name < rcDump.IRules[j].Name
+	})
+	for _, dgnm := range rc.IntDgMap {
+		for _, idg := range dgnm {
+			rcDump.DataGroups = append(rcDump.DataGroups, *idg)
+		}
+	}
+	sort.Slice(rcDump.DataGroups, func(i, j int) bool {
+		return rcDump.DataGroups[i].Name < rcDump.DataGroups[j].Name
+	})
+	for _, key := range getSortedCustomProfileKeys(rc.customProfiles) {
+		prof := rc.customProfiles[key]
+		prof.Cert = ""
+		prof.Key = ""
+		prof.CAFile = ""
+		prof.ChainCA = ""
+		rcDump.CustomProfiles = append(rcDump.CustomProfiles, prof)
+	}
+	return rcDump
+}
+
 func (rs *ResourceStore) isConfigUpdated() bool {
 	return !reflect.DeepEqual(rs.ltmConfig, rs.ltmConfigCache) ||
 		!reflect.DeepEqual(rs.gtmConfig, rs.gtmConfigCache)
@@ -1153,7 +2454,137 @@ func (lc LTMConfig) GetAllPoolMembers() []PoolMember {
 		}
 	}
 
-	return allPoolMembers
+	return allPoolMembers
+}
+
+// DiffLTMConfig returns the virtuals, pools, monitors and pool members which are
+// added, removed or changed in b compared to a. Both configs are left untouched.
+func DiffLTMConfig(a, b LTMConfig) LTMConfigDiff {
+	oldCfg := flattenLTMConfig(a)
+	newCfg := flattenLTMConfig(b)
+
+	diff := LTMConfigDiff{
+		Virtuals: diffConfigItems(oldCfg.virtuals, newCfg.virtuals),
+		Monitors: diffConfigItems(oldCfg.monitors, newCfg.monitors),
+		Members:  make(map[string]PoolMembersDiff),
+	}
+
+	// Pools are compared without members, changes to the members are listed per pool
+	oldPools := make(map[string]interface{}, len(oldCfg.pools))
+	for name, pool := range oldCfg.pools {
+		pool.Members = nil
+		oldPools[name] = pool
+	}
+	newPools := make(map[string]interface{}, len(newCfg.pools))
+	for name, pool := range newCfg.pools {
+		pool.Members = nil
+		newPools[name] = pool
+	}
+	diff.Pools = diffConfigItems(oldPools, newPools)
+
+	poolNames := make(map[string]struct{})
+	for name := range oldCfg.pools {
+		poolNames[name] = struct{}{}
+	}
+	for name := range newCfg.pools {
+		poolNames[name] = struct{}{}
+	}
+	for name := range poolNames {
+		memDiff := diffPoolMembers(oldCfg.pools[name].Members, newCfg.pools[name].Members)
+		if len(memDiff.Added) > 0 || len(memDiff.Removed) > 0 || len(memDiff.Changed) > 0 {
+			diff.Members[name] = memDiff
+		}
+	}
+
+	return diff
+}
+
+// flatLTMConfig holds the resources of an LTMConfig by their partition qualified name
+type flatLTMConfig struct {
+	virtuals map[string]interface{}
+	pools    map[string]Pool
+	monitors map[string]interface{}
+}
+
+func flattenLTMConfig(ltmConfig LTMConfig) flatLTMConfig {
+	flatCfg := flatLTMConfig{
+		virtuals: make(map[string]interface{}),
+		pools:    make(map[string]Pool),
+		monitors: make(map[string]interface{}),
+	}
+	for prtn, partitionConfig := range ltmConfig {
+		if partitionConfig == nil {
+			continue
+		}
+		for _, res := range partitionConfig.ResourceMap {
+			rsCfg := &ResourceConfig{}
+			rsCfg.copyConfig(res)
+			flatCfg.virtuals[JoinBigipPath(prtn, rsCfg.Virtual.Name)] = rsCfg.Virtual
+			for _, pool := range rsCfg.Pools {
+				flatCfg.pools[JoinBigipPath(prtn, pool.Name)] = pool
+			}
+			for _, monitor := range rsCfg.Monitors {
+				flatCfg.monitors[JoinBigipPath(prtn, monitor.Name)] = monitor
+			}
+		}
+	}
+	return flatCfg
+}
+
+// diffConfigItems compares the items keyed by name and returns the sorted names
+func diffConfigItems(a, b map[string]interface{}) ConfigDiff {
+	var diff ConfigDiff
+	for name, oldItem := range a {
+		newItem, found := b[name]
+		if !found {
+			diff.Removed = append(diff.Removed, name)
+		} else if !reflect.DeepEqual(oldItem, newItem) {
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range b {
+		if _, found := a[name]; !found {
+			diff.Added = append(diff.Added, name)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
+// diffPoolMembers compares the pool members identified by address and port
+func diffPoolMembers(a, b []PoolMember) PoolMembersDiff {
+	var diff PoolMembersDiff
+	oldMems := make(map[string]PoolMember, len(a))
+	for _, mem := range a {
+		oldMems[fmt.Sprintf("%v:%v", mem.Address, mem.Port)] = mem
+	}
+	newMems := make(map[string]struct{}, len(b))
+	for _, mem := range b {
+		key := fmt.Sprintf("%v:%v", mem.Address, mem.Port)
+		newMems[key] = struct{}{}
+		if oldMem, found := oldMems[key]; !found {
+			diff.Added = append(diff.Added, mem)
+		} else if !reflect.DeepEqual(oldMem, mem) {
+			diff.Changed = append(diff.Changed, mem)
+		}
+	}
+	for _, mem := range a {
+		if _, found := newMems[fmt.Sprintf("%v:%v", mem.Address, mem.Port)]; !found {
+			diff.Removed = append(diff.Removed, mem)
+		}
+	}
+	return diff
+}
+
+// copyPoolsConfig returns a copy of the config which owns its pools only, the rest of the config
+// is shared with the original as the updates of the pool members don't change it
+func (rc *ResourceConfig) copyPoolsConfig() *ResourceConfig {
+	cfg := *rc
+	cfg.Pools = make(Pools, len(rc.Pools))
+	copy(cfg.Pools, rc.Pools)
+	return &cfg
 }
 
 // Copies from an existing config into our new config
@@ -1164,6 +2595,12 @@ func (rc *ResourceConfig) copyConfig(cfg *ResourceConfig) {
 	for k, v := range cfg.MetaData.baseResources {
 		rc.MetaData.baseResources[k] = v
 	}
+	if cfg.MetaData.iRulePriorities != nil {
+		rc.MetaData.iRulePriorities = make(map[string]int, len(cfg.MetaData.iRulePriorities))
+		for k, v := range cfg.MetaData.iRulePriorities {
+			rc.MetaData.iRulePriorities[k] = v
+		}
+	}
 	copy(rc.MetaData.hosts, rc.MetaData.hosts)
 
 	// Virtual
@@ -1180,6 +2617,48 @@ func (rc *ResourceConfig) copyConfig(cfg *ResourceConfig) {
 	//AllowVLANS
 	rc.Virtual.AllowVLANs = make([]string, len(cfg.Virtual.AllowVLANs))
 	copy(rc.Virtual.AllowVLANs, cfg.Virtual.AllowVLANs)
+	//RejectVLANS
+	rc.Virtual.RejectVLANs = make([]string, len(cfg.Virtual.RejectVLANs))
+	copy(rc.Virtual.RejectVLANs, cfg.Virtual.RejectVLANs)
+	//Metadata
+	if cfg.Virtual.Metadata != nil {
+		rc.Virtual.Metadata = make(map[string]string, len(cfg.Virtual.Metadata))
+		for k, v := range cfg.Virtual.Metadata {
+			rc.Virtual.Metadata[k] = v
+		}
+	}
+	//ABPersistence
+	if cfg.Virtual.ABPersistence != nil {
+		abPersistence := *cfg.Virtual.ABPersistence
+		rc.Virtual.ABPersistence = &abPersistence
+	}
+	//Persistence
+	if cfg.Virtual.Persistence != nil {
+		persistence := *cfg.Virtual.Persistence
+		rc.Virtual.Persistence = &persistence
+	}
+	//RouteDomain
+	if cfg.Virtual.RouteDomain != nil {
+		routeDomain := *cfg.Virtual.RouteDomain
+		rc.Virtual.RouteDomain = &routeDomain
+	}
+	//ProfileL4 Options
+	if cfg.Virtual.ProfileL4Options != nil {
+		rc.Virtual.ProfileL4Options = &ProfileL4Options{
+			LooseInitialization: copyBool(cfg.Virtual.ProfileL4Options.LooseInitialization),
+			ResetOnTimeout:      copyBool(cfg.Virtual.ProfileL4Options.ResetOnTimeout),
+			IdleTimeout:         copyInt32(cfg.Virtual.ProfileL4Options.IdleTimeout),
+			TCPHandshakeTimeout: copyInt32(cfg.Virtual.ProfileL4Options.TCPHandshakeTimeout),
+		}
+	}
+	//HTTP Profile Options
+	if cfg.Virtual.HTTPProfileOptions != nil {
+		httpProfileOptions := *cfg.Virtual.HTTPProfileOptions
+		rc.Virtual.HTTPProfileOptions = &httpProfileOptions
+	}
+	//Address and Port Translation
+	rc.Virtual.TranslateServerAddress = copyBool(cfg.Virtual.TranslateServerAddress)
+	rc.Virtual.TranslateServerPort = copyBool(cfg.Virtual.TranslateServerPort)
 
 	// Pools
 	rc.Pools = make(Pools, len(cfg.Pools))
@@ -1190,6 +2669,10 @@ func (rc *ResourceConfig) copyConfig(cfg *ResourceConfig) {
 		copy(rc.Pools[i].Members, cfg.Pools[i].Members)
 		rc.Pools[i].MonitorNames = make([]MonitorName, len(cfg.Pools[i].MonitorNames))
 		copy(rc.Pools[i].MonitorNames, cfg.Pools[i].MonitorNames)
+		if cfg.Pools[i].WeightedBackends != nil {
+			rc.Pools[i].WeightedBackends = make([]WeightedBackend, len(cfg.Pools[i].WeightedBackends))
+			copy(rc.Pools[i].WeightedBackends, cfg.Pools[i].WeightedBackends)
+		}
 	}
 
 	// Policies
@@ -1286,6 +2769,17 @@ func split_ip_with_route_domain(address string) (ip string, rd string) {
 	return
 }
 
+// getAddressWithRouteDomain returns the address with the route domain, where the route domain embedded
+// in the address takes precedence over the route domain of the resource. An address with neither of them
+// is left as is to use the default route domain of the controller, which is set on the tenant.
+func getAddressWithRouteDomain(address string, routeDomain *int32) string {
+	ip, rd := split_ip_with_route_domain(address)
+	if rd != "" || routeDomain == nil {
+		return address
+	}
+	return fmt.Sprintf("%s%%%d", ip, *routeDomain)
+}
+
 func (pol *Policy) mergeRules(rls *Rules) Rules {
 	existingRlMap := make(ruleMap)
 	// populate existing rules into a map
@@ -1344,6 +2838,17 @@ func (pol *Policy) AddRules(rls *Rules) {
 		}
 	}
 
+	// Add the response control if any rule is evaluated on the response
+	// event, so that FindPolicy can locate the policy by it.
+	if !containsString(pol.Controls, PolicyControlResponse) {
+		for _, x := range newRules {
+			if x.Event == PolicyEventResponse {
+				pol.Controls = append(pol.Controls, PolicyControlResponse)
+				break
+			}
+		}
+	}
+
 	pol.Rules = append(pol.Rules, newRules...)
 	sort.Sort(pol.Rules)
 }
@@ -1380,6 +2885,9 @@ const AllowSourceRangeDgName = "allowSourceRange"
 // Internal data group for ab deployment routes.
 const AbDeploymentDgName = "ab_deployment_dg"
 
+// Default cookie to persist the pool of ab deployment routes.
+const DefaultABPersistenceCookieName = "cis_ab_pool"
+
 func (slice InternalDataGroupRecords) Less(i, j int) bool {
 	return slice[i].Name < slice[j].Name
 }
@@ -1454,6 +2962,98 @@ func AS3NameFormatter(name string) string {
 	return name
 }
 
+// getUniqueAS3Name returns the formatted AS3 name of the source name, AS3NameFormatter maps several
+// characters to "_" so different source names of the partition may be formatted to the same name.
+// Such names are disambiguated with a hash suffix of their source names, e.g. the monitors of the same
+// name placed in a pool partition by the virtuals of several partitions. The names don't depend on the
+// order the sources are seen in, but a name changes when its source starts or stops colliding.
+func (rs *ResourceStore) getUniqueAS3Name(partition, source, name string) string {
+	names, ok := rs.as3NameSources[partition]
+	if !ok {
+		names = make(map[string]map[string]struct{})
+		rs.as3NameSources[partition] = names
+	}
+	sources, ok := names[name]
+	if !ok {
+		sources = make(map[string]struct{})
+		names[name] = sources
+	}
+	if _, found := sources[source]; !found {
+		sources[source] = struct{}{}
+		if len(sources) > 1 {
+			var sourceNames []string
+			for src := range sources {
+				sourceNames = append(sourceNames, src)
+			}
+			sort.Strings(sourceNames)
+			log.Warningf("[CORE] %v are formatted to the same name %v in partition %v, using the hash suffixed names",
+				strings.Join(sourceNames, ", "), name, partition)
+		}
+	}
+	return getSourceAS3Name(sources, source, name)
+}
+
+// getSourceAS3Name returns the name of the source among the sources formatted to the name, the source
+// which is the name itself keeps it
+func getSourceAS3Name(sources map[string]struct{}, source, name string) string {
+	if len(sources) == 1 || source == name {
+		return name
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(source))
+	return fmt.Sprintf("%s_%08x", name, hash.Sum32())
+}
+
+// pruneAS3NameSources forgets the source names whose AS3 names are not used by the virtuals, pools
+// and monitors of the partitions anymore, so that they don't disambiguate the names of other sources
+func (rs *ResourceStore) pruneAS3NameSources() {
+	inUse := make(map[string]map[string]struct{})
+	use := func(partition, name string) {
+		if _, ok := inUse[partition]; !ok {
+			inUse[partition] = make(map[string]struct{})
+		}
+		inUse[partition][name] = struct{}{}
+	}
+	for _, partitionConfig := range rs.ltmConfig {
+		for _, rsCfg := range partitionConfig.ResourceMap {
+			use(rsCfg.Virtual.Partition, rsCfg.Virtual.Name)
+			for _, pool := range rsCfg.Pools {
+				if pool.Partition != "" {
+					use(pool.Partition, pool.Name)
+				} else {
+					use(rsCfg.Virtual.Partition, pool.Name)
+				}
+			}
+			for _, monitor := range rsCfg.Monitors {
+				if monitor.Partition != "" {
+					use(monitor.Partition, monitor.Name)
+				} else {
+					use(rsCfg.Virtual.Partition, monitor.Name)
+				}
+			}
+		}
+	}
+	for partition, names := range rs.as3NameSources {
+		for name, sources := range names {
+			var unused []string
+			for source := range sources {
+				if _, ok := inUse[partition][getSourceAS3Name(sources, source, name)]; !ok {
+					unused = append(unused, source)
+				}
+			}
+			for _, source := range unused {
+				delete(sources, source)
+			}
+			if len(sources) == 0 {
+				delete(names, name)
+			}
+		}
+		if len(names) == 0 {
+			delete(rs.as3NameSources, partition)
+		}
+	}
+}
+
 func (ctlr *Controller) handleDataGroupIRules(
 	rsCfg *ResourceConfig,
 	vsHost string,
@@ -1464,7 +3064,8 @@ func (ctlr *Controller) handleDataGroupIRules(
 		tlsIRuleName := JoinBigipPath(rsCfg.Virtual.Partition,
 			getRSCfgResName(rsCfg.Virtual.Name, TLSIRuleName))
 		rsCfg.addIRule(
-			getRSCfgResName(rsCfg.Virtual.Name, TLSIRuleName), rsCfg.Virtual.Partition, ctlr.getTLSIRule(rsCfg.Virtual.Name, rsCfg.Virtual.Partition, rsCfg.Virtual.AllowSourceRange))
+			getRSCfgResName(rsCfg.Virtual.Name, TLSIRuleName), rsCfg.Virtual.Partition, ctlr.getTLSIRule(rsCfg.Virtual.Name, rsCfg.Virtual.Partition, rsCfg.Virtual.AllowSourceRange, rsCfg.Virtual.ABPersistence,
+				rsCfg.Virtual.DefaultPassthroughPool))
 		switch tlsTerminationType {
 		case TLSEdge:
 			rsCfg.addInternalDataGroup(getRSCfgResName(rsCfg.Virtual.Name, EdgeHostsDgName), rsCfg.Virtual.Partition)
@@ -1485,6 +3086,49 @@ func (ctlr *Controller) deleteVirtualServer(partition, rsName string) {
 	ctlr.resources.deleteVirtualServer(partition, rsName)
 }
 
+// deleteRenamedVirtualServers deletes the virtuals of the destination other than rsName, the unique AS3
+// name of a virtual server name changes when it starts or stops colliding with another one
+func (ctlr *Controller) deleteRenamedVirtualServers(rsName, destination string) {
+	if destination == "" {
+		return
+	}
+	rsMap := ctlr.resources.getPartitionResourceMap(ctlr.Partition)
+	for name, rsCfg := range rsMap {
+		if name != rsName && rsCfg.Virtual.Destination == destination {
+			log.Debugf("[CORE] Deleting virtual %v renamed to %v", name, rsName)
+			ctlr.deleteSvcDepResource(name, rsCfg)
+			ctlr.deleteVirtualServer(ctlr.Partition, name)
+		}
+	}
+}
+
+// getVirtualDestination returns the destination of the virtual of the address and port
+func getVirtualDestination(partition, ip string, port int32, routeDomain *int32) string {
+	v := Virtual{Partition: partition, RouteDomain: routeDomain}
+	v.SetVirtualAddress(ip, port)
+	return v.Destination
+}
+
+// getTransportServerMonitorType returns the type of the health monitor defined in a TransportServer,
+// sctp TransportServers default to the icmp monitor and do not support the tcp and http monitors
+func getTransportServerMonitorType(ts *cisapiv1.TransportServer, monitorType string) (string, error) {
+	// http2 monitor requires a serverssl profile which is not available for TransportServer
+	if monitorType == MonitorTypeHTTP2 {
+		return "", fmt.Errorf("http2 monitor is not supported in TransportServer %v/%v", ts.Namespace, ts.Name)
+	}
+	if ts.Spec.Type != "sctp" {
+		return monitorType, nil
+	}
+	switch monitorType {
+	case "":
+		return MonitorTypeICMP, nil
+	case "tcp", "tcp-half-open", "http", "https":
+		return "", fmt.Errorf("%v monitor is not supported for sctp in TransportServer %v/%v",
+			monitorType, ts.Namespace, ts.Name)
+	}
+	return monitorType, nil
+}
+
 func (ctlr *Controller) getVirtualServer(partition, rsName string) *ResourceConfig {
 	res, _ := ctlr.resources.getResourceConfig(partition, rsName)
 	return res
@@ -1499,7 +3143,8 @@ func (ctlr *Controller) prepareRSConfigFromTransportServer(
 	if (intstr.IntOrString{}) == targetPort {
 		targetPort = intstr.IntOrString{IntVal: vs.Spec.Pool.ServicePort}
 	}
-	poolName := framePoolName(
+	poolName := ctlr.framePoolName(
+		rsCfg.Virtual.Partition,
 		vs.ObjectMeta.Namespace,
 		vs.Spec.Pool,
 		targetPort,
@@ -1512,41 +3157,77 @@ func (ctlr *Controller) prepareRSConfigFromTransportServer(
 	} else {
 		monitorName = poolName + "-monitor"
 	}
+	monitorType := vs.Spec.Pool.Monitor.Type
+	if !reflect.DeepEqual(vs.Spec.Pool.Monitor, cisapiv1.Monitor{}) && vs.Spec.Pool.Monitor.Reference != BIGIP {
+		var err error
+		if monitorType, err = getTransportServerMonitorType(vs, monitorType); err != nil {
+			return err
+		}
+	}
 
 	pool := Pool{
-		Name:             poolName,
-		Partition:        rsCfg.Virtual.Partition,
-		ServiceName:      vs.Spec.Pool.Service,
-		ServiceNamespace: vs.ObjectMeta.Namespace,
-		ServicePort:      targetPort,
-		NodeMemberLabel:  vs.Spec.Pool.NodeMemberLabel,
-		Balance:          vs.Spec.Pool.Balance,
+		Name:              poolName,
+		Partition:         rsCfg.Virtual.Partition,
+		ServiceName:       vs.Spec.Pool.Service,
+		ServiceNamespace:  vs.ObjectMeta.Namespace,
+		ServicePort:       targetPort,
+		NodeMemberLabel:   vs.Spec.Pool.NodeMemberLabel,
+		Balance:           vs.Spec.Pool.Balance,
+		ServiceDownAction: vs.Spec.Pool.ServiceDownAction,
+		ReselectTries:     vs.Spec.Pool.ReselectTries,
+		MinimumMonitors:   vs.Spec.Pool.MinimumMonitors,
+		Metadata:          ctlr.getPoolMetadata(vs.ObjectMeta.Namespace, vs.Spec.Pool.Service),
+	}
+	if err := validateServiceDownAction(pool); err != nil {
+		return fmt.Errorf("%v in TransportServer %v/%v", err, vs.Namespace, vs.Name)
+	}
+	// the hash key is persisted by the forwarding policy of the HTTP virtuals
+	if vs.Spec.Pool.HashKey != nil || isHashBalance(vs.Spec.Pool.Balance) {
+		return fmt.Errorf("hashing loadBalancingMethod and hashKey are not supported in TransportServer %v/%v",
+			vs.Namespace, vs.Name)
 	}
 	if vs.Spec.Pool.Monitor.Name != "" && vs.Spec.Pool.Monitor.Reference == BIGIP {
 		pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: monitorName, Reference: vs.Spec.Pool.Monitor.Reference})
-	} else if vs.Spec.Pool.Monitor.Type != "" {
+	} else if monitorType != "" {
 		if vs.Spec.Pool.Name == "" {
-			monitorName = formatMonitorName(vs.ObjectMeta.Namespace, vs.Spec.Pool.Service, vs.Spec.Pool.Monitor.Type, vs.Spec.Pool.ServicePort, "", "")
+			monitorName = formatMonitorName(vs.ObjectMeta.Namespace, vs.Spec.Pool.Service, monitorType, vs.Spec.Pool.ServicePort, "", "")
 		}
 		pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: JoinBigipPath(rsCfg.Virtual.Partition, monitorName)})
 
 		monitor := Monitor{
-			Name:       monitorName,
-			Partition:  rsCfg.Virtual.Partition,
-			Type:       vs.Spec.Pool.Monitor.Type,
-			Interval:   vs.Spec.Pool.Monitor.Interval,
-			Send:       "",
-			Recv:       "",
-			Timeout:    vs.Spec.Pool.Monitor.Timeout,
-			TargetPort: vs.Spec.Pool.Monitor.TargetPort,
+			Name:                    monitorName,
+			Partition:               rsCfg.Virtual.Partition,
+			Type:                    monitorType,
+			Interval:                vs.Spec.Pool.Monitor.Interval,
+			Send:                    "",
+			Recv:                    "",
+			Timeout:                 vs.Spec.Pool.Monitor.Timeout,
+			TargetPort:              vs.Spec.Pool.Monitor.TargetPort,
+			TargetAddress:           vs.Spec.Pool.Monitor.TargetAddress,
+			Transparent:             vs.Spec.Pool.Monitor.Transparent,
+			Adaptive:                vs.Spec.Pool.Monitor.Adaptive,
+			AdaptiveDivergenceType:  vs.Spec.Pool.Monitor.AdaptiveDivergenceType,
+			AdaptiveDivergenceValue: vs.Spec.Pool.Monitor.AdaptiveDivergenceValue,
+			AdaptiveLimit:           vs.Spec.Pool.Monitor.AdaptiveLimit,
+		}
+		if err := validateMonitorTarget(monitor); err != nil {
+			return fmt.Errorf("%v in TransportServer %v/%v", err, vs.Namespace, vs.Name)
+		}
+		if err := validateAdaptiveMonitor(monitor); err != nil {
+			return fmt.Errorf("%v in TransportServer %v/%v", err, vs.Namespace, vs.Name)
 		}
 		rsCfg.Monitors = append(rsCfg.Monitors, monitor)
 	} else if vs.Spec.Pool.Monitors != nil {
 		pl := vs.Spec.Pool
 		for _, monitor := range pl.Monitors {
 			if monitor.Name != "" && monitor.Reference == BIGIP {
-				pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: monitor.Name, Reference: monitor.Reference})
+				pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: monitor.Name, Reference: monitor.Reference,
+					MemberAddresses: monitor.MemberAddresses})
 			} else {
+				monitorType, err := getTransportServerMonitorType(vs, monitor.Type)
+				if err != nil {
+					return err
+				}
 				var formatPort int32
 				if monitor.TargetPort != 0 {
 					formatPort = monitor.TargetPort
@@ -1554,19 +3235,34 @@ func (ctlr *Controller) prepareRSConfigFromTransportServer(
 					formatPort = pl.ServicePort
 				}
 
-				if monitor.Name == "" {
-					monitorName = formatMonitorName(vs.ObjectMeta.Namespace, pl.Service, monitor.Type, formatPort, "", "")
+				if pl.Name != "" {
+					monitorName = formatPoolMonitorName(poolName, monitorType, formatPort)
+				} else if monitor.Name == "" {
+					monitorName = formatMonitorName(vs.ObjectMeta.Namespace, pl.Service, monitorType, formatPort, "", "")
 				}
-				pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: JoinBigipPath(rsCfg.Virtual.Partition, monitorName)})
+				pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: JoinBigipPath(rsCfg.Virtual.Partition, monitorName),
+					MemberAddresses: monitor.MemberAddresses})
 				monitor := Monitor{
-					Name:       monitorName,
-					Partition:  rsCfg.Virtual.Partition,
-					Type:       monitor.Type,
-					Interval:   monitor.Interval,
-					Send:       "",
-					Recv:       "",
-					Timeout:    monitor.Timeout,
-					TargetPort: monitor.TargetPort,
+					Name:                    monitorName,
+					Partition:               rsCfg.Virtual.Partition,
+					Type:                    monitorType,
+					Interval:                monitor.Interval,
+					Send:                    "",
+					Recv:                    "",
+					Timeout:                 monitor.Timeout,
+					TargetPort:              monitor.TargetPort,
+					TargetAddress:           monitor.TargetAddress,
+					Transparent:             monitor.Transparent,
+					Adaptive:                monitor.Adaptive,
+					AdaptiveDivergenceType:  monitor.AdaptiveDivergenceType,
+					AdaptiveDivergenceValue: monitor.AdaptiveDivergenceValue,
+					AdaptiveLimit:           monitor.AdaptiveLimit,
+				}
+				if err := validateMonitorTarget(monitor); err != nil {
+					return fmt.Errorf("%v in TransportServer %v/%v", err, vs.Namespace, vs.Name)
+				}
+				if err := validateAdaptiveMonitor(monitor); err != nil {
+					return fmt.Errorf("%v in TransportServer %v/%v", err, vs.Namespace, vs.Name)
 				}
 				rsCfg.Monitors = append(rsCfg.Monitors, monitor)
 			}
@@ -1576,10 +3272,55 @@ func (ctlr *Controller) prepareRSConfigFromTransportServer(
 	rsCfg.Virtual.Mode = vs.Spec.Mode
 	rsCfg.Virtual.IpProtocol = vs.Spec.Type
 	rsCfg.Virtual.PoolName = pool.Name
+	namedPools := make(map[string]string)
+	if vs.Spec.Pool.Name != "" {
+		namedPools[vs.Spec.Pool.Name] = pool.Name
+	}
+	if err := rsCfg.setClonePools(vs.Spec.ClonePools, namedPools); err != nil {
+		return fmt.Errorf("%v in TransportServer %v/%v", err, vs.Namespace, vs.Name)
+	}
+	if err := rsCfg.setConnectionRateLimit(vs.Spec.ConnectionRateLimit); err != nil {
+		return fmt.Errorf("%v in TransportServer %v/%v", err, vs.Namespace, vs.Name)
+	}
+	// sctp virtual uses the default sctp profile unless one is provided through the policy
+	if vs.Spec.Type == "sctp" {
+		sctpProfile := false
+		for _, prof := range rsCfg.Virtual.Profiles {
+			if prof.Context == "sctp" {
+				sctpProfile = true
+				break
+			}
+		}
+		if !sctpProfile {
+			rsCfg.Virtual.Profiles = append(rsCfg.Virtual.Profiles, ProfileRef{
+				Name:         DEFAULT_SCTP_PROFILE,
+				Context:      "sctp",
+				BigIPProfile: true,
+			})
+		}
+	}
+	if err := validateMinimumMonitors(pool); err != nil {
+		return fmt.Errorf("%v in TransportServer %v/%v", err, vs.Namespace, vs.Name)
+	}
 	rsCfg.Pools = append(rsCfg.Pools, pool)
 
-	if vs.Spec.ProfileL4 != "" {
-		rsCfg.Virtual.ProfileL4 = vs.Spec.ProfileL4
+	// profileL4 of the TS spec replaces the one set from policy CR
+	if vs.Spec.ProfileL4 != nil {
+		if vs.Spec.ProfileL4.Reference != "" {
+			rsCfg.Virtual.ProfileL4 = vs.Spec.ProfileL4.Reference
+			rsCfg.Virtual.ProfileL4Options = nil
+		} else {
+			if err := validateProfileL4Timeouts(vs.Spec.ProfileL4, vs.Spec.Type); err != nil {
+				return fmt.Errorf("%v in TransportServer %v/%v", err, vs.Namespace, vs.Name)
+			}
+			rsCfg.Virtual.ProfileL4 = ""
+			rsCfg.Virtual.ProfileL4Options = &ProfileL4Options{
+				LooseInitialization: copyBool(vs.Spec.ProfileL4.LooseInitialization),
+				ResetOnTimeout:      copyBool(vs.Spec.ProfileL4.ResetOnTimeout),
+				IdleTimeout:         copyInt32(vs.Spec.ProfileL4.IdleTimeout),
+				TCPHandshakeTimeout: copyInt32(vs.Spec.ProfileL4.TCPHandshakeTimeout),
+			}
+		}
 	}
 	// Replace SNAT set from policy CR to the one defined by user in the TS spec
 	if vs.Spec.SNAT == "" {
@@ -1589,6 +3330,16 @@ func (ctlr *Controller) prepareRSConfigFromTransportServer(
 	} else {
 		rsCfg.Virtual.SNAT = vs.Spec.SNAT
 	}
+	// Replace translation set from policy CR to the one defined by user in the TS spec
+	if vs.Spec.TranslateAddress != nil {
+		rsCfg.Virtual.TranslateServerAddress = copyBool(vs.Spec.TranslateAddress)
+	}
+	if vs.Spec.TranslatePort != nil {
+		rsCfg.Virtual.TranslateServerPort = copyBool(vs.Spec.TranslatePort)
+	}
+	if err := validateAddressTranslation(rsCfg); err != nil {
+		return fmt.Errorf("TransportServer %v/%v: %v", vs.Namespace, vs.Name, err)
+	}
 
 	if vs.Spec.DOS != "" {
 		rsCfg.Virtual.ProfileDOS = vs.Spec.DOS
@@ -1608,77 +3359,210 @@ func (ctlr *Controller) prepareRSConfigFromTransportServer(
 			rsCfg.ServiceAddress = append(rsCfg.ServiceAddress, ServiceAddress(sa))
 		}
 	}
+	if err := rsCfg.Virtual.SetVirtualAddressMask(rsCfg.ServiceAddress); err != nil {
+		return fmt.Errorf("%v in TransportServer %v/%v", err, vs.Namespace, vs.Name)
+	}
+
+	rsCfg.MetaData.poolMemberType = ctlr.getPoolMemberType(vs.Spec.PoolMemberType)
 
-	//set allowed VLAN's per TS config
-	rsCfg.Virtual.AllowVLANs = vs.Spec.AllowVLANs
+	//set allowed or rejected VLAN's per TS config
+	if err := rsCfg.Virtual.setVLANs(vs.Spec.AllowVLANs, vs.Spec.RejectVLANs, vs.Spec.VLANsEnabled); err != nil {
+		return fmt.Errorf("%v in TransportServer %v/%v", err, vs.Namespace, vs.Name)
+	}
+	rsCfg.Virtual.SetDescription(vs.Namespace, vs.Name, vs.Annotations)
 
 	if vs.Spec.PersistenceProfile != "" {
 		rsCfg.Virtual.PersistenceProfile = vs.Spec.PersistenceProfile
 	}
 
+	if vs.Spec.Persistence != nil {
+		if vs.Spec.PersistenceProfile != "" {
+			return fmt.Errorf("persistenceProfile and persistence are mutually exclusive in TransportServer %v/%v",
+				vs.Namespace, vs.Name)
+		}
+		err := validatePersistence(vs.Spec.Persistence, SourceAddressPersistence, DestinationAddressPersistence)
+		if err != nil {
+			return fmt.Errorf("%v for L4 in TransportServer %v/%v", err, vs.Namespace, vs.Name)
+		}
+		// persistence defined in TS overrides the persistence profile from policy CR
+		rsCfg.Virtual.PersistenceProfile = ""
+		rsCfg.Virtual.Persistence = newPersistenceProfile(vs.Spec.Persistence)
+	}
+
 	// Attach user specified iRules
 	if len(vs.Spec.IRules) > 0 {
 		rsCfg.Virtual.IRules = append(rsCfg.Virtual.IRules, vs.Spec.IRules...)
+		rsCfg.sortIRules()
+	}
+	return nil
+}
+
+// validatePersistence checks the persistence type is supported by the resource
+// and the match across options are allowed for the type
+func validatePersistence(persistence *cisapiv1.Persistence, supportedTypes ...string) error {
+	supported := false
+	for _, persistenceType := range supportedTypes {
+		if persistence.Type == persistenceType {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return fmt.Errorf("unsupported persistence type %v", persistence.Type)
+	}
+	if (persistence.MatchAcrossPools || persistence.MatchAcrossVirtuals) &&
+		persistence.Type != SourceAddressPersistence && persistence.Type != UniversalPersistence {
+		return fmt.Errorf("matchAcrossPools and matchAcrossVirtuals are not supported with %v persistence",
+			persistence.Type)
 	}
 	return nil
 }
 
+func newPersistenceProfile(persistence *cisapiv1.Persistence) *PersistenceProfile {
+	return &PersistenceProfile{
+		Type:                persistence.Type,
+		Timeout:             persistence.Timeout,
+		MatchAcrossServices: persistence.MatchAcrossServices,
+		MatchAcrossPools:    persistence.MatchAcrossPools,
+		MatchAcrossVirtuals: persistence.MatchAcrossVirtuals,
+	}
+}
+
 // Prepares resource config based on VirtualServer resource config
 func (ctlr *Controller) prepareRSConfigFromLBService(
 	rsCfg *ResourceConfig,
 	svc *v1.Service,
 	svcPort v1.ServicePort,
 ) error {
+	protocol, err := getLBServiceProtocol(svcPort)
+	if err != nil {
+		return fmt.Errorf("service %s/%s port %v: %v", svc.Namespace, svc.Name, svcPort.Port, err)
+	}
+	targetPort := svcPort.TargetPort
+	// node port members are looked up by the service port, only the cluster
+	// members are matched against the target port of the endpoints
+	if targetPort.Type == intstr.String && ctlr.PoolMemberType != NodePort && ctlr.PoolMemberType != NodePortLocal {
+		targetPort, err = ctlr.resolveNamedTargetPort(svc, svcPort)
+		if err != nil {
+			return fmt.Errorf("service %s/%s port %v: %v", svc.Namespace, svc.Name, svcPort.Port, err)
+		}
+	}
 	poolName := formatPoolName(
 		svc.Namespace,
 		svc.Name,
-		svcPort.TargetPort,
+		targetPort,
 		"", "")
 	pool := Pool{
 		Name:             poolName,
 		Partition:        rsCfg.Virtual.Partition,
 		ServiceName:      svc.Name,
 		ServiceNamespace: svc.Namespace,
-		ServicePort:      svcPort.TargetPort,
+		ServicePort:      targetPort,
 		NodeMemberLabel:  "",
+		Metadata:         ctlr.getPoolMetadata(svc.Namespace, svc.Name),
 	}
 
 	// Health Monitor Annotation
 	hmStr, found := svc.Annotations[HealthMonitorAnnotation]
 	var monitor Monitor
-	if found {
-		monitorType := strings.ToLower(string(svcPort.Protocol))
-		var mon ServiceTypeLBHealthMonitor
-		err := json.Unmarshal([]byte(hmStr), &mon)
+	if found && protocol == "sctp" {
+		// AS3 does not provide a sctp monitor
+		log.Errorf("[CORE] Health monitor is not supported for sctp port %v of service %s/%s",
+			svcPort.Port, svc.Namespace, svc.Name)
+	} else if found {
+		monitorType := protocol
+		mon, err := getLBServiceHealthMonitor(hmStr, svcPort.Port)
 		if err != nil {
 			msg := fmt.Sprintf(
-				"Unable to parse health monitor JSON array '%v': %v", hmStr, err)
+				"Unable to parse health monitor JSON '%v': %v", hmStr, err)
 			log.Errorf("[CORE] %s", msg)
+		} else if mon != nil {
+			pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: JoinBigipPath(rsCfg.Virtual.Partition,
+				formatMonitorName(svc.Namespace, svc.Name, monitorType, targetPort.IntVal, "", ""))})
+			monitor = Monitor{
+				Name:      formatMonitorName(svc.Namespace, svc.Name, monitorType, targetPort.IntVal, "", ""),
+				Partition: rsCfg.Virtual.Partition,
+				Type:      monitorType,
+				Interval:  mon.Interval,
+				Send:      "",
+				Recv:      "",
+				Timeout:   mon.Timeout,
+			}
+			rsCfg.Monitors = append(rsCfg.Monitors, monitor)
 		}
-		pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: JoinBigipPath(rsCfg.Virtual.Partition,
-			formatMonitorName(svc.Namespace, svc.Name, monitorType, svcPort.TargetPort.IntVal, "", ""))})
-		monitor = Monitor{
-			Name:      formatMonitorName(svc.Namespace, svc.Name, monitorType, svcPort.TargetPort.IntVal, "", ""),
-			Partition: rsCfg.Virtual.Partition,
-			Type:      monitorType,
-			Interval:  mon.Interval,
-			Send:      "",
-			Recv:      "",
-			Timeout:   mon.Timeout,
-		}
-		rsCfg.Monitors = append(rsCfg.Monitors, monitor)
 	}
 	rsCfg.Pools = Pools{pool}
 	rsCfg.Virtual.PoolName = poolName
+	rsCfg.Virtual.IpProtocol = protocol
 	rsCfg.Virtual.Mode = "standard"
+	// udp virtual uses the default udp profile unless one is provided through the policy
+	if protocol == "udp" {
+		udpProfile := false
+		for _, prof := range rsCfg.Virtual.Profiles {
+			if prof.Context == "udp" {
+				udpProfile = true
+				break
+			}
+		}
+		if !udpProfile {
+			rsCfg.Virtual.Profiles = append(rsCfg.Virtual.Profiles, ProfileRef{
+				Name:         DEFAULT_UDP_PROFILE,
+				Context:      "udp",
+				BigIPProfile: true,
+			})
+		}
+	}
 	// Use default SNAT if not provided by user
 	if rsCfg.Virtual.SNAT == "" {
 		rsCfg.Virtual.SNAT = DEFAULT_SNAT
 	}
+	if err := validateAddressTranslation(rsCfg); err != nil {
+		return fmt.Errorf("service %s/%s: %v", svc.Namespace, svc.Name, err)
+	}
 
 	return nil
 }
 
+// getLBServiceHealthMonitor returns the health monitor of the LoadBalancer service port from the
+// annotation, which is either a single monitor for all the ports or a list of monitors keyed by port
+func getLBServiceHealthMonitor(hmStr string, port int32) (*ServiceTypeLBHealthMonitor, error) {
+	if !strings.HasPrefix(strings.TrimSpace(hmStr), "[") {
+		var mon ServiceTypeLBHealthMonitor
+		if err := json.Unmarshal([]byte(hmStr), &mon); err != nil {
+			return nil, err
+		}
+		return &mon, nil
+	}
+	var monitors []ServiceTypeLBHealthMonitor
+	if err := json.Unmarshal([]byte(hmStr), &monitors); err != nil {
+		return nil, err
+	}
+	for i := range monitors {
+		if monitors[i].Port == 0 {
+			return nil, fmt.Errorf("port is required for each health monitor in the list")
+		}
+	}
+	for i := range monitors {
+		if monitors[i].Port == port {
+			return &monitors[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// getLBServiceProtocol returns the L4 protocol of the LoadBalancer service port
+func getLBServiceProtocol(svcPort v1.ServicePort) (string, error) {
+	switch svcPort.Protocol {
+	case "", v1.ProtocolTCP:
+		return "tcp", nil
+	case v1.ProtocolUDP:
+		return "udp", nil
+	case v1.ProtocolSCTP:
+		return "sctp", nil
+	}
+	return "", fmt.Errorf("unsupported protocol %q, supported protocols are TCP, UDP and SCTP", svcPort.Protocol)
+}
+
 // Returns Partition and resourceName
 func getPartitionAndName(objectName string) (string, string) {
 	allParts := strings.Split(objectName, "/")
@@ -1693,6 +3577,9 @@ func (ctlr *Controller) handleVSResourceConfigForPolicy(
 	plc *cisapiv1.Policy,
 ) error {
 	rsCfg.Virtual.WAF = plc.Spec.L7Policies.WAF
+	if err := rsCfg.Virtual.setForwardingPolicyOrder(plc.Spec.L7Policies.ForwardingPolicyOrder); err != nil {
+		return fmt.Errorf("Policy %v/%v: %v", plc.Namespace, plc.Name, err)
+	}
 	rsCfg.Virtual.Firewall = plc.Spec.L3Policies.FirewallPolicy
 	rsCfg.Virtual.PersistenceProfile = plc.Spec.Profiles.PersistenceProfile
 	rsCfg.Virtual.ProfileMultiplex = plc.Spec.Profiles.ProfileMultiplex
@@ -1701,10 +3588,19 @@ func (ctlr *Controller) handleVSResourceConfigForPolicy(
 	rsCfg.Virtual.TCP.Client = plc.Spec.Profiles.TCP.Client
 	rsCfg.Virtual.TCP.Server = plc.Spec.Profiles.TCP.Server
 	rsCfg.Virtual.AllowSourceRange = plc.Spec.L3Policies.AllowSourceRange
-
-	if len(plc.Spec.Profiles.LogProfiles) > 0 {
-		rsCfg.Virtual.LogProfiles = append(rsCfg.Virtual.LogProfiles, plc.Spec.Profiles.LogProfiles...)
+	rsCfg.Virtual.DenySourceRange = plc.Spec.L3Policies.DenySourceRange
+	rsCfg.MetaData.policyName = plc.Name
+	rsCfg.MetaData.policyMonitors = plc.Spec.Monitors
+	if plc.Spec.L3Policies.ConnectionRateLimit < 0 {
+		return fmt.Errorf("invalid connectionRateLimit %v in Policy %v/%v, the limit can't be negative",
+			plc.Spec.L3Policies.ConnectionRateLimit, plc.Namespace, plc.Name)
 	}
+	rsCfg.Virtual.ConnectionRateLimit = plc.Spec.L3Policies.ConnectionRateLimit
+
+	rsCfg.Virtual.LogProfiles = append(rsCfg.Virtual.LogProfiles, getSecurityLogProfiles(plc)...)
+	rsCfg.Virtual.RequestLogProfile = plc.Spec.Profiles.RequestLogProfile
+	rsCfg.Virtual.AnalyticsProfile = plc.Spec.Profiles.AnalyticsProfile
+	rsCfg.Virtual.TCPAnalyticsProfile = plc.Spec.Profiles.TCPAnalyticsProfile
 	var iRule string
 	// Profiles common for both HTTP and HTTPS
 	// service_HTTP supports profileTCP and profileHTTP
@@ -1716,6 +3612,21 @@ func (ctlr *Controller) handleVSResourceConfigForPolicy(
 			BigIPProfile: true,
 		})
 	}
+	if plc.Spec.Profiles.InsertXForwardedFor && isHTTPProtocol(rsCfg.MetaData.Protocol) {
+		rsCfg.Virtual.InsertXForwardedFor = true
+	}
+	if plc.Spec.Profiles.HTTPOptions != nil {
+		if len(plc.Spec.Profiles.HTTP) > 0 {
+			return fmt.Errorf("http and httpOptions are mutually exclusive in Policy %v/%v", plc.Namespace, plc.Name)
+		}
+		opts, err := newHTTPProfileOptions(plc.Spec.Profiles.HTTPOptions)
+		if err != nil {
+			return fmt.Errorf("%v in Policy %v/%v", err, plc.Namespace, plc.Name)
+		}
+		if isHTTPProtocol(rsCfg.MetaData.Protocol) {
+			rsCfg.Virtual.HTTPProfileOptions = opts
+		}
+	}
 
 	switch rsCfg.MetaData.Protocol {
 	case "https":
@@ -1729,6 +3640,18 @@ func (ctlr *Controller) handleVSResourceConfigForPolicy(
 		}
 	case "http":
 		iRule = plc.Spec.IRules.InSecure
+		// HTTP/2 over cleartext is only attached when allowed, so that the http2 profile
+		// of the Policy is otherwise applied to the https virtual alone
+		if plc.Spec.Profiles.AllowH2C {
+			rsCfg.Virtual.AllowH2C = true
+			if len(plc.Spec.Profiles.HTTP2) > 0 {
+				rsCfg.Virtual.Profiles = append(rsCfg.Virtual.Profiles, ProfileRef{
+					Name:         plc.Spec.Profiles.HTTP2,
+					Context:      "http2",
+					BigIPProfile: true,
+				})
+			}
+		}
 	}
 	if len(iRule) > 0 {
 		switch plc.Spec.IRules.Priority {
@@ -1740,6 +3663,12 @@ func (ctlr *Controller) handleVSResourceConfigForPolicy(
 			rsCfg.Virtual.IRules = append(rsCfg.Virtual.IRules, iRule)
 		}
 	}
+	switch rsCfg.MetaData.Protocol {
+	case "https":
+		rsCfg.addPriorityIRules(plc.Spec.IRules.SecureList)
+	case "http":
+		rsCfg.addPriorityIRules(plc.Spec.IRules.InSecureList)
+	}
 	// set snat as specified by user in the policy
 	snat := plc.Spec.SNAT
 	if snat != "" {
@@ -1749,6 +3678,180 @@ func (ctlr *Controller) handleVSResourceConfigForPolicy(
 	return nil
 }
 
+// validateServiceDownAction verifies the action on service down and the reselect tries of the pool
+func validateServiceDownAction(pool Pool) error {
+	switch pool.ServiceDownAction {
+	case "", ServiceDownActionNone, ServiceDownActionReset, ServiceDownActionDrop, ServiceDownActionReselect:
+	default:
+		return fmt.Errorf("invalid serviceDownAction %v for pool %v, allowed actions are %v, %v, %v and %v",
+			pool.ServiceDownAction, pool.Name, ServiceDownActionNone, ServiceDownActionReset, ServiceDownActionDrop,
+			ServiceDownActionReselect)
+	}
+	if pool.ReselectTries < 0 || pool.ReselectTries > 65535 {
+		return fmt.Errorf("invalid reselectTries %v for pool %v, allowed range is 0-65535", pool.ReselectTries, pool.Name)
+	}
+	return nil
+}
+
+// isHashBalance returns whether the balance is a hashing algorithm
+func isHashBalance(balance string) bool {
+	return balance == BalanceCARP || balance == BalanceHash
+}
+
+// token grammar of the header and cookie names of a hashKey
+var hashKeyNameRegex = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// validateHashKey validates the hash key of the pool, it is valid only with a hashing algorithm
+func validateHashKey(pl cisapiv1.Pool, poolName string) error {
+	if pl.HashKey == nil {
+		return nil
+	}
+	if !isHashBalance(pl.Balance) {
+		return fmt.Errorf("hashKey of pool %v requires loadBalancingMethod %v or %v", poolName, BalanceCARP, BalanceHash)
+	}
+	switch pl.HashKey.Source {
+	case HashKeySourceIP:
+	case HashKeyHeader, HashKeyCookie:
+		if pl.HashKey.Name == "" {
+			return fmt.Errorf("hashKey %v of pool %v requires a name", pl.HashKey.Source, poolName)
+		}
+		if !hashKeyNameRegex.MatchString(pl.HashKey.Name) {
+			return fmt.Errorf("invalid hashKey %v name %q for pool %v", pl.HashKey.Source, pl.HashKey.Name, poolName)
+		}
+	default:
+		return fmt.Errorf("invalid hashKey source %q for pool %v, allowed sources are %v, %v and %v",
+			pl.HashKey.Source, poolName, HashKeySourceIP, HashKeyHeader, HashKeyCookie)
+	}
+	if pl.HashKey.Timeout < 0 || pl.HashKey.Timeout > 65535 {
+		return fmt.Errorf("invalid hashKey timeout %v for pool %v, allowed range is 0-65535", pl.HashKey.Timeout, poolName)
+	}
+	return nil
+}
+
+// getHashPersistAction returns the action persisting the connections to the pool by the hash of its key
+func getHashPersistAction(pl cisapiv1.Pool, actionNameIndex int) *action {
+	hashKey := cisapiv1.HashKey{Source: HashKeySourceIP}
+	if pl.HashKey != nil {
+		hashKey = *pl.HashKey
+	}
+	var key string
+	switch hashKey.Source {
+	case HashKeyHeader:
+		key = fmt.Sprintf("[HTTP::header value \"%s\"]", escapeTclString(hashKey.Name))
+	case HashKeyCookie:
+		key = fmt.Sprintf("[HTTP::cookie value \"%s\"]", escapeTclString(hashKey.Name))
+	default:
+		key = "[IP::client_addr]"
+	}
+	timeout := hashKey.Timeout
+	if timeout == 0 {
+		timeout = DefaultHashKeyTimeout
+	}
+	return &action{
+		Name:       strconv.Itoa(actionNameIndex),
+		Persist:    pl.Balance,
+		PersistKey: key,
+		Timeout:    timeout,
+		Request:    true,
+	}
+}
+
+// copyBool returns a copy of the optional bool
+func copyBool(b *bool) *bool {
+	if b == nil {
+		return nil
+	}
+	val := *b
+	return &val
+}
+
+// copyInt32 returns a copy of the optional int32
+func copyInt32(i *int32) *int32 {
+	if i == nil {
+		return nil
+	}
+	val := *i
+	return &val
+}
+
+// validateProfileL4Timeouts validates the timeouts of the fastL4 options, which range from 1 to
+// 86400 seconds for the idle timeout and 0 to 86400 for the handshake timeout or -1 for no timeout
+func validateProfileL4Timeouts(profileL4 *cisapiv1.ProfileL4, protocol string) error {
+	if timeout := profileL4.IdleTimeout; timeout != nil && *timeout != -1 && (*timeout < 1 || *timeout > 86400) {
+		return fmt.Errorf("invalid idleTimeout %v, allowed range is 1-86400 or -1", *timeout)
+	}
+	if timeout := profileL4.TCPHandshakeTimeout; timeout != nil {
+		if protocol == "udp" || protocol == "sctp" {
+			return fmt.Errorf("tcpHandshakeTimeout is not applicable to %v", protocol)
+		}
+		if *timeout < -1 || *timeout > 86400 {
+			return fmt.Errorf("invalid tcpHandshakeTimeout %v, allowed range is 0-86400 or -1", *timeout)
+		}
+	}
+	return nil
+}
+
+// validateAddressTranslation rejects the virtual without address translation using the auto map SNAT,
+// as the server side connection has to be sourced from the client address in such deployments
+func validateAddressTranslation(rsCfg *ResourceConfig) error {
+	if rsCfg.Virtual.TranslateServerAddress != nil && !*rsCfg.Virtual.TranslateServerAddress &&
+		rsCfg.Virtual.SNAT == DEFAULT_SNAT {
+		return fmt.Errorf("address translation can not be disabled with snat %v", DEFAULT_SNAT)
+	}
+	return nil
+}
+
+// isHTTPProtocol checks whether the virtual serves HTTP or HTTPS traffic
+func isHTTPProtocol(protocol string) bool {
+	return protocol == "http" || protocol == "https"
+}
+
+// getSecurityLogProfiles returns the security log profiles of the Policy,
+// including the ones given with the combined logProfiles
+func getSecurityLogProfiles(plc *cisapiv1.Policy) []string {
+	var logProfiles []string
+	for _, profiles := range [][]string{plc.Spec.Profiles.LogProfiles, plc.Spec.Profiles.SecurityLogProfiles} {
+		for _, lp := range profiles {
+			if !containsString(logProfiles, lp) {
+				logProfiles = append(logProfiles, lp)
+			}
+		}
+	}
+	return logProfiles
+}
+
+// setForwardingPolicyOrder sets the evaluation order of the forwarding policy relative to
+// the WAF policy of the virtual
+func (v *Virtual) setForwardingPolicyOrder(order string) error {
+	switch order {
+	case "", ForwardingPolicyAfterWAF, ForwardingPolicyBeforeWAF:
+		v.ForwardingPolicyOrder = order
+		return nil
+	}
+	return fmt.Errorf("invalid forwardingPolicyOrder %q, supported values are %v and %v",
+		order, ForwardingPolicyAfterWAF, ForwardingPolicyBeforeWAF)
+}
+
+// newHTTPProfileOptions validates the options of the HTTP profile created for the virtual
+func newHTTPProfileOptions(opts *cisapiv1.HTTPProfileOptions) (*HTTPProfileOptions, error) {
+	switch opts.ProxyType {
+	case "", HTTPProxyReverse, HTTPProxyTransparent, HTTPProxyExplicit:
+	default:
+		return nil, fmt.Errorf("invalid httpOptions proxyType %q, supported values are %v, %v and %v",
+			opts.ProxyType, HTTPProxyReverse, HTTPProxyTransparent, HTTPProxyExplicit)
+	}
+	return &HTTPProfileOptions{
+		ServerAgentName:     opts.ServerAgentName,
+		ProxyType:           opts.ProxyType,
+		InsertXForwardedFor: opts.InsertXForwardedFor,
+	}, nil
+}
+
+// isVirtualEnabled returns the enabled state of a virtual, virtuals are enabled by default
+func isVirtualEnabled(enabled *bool) bool {
+	return enabled == nil || *enabled
+}
+
 func (ctlr *Controller) handleTSResourceConfigForPolicy(
 	rsCfg *ResourceConfig,
 	plc *cisapiv1.Policy,
@@ -1758,13 +3861,22 @@ func (ctlr *Controller) handleTSResourceConfigForPolicy(
 	rsCfg.Virtual.PersistenceProfile = plc.Spec.Profiles.PersistenceProfile
 	rsCfg.Virtual.ProfileL4 = plc.Spec.Profiles.ProfileL4
 	rsCfg.Virtual.ProfileDOS = plc.Spec.L3Policies.DOS
-	rsCfg.Virtual.ProfileBotDefense = plc.Spec.L3Policies.BotDefense
+	// Bot Defense profile is supported only on the HTTP and HTTPS virtuals
+	if plc.Spec.L3Policies.BotDefense != "" {
+		log.Warningf("Ignoring botDefense profile %v of Policy %v/%v on the L4 virtual %v",
+			plc.Spec.L3Policies.BotDefense, plc.Namespace, plc.Name, rsCfg.Virtual.Name)
+	}
 	rsCfg.Virtual.TCP.Client = plc.Spec.Profiles.TCP.Client
 	rsCfg.Virtual.TCP.Server = plc.Spec.Profiles.TCP.Server
-
-	if len(plc.Spec.Profiles.LogProfiles) > 0 {
-		rsCfg.Virtual.LogProfiles = append(rsCfg.Virtual.LogProfiles, plc.Spec.Profiles.LogProfiles...)
+	if plc.Spec.L3Policies.ConnectionRateLimit < 0 {
+		return fmt.Errorf("invalid connectionRateLimit %v in Policy %v/%v, the limit can't be negative",
+			plc.Spec.L3Policies.ConnectionRateLimit, plc.Namespace, plc.Name)
 	}
+	rsCfg.Virtual.ConnectionRateLimit = plc.Spec.L3Policies.ConnectionRateLimit
+
+	rsCfg.Virtual.LogProfiles = append(rsCfg.Virtual.LogProfiles, getSecurityLogProfiles(plc)...)
+	// HTTP analytics profile is not applicable to the L4 virtuals
+	rsCfg.Virtual.TCPAnalyticsProfile = plc.Spec.Profiles.TCPAnalyticsProfile
 	if len(plc.Spec.Profiles.UDP) > 0 {
 		rsCfg.Virtual.Profiles = append(rsCfg.Virtual.Profiles, ProfileRef{
 			Name:         plc.Spec.Profiles.UDP,
@@ -1772,6 +3884,13 @@ func (ctlr *Controller) handleTSResourceConfigForPolicy(
 			BigIPProfile: true,
 		})
 	}
+	if len(plc.Spec.Profiles.SCTP) > 0 {
+		rsCfg.Virtual.Profiles = append(rsCfg.Virtual.Profiles, ProfileRef{
+			Name:         plc.Spec.Profiles.SCTP,
+			Context:      "sctp",
+			BigIPProfile: true,
+		})
+	}
 
 	var iRule string
 	iRule = plc.Spec.IRules.InSecure
@@ -1785,6 +3904,7 @@ func (ctlr *Controller) handleTSResourceConfigForPolicy(
 			rsCfg.Virtual.IRules = append(rsCfg.Virtual.IRules, iRule)
 		}
 	}
+	rsCfg.addPriorityIRules(plc.Spec.IRules.InSecureList)
 	// set snat as specified by user or else use auto as default
 	snat := plc.Spec.SNAT
 	if snat != "" {
@@ -1792,6 +3912,8 @@ func (ctlr *Controller) handleTSResourceConfigForPolicy(
 	} else {
 		rsCfg.Virtual.SNAT = DEFAULT_SNAT
 	}
+	rsCfg.Virtual.TranslateServerAddress = copyBool(plc.Spec.TranslateAddress)
+	rsCfg.Virtual.TranslateServerPort = copyBool(plc.Spec.TranslatePort)
 	return nil
 }
 
@@ -1799,6 +3921,36 @@ func getRSCfgResName(rsVSName, resName string) string {
 	return fmt.Sprintf("%s_%s", rsVSName, resName)
 }
 
+// getNamespacePartition returns the partition of the route group virtuals serving the namespace
+func (rs *ResourceStore) getNamespacePartition(routeGroup, namespace string) string {
+	extdSpec, ok := rs.extdSpecMap[routeGroup]
+	if !ok {
+		return ""
+	}
+	if partition, ok := extdSpec.namespacePartitions[namespace]; ok {
+		return partition
+	}
+	return extdSpec.partition
+}
+
+// getRouteGroupPartitions returns every partition holding virtuals of the route group
+func (rs *ResourceStore) getRouteGroupPartitions(routeGroup string) []string {
+	extdSpec, ok := rs.extdSpecMap[routeGroup]
+	if !ok {
+		return nil
+	}
+	var partitions []string
+	seen := map[string]bool{extdSpec.partition: true}
+	for _, partition := range extdSpec.namespacePartitions {
+		if !seen[partition] {
+			seen[partition] = true
+			partitions = append(partitions, partition)
+		}
+	}
+	sort.Strings(partitions)
+	return append([]string{extdSpec.partition}, partitions...)
+}
+
 func (rs *ResourceStore) getExtendedRouteSpec(routeGroup string) (*ExtendedRouteGroupSpec, string) {
 	extdSpec, ok := rs.extdSpecMap[routeGroup]
 
@@ -1814,6 +3966,7 @@ func (rs *ResourceStore) getExtendedRouteSpec(routeGroup string) (*ExtendedRoute
 			SNAT:          extdSpec.global.SNAT,
 			WAF:           extdSpec.global.WAF,
 			TLS:           extdSpec.global.TLS,
+			Enabled:       extdSpec.global.Enabled,
 		}
 
 		if extdSpec.local.VServerName != "" {
@@ -1831,6 +3984,14 @@ func (rs *ResourceStore) getExtendedRouteSpec(routeGroup string) (*ExtendedRoute
 		if extdSpec.local.TLS != (TLS{}) {
 			ergc.TLS = extdSpec.local.TLS
 		}
+		if extdSpec.local.Enabled != nil {
+			ergc.Enabled = extdSpec.local.Enabled
+		}
+		if extdSpec.local.DefaultPassthroughPool != nil {
+			ergc.DefaultPassthroughPool = extdSpec.local.DefaultPassthroughPool
+		} else {
+			ergc.DefaultPassthroughPool = extdSpec.global.DefaultPassthroughPool
+		}
 
 		if extdSpec.local.AllowSourceRange != nil {
 			ergc.AllowSourceRange = make([]string, len(extdSpec.local.AllowSourceRange))
@@ -1846,6 +4007,13 @@ func (rs *ResourceStore) getExtendedRouteSpec(routeGroup string) (*ExtendedRoute
 			ergc.IRules = make([]string, len(extdSpec.global.IRules))
 			copy(ergc.IRules, extdSpec.global.IRules)
 		}
+		// mandatory iRules are not overridable by the local configMap
+		if extdSpec.global.MandatoryIRules != nil {
+			ergc.MandatoryIRules = make([]string, len(extdSpec.global.MandatoryIRules))
+			copy(ergc.MandatoryIRules, extdSpec.global.MandatoryIRules)
+		}
+		// neither is HTTPS only
+		ergc.HTTPSOnly = extdSpec.global.HTTPSOnly
 
 		if extdSpec.local.HealthMonitors != nil {
 			ergc.HealthMonitors = make(Monitors, len(extdSpec.local.HealthMonitors))
@@ -1933,6 +4101,7 @@ func (ctlr *Controller) handleRouteTLS(
 						pl.ServicePort,
 						"",
 						""),
+					"",
 				})
 		}
 	}
@@ -1958,5 +4127,8 @@ func (ctlr *Controller) handleRouteTLS(
 		strings.ToLower(string(route.Spec.TLS.InsecureEdgeTerminationPolicy)),
 		poolPathRefs,
 		bigIPSSLProfiles,
+		extdSpec.HTTPRedirectCode,
+		nil,
+		getRouteRedirectTarget(route),
 	})
 }
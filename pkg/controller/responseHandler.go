@@ -65,7 +65,16 @@ func (ctlr *Controller) responseHandler(respChan chan resourceStatusMeta) {
 				}
 				virtual := obj.(*cisapiv1.VirtualServer)
 				if virtual.Namespace+"/"+virtual.Name == rscKey {
-					ctlr.updateVirtualServerStatus(virtual, virtual.Status.VSAddress, "Ok")
+					ip := ctlr.getResourceVirtualAddress(partition, rscKey, kind)
+					if ip == "" {
+						ip = virtual.Spec.VirtualServerAddress
+					}
+					if _, found := rscUpdateMeta.failedTenants[partition]; found {
+						ctlr.updateVirtualServerStatus(virtual, ip, "Error",
+							"Failure while updating config", "Please check logs for more information")
+					} else {
+						ctlr.updateVirtualServerStatus(virtual, ip, "Ok", "", "")
+					}
 				}
 			case TransportServer:
 				// update status
@@ -85,7 +94,16 @@ func (ctlr *Controller) responseHandler(respChan chan resourceStatusMeta) {
 				}
 				virtual := obj.(*cisapiv1.TransportServer)
 				if virtual.Namespace+"/"+virtual.Name == rscKey {
-					ctlr.updateTransportServerStatus(virtual, virtual.Status.VSAddress, "Ok")
+					ip := ctlr.getResourceVirtualAddress(partition, rscKey, kind)
+					if ip == "" {
+						ip = virtual.Spec.VirtualServerAddress
+					}
+					if _, found := rscUpdateMeta.failedTenants[partition]; found {
+						ctlr.updateTransportServerStatus(virtual, ip, "Error",
+							"Failure while updating config", "Please check logs for more information")
+					} else {
+						ctlr.updateTransportServerStatus(virtual, ip, "Ok", "", "")
+					}
 				}
 			case Route:
 				if _, found := rscUpdateMeta.failedTenants[partition]; found {
@@ -93,7 +111,9 @@ func (ctlr *Controller) responseHandler(respChan chan resourceStatusMeta) {
 					go ctlr.updateRouteAdmitStatus(rscKey, "Failure while updating config", "Please check logs for more information", v1.ConditionFalse)
 				} else {
 					// updating the tenant priority back to zero if it's not in failed tenants
+					ctlr.resources.cacheMutex.Lock()
 					ctlr.resources.updatePartitionPriority(partition, 0)
+					ctlr.resources.cacheMutex.Unlock()
 					go ctlr.updateRouteAdmitStatus(rscKey, "", "", v1.ConditionTrue)
 				}
 			}
@@ -101,6 +121,23 @@ func (ctlr *Controller) responseHandler(respChan chan resourceStatusMeta) {
 	}
 }
 
+// getResourceVirtualAddress returns the address of the virtual the resource is posted with, which
+// is also allocated by IPAM or shared by the host group when it is not given in the resource
+func (ctlr *Controller) getResourceVirtualAddress(partition, rscKey, kind string) string {
+	ctlr.resources.cacheMutex.RLock()
+	defer ctlr.resources.cacheMutex.RUnlock()
+	partitionConfig, ok := ctlr.resources.ltmConfig[partition]
+	if !ok {
+		return ""
+	}
+	for _, rsCfg := range partitionConfig.ResourceMap {
+		if rsCfg.MetaData.baseResources[rscKey] == kind && rsCfg.Virtual.VirtualAddress != nil {
+			return rsCfg.Virtual.VirtualAddress.BindAddr
+		}
+	}
+	return ""
+}
+
 func (ctlr *Controller) dequeueReq(id int, failedTenantsLen int) requestMeta {
 	var rm requestMeta
 	if id == 0 {
@@ -17,14 +17,15 @@
 package controller
 
 import (
+	"encoding/json"
 	"fmt"
 	routeapi "github.com/openshift/api/route/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"net/url"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/resource"
 
@@ -64,7 +65,7 @@ func (ctlr *Controller) prepareVirtualServerRules(
 
 		path := pl.Path
 		var tls *cisapiv1.TLSProfile
-		if vs.Spec.TLSProfileName != "" {
+		if isTLSVirtualServer(vs) {
 			tls = ctlr.getTLSProfileForVirtualServer(vs, vs.Namespace)
 
 			if tls != nil && tls.Spec.TLS.Termination == TLSPassthrough {
@@ -77,7 +78,9 @@ func (ctlr *Controller) prepareVirtualServerRules(
 			path = vs.Spec.RewriteAppRoot
 		}
 
-		poolName := framePoolName(
+		poolPartition := ctlr.getPoolPartition(rsCfg, pl)
+		poolName := ctlr.framePoolName(
+			poolPartition,
 			vs.ObjectMeta.Namespace,
 			pl,
 			intstr.IntOrString{IntVal: pl.ServicePort},
@@ -85,7 +88,8 @@ func (ctlr *Controller) prepareVirtualServerRules(
 		)
 		ruleName := formatVirtualServerRuleName(vs.Spec.Host, vs.Spec.HostGroup, path, poolName)
 		var err error
-		rl, err := createRule(uri, poolName, ruleName, rsCfg.Virtual.AllowSourceRange)
+		rl, err := createRule(uri, getPoolReference(rsCfg.Virtual.Partition, poolPartition, poolName), ruleName,
+			rsCfg.Virtual.AllowSourceRange)
 		if nil != err {
 			log.Errorf("Error configuring rule: %v", err)
 			return nil
@@ -102,13 +106,43 @@ func (ctlr *Controller) prepareVirtualServerRules(
 			}
 			rl.Actions = append(rl.Actions, rewriteActions...)
 		}
+		if pl.StripPathPrefix != "" {
+			if pl.Rewrite != "" {
+				log.Errorf("Error configuring rule: rewrite and stripPathPrefix are mutually exclusive for path %v", pl.Path)
+				return nil
+			}
+			stripActions, err := getStripPathPrefixActions(
+				path,
+				pl.StripPathPrefix,
+				len(rl.Actions),
+			)
+			if nil != err {
+				log.Errorf("Error configuring rule: %v", err)
+				return nil
+			}
+			rl.Actions = append(rl.Actions, stripActions...)
+		}
+		if isHashBalance(pl.Balance) {
+			rl.Actions = append(rl.Actions, getHashPersistAction(pl, len(rl.Actions)))
+		}
+		ruleKey := uri
+		if len(pl.Headers) > 0 {
+			headerConditions, err := createHeaderConditions(pl.Headers)
+			if nil != err {
+				log.Errorf("Error configuring rule: %v", err)
+				return nil
+			}
+			rl.Conditions = append(rl.Conditions, headerConditions...)
+			// rules with same uri and different headers should not overwrite each other
+			ruleKey = uri + "_" + poolName
+		}
 
-		if pl.Path == "/" {
+		if pl.Path == "/" && len(pl.Headers) == 0 {
 			redirects = append(redirects, rl)
 		} else if true == strings.HasPrefix(uri, "*.") {
-			wildcards[uri] = rl
+			wildcards[ruleKey] = rl
 		} else {
-			rlMap[uri] = rl
+			rlMap[ruleKey] = rl
 		}
 	}
 
@@ -129,33 +163,24 @@ func (ctlr *Controller) prepareVirtualServerRules(
 		redirects = append(redirects, rl)
 	}
 
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	sortrules := func(r ruleMap, rls *Rules, ordinal int) {
-		for _, v := range r {
-			*rls = append(*rls, v)
-		}
-		//sort.Sort(sort.Reverse(*rls))
-		for _, v := range *rls {
-			v.Ordinal = ordinal
-			ordinal++
-		}
-		wg.Done()
-	}
-
-	rls := Rules{}
-	go sortrules(rlMap, &rls, 0)
-
-	w := Rules{}
-	go sortrules(wildcards, &w, len(rlMap))
-
-	wg.Wait()
-
+	rls := assignRuleOrdinals(rlMap, 0)
+	w := assignRuleOrdinals(wildcards, len(rlMap))
 	rls = append(rls, w...)
 
 	sort.Sort(rls)
 	rls = append(redirects, rls...)
+
+	// Connections from the denied sources are rejected ahead of the redirect and forward rules,
+	// so that the deny list wins over the allow list
+	if len(rsCfg.Virtual.DenySourceRange) > 0 {
+		ruleName := formatVirtualServerRuleName(vs.Spec.Host, vs.Spec.HostGroup, "", "deny_source_range")
+		rl, err := createDenySourceRangeRule(vs.Spec.Host, ruleName, rsCfg.Virtual.DenySourceRange)
+		if nil != err {
+			log.Errorf("Error configuring deny source range rule: %v", err)
+			return nil
+		}
+		rls = append(Rules{rl}, rls...)
+	}
 	return &rls
 }
 
@@ -247,6 +272,63 @@ func createRule(uri, poolName, ruleName string, allowSourceRange []string) (*Rul
 	return &rl, nil
 }
 
+// createDenySourceRangeRule creates the LTM policy rule resetting the connections from the denied source addresses
+func createDenySourceRangeRule(host, ruleName string, denySourceRange []string) (*Rule, error) {
+	// the host conditions are same as the forward rules of the host
+	rl, err := createRule(host, "", ruleName, nil)
+	if nil != err {
+		return nil, err
+	}
+	rl.Conditions = append(rl.Conditions, &condition{
+		Tcp:     true,
+		Address: true,
+		Values:  denySourceRange,
+	})
+	rl.Actions = []*action{{
+		Name:    "0",
+		Reset:   true,
+		Request: true,
+	}}
+	return rl, nil
+}
+
+// isDenySourceRangeRule checks whether the rule resets the connections of denied sources
+func isDenySourceRangeRule(rule *Rule) bool {
+	for _, a := range rule.Actions {
+		if a.Reset {
+			return true
+		}
+	}
+	return false
+}
+
+// createHeaderConditions creates the request header match conditions
+func createHeaderConditions(headers []cisapiv1.HeaderMatch) ([]*condition, error) {
+	var c []*condition
+	for _, hdr := range headers {
+		if hdr.Name == "" || len(hdr.Values) == 0 {
+			return nil, fmt.Errorf("header match should contain both name and values")
+		}
+		cond := &condition{
+			HTTPHeader: true,
+			Name:       hdr.Name,
+			Request:    true,
+			Values:     hdr.Values,
+		}
+		switch hdr.Operator {
+		case "", HeaderMatchEquals:
+			cond.Equals = true
+		case HeaderMatchContains:
+			cond.Contains = true
+		default:
+			return nil, fmt.Errorf("invalid operator %v for header %v, supported operators are %v and %v",
+				hdr.Operator, hdr.Name, HeaderMatchEquals, HeaderMatchContains)
+		}
+		c = append(c, cond)
+	}
+	return c, nil
+}
+
 func createPathSegmentConditions(u *url.URL) []*condition {
 
 	var c []*condition
@@ -322,6 +404,71 @@ func getRewriteActions(path, rwPath string, actionNameIndex int) ([]*action, err
 	return actions, nil
 }
 
+// getStripPathPrefixActions returns the action which removes the prefix from the request path before
+// forwarding, the path is replaced with "/" when nothing remains after the prefix
+func getStripPathPrefixActions(path, prefix string, actionNameIndex int) ([]*action, error) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if !strings.HasPrefix(prefix, "/") {
+		return nil, fmt.Errorf("Invalid path prefix %v", prefix)
+	}
+	path = strings.TrimSuffix(path, "/")
+	if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+		return nil, fmt.Errorf("Path prefix %v is not a prefix of path %v", prefix, path)
+	}
+	return []*action{{
+		Name:     fmt.Sprintf("%d", actionNameIndex),
+		HTTPURI:  true,
+		HTTPPath: true,
+		Replace:  true,
+		Request:  true,
+		Value: fmt.Sprintf("tcl:[expr {[string length [HTTP::path]] > %d ? [string range [HTTP::path] %d end] : \"/\"}]",
+			len(prefix), len(prefix)),
+	}}, nil
+}
+
+// getRequestHeaderActions creates the actions removing and inserting the request headers,
+// headers are removed ahead of the insertion so that an inserted header replaces the client one
+func getRequestHeaderActions(insertHeaders, removeHeaders string) ([]*action, error) {
+	var actions []*action
+	if removeHeaders != "" {
+		var names []string
+		if err := json.Unmarshal([]byte(removeHeaders), &names); err != nil {
+			return nil, fmt.Errorf("Invalid request headers to remove %v: %v", removeHeaders, err)
+		}
+		for _, name := range names {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				return nil, fmt.Errorf("Invalid request headers to remove %v", removeHeaders)
+			}
+			actions = append(actions, &action{
+				HTTPHeader: true,
+				Header:     &actionHeader{Name: name},
+				Remove:     true,
+				Request:    true,
+			})
+		}
+	}
+	if insertHeaders != "" {
+		var headers []RequestHeader
+		if err := json.Unmarshal([]byte(insertHeaders), &headers); err != nil {
+			return nil, fmt.Errorf("Invalid request headers to insert %v: %v", insertHeaders, err)
+		}
+		for _, hdr := range headers {
+			name, value := strings.TrimSpace(hdr.Name), strings.TrimSpace(hdr.Value)
+			if name == "" || value == "" {
+				return nil, fmt.Errorf("Invalid request header to insert %v, expected a name and a value", hdr)
+			}
+			actions = append(actions, &action{
+				HTTPHeader: true,
+				Header:     &actionHeader{Name: name, Value: value},
+				Insert:     true,
+				Request:    true,
+			})
+		}
+	}
+	return actions, nil
+}
+
 func createRedirectRule(source, target, ruleName string, allowSourceRange []string) (*Rule, error) {
 	_u := "scheme://" + source
 	_u = strings.TrimSuffix(_u, "/")
@@ -391,6 +538,57 @@ func createRedirectRule(source, target, ruleName string, allowSourceRange []stri
 	return &rl, nil
 }
 
+// assignRuleOrdinals orders the rules of the map by host, path length
+// (longest first) and pool, and numbers them starting from ordinal, so
+// that the same input always yields the same ordinals
+func assignRuleOrdinals(r ruleMap, ordinal int) Rules {
+	rls := make(Rules, 0, len(r))
+	for _, v := range r {
+		rls = append(rls, v)
+	}
+	sort.Slice(rls, func(i, j int) bool {
+		hostI, pathI := splitRuleURI(rls[i].FullURI)
+		hostJ, pathJ := splitRuleURI(rls[j].FullURI)
+		if hostI != hostJ {
+			return hostI < hostJ
+		}
+		if len(pathI) != len(pathJ) {
+			return len(pathI) > len(pathJ)
+		}
+		if pathI != pathJ {
+			return pathI < pathJ
+		}
+		poolI, poolJ := getRulePool(rls[i]), getRulePool(rls[j])
+		if poolI != poolJ {
+			return poolI < poolJ
+		}
+		return rls[i].Name < rls[j].Name
+	})
+	for _, v := range rls {
+		v.Ordinal = ordinal
+		ordinal++
+	}
+	return rls
+}
+
+// splitRuleURI splits the uri of a rule into its host and path
+func splitRuleURI(uri string) (string, string) {
+	if idx := strings.Index(uri, "/"); idx != -1 {
+		return uri[:idx], uri[idx:]
+	}
+	return uri, ""
+}
+
+// getRulePool returns the pool forwarded to by the rule
+func getRulePool(rl *Rule) string {
+	for _, act := range rl.Actions {
+		if act.Pool != "" {
+			return act.Pool
+		}
+	}
+	return ""
+}
+
 func (rules Rules) Len() int {
 	return len(rules)
 }
@@ -398,6 +596,11 @@ func (rules Rules) Len() int {
 func (rules Rules) Less(i, j int) bool {
 	ruleI := rules[i]
 	ruleJ := rules[j]
+	// Rules rejecting the denied sources are evaluated first
+	denyI, denyJ := isDenySourceRangeRule(ruleI), isDenySourceRangeRule(ruleJ)
+	if denyI != denyJ {
+		return denyI
+	}
 	// Strategy 1: Rule with Highest number of conditions
 	l1 := len(ruleI.Conditions)
 	l2 := len(ruleJ.Conditions)
@@ -461,22 +664,27 @@ func (rules Rules) Swap(i, j int) {
 
 // httpRedirectIRuleNoHost redirects traffic to BIG-IP https vs
 // for hostLess CRDs.
-func httpRedirectIRuleNoHost(port int32) string {
+func httpRedirectIRuleNoHost(port int32, redirectCode int32) string {
 	// The key in the data group is the host name or * to match all.
 	// The data is a list of paths for the host delimited by '|' or '/' for all.
 	iRuleCode := fmt.Sprintf(`
 		when HTTP_REQUEST {
-			HTTP::redirect https://[getfield [HTTP::host] ":" 1]:%d[HTTP::uri]	
-		}`, port)
+			%s	
+		}`, httpRedirectCommand(port, redirectCode))
 	return iRuleCode
 }
 
 // httpRedirectIRule redirects traffic to BIG-IP https vs
-// except for the hostLess CRDs.
-func httpRedirectIRule(port int32, rsVSName string, partition string) string {
+// except for the hostLess CRDs, hosts found in the target data group are
+// redirected to their target when the target data group is given.
+func httpRedirectIRule(port int32, rsVSName string, partition string, redirectCode int32, targetDgName string) string {
 	// The key in the data group is the host name or * to match all.
 	// The data is a list of paths for the host delimited by '|' or '/' for all.
-	dgName := "/" + partition + "/" + Shared + "/" + rsVSName + "_https_redirect_dg"
+	dgName := "/" + partition + "/" + Shared + "/" + getHttpsRedirectDgName(rsVSName, port)
+	redirectCmd := httpRedirectCommand(port, redirectCode)
+	if targetDgName != "" {
+		redirectCmd = httpRedirectTargetCommand("/"+partition+"/"+Shared+"/"+targetDgName, redirectCmd, redirectCode)
+	}
 	iRuleCode := fmt.Sprintf(`
 		when HTTP_REQUEST {
 			
@@ -484,7 +692,7 @@ func httpRedirectIRule(port int32, rsVSName string, partition string) string {
 			# */ represents [* -> Any host / -> default path]
 			set allHosts [class match -value "*/" equals %[1]s]
 			if {$allHosts != ""} {
-				HTTP::redirect https://[getfield [HTTP::host] ":" 1]:443[HTTP::uri]
+				%[2]s
 				return
 			}
 			set host [HTTP::host]
@@ -544,15 +752,144 @@ func httpRedirectIRule(port int32, rsVSName string, partition string) string {
 					}
 				}
 				if {$redir == 1} {
-					HTTP::redirect https://[getfield [HTTP::host] ":" 1]:%[2]d[HTTP::uri]
+					%[2]s
 				}
 			}
-		}`, dgName, port)
+		}`, dgName, redirectCmd)
 
 	return iRuleCode
 }
 
-func (ctlr *Controller) getTLSIRule(rsVSName string, partition string, allowSourceRange []string) string {
+// httpRedirectCommand redirects the request to the https port with the given status code,
+// HTTP::redirect always responds with 302
+func httpRedirectCommand(port int32, redirectCode int32) string {
+	if redirectCode == 0 || redirectCode == DefaultHTTPRedirectCode {
+		return fmt.Sprintf(`HTTP::redirect https://[getfield [HTTP::host] ":" 1]:%d[HTTP::uri]`, port)
+	}
+	return fmt.Sprintf(`HTTP::respond %d Location "https://[getfield [HTTP::host] ":" 1]:%d[HTTP::uri]"`,
+		redirectCode, port)
+}
+
+// httpRedirectTargetCommand redirects the request to the target of the host in the target data group,
+// the request uri is appended to the targets without a path. Requests of the hosts without
+// a target fall back to the default redirect command
+func httpRedirectTargetCommand(targetDgName string, defaultCmd string, redirectCode int32) string {
+	redirect := `HTTP::redirect "https://$target"`
+	if redirectCode != 0 && redirectCode != DefaultHTTPRedirectCode {
+		redirect = fmt.Sprintf(`HTTP::respond %d Location "https://$target"`, redirectCode)
+	}
+	return fmt.Sprintf(`set target [class match -value [getfield [HTTP::host] ":" 1] equals %[1]s]
+				if {$target == ""} {
+					set target [class match -value [getfield [HTTP::host] ":" 1] ends_with %[1]s]
+				}
+				if {$target != ""} {
+					if {[string first "/" $target] == -1} {
+						append target [HTTP::uri]
+					}
+					%[2]s
+				} else {
+					%[3]s
+				}`, targetDgName, redirect, defaultCmd)
+}
+
+// sorryPageIRule responds with the fixed status code and body to the requests of the host,
+// or to all requests for hostLess CRDs, before any other iRule or the forwarding policy
+func sorryPageIRule(host string, statusCode int32, contentType, body string) string {
+	respondCmd := fmt.Sprintf(`HTTP::respond %d content "%s" "Content-Type" "%s" "Connection" "Close"`,
+		statusCode, escapeTclString(body), escapeTclString(contentType))
+	if host == "" {
+		return fmt.Sprintf(`
+		when HTTP_REQUEST priority 100 {
+			%s
+			event disable all
+		}`, respondCmd)
+	}
+	return fmt.Sprintf(`
+		when HTTP_REQUEST priority 100 {
+			if { [string match -nocase "%s" [getfield [HTTP::host] ":" 1]] } {
+				%s
+				event disable all
+			}
+		}`, escapeTclString(host), respondCmd)
+}
+
+// poolSNATIRule disables SNAT for the connections load balanced to the pools
+func poolSNATIRule(poolPaths []string) string {
+	var cases []string
+	for _, poolPath := range poolPaths {
+		cases = append(cases, fmt.Sprintf(`"%s"`, escapeTclString(poolPath)))
+	}
+	return fmt.Sprintf(`
+		when LB_SELECTED {
+			switch -- [LB::server pool] {
+				%s {
+					snat none
+				}
+			}
+		}`, strings.Join(cases, " -\n\t\t\t\t"))
+}
+
+// escapeTclString escapes the characters substituted by Tcl within a quoted string
+func escapeTclString(str string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, `[`, `\[`, `]`, `\]`).Replace(str)
+}
+
+// getHttpRedirectIRuleName returns the redirect iRule name scoped to the https port,
+// a non default redirect code is appended so that changing the code replaces the iRule
+func getHttpRedirectIRuleName(rsVSName string, iRuleName string, httpsPort int32, redirectCode int32) string {
+	name := fmt.Sprintf("%s_%d", getRSCfgResName(rsVSName, iRuleName), httpsPort)
+	if redirectCode != 0 && redirectCode != DefaultHTTPRedirectCode {
+		name = fmt.Sprintf("%s_%d", name, redirectCode)
+	}
+	return name
+}
+
+// isHttpRedirectIRule checks whether the iRule is a redirect iRule created by CIS,
+// the name of which is suffixed with the https port and optionally the redirect code
+func isHttpRedirectIRule(iRuleName string) bool {
+	for i := 0; i < 2; i++ {
+		lastIndex := strings.LastIndex(iRuleName, "_")
+		if lastIndex <= 0 {
+			return false
+		}
+		iRuleName = iRuleName[:lastIndex]
+		if strings.HasSuffix(iRuleName, HttpRedirectIRuleName) ||
+			strings.HasSuffix(iRuleName, HttpRedirectNoHostIRuleName) {
+			return true
+		}
+	}
+	return false
+}
+
+// redirectTargetRegex matches a host with an optional port and path
+var redirectTargetRegex = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9.-]*[A-Za-z0-9])?(:[0-9]{1,5})?(/[A-Za-z0-9._~%!&'()*+,;=:@/?-]*)?$`)
+
+// getRouteRedirectTarget returns the redirect target set with the redirect target annotation,
+// invalid targets are ignored and the route is redirected to the same host
+func getRouteRedirectTarget(route *routeapi.Route) string {
+	target, ok := route.Annotations[string(RedirectTargetAnnotation)]
+	if !ok {
+		return ""
+	}
+	target = strings.TrimSpace(target)
+	if !redirectTargetRegex.MatchString(target) {
+		log.Warningf("[CORE] Ignoring invalid redirect target %v of route %v/%v", target, route.Namespace, route.Name)
+		return ""
+	}
+	return target
+}
+
+// isValidHttpRedirectCode checks whether the status code is supported for the http redirect
+func isValidHttpRedirectCode(redirectCode int32) bool {
+	switch redirectCode {
+	case 0, 301, 302, 307, 308:
+		return true
+	}
+	return false
+}
+
+func (ctlr *Controller) getTLSIRule(rsVSName string, partition string, allowSourceRange []string, abPersistence *ABPersistence,
+	defaultPassthroughPool string) string {
 	dgPath := strings.Join([]string{partition, Shared}, "/")
 
 	iRule := fmt.Sprintf(`
@@ -641,13 +978,15 @@ func (ctlr *Controller) getTLSIRule(rsVSName string, partition string, allowSour
 							}
 							if { [info exists tls_servername] } {
 								set passthru_class "/%[1]s/%[2]s_ssl_passthrough_servername_dg"
+								set servername_lower [string tolower $tls_servername]
+								set dflt_pool_passthrough ""
 								if { [class exists $passthru_class] } {
-									set servername_lower [string tolower $tls_servername]
+									set dflt_pool_passthrough [class match -value $servername_lower equals $passthru_class]
+								}%[3]s
+								if { [class exists $passthru_class] || not ($dflt_pool_passthrough equals "") } {
 									SSL::disable serverside
-									set dflt_pool_passthrough ""
 
 									# Disable Serverside SSL for Passthrough Class
-									set dflt_pool_passthrough [class match -value $servername_lower equals $passthru_class]
 									if { not ($dflt_pool_passthrough equals "") } {
 										SSL::disable
 										HTTP::disable
@@ -833,13 +1172,42 @@ func (ctlr *Controller) getTLSIRule(rsVSName string, partition string, allowSour
 						SSL::profile $reen
 				}
 			}
-        }`, dgPath, rsVSName)
+        }`, dgPath, rsVSName, selectDefaultPassthroughPool(rsVSName, dgPath, defaultPassthroughPool))
 
-	iRuleCode := fmt.Sprintf("%s\n\n%s\n\n%s", ctlr.selectClientAcceptediRule(rsVSName, dgPath, allowSourceRange), ctlr.selectPoolIRuleFunc(rsVSName, dgPath), iRule)
+	iRuleCode := fmt.Sprintf("%s\n\n%s\n\n%s", ctlr.selectClientAcceptediRule(rsVSName, dgPath, allowSourceRange), ctlr.selectPoolIRuleFunc(rsVSName, dgPath, abPersistence), iRule)
+	if abPersistence != nil {
+		iRuleCode = fmt.Sprintf("%s\n\n%s", iRuleCode, ctlr.selectABPersistenceIRule(abPersistence))
+	}
 
 	return iRuleCode
 }
 
+// selectDefaultPassthroughPool selects the default passthrough pool for the server names which match neither
+// the passthrough hosts nor the edge and reencrypt hosts of the virtual
+func selectDefaultPassthroughPool(rsVSName string, dgPath string, defaultPassthroughPool string) string {
+	if defaultPassthroughPool == "" {
+		return ""
+	}
+	return fmt.Sprintf(`
+								if { $dflt_pool_passthrough equals "" } {
+									set tls_host_found 0
+									set domain_length [llength [split $servername_lower "."]]
+									set wc_host ".[domain $servername_lower [expr {$domain_length - 1}]]"
+									foreach tls_class [list "/%[1]s/%[2]s_ssl_edge_servername_dg" "/%[1]s/%[2]s_ssl_reencrypt_servername_dg"] {
+										if { [class exists $tls_class] } {
+											foreach tls_host [list $servername_lower $wc_host] {
+												if { [class match $tls_host equals $tls_class] || [llength [class names $tls_class "$tls_host/*"]] > 0 } {
+													set tls_host_found 1
+												}
+											}
+										}
+									}
+									if { not $tls_host_found } {
+										set dflt_pool_passthrough "%[3]s"
+									}
+								}`, dgPath, rsVSName, defaultPassthroughPool)
+}
+
 func (ctlr *Controller) selectClientAcceptediRule(rsVSName string, dgPath string, allowSourceRange []string) string {
 
 	iRulePrefix := fmt.Sprintf(`when CLIENT_ACCEPTED { TCP::collect }`)
@@ -849,10 +1217,24 @@ func (ctlr *Controller) selectClientAcceptediRule(rsVSName string, dgPath string
 	return iRulePrefix
 }
 
-func (ctlr *Controller) selectPoolIRuleFunc(rsVSName string, dgPath string) string {
-
+func (ctlr *Controller) selectPoolIRuleFunc(rsVSName string, dgPath string, abPersistence *ABPersistence) string {
+
+	// Pool persisted in the cookie is selected if it is still one of the A/B backends
+	procArgs := "path default_pool "
+	persistedPoolSelection := ""
+	if abPersistence != nil {
+		procArgs = `path default_pool {persisted_pool ""}`
+		persistedPoolSelection = `
+					if {$persisted_pool != ""} then {
+						foreach service_rule $service_rules {
+							if {[lindex [split $service_rule ","] 0] == $persisted_pool} then {
+								return $persisted_pool
+							}
+						}
+					}`
+	}
 	iRuleFunc := fmt.Sprintf(`
-		proc select_ab_pool {path default_pool } {
+		proc select_ab_pool {%[3]s} {
 			set last_slash [string length $path]
 			set ab_class "/%[1]s/%[2]s_ab_deployment_dg"
 			while {$last_slash >= 0} {
@@ -868,7 +1250,7 @@ func (ctlr *Controller) selectPoolIRuleFunc(rsVSName string, dgPath string) stri
 				set ab_rule [class match -value $path equals $ab_class]
 				if {$ab_rule != ""} then {
 					set weight_selection [expr {rand()}]
-					set service_rules [split $ab_rule ";"]
+					set service_rules [split $ab_rule ";"]%[4]s
 					foreach service_rule $service_rules {
 						set fields [split $service_rule ","]
 						set pool_name [lindex $fields 0]
@@ -883,11 +1265,43 @@ func (ctlr *Controller) selectPoolIRuleFunc(rsVSName string, dgPath string) stri
 				HTTP::respond 503
 			}
 			return $default_pool
-		}`, dgPath, rsVSName)
+		}`, dgPath, rsVSName, procArgs, persistedPoolSelection)
 
 	return iRuleFunc
 }
 
+// selectABPersistenceIRule persists the A/B deployment pool selected for a client in a cookie
+func (ctlr *Controller) selectABPersistenceIRule(abPersistence *ABPersistence) string {
+	if abPersistence == nil {
+		return ""
+	}
+	cookieName := abPersistence.CookieName
+	if cookieName == "" {
+		cookieName = DefaultABPersistenceCookieName
+	}
+	cookieExpiry := ""
+	if abPersistence.TTL > 0 {
+		cookieExpiry = fmt.Sprintf(`
+				HTTP::cookie expires "%s" %d relative`, cookieName, abPersistence.TTL)
+	}
+	return fmt.Sprintf(`
+		when HTTP_REQUEST {
+			set ab_persist_pool ""
+			if { [info exists ab_class] && [class exists $ab_class] && [info exists servername_lower] } {
+				set ab_persist_pool [call select_ab_pool $servername_lower "" [HTTP::cookie value "%[1]s"]]
+				if { $ab_persist_pool != "" } {
+					pool $ab_persist_pool
+				}
+			}
+		}
+
+		when HTTP_RESPONSE {
+			if { [info exists ab_persist_pool] && $ab_persist_pool != "" } {
+				HTTP::cookie insert name "%[1]s" value $ab_persist_pool path "/"%[2]s
+			}
+		}`, cookieName, cookieExpiry)
+}
+
 func updateDataGroupOfDgName(
 	intDgMap InternalDataGroupMap,
 	poolPathRefs []poolPathRef,
@@ -916,16 +1330,6 @@ func updateDataGroupOfDgName(
 			updateDataGroup(intDgMap, rsDGName,
 				partition, namespace, hostName, pl.poolName, DataGroupType)
 		}
-	case HttpsRedirectDgName:
-		for _, pl := range poolPathRefs {
-			path := pl.path
-			if path == "" {
-				path = "/"
-			}
-			routePath := hostName + path
-			updateDataGroup(intDgMap, rsDGName,
-				partition, namespace, routePath, path, DataGroupType)
-		}
 	case AllowSourceRange:
 		for _, sourceNw := range allowSourceRange {
 			updateDataGroup(intDgMap, rsDGName,
@@ -934,6 +1338,53 @@ func updateDataGroupOfDgName(
 	}
 }
 
+// updateHttpsRedirectDataGroup updates the https redirect data group of the given https port
+func updateHttpsRedirectDataGroup(
+	intDgMap InternalDataGroupMap,
+	poolPathRefs []poolPathRef,
+	rsVSName string,
+	hostName string,
+	namespace string,
+	partition string,
+	httpsPort int32,
+) {
+	rsDGName := getHttpsRedirectDgName(rsVSName, httpsPort)
+	for _, pl := range poolPathRefs {
+		path := pl.path
+		if path == "" {
+			path = "/"
+		}
+		routePath := hostName + path
+		updateDataGroup(intDgMap, rsDGName,
+			partition, namespace, routePath, path, DataGroupType)
+	}
+}
+
+// updateHttpsRedirectTargetDataGroup updates the redirect target of the host in the
+// https redirect target data group of the given https port
+func updateHttpsRedirectTargetDataGroup(
+	intDgMap InternalDataGroupMap,
+	rsVSName string,
+	hostName string,
+	namespace string,
+	partition string,
+	httpsPort int32,
+	target string,
+) {
+	updateDataGroup(intDgMap, getHttpsRedirectTargetDgName(rsVSName, httpsPort),
+		partition, namespace, hostName, target, DataGroupType)
+}
+
+// getHttpsRedirectTargetDgName returns the https redirect target data group name scoped to the https port
+func getHttpsRedirectTargetDgName(rsVSName string, httpsPort int32) string {
+	return fmt.Sprintf("%s_%d", getRSCfgResName(rsVSName, HttpsRedirectTargetDgName), httpsPort)
+}
+
+// getHttpsRedirectDgName returns the https redirect data group name scoped to the https port
+func getHttpsRedirectDgName(rsVSName string, httpsPort int32) string {
+	return fmt.Sprintf("%s_%d", getRSCfgResName(rsVSName, HttpsRedirectDgName), httpsPort)
+}
+
 // Add or update a data group record
 func updateDataGroup(
 	intDgMap InternalDataGroupMap,
@@ -982,7 +1433,7 @@ func (ctlr *Controller) updateDataGroupForABRoute(
 	dgMap InternalDataGroupMap,
 	port intstr.IntOrString,
 ) {
-	if !IsRouteABDeployment(route) {
+	if !IsRouteABDeployment(route) || isRouteABRatioDeployment(route) {
 		return
 	}
 
@@ -1045,6 +1496,59 @@ func IsRouteABDeployment(route *routeapi.Route) bool {
 	return route.Spec.AlternateBackends != nil && len(route.Spec.AlternateBackends) > 0
 }
 
+// isRouteABRatioDeployment checks whether the traffic of an A/B route is split by the ratio of
+// the pool members instead of the A/B iRule, invalid configurations fall back to the iRule
+func isRouteABRatioDeployment(route *routeapi.Route) bool {
+	if !IsRouteABDeployment(route) {
+		return false
+	}
+	mode, ok := route.Annotations[string(ABDeploymentModeAnnotation)]
+	if !ok || mode == ABDeploymentModeDataGroup {
+		return false
+	}
+	if mode != ABDeploymentModeRatio {
+		log.Warningf("Invalid %v annotation %v in route %v/%v, supported modes are %v and %v",
+			ABDeploymentModeAnnotation, mode, route.Namespace, route.Name,
+			ABDeploymentModeDataGroup, ABDeploymentModeRatio)
+		return false
+	}
+	if _, err := getRouteRatioBackends(route); err != nil {
+		log.Warningf("Unable to use %v A/B deployment for route %v/%v: %v", ABDeploymentModeRatio,
+			route.Namespace, route.Name, err)
+		return false
+	}
+	return true
+}
+
+// getRouteRatioBackends returns the backends of an A/B route with a ratio of the percentage of their
+// weight in the total weight, the backends with 0 weight don't receive any traffic and are skipped
+func getRouteRatioBackends(route *routeapi.Route) ([]WeightedBackend, error) {
+	weightTotal := 0
+	backends := GetRouteBackends(route)
+	for _, be := range backends {
+		if be.Weight < 0 || be.Weight > 256 {
+			return nil, fmt.Errorf("weight %v of backend %v is not in the range 0-256", be.Weight, be.Name)
+		}
+		weightTotal = weightTotal + be.Weight
+	}
+	if weightTotal == 0 {
+		return nil, fmt.Errorf("weights of the backends sum to 0")
+	}
+	var weightedBackends []WeightedBackend
+	for _, be := range backends {
+		if be.Weight == 0 {
+			continue
+		}
+		// pool member ratio ranges from 1 to 100
+		ratio := be.Weight * 100 / weightTotal
+		if ratio == 0 {
+			ratio = 1
+		}
+		weightedBackends = append(weightedBackends, WeightedBackend{ServiceName: be.Name, Ratio: ratio})
+	}
+	return weightedBackends, nil
+}
+
 // return the services associated with a route (names + weight)
 func GetRouteBackends(route *routeapi.Route) []RouteBackendCxt {
 	numOfBackends := 1
@@ -1071,5 +1575,21 @@ func GetRouteBackends(route *routeapi.Route) []RouteBackendCxt {
 		}
 	}
 
+	for i := range rbcs {
+		rbcs[i].Balance = getRouteBackendBalance(route, rbcs[i].Name)
+	}
+
 	return rbcs
 }
+
+// getRouteBackendBalance returns the balance of the route backend, the backend balance annotation
+// takes precedence over the balance annotation of the route
+func getRouteBackendBalance(route *routeapi.Route, backend string) string {
+	if balance, ok := route.ObjectMeta.Annotations[BackendBalanceAnnotationPrefix+backend]; ok && balance != "" {
+		return balance
+	}
+	if balance, ok := route.ObjectMeta.Annotations[resource.F5VsBalanceAnnotation]; ok && balance != "" {
+		return balance
+	}
+	return DEFAULT_BALANCE
+}
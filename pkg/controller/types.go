@@ -21,6 +21,7 @@ import (
 	ficV1 "github.com/F5Networks/f5-ipam-controller/pkg/ipamapis/apis/fic/v1"
 	"net/http"
 	"sync"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -31,6 +32,7 @@ import (
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/teem"
 
 	"github.com/F5Networks/f5-ipam-controller/pkg/ipammachinery"
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/config/apis/cis/v1"
 	"github.com/F5Networks/k8s-bigip-ctlr/config/client/clientset/versioned"
 	apm "github.com/F5Networks/k8s-bigip-ctlr/pkg/appmanager"
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/pollers"
@@ -68,7 +70,6 @@ type (
 		UseNodeInternal    bool
 		initState          bool
 		SSLContext         map[string]*v1.Secret
-		dgPath             string
 		shareNodes         bool
 		ipamCli            *ipammachinery.IPAMClient
 		ipamCR             string
@@ -76,8 +77,41 @@ type (
 		TeemData           *teem.TeemsData
 		requestQueue       *requestQueue
 		namespaceLabel     string
+		// poolMemberDrainTimeout is the duration for which pool members of a
+		// deleted service are kept disabled before they are removed
+		poolMemberDrainTimeout time.Duration
+		// routeGroupDeleteGrace is the duration for which a route group has to be
+		// without routes before its virtuals are deleted
+		routeGroupDeleteGrace time.Duration
+		// clusterName tags the pool members with their source cluster
+		clusterName string
+		// poolMetadataLabels are the namespace and service labels added as metadata to the pools
+		poolMetadataLabels []string
+		// poolMetadataNsInformer caches the namespaces for the pool metadata without namespace label informers
+		poolMetadataNsInformer *NSInformer
+		// maxResourceRetries is the number of retries of a failed resource before it is dropped
+		maxResourceRetries int
+		// poolPartitions are the partitions other than Partition in which the pools may be placed
+		poolPartitions []string
+		// dryRun logs the changes to the config instead of posting it to the Agent
+		dryRun bool
+		// tlsProfileFailOpen serves the VirtualServers whose TLSProfiles can't be resolved without TLS
+		tlsProfileFailOpen bool
+		// strictCertificateHost rejects the certificates which don't cover the host of their resource
+		strictCertificateHost bool
+		// lastSyncTime is the time the last config batch was posted to the Agent
+		lastSyncTime time.Time
+		syncMutex    sync.RWMutex
+		// syncComplete is called with the sync time after each config batch is posted to the Agent
+		syncComplete func(syncTime time.Time)
+		// translateMemberAddress maps the endpoint addresses before they are added as pool members
+		translateMemberAddress addressTranslator
 		nativeResourceContext
 	}
+
+	// addressTranslator maps a pool member address, e.g. to its NAT mapped address
+	addressTranslator func(address string) string
+
 	nativeResourceContext struct {
 		nativeResourceQueue workqueue.RateLimitingInterface
 		routeClientV1       routeclient.RouteV1Interface
@@ -87,6 +121,16 @@ type (
 		routeLabel          string
 		namespaceLabelMode  bool
 		processedHostPath   *ProcessedHostPath
+		// routerName identifies the route admit status of this instance among the other routers
+		routerName string
+		// routeGroupDeletes are the pending deletes of the virtuals of the route groups without routes
+		routeGroupDeletes map[string]routeGroupDelete
+	}
+
+	// routeGroupDelete is a delete of the virtuals of a route group deferred until the expiry
+	routeGroupDelete struct {
+		expiry time.Time
+		timer  *time.Timer
 	}
 
 	// Params defines parameters
@@ -108,21 +152,44 @@ type (
 		Mode               ControllerMode
 		RouteSpecConfigmap string
 		RouteLabel         string
+		// RouterName is the router name of the route admit status, defaults to F5RouterName
+		RouterName string
+		// PoolMemberDrainTimeout in seconds
+		PoolMemberDrainTimeout int
+		// RouteGroupDeleteGrace in seconds
+		RouteGroupDeleteGrace int
+		ClusterName           string
+		// PoolMetadataLabels are the namespace and service labels added as metadata to the pools
+		PoolMetadataLabels []string
+		// MaxResourceRetries is the number of retries of a failed resource, defaults to DefaultMaxResourceRetries
+		MaxResourceRetries int
+		// PoolPartitions are the partitions other than Partition in which the pools may be placed
+		PoolPartitions []string
+		// DryRun logs the changes to the config instead of posting it to the Agent
+		DryRun bool
+		// TLSProfileFailOpen serves the VirtualServers whose TLSProfiles can't be resolved without TLS,
+		// otherwise their virtuals aren't created
+		TLSProfileFailOpen bool
+		// StrictCertificateHost rejects the certificates which don't cover the host of their resource
+		StrictCertificateHost bool
+		// SyncComplete is called with the sync time after each config batch is posted to the Agent
+		SyncComplete func(syncTime time.Time)
 	}
 
 	// CRInformer defines the structure of Custom Resource Informer
 	CRInformer struct {
-		namespace    string
-		stopCh       chan struct{}
-		svcInformer  cache.SharedIndexInformer
-		epsInformer  cache.SharedIndexInformer
-		vsInformer   cache.SharedIndexInformer
-		tlsInformer  cache.SharedIndexInformer
-		tsInformer   cache.SharedIndexInformer
-		ilInformer   cache.SharedIndexInformer
-		ednsInformer cache.SharedIndexInformer
-		plcInformer  cache.SharedIndexInformer
-		podInformer  cache.SharedIndexInformer
+		namespace      string
+		stopCh         chan struct{}
+		svcInformer    cache.SharedIndexInformer
+		epsInformer    cache.SharedIndexInformer
+		vsInformer     cache.SharedIndexInformer
+		tlsInformer    cache.SharedIndexInformer
+		tsInformer     cache.SharedIndexInformer
+		ilInformer     cache.SharedIndexInformer
+		ednsInformer   cache.SharedIndexInformer
+		plcInformer    cache.SharedIndexInformer
+		podInformer    cache.SharedIndexInformer
+		secretInformer cache.SharedIndexInformer
 	}
 
 	EssentialInformer struct {
@@ -134,10 +201,11 @@ type (
 
 	// NRInformer is informer context for Native Resources of Kubernetes/Openshift
 	NRInformer struct {
-		namespace     string
-		stopCh        chan struct{}
-		routeInformer cache.SharedIndexInformer
-		cmInformer    cache.SharedIndexInformer
+		namespace      string
+		stopCh         chan struct{}
+		routeInformer  cache.SharedIndexInformer
+		cmInformer     cache.SharedIndexInformer
+		secretInformer cache.SharedIndexInformer
 	}
 
 	NSInformer struct {
@@ -161,6 +229,13 @@ type (
 		hosts         []string
 		Protocol      string
 		httpTraffic   string
+		// iRule name as key, priority from Policy as value
+		iRulePriorities map[string]int
+		// poolMemberType overrides the pool member type of the controller
+		poolMemberType string
+		// monitors of the Policy for the pools without own monitors
+		policyName     string
+		policyMonitors []cisapiv1.Monitor
 	}
 
 	// Virtual Server Key - unique server is Name + Port
@@ -183,24 +258,42 @@ type (
 		Profiles               ProfileRefs           `json:"profiles,omitempty"`
 		IRules                 []string              `json:"rules,omitempty"`
 		Description            string                `json:"description,omitempty"`
+		Metadata               map[string]string     `json:"metadata,omitempty"`
 		VirtualAddress         *virtualAddress       `json:"-"`
+		RouteDomain            *int32                `json:"routeDomain,omitempty"`
 		SNAT                   string                `json:"snat,omitempty"`
 		WAF                    string                `json:"waf,omitempty"`
+		ForwardingPolicyOrder  string                `json:"forwardingPolicyOrder,omitempty"`
 		Firewall               string                `json:"firewallPolicy,omitempty"`
 		LogProfiles            []string              `json:"logProfiles,omitempty"`
+		RequestLogProfile      string                `json:"requestLogProfile,omitempty"`
+		AnalyticsProfile       string                `json:"analyticsProfile,omitempty"`
+		TCPAnalyticsProfile    string                `json:"tcpAnalyticsProfile,omitempty"`
 		ProfileL4              string                `json:"profileL4,omitempty"`
+		ProfileL4Options       *ProfileL4Options     `json:"profileL4Options,omitempty"`
 		ProfileMultiplex       string                `json:"profileMultiplex,omitempty"`
 		ProfileDOS             string                `json:"profileDOS,omitempty"`
 		ProfileBotDefense      string                `json:"profileBotDefense,omitempty"`
 		TCP                    ProfileTCP            `json:"tcp,omitempty"`
 		Mode                   string                `json:"mode,omitempty"`
-		TranslateServerAddress bool                  `json:"translateServerAddress"`
-		TranslateServerPort    bool                  `json:"translateServerPort"`
+		TranslateServerAddress *bool                 `json:"translateServerAddress,omitempty"`
+		TranslateServerPort    *bool                 `json:"translateServerPort,omitempty"`
 		Source                 string                `json:"source,omitempty"`
 		AllowVLANs             []string              `json:"allowVlans,omitempty"`
+		RejectVLANs            []string              `json:"rejectVlans,omitempty"`
+		VLANsEnabled           bool                  `json:"vlansEnabled,omitempty"`
 		PersistenceProfile     string                `json:"persistenceProfile,omitempty"`
+		Persistence            *PersistenceProfile   `json:"persistence,omitempty"`
 		TLSTermination         string                `json:"-"`
+		ABPersistence          *ABPersistence        `json:"-"`
+		DefaultPassthroughPool string                `json:"-"`
 		AllowSourceRange       []string              `json:"allowSourceRange,omitempty"`
+		DenySourceRange        []string              `json:"denySourceRange,omitempty"`
+		InsertXForwardedFor    bool                  `json:"insertXForwardedFor,omitempty"`
+		HTTPProfileOptions     *HTTPProfileOptions   `json:"httpProfileOptions,omitempty"`
+		AllowH2C               bool                  `json:"allowH2C,omitempty"`
+		ConnectionRateLimit    int32                 `json:"connectionRateLimit,omitempty"`
+		ClonePools             *ClonePools           `json:"clonePools,omitempty"`
 	}
 	// Virtuals is slice of virtuals
 	Virtuals []Virtual
@@ -210,6 +303,42 @@ type (
 		Server string `json:"server,omitempty"`
 	}
 
+	// ClonePools are the pools the virtual replicates the ingress and egress traffic to
+	ClonePools struct {
+		Ingress *ClonePool `json:"ingress,omitempty"`
+		Egress  *ClonePool `json:"egress,omitempty"`
+	}
+
+	// ClonePool is the path of a BIG-IP pool with reference bigip, otherwise the reference of a pool of the virtual
+	ClonePool struct {
+		Name      string `json:"name"`
+		Reference string `json:"reference,omitempty"`
+	}
+
+	// PersistenceProfile is the L4 persistence of a TransportServer
+	PersistenceProfile struct {
+		Type                string `json:"type"`
+		Timeout             int    `json:"timeout,omitempty"`
+		MatchAcrossServices bool   `json:"matchAcrossServices,omitempty"`
+		MatchAcrossPools    bool   `json:"matchAcrossPools,omitempty"`
+		MatchAcrossVirtuals bool   `json:"matchAcrossVirtuals,omitempty"`
+	}
+
+	// ProfileL4Options are the fastL4 options of the L4 profile created for a TransportServer
+	ProfileL4Options struct {
+		LooseInitialization *bool  `json:"looseInitialization,omitempty"`
+		ResetOnTimeout      *bool  `json:"resetOnTimeout,omitempty"`
+		IdleTimeout         *int32 `json:"idleTimeout,omitempty"`
+		TCPHandshakeTimeout *int32 `json:"tcpHandshakeTimeout,omitempty"`
+	}
+
+	// HTTPProfileOptions are the options of the HTTP profile created for a virtual
+	HTTPProfileOptions struct {
+		ServerAgentName     string `json:"serverAgentName,omitempty"`
+		ProxyType           string `json:"proxyType,omitempty"`
+		InsertXForwardedFor bool   `json:"insertXForwardedFor,omitempty"`
+	}
+
 	// ServiceAddress Service IP address definition (BIG-IP virtual-address).
 	ServiceAddress struct {
 		ArpEnabled         bool   `json:"arpEnabled,omitempty"`
@@ -217,6 +346,7 @@ type (
 		RouteAdvertisement string `json:"routeAdvertisement,omitempty"`
 		TrafficGroup       string `json:"trafficGroup,omitempty"`
 		SpanningEnabled    bool   `json:"spanningEnabled,omitempty"`
+		Mask               string `json:"mask,omitempty"`
 	}
 
 	// SourceAddrTranslation is Virtual Server Source Address Translation
@@ -229,6 +359,8 @@ type (
 	virtualAddress struct {
 		BindAddr string `json:"bindAddr,omitempty"`
 		Port     int32  `json:"port,omitempty"`
+		// PrefixLen is set for the network virtual addresses
+		PrefixLen int `json:"prefixLen,omitempty"`
 	}
 
 	// nameRef is virtual server policy/profile reference
@@ -259,6 +391,11 @@ type (
 		gtmConfig      GTMConfig
 		gtmConfigCache GTMConfig
 		nplStore       NPLStore
+		// poolPartitions are retained with an empty config, as they hold the pools of other partitions
+		poolPartitions []string
+		// cacheMutex guards ltmConfig and ltmConfigCache against readers outside the worker,
+		// the worker holds it while it updates ltmConfig
+		cacheMutex sync.RWMutex
 		supplementContextCache
 	}
 
@@ -274,6 +411,52 @@ type (
 	// ResourceMap key is resource name, value is pointer to config. May be shared.
 	ResourceMap map[string]*ResourceConfig
 
+	// ltmConfigDump is the JSON representation of LTMConfig used for debugging
+	ltmConfigDump map[string]partitionConfigDump
+
+	partitionConfigDump struct {
+		Priority  int                           `json:"priority"`
+		Resources map[string]resourceConfigDump `json:"resources"`
+	}
+
+	// resourceConfigDump flattens the NameRef keyed maps of ResourceConfig
+	// so that it can be marshalled
+	resourceConfigDump struct {
+		Virtual        Virtual             `json:"virtual"`
+		Pools          Pools               `json:"pools,omitempty"`
+		Policies       Policies            `json:"policies,omitempty"`
+		Monitors       []Monitor           `json:"monitors,omitempty"`
+		ServiceAddress []ServiceAddress    `json:"serviceAddress,omitempty"`
+		IRules         []IRule             `json:"iRules,omitempty"`
+		DataGroups     []InternalDataGroup `json:"dataGroups,omitempty"`
+		CustomProfiles []CustomProfile     `json:"customProfiles,omitempty"`
+	}
+
+	// LTMConfigDiff is the difference between two LTMConfigs, virtuals, pools
+	// and monitors are identified by their partition qualified name
+	LTMConfigDiff struct {
+		Virtuals ConfigDiff `json:"virtuals"`
+		Pools    ConfigDiff `json:"pools"`
+		Monitors ConfigDiff `json:"monitors"`
+		// Members key is the partition qualified name of the pool
+		Members map[string]PoolMembersDiff `json:"members,omitempty"`
+	}
+
+	// ConfigDiff holds the names of the added, removed and changed resources
+	ConfigDiff struct {
+		Added   []string `json:"added,omitempty"`
+		Removed []string `json:"removed,omitempty"`
+		Changed []string `json:"changed,omitempty"`
+	}
+
+	// PoolMembersDiff holds the members of a pool identified by address and port
+	// which are added, removed or changed, changed members are in their new state
+	PoolMembersDiff struct {
+		Added   []PoolMember `json:"added,omitempty"`
+		Removed []PoolMember `json:"removed,omitempty"`
+		Changed []PoolMember `json:"changed,omitempty"`
+	}
+
 	// PoolMemberCache key is namespace/service
 	PoolMemberCache map[string]poolMembersInfo
 	// Store of CustomProfiles
@@ -340,10 +523,29 @@ type (
 		Members          []PoolMember       `json:"members"`
 		NodeMemberLabel  string             `json:"-"`
 		MonitorNames     []MonitorName      `json:"monitors,omitempty"`
+		// ServiceDownAction and ReselectTries handle the connections of non-responsive members
+		ServiceDownAction string `json:"serviceDownAction,omitempty"`
+		ReselectTries     int32  `json:"reselectTries,omitempty"`
+		// SNAT overrides the SNAT of the virtual for the connections to the pool
+		SNAT string `json:"-"`
+		// WeightedBackends are the services of the pool members weighted by ratio, the
+		// pool members are taken from the service of the pool if there are none
+		WeightedBackends []WeightedBackend `json:"-"`
+		// MinimumMonitors is the number of monitors which must be up for a member to be available
+		MinimumMonitors int `json:"minimumMonitors,omitempty"`
+		// Metadata is taken from the poolMetadataLabels of the namespace and the service of the pool,
+		// it is posted as the remark of the pool as AS3 has no metadata of pools
+		Metadata map[string]string `json:"metadata,omitempty"`
 	}
 	// Pools is slice of pool
 	Pools []Pool
 
+	// WeightedBackend is a service in the namespace of the pool whose members get the ratio
+	WeightedBackend struct {
+		ServiceName string
+		Ratio       int
+	}
+
 	portRef struct {
 		name string
 		port int32
@@ -352,25 +554,41 @@ type (
 		svcType   v1.ServiceType
 		portSpec  []v1.ServicePort
 		memberMap map[portRef][]PoolMember
+		// memberSessions are the session states of the pool members keyed by their address
+		memberSessions map[string]string
+		// drainExpiry is set when the service is deleted and its members are being drained
+		drainExpiry time.Time
+		drainTimer  *time.Timer
 	}
 
 	// Monitor is Pool health monitor
 	Monitor struct {
-		Name       string `json:"name"`
-		Partition  string `json:"-"`
-		Interval   int    `json:"interval,omitempty"`
-		Type       string `json:"type,omitempty"`
-		Send       string `json:"send,omitempty"`
-		Recv       string `json:"recv"`
-		Timeout    int    `json:"timeout,omitempty"`
-		TargetPort int32  `json:"targetPort,omitempty"`
-		Path       string `json:"path,omitempty"`
-		InUse      bool   `json:"-"`
+		Name           string `json:"name"`
+		Partition      string `json:"-"`
+		Interval       int    `json:"interval,omitempty"`
+		Type           string `json:"type,omitempty"`
+		Send           string `json:"send,omitempty"`
+		Recv           string `json:"recv"`
+		Timeout        int    `json:"timeout,omitempty"`
+		TargetPort     int32  `json:"targetPort,omitempty"`
+		Path           string `json:"path,omitempty"`
+		AutoHostHeader bool   `json:"-" yaml:"autoHostHeader,omitempty"`
+		TargetAddress  string `json:"targetAddress,omitempty"`
+		Transparent    bool   `json:"transparent,omitempty"`
+		InUse          bool   `json:"-"`
+		// adaptive response time settings
+		Adaptive                bool   `json:"adaptive,omitempty"`
+		AdaptiveDivergenceType  string `json:"adaptiveDivergenceType,omitempty"`
+		AdaptiveDivergenceValue *int   `json:"adaptiveDivergenceValue,omitempty"`
+		AdaptiveLimit           *int   `json:"adaptiveLimit,omitempty"`
 	}
 	MonitorName struct {
 		Name string `json:"name"`
 		//Reference is used to link existing health monitor on bigip
 		Reference string `json:"reference,omitempty"`
+		// MemberAddresses restricts the monitor to the members of the addresses, meant for the
+		// node addresses of nodeport mode as the pod addresses of cluster mode aren't stable
+		MemberAddresses []string `json:"memberAddresses,omitempty"`
 	}
 	// Monitors  is slice of monitor
 	Monitors []Monitor
@@ -382,9 +600,17 @@ type (
 		extdSpecMap               extendedSpecMap
 		invertedNamespaceLabelMap map[string]string
 		svcResourceCache          map[string]map[string]struct{}
+		// secret name as key, resources consuming the secret as value
+		secretResourceCache map[string]map[resourceRef]struct{}
 		// key of the map is IPSpec.Key
 		ipamContext              map[string]ficV1.IPSpec
 		processedNativeResources map[resourceRef]struct{}
+		// partition and formatted AS3 name as keys, source names formatted to the name as value
+		as3NameSources map[string]map[string]map[string]struct{}
+		// keys of the configmaps contributing to the base route config of the global configmap
+		baseRouteCMKeys []string
+		// pool member address maps of the global configmap
+		poolMemberAddressMaps []PoolMemberAddressMap
 	}
 
 	// key is group identifier
@@ -397,13 +623,17 @@ type (
 		global     *ExtendedRouteGroupSpec
 		namespaces []string
 		partition  string
+		// partition overrides of the namespaces matching partition labels
+		namespacePartitions map[string]string
 	}
 
 	// This is the format for each item in the health monitor annotation used
-	// in the ServiceType LB objects.
+	// in the ServiceType LB objects. Port is required when the annotation is
+	// a list of monitors, each applied to the service port it is keyed by.
 	ServiceTypeLBHealthMonitor struct {
-		Interval int `json:"interval"`
-		Timeout  int `json:"timeout"`
+		Port     int32 `json:"port,omitempty"`
+		Interval int   `json:"interval"`
+		Timeout  int   `json:"timeout"`
 	}
 
 	// Rule config for a Policy
@@ -411,26 +641,50 @@ type (
 		Name       string       `json:"name"`
 		FullURI    string       `json:"-"`
 		Ordinal    int          `json:"ordinal,omitempty"`
+		Event      string       `json:"event,omitempty"`
 		Actions    []*action    `json:"actions,omitempty"`
 		Conditions []*condition `json:"conditions,omitempty"`
 	}
 
+	// RequestHeader is a request header inserted by a route
+	RequestHeader struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	// actionHeader is the name and the inserted value of the header of an action
+	actionHeader struct {
+		Name  string `json:"name"`
+		Value string `json:"value,omitempty"`
+	}
+
 	// action config for a Rule
 	action struct {
-		Name      string `json:"name"`
-		Pool      string `json:"pool,omitempty"`
-		HTTPHost  bool   `json:"httpHost,omitempty"`
-		HttpReply bool   `json:"httpReply,omitempty"`
-		HTTPURI   bool   `json:"httpUri,omitempty"`
-		Forward   bool   `json:"forward,omitempty"`
-		Location  string `json:"location,omitempty"`
-		Path      string `json:"path,omitempty"`
-		Redirect  bool   `json:"redirect,omitempty"`
-		Replace   bool   `json:"replace,omitempty"`
-		Request   bool   `json:"request,omitempty"`
-		Reset     bool   `json:"reset,omitempty"`
-		Select    bool   `json:"select,omitempty"`
-		Value     string `json:"value,omitempty"`
+		Name       string `json:"name"`
+		Pool       string `json:"pool,omitempty"`
+		HTTPHeader bool   `json:"httpHeader,omitempty"`
+		HTTPHost   bool   `json:"httpHost,omitempty"`
+		HttpReply  bool   `json:"httpReply,omitempty"`
+		HTTPURI    bool   `json:"httpUri,omitempty"`
+		HTTPPath   bool   `json:"httpPath,omitempty"`
+		Forward    bool   `json:"forward,omitempty"`
+		Insert     bool   `json:"insert,omitempty"`
+		Location   string `json:"location,omitempty"`
+		Path       string `json:"path,omitempty"`
+		Redirect   bool   `json:"redirect,omitempty"`
+		Remove     bool   `json:"remove,omitempty"`
+		Replace    bool   `json:"replace,omitempty"`
+		Request    bool   `json:"request,omitempty"`
+		Reset      bool   `json:"reset,omitempty"`
+		Response   bool   `json:"response,omitempty"`
+		Select     bool   `json:"select,omitempty"`
+		Value      string `json:"value,omitempty"`
+		// Persist is the hashing algorithm persisting the connections by the hash of PersistKey
+		Persist    string `json:"persist,omitempty"`
+		PersistKey string `json:"persistKey,omitempty"`
+		Timeout    int32  `json:"timeout,omitempty"`
+		// Header is the header inserted or removed by an httpHeader action
+		Header *actionHeader `json:"header,omitempty"`
 	}
 
 	// condition config for a Rule
@@ -442,6 +696,8 @@ type (
 		EndsWith        bool     `json:"endsWith,omitempty"`
 		External        bool     `json:"external,omitempty"`
 		HTTPHost        bool     `json:"httpHost,omitempty"`
+		HTTPHeader      bool     `json:"httpHeader,omitempty"`
+		Contains        bool     `json:"contains,omitempty"`
 		Host            bool     `json:"host,omitempty"`
 		HTTPURI         bool     `json:"httpUri,omitempty"`
 		Index           int      `json:"index,omitempty"`
@@ -528,19 +784,33 @@ type (
 
 	// SSL Profile loaded from Secret or Route object
 	CustomProfile struct {
-		Name          string `json:"name"`
-		Partition     string `json:"-"`
-		Context       string `json:"context"` // 'clientside', 'serverside', or 'all'
-		Cert          string `json:"cert"`
-		Key           string `json:"key"`
-		Ciphers       string `json:"ciphers,omitempty"`
-		CipherGroup   string `json:"cipherGroup,omitempty"`
-		TLS1_3Enabled bool   `json:"tls1_3Enabled"`
-		ServerName    string `json:"serverName,omitempty"`
-		SNIDefault    bool   `json:"sniDefault,omitempty"`
-		PeerCertMode  string `json:"peerCertMode,omitempty"`
-		CAFile        string `json:"caFile,omitempty"`
-		ChainCA       string `json:"chainCA,omitempty"`
+		Name          string   `json:"name"`
+		Partition     string   `json:"-"`
+		Context       string   `json:"context"` // 'clientside', 'serverside', or 'all'
+		Cert          string   `json:"cert"`
+		Key           string   `json:"key"`
+		Ciphers       string   `json:"ciphers,omitempty"`
+		CipherGroup   string   `json:"cipherGroup,omitempty"`
+		TLS1_3Enabled bool     `json:"tls1_3Enabled"`
+		ServerName    string   `json:"serverName,omitempty"`
+		SNIDefault    bool     `json:"sniDefault,omitempty"`
+		PeerCertMode  string   `json:"peerCertMode,omitempty"`
+		CAFile        string   `json:"caFile,omitempty"`
+		ChainCA       string   `json:"chainCA,omitempty"`
+		ALPNProtocols []string `json:"alpnProtocols,omitempty"`
+		TLSOptions    []string `json:"tlsOptions,omitempty"`
+		// DisableRenegotiation disables TLS renegotiation, which is enabled by default
+		DisableRenegotiation bool `json:"disableRenegotiation,omitempty"`
+		// IgnoreServerNameCheck accepts the server certificate of the backends regardless of its name
+		IgnoreServerNameCheck bool `json:"ignoreServerNameCheck,omitempty"`
+		// PathServerNames are the server names presented in SNI to the backends of the
+		// pool paths, each of them gets a serverssl profile besides the one of ServerName
+		PathServerNames []string `json:"pathServerNames,omitempty"`
+		// SNIServerNames are the host names the clientssl profile is selected for with SNI
+		SNIServerNames []string `json:"sniServerNames,omitempty"`
+		// PairedProfile is the clientssl profile of the certificate of the other key type
+		// whose server names this profile shares
+		PairedProfile string `json:"pairedProfile,omitempty"`
 	}
 
 	portStruct struct {
@@ -578,14 +848,17 @@ type (
 	ProcessedHostPath struct {
 		sync.Mutex
 		processedHostPathMap map[string]metav1.Time
+		// priority of the route owning the host path
+		processedHostPathPriority map[string]int
 	}
 )
 
 type (
 	Services        []v1.Service
 	RouteBackendCxt struct {
-		Weight int
-		Name   string
+		Weight  int
+		Name    string
+		Balance string
 	}
 )
 
@@ -611,6 +884,12 @@ type (
 		tenantPriorityMap map[string]int
 		// retryTenantDeclMap holds tenant name and its agent Config,tenant details
 		retryTenantDeclMap map[string]*tenantParams
+		// lastSyncTime returns the last sync time of the controller exposed on the health endpoint
+		lastSyncTime  func() time.Time
+		syncTimeMutex sync.RWMutex
+		// ltmConfig holds the latest config of all partitions, as the config requests
+		// carry only the partitions updated since the previous request
+		ltmConfig LTMConfig
 	}
 
 	AgentParams struct {
@@ -730,6 +1009,12 @@ type (
 		Egress  *as3ResourcePointer `json:"egress,omitempty"`
 	}
 
+	// as3ClonePools maps to Clone_Pools in AS3 Resources
+	as3ClonePools struct {
+		Ingress *as3ResourcePointer `json:"ingress,omitempty"`
+		Egress  *as3ResourcePointer `json:"egress,omitempty"`
+	}
+
 	// as3Action maps to Policy_Action in AS3 Resources
 	as3Action struct {
 		Type     string                  `json:"type,omitempty"`
@@ -739,6 +1024,16 @@ type (
 		Enabled  *bool                   `json:"enabled,omitempty"`
 		Location string                  `json:"location,omitempty"`
 		Replace  *as3ActionReplaceMap    `json:"replace,omitempty"`
+		Insert   *as3ActionReplaceMap    `json:"insert,omitempty"`
+		Remove   *as3ActionReplaceMap    `json:"remove,omitempty"`
+		Carp     *as3ActionPersist       `json:"carp,omitempty"`
+		Hash     *as3ActionPersist       `json:"hash,omitempty"`
+	}
+
+	// as3ActionPersist maps to the hash and carp options of Policy_Action_Persist in AS3 Resources
+	as3ActionPersist struct {
+		Key     string `json:"key"`
+		Timeout int32  `json:"timeout"`
 	}
 
 	as3ActionReplaceMap struct {
@@ -789,14 +1084,23 @@ type (
 		LoadBalancingMode string               `json:"loadBalancingMode,omitempty"`
 		Members           []as3PoolMember      `json:"members,omitempty"`
 		Monitors          []as3ResourcePointer `json:"monitors,omitempty"`
+		ServiceDownAction string               `json:"serviceDownAction,omitempty"`
+		ReselectTries     int32                `json:"reselectTries,omitempty"`
+		MinimumMonitors   int                  `json:"minimumMonitors,omitempty"`
+		Remark            string               `json:"remark,omitempty"`
 	}
 
 	// as3PoolMember maps to Pool_Member in AS3 Resources
 	as3PoolMember struct {
-		AddressDiscovery string   `json:"addressDiscovery,omitempty"`
-		ServerAddresses  []string `json:"serverAddresses,omitempty"`
-		ServicePort      int32    `json:"servicePort,omitempty"`
-		ShareNodes       bool     `json:"shareNodes,omitempty"`
+		AddressDiscovery string               `json:"addressDiscovery,omitempty"`
+		ServerAddresses  []string             `json:"serverAddresses,omitempty"`
+		ServicePort      int32                `json:"servicePort,omitempty"`
+		ShareNodes       bool                 `json:"shareNodes,omitempty"`
+		AdminState       string               `json:"adminState,omitempty"`
+		Ratio            int                  `json:"ratio,omitempty"`
+		Remark           string               `json:"remark,omitempty"`
+		Monitors         []as3ResourcePointer `json:"monitors,omitempty"`
+		MinimumMonitors  int                  `json:"minimumMonitors,omitempty"`
 	}
 
 	// as3ResourcePointer maps to following in AS3 Resources
@@ -816,33 +1120,48 @@ type (
 	// - Service_TCP
 	// - Service_UDP
 	as3Service struct {
-		Layer4                 string               `json:"layer4,omitempty"`
-		Source                 string               `json:"source,omitempty"`
-		TranslateServerAddress bool                 `json:"translateServerAddress,omitempty"`
-		TranslateServerPort    bool                 `json:"translateServerPort,omitempty"`
-		Class                  string               `json:"class,omitempty"`
-		VirtualAddresses       []as3MultiTypeParam  `json:"virtualAddresses,omitempty"`
-		VirtualPort            int                  `json:"virtualPort,omitempty"`
-		SNAT                   as3MultiTypeParam    `json:"snat,omitempty"`
-		PolicyEndpoint         as3MultiTypeParam    `json:"policyEndpoint,omitempty"`
-		ClientTLS              as3MultiTypeParam    `json:"clientTLS,omitempty"`
-		ServerTLS              as3MultiTypeParam    `json:"serverTLS,omitempty"`
-		IRules                 as3MultiTypeParam    `json:"iRules,omitempty"`
-		Redirect80             *bool                `json:"redirect80,omitempty"`
-		Pool                   string               `json:"pool,omitempty"`
-		WAF                    as3MultiTypeParam    `json:"policyWAF,omitempty"`
-		Firewall               as3MultiTypeParam    `json:"policyFirewallEnforced,omitempty"`
-		LogProfiles            []as3ResourcePointer `json:"securityLogProfiles,omitempty"`
-		ProfileL4              as3MultiTypeParam    `json:"profileL4,omitempty"`
-		AllowVLANs             []as3ResourcePointer `json:"allowVlans,omitempty"`
-		PersistenceMethods     *[]string            `json:"persistenceMethods,omitempty"`
-		ProfileTCP             as3MultiTypeParam    `json:"profileTCP,omitempty"`
-		ProfileUDP             as3MultiTypeParam    `json:"profileUDP,omitempty"`
-		ProfileHTTP            as3MultiTypeParam    `json:"profileHTTP,omitempty"`
-		ProfileHTTP2           as3MultiTypeParam    `json:"profileHTTP2,omitempty"`
-		ProfileMultiplex       as3MultiTypeParam    `json:"profileMultiplex,omitempty"`
-		ProfileDOS             as3MultiTypeParam    `json:"profileDOS,omitempty"`
-		ProfileBotDefense      as3MultiTypeParam    `json:"profileBotDefense,omitempty"`
+		Layer4                 string                      `json:"layer4,omitempty"`
+		Source                 string                      `json:"source,omitempty"`
+		TranslateServerAddress *bool                       `json:"translateServerAddress,omitempty"`
+		TranslateServerPort    *bool                       `json:"translateServerPort,omitempty"`
+		Class                  string                      `json:"class,omitempty"`
+		VirtualAddresses       []as3MultiTypeParam         `json:"virtualAddresses,omitempty"`
+		VirtualPort            int                         `json:"virtualPort,omitempty"`
+		SNAT                   as3MultiTypeParam           `json:"snat,omitempty"`
+		PolicyEndpoint         as3MultiTypeParam           `json:"policyEndpoint,omitempty"`
+		ClientTLS              as3MultiTypeParam           `json:"clientTLS,omitempty"`
+		ServerTLS              as3MultiTypeParam           `json:"serverTLS,omitempty"`
+		IRules                 as3MultiTypeParam           `json:"iRules,omitempty"`
+		Redirect80             *bool                       `json:"redirect80,omitempty"`
+		Pool                   string                      `json:"pool,omitempty"`
+		WAF                    as3MultiTypeParam           `json:"policyWAF,omitempty"`
+		Firewall               as3MultiTypeParam           `json:"policyFirewallEnforced,omitempty"`
+		LogProfiles            []as3ResourcePointer        `json:"securityLogProfiles,omitempty"`
+		ProfileTrafficLog      as3MultiTypeParam           `json:"profileTrafficLog,omitempty"`
+		ProfileL4              as3MultiTypeParam           `json:"profileL4,omitempty"`
+		AllowVLANs             *[]as3ResourcePointer       `json:"allowVlans,omitempty"`
+		RejectVLANs            []as3ResourcePointer        `json:"rejectVlans,omitempty"`
+		PersistenceMethods     *[]as3MultiTypeParam        `json:"persistenceMethods,omitempty"`
+		ProfileTCP             as3MultiTypeParam           `json:"profileTCP,omitempty"`
+		ProfileUDP             as3MultiTypeParam           `json:"profileUDP,omitempty"`
+		ProfileSCTP            as3MultiTypeParam           `json:"profileSCTP,omitempty"`
+		ProfileHTTP            as3MultiTypeParam           `json:"profileHTTP,omitempty"`
+		ProfileHTTP2           as3MultiTypeParam           `json:"profileHTTP2,omitempty"`
+		ProfileMultiplex       as3MultiTypeParam           `json:"profileMultiplex,omitempty"`
+		ProfileAnalytics       as3MultiTypeParam           `json:"profileAnalytics,omitempty"`
+		ProfileAnalyticsTcp    as3MultiTypeParam           `json:"profileAnalyticsTcp,omitempty"`
+		ProfileDOS             as3MultiTypeParam           `json:"profileDOS,omitempty"`
+		ProfileBotDefense      as3MultiTypeParam           `json:"profileBotDefense,omitempty"`
+		Remark                 string                      `json:"remark,omitempty"`
+		Metadata               map[string]as3MetadataValue `json:"metadata,omitempty"`
+		Enable                 *bool                       `json:"enable,omitempty"`
+		ClonePools             *as3ClonePools              `json:"clonePools,omitempty"`
+		RateLimit              int32                       `json:"rateLimit,omitempty"`
+	}
+
+	// as3MetadataValue maps to the metadata value of a Service in AS3 Resources
+	as3MetadataValue struct {
+		Value string `json:"value"`
 	}
 
 	// as3ServiceAddress maps to VirtualAddress in AS3 Resources
@@ -861,19 +1180,45 @@ type (
 	// - Monitor_HTTP
 	// - Monitor_HTTPS
 	as3Monitor struct {
-		Class             string  `json:"class,omitempty"`
-		Interval          int     `json:"interval,omitempty"`
-		MonitorType       string  `json:"monitorType,omitempty"`
-		TargetAddress     *string `json:"targetAddress,omitempty"`
-		Timeout           int     `json:"timeout,omitempty"`
-		TimeUnitilUp      *int    `json:"timeUntilUp,omitempty"`
-		Adaptive          *bool   `json:"adaptive,omitempty"`
-		Dscp              *int    `json:"dscp,omitempty"`
-		Receive           string  `json:"receive"`
-		Send              string  `json:"send"`
-		TargetPort        int32   `json:"targetPort,omitempty"`
-		ClientCertificate string  `json:"clientCertificate,omitempty"`
-		Ciphers           string  `json:"ciphers,omitempty"`
+		Class             string              `json:"class,omitempty"`
+		Interval          int                 `json:"interval,omitempty"`
+		MonitorType       string              `json:"monitorType,omitempty"`
+		TargetAddress     *string             `json:"targetAddress,omitempty"`
+		Timeout           int                 `json:"timeout,omitempty"`
+		TimeUnitilUp      *int                `json:"timeUntilUp,omitempty"`
+		Adaptive          *bool               `json:"adaptive,omitempty"`
+		Dscp              *int                `json:"dscp,omitempty"`
+		Receive           string              `json:"receive"`
+		Send              string              `json:"send"`
+		TargetPort        int32               `json:"targetPort,omitempty"`
+		ClientCertificate string              `json:"clientCertificate,omitempty"`
+		Ciphers           string              `json:"ciphers,omitempty"`
+		ClientTLS         *as3ResourcePointer `json:"clientTLS,omitempty"`
+		Transparent       *bool               `json:"transparent,omitempty"`
+
+		AdaptiveDivergenceType         string `json:"adaptiveDivergenceType,omitempty"`
+		AdaptiveDivergenceMilliseconds int    `json:"adaptiveDivergenceMilliseconds,omitempty"`
+		AdaptiveDivergencePercentage   int    `json:"adaptiveDivergencePercentage,omitempty"`
+		AdaptiveLimitMilliseconds      int    `json:"adaptiveLimitMilliseconds,omitempty"`
+	}
+
+	// as3ProfileL4 maps to L4_Profile in AS3 Resources
+	as3ProfileL4 struct {
+		Class               string `json:"class,omitempty"`
+		LooseInitialization *bool  `json:"looseInitialization,omitempty"`
+		ResetOnTimeout      *bool  `json:"resetOnTimeout,omitempty"`
+		IdleTimeout         *int32 `json:"idleTimeout,omitempty"`
+		TCPHandshakeTimeout *int32 `json:"tcpHandshakeTimeout,omitempty"`
+	}
+
+	// as3Persist maps to Persist in AS3 Resources
+	as3Persist struct {
+		Class                       string `json:"class,omitempty"`
+		PersistenceMethod           string `json:"persistenceMethod,omitempty"`
+		Duration                    int    `json:"duration,omitempty"`
+		MatchAcrossVirtualPorts     bool   `json:"matchAcrossVirtualPorts,omitempty"`
+		MatchAcrossPools            bool   `json:"matchAcrossPools,omitempty"`
+		MatchAcrossVirtualAddresses bool   `json:"matchAcrossVirtualAddresses,omitempty"`
 	}
 
 	// as3CABundle maps to CA_Bundle in AS3 Resources
@@ -882,6 +1227,20 @@ type (
 		Bundle string `json:"bundle,omitempty"`
 	}
 
+	// as3HTTPProfile maps to HTTP_Profile in AS3 Resources
+	as3HTTPProfile struct {
+		Class             string `json:"class,omitempty"`
+		XForwardedFor     bool   `json:"xForwardedFor"`
+		ServerHeaderValue string `json:"serverHeaderValue,omitempty"`
+		ProxyType         string `json:"proxyType,omitempty"`
+	}
+
+	// as3HTTP2Profile maps to HTTP2_Profile in AS3 Resources
+	as3HTTP2Profile struct {
+		Class          string `json:"class,omitempty"`
+		ActivationMode string `json:"activationMode,omitempty"`
+	}
+
 	// as3Certificate maps to Certificate in AS3 Resources
 	as3Certificate struct {
 		Class       string            `json:"class,omitempty"`
@@ -897,21 +1256,37 @@ type (
 		Ciphers       string                     `json:"ciphers,omitempty"`
 		CipherGroup   *as3ResourcePointer        `json:"cipherGroup,omitempty"`
 		TLS1_3Enabled bool                       `json:"tls1_3Enabled,omitempty"`
+		// protocol versions disabled with the TLS options of the clientssl profiles
+		SSLEnabled     *bool `json:"sslEnabled,omitempty"`
+		SSL3Enabled    *bool `json:"ssl3Enabled,omitempty"`
+		TLS1_0Enabled  *bool `json:"tls1_0Enabled,omitempty"`
+		TLS1_1Enabled  *bool `json:"tls1_1Enabled,omitempty"`
+		TLS1_2Enabled  *bool `json:"tls1_2Enabled,omitempty"`
+		DTLSEnabled    *bool `json:"dtlsEnabled,omitempty"`
+		DTLS1_2Enabled *bool `json:"dtls1_2Enabled,omitempty"`
+		// client certificate authentication
+		AuthenticationMode    string              `json:"authenticationMode,omitempty"`
+		AuthenticationTrustCA *as3ResourcePointer `json:"authenticationTrustCA,omitempty"`
+		RenegotiationEnabled  *bool               `json:"renegotiationEnabled,omitempty"`
 	}
 
 	// as3TLSServerCertificates maps to TLS_Server_certificates in AS3 Resources
 	as3TLSServerCertificates struct {
 		Certificate string `json:"certificate,omitempty"`
+		MatchToSNI  string `json:"matchToSNI,omitempty"`
 	}
 
 	// as3TLSClient maps to TLS_Client in AS3 Resources
 	as3TLSClient struct {
-		Class               string              `json:"class,omitempty"`
-		TrustCA             *as3ResourcePointer `json:"trustCA,omitempty"`
-		ValidateCertificate bool                `json:"validateCertificate,omitempty"`
-		Ciphers             string              `json:"ciphers,omitempty"`
-		CipherGroup         *as3ResourcePointer `json:"cipherGroup,omitempty"`
-		TLS1_3Enabled       bool                `json:"tls1_3Enabled,omitempty"`
+		Class                string              `json:"class,omitempty"`
+		TrustCA              *as3ResourcePointer `json:"trustCA,omitempty"`
+		ValidateCertificate  bool                `json:"validateCertificate,omitempty"`
+		ServerName           string              `json:"serverName,omitempty"`
+		SendSNI              string              `json:"sendSNI,omitempty"`
+		Ciphers              string              `json:"ciphers,omitempty"`
+		CipherGroup          *as3ResourcePointer `json:"cipherGroup,omitempty"`
+		TLS1_3Enabled        bool                `json:"tls1_3Enabled,omitempty"`
+		RenegotiationEnabled *bool               `json:"renegotiationEnabled,omitempty"`
 	}
 
 	// as3DataGroup maps to Data_Group in AS3 Resources
@@ -938,6 +1313,12 @@ type (
 		Port    int32  `json:"port"`
 		SvcPort int32  `json:"svcPort,omitempty"`
 		Session string `json:"session,omitempty"`
+		Ratio   int    `json:"ratio,omitempty"`
+		// Cluster identifies the cluster the member belongs to
+		Cluster string `json:"cluster,omitempty"`
+		// MonitorNames and MinimumMonitors replace the monitors of the pool for the member
+		MonitorNames    []MonitorName `json:"monitors,omitempty"`
+		MinimumMonitors int           `json:"minimumMonitors,omitempty"`
 	}
 )
 
@@ -1020,11 +1401,27 @@ type (
 		caCertificate            string
 		destinationCACertificate string
 		tlsCipher                TLSCipher
+		// client certificate authentication of the clientssl profile
+		peerCertMode   string
+		clientCASecret string
+		// TLS renegotiation of the clientssl and serverssl profiles, enabled when not set
+		renegotiation *bool
+		// protocols negotiated with ALPN on the clientssl profile
+		alpnProtocols []string
+		// protocol versions disabled on the clientssl profile
+		tlsOptions []string
+		// server name check of the backend certificates on the serverssl profile
+		serverName            string
+		ignoreServerNameCheck bool
+		// secret of the certificate of the other key type served along with the one of clientSSL
+		pairedClientSSL string
 	}
 
 	poolPathRef struct {
 		path     string
 		poolName string
+		// serverName is presented in SNI to the backends of the pool with reencrypt termination
+		serverName string
 	}
 
 	TLSContext struct {
@@ -1039,6 +1436,9 @@ type (
 		httpTraffic      string
 		poolPathRefs     []poolPathRef
 		bigIPSSLProfiles BigIPSSLProfiles
+		redirectCode     int32
+		serverNames      []string
+		redirectTarget   string
 	}
 )
 
@@ -1046,12 +1446,25 @@ type (
 	extendedSpec struct {
 		ExtendedRouteGroupConfigs []ExtendedRouteGroupConfig `yaml:"extendedRouteSpec"`
 		BaseRouteConfig           `yaml:"baseRouteSpec"`
+		// BaseRouteConfigMaps are the namespace/name keys of the configmaps whose baseRouteSpec
+		// fragments are merged into the base route config in order, later ones override earlier ones
+		BaseRouteConfigMaps []string `yaml:"baseRouteSpecConfigMaps,omitempty"`
+		// PoolMemberAddressMaps remap the pool member addresses and can only be set in the global configmap
+		PoolMemberAddressMaps []PoolMemberAddressMap `yaml:"poolMemberAddressMaps,omitempty"`
+	}
+
+	// PoolMemberAddressMap remaps the pool member addresses of the From CIDR to the To CIDR
+	// keeping their host part, e.g. for the pod addresses which aren't routable from BIG-IP
+	PoolMemberAddressMap struct {
+		From string `yaml:"from"`
+		To   string `yaml:"to"`
 	}
 
 	ExtendedRouteGroupConfig struct {
-		Namespace              string `yaml:"namespace"`      // Group Identifier
-		NamespaceLabel         string `yaml:"namespaceLabel"` // Group Identifier
-		BigIpPartition         string `yaml:"bigIpPartition"` // bigip Partition
+		Namespace              string            `yaml:"namespace"`                 // Group Identifier
+		NamespaceLabel         string            `yaml:"namespaceLabel"`            // Group Identifier
+		BigIpPartition         string            `yaml:"bigIpPartition"`            // bigip Partition
+		PartitionLabels        map[string]string `yaml:"partitionLabels,omitempty"` // namespace label to bigip Partition
 		ExtendedRouteGroupSpec `yaml:",inline"`
 	}
 
@@ -1063,9 +1476,34 @@ type (
 		SNAT             string   `yaml:"snat"`
 		WAF              string   `yaml:"waf"`
 		IRules           []string `yaml:"iRules,omitempty"`
-		TLS              TLS      `yaml:"tls"`
-		HealthMonitors   Monitors `yaml:"healthMonitors,omitempty"`
-		Meta             Meta
+		// MandatoryIRules are attached ahead of all other iRules and can only be set in the global configMap
+		MandatoryIRules  []string       `yaml:"mandatoryIRules,omitempty"`
+		TLS              TLS            `yaml:"tls"`
+		HealthMonitors   Monitors       `yaml:"healthMonitors,omitempty"`
+		ABPersistence    *ABPersistence `yaml:"abPersistence,omitempty"`
+		HTTPRedirectCode int32          `yaml:"httpRedirectCode,omitempty"`
+		Enabled          *bool          `yaml:"enabled,omitempty"`
+		// DefaultPassthroughPool receives the TLS connections whose server name matches no route
+		DefaultPassthroughPool *DefaultPassthroughPool `yaml:"defaultPassthroughPool,omitempty"`
+		// HTTPSOnly suppresses the HTTP virtual regardless of the insecure policies of the routes,
+		// it can only be set in the global configMap
+		HTTPSOnly bool `yaml:"httpsOnly,omitempty"`
+		Meta      Meta
+	}
+
+	// DefaultPassthroughPool is the service of a route group namespace used as the default passthrough pool
+	DefaultPassthroughPool struct {
+		Service          string `yaml:"service"`
+		ServiceNamespace string `yaml:"serviceNamespace"`
+		// ServicePort defaults to the first port of the service
+		ServicePort int32 `yaml:"servicePort,omitempty"`
+	}
+
+	// ABPersistence persists the backend of A/B deployment routes for a client in a cookie
+	ABPersistence struct {
+		CookieName string `yaml:"cookieName,omitempty"`
+		// TTL of the cookie in seconds, cookie expires with the session if not set
+		TTL int `yaml:"ttl,omitempty"`
 	}
 
 	Meta struct {
@@ -1086,6 +1524,8 @@ type (
 		TLSVersion  string `yaml:"tlsVersion,omitempty"`
 		Ciphers     string `yaml:"ciphers,omitempty"`
 		CipherGroup string `yaml:"cipherGroup,omitempty"` // by default this is bigip reference
+		// protocol versions disabled on the clientssl profiles, e.g. no-tlsv1
+		TLSOptions []string `yaml:"tlsOptions,omitempty"`
 	}
 )
 
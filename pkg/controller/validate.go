@@ -59,6 +59,13 @@ func (ctlr *Controller) checkValidVirtualServer(
 		}
 	}
 
+	switch vsResource.Spec.PoolMemberType {
+	case "", Cluster, NodePort, NodePortLocal:
+	default:
+		log.Errorf("Invalid poolMemberType value for virtual server %s. Supported values are cluster, nodeport and nodeportlocal only", vsName)
+		return false
+	}
+
 	return true
 }
 
@@ -106,6 +113,13 @@ func (ctlr *Controller) checkValidTransportServer(
 		return false
 	}
 
+	switch tsResource.Spec.PoolMemberType {
+	case "", Cluster, NodePort:
+	default:
+		log.Errorf("Invalid poolMemberType value for transport server %s. Supported values are cluster and nodeport only", vsName)
+		return false
+	}
+
 	return true
 }
 
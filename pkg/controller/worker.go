@@ -18,10 +18,15 @@ package controller
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"math"
+	"net"
 	"sort"
 	"strings"
 	"time"
@@ -30,11 +35,15 @@ import (
 
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 
 	ficV1 "github.com/F5Networks/f5-ipam-controller/pkg/ipamapis/apis/fic/v1"
 	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/config/apis/cis/v1"
+	bigIPPrometheus "github.com/F5Networks/k8s-bigip-ctlr/pkg/prometheus"
 	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
+	routeapi "github.com/openshift/api/route/v1"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 )
@@ -97,7 +106,8 @@ func (ctlr *Controller) processCustomResource() bool {
 	// During Init time, just accumulate all the poolMembers by processing only services
 	if ctlr.initState && rKey.kind != Namespace {
 		if rKey.kind != Service {
-			ctlr.rscQueue.AddRateLimited(key)
+			// deferring is not a failure, so it does not count towards the retries of the resource
+			ctlr.rscQueue.AddAfter(key, initStateRequeueDelay)
 			return true
 		}
 		ctlr.initialSvcCount--
@@ -112,6 +122,7 @@ func (ctlr *Controller) processCustomResource() bool {
 	}
 
 	// Check the type of resource and process accordingly.
+	ctlr.resources.cacheMutex.Lock()
 	switch rKey.kind {
 	case VirtualServer:
 		virtual := rKey.rsc.(*cisapiv1.VirtualServer)
@@ -128,6 +139,9 @@ func (ctlr *Controller) processCustomResource() bool {
 				delete(ctlr.resources.processedNativeResources, rscRefKey)
 			}
 		}
+		if rscDelete {
+			ctlr.deleteSecretResource(rscRefKey)
+		}
 
 		err := ctlr.processVirtualServers(virtual, rscDelete)
 		if err != nil {
@@ -273,8 +287,20 @@ func (ctlr *Controller) processCustomResource() bool {
 
 		_ = ctlr.processService(svc, ep, rscDelete)
 
+		// named target ports of LB services are resolved against the endpoints
+		if svc.Spec.Type == v1.ServiceTypeLoadBalancer && hasNamedTargetPort(svc) {
+			err := ctlr.processLBServices(svc, false)
+			if err != nil {
+				utilruntime.HandleError(fmt.Errorf("Sync %v failed with %v", key, err))
+				isError = true
+			}
+		}
+
 		// once we fetch the VS, just update the endpoints instead of processing them entirely
 		ctlr.updatePoolMembersForVirtuals(svc)
+	case K8sSecret:
+		secret := rKey.rsc.(*v1.Secret)
+		ctlr.processSecret(secret, rscDelete)
 	case Pod:
 		pod := rKey.rsc.(*v1.Pod)
 		_ = ctlr.processPod(pod, rscDelete)
@@ -366,29 +392,53 @@ func (ctlr *Controller) processCustomResource() bool {
 	default:
 		log.Errorf("Unknown resource Kind: %v", rKey.kind)
 	}
+	if rscDelete {
+		// names of the deleted resources don't disambiguate the names of other resources anymore
+		ctlr.resources.pruneAS3NameSources()
+	}
+	ctlr.resources.cacheMutex.Unlock()
 
 	if isError {
-		ctlr.rscQueue.AddRateLimited(key)
+		ctlr.requeueFailedResource(ctlr.rscQueue, key)
 	} else {
 		ctlr.rscQueue.Forget(key)
 	}
 
-	if ctlr.rscQueue.Len() == 0 && ctlr.resources.isConfigUpdated() {
-		config := ResourceConfigRequest{
-			ltmConfig:          ctlr.resources.getLTMConfigDeepCopy(),
-			shareNodes:         ctlr.shareNodes,
-			gtmConfig:          ctlr.resources.getGTMConfigCopy(),
-			defaultRouteDomain: ctlr.defaultRouteDomain,
-		}
-		go ctlr.TeemData.PostTeemsData()
-		config.reqId = ctlr.enqueueReq(config)
-		ctlr.Agent.PostConfig(config)
-		ctlr.initState = false
-		ctlr.resources.updateCaches()
+	if ctlr.rscQueue.Len() == 0 {
+		ctlr.postResourceConfigRequest()
 	}
 	return true
 }
 
+// requeueFailedResource requeues a resource that failed to be processed with rate limiting,
+// the resource is dropped from the queue once it has been retried maxResourceRetries times
+func (ctlr *Controller) requeueFailedResource(queue workqueue.RateLimitingInterface, key interface{}) {
+	rKey := key.(*rqKey)
+	if queue.NumRequeues(key) >= ctlr.maxResourceRetries {
+		log.Errorf("[CORE] Dropping %v %v/%v after %v retries, it will be processed again on its next update",
+			rKey.kind, rKey.namespace, rKey.rscName, ctlr.maxResourceRetries)
+		bigIPPrometheus.ResourceDrops.WithLabelValues(rKey.kind).Inc()
+		queue.Forget(key)
+		ctlr.reportDroppedResource(rKey)
+		return
+	}
+	bigIPPrometheus.ResourceRetries.WithLabelValues(rKey.kind).Inc()
+	queue.AddRateLimited(key)
+}
+
+// reportDroppedResource updates the status of a resource dropped after exhausting its retries
+func (ctlr *Controller) reportDroppedResource(rKey *rqKey) {
+	message := fmt.Sprintf("Dropped after %v retries, please check logs for more information", ctlr.maxResourceRetries)
+	switch rsc := rKey.rsc.(type) {
+	case *cisapiv1.VirtualServer:
+		ctlr.updateVirtualServerStatus(rsc, rsc.Status.VSAddress, "Error", "RetriesExhausted", message)
+	case *cisapiv1.TransportServer:
+		ctlr.updateTransportServerStatus(rsc, rsc.Status.VSAddress, "Error", "RetriesExhausted", message)
+	case *routeapi.Route:
+		go ctlr.updateRouteAdmitStatus(rKey.namespace+"/"+rKey.rscName, "RetriesExhausted", message, v1.ConditionFalse)
+	}
+}
+
 // getServiceForEndpoints returns the service associated with endpoints.
 func (ctlr *Controller) getServiceForEndpoints(ep *v1.Endpoints) *v1.Service {
 
@@ -443,9 +493,10 @@ func (ctlr *Controller) updatePoolMembersForVirtuals(svc *v1.Service) {
 		freshRsCfg := &ResourceConfig{}
 		freshRsCfg.copyConfig(rsCfg)
 
-		if ctlr.PoolMemberType == NodePort {
+		poolMemberType := ctlr.getPoolMemberType(freshRsCfg.MetaData.poolMemberType)
+		if poolMemberType == NodePort {
 			ctlr.updatePoolMembersForNodePort(freshRsCfg, namespace)
-		} else if ctlr.PoolMemberType == NodePortLocal {
+		} else if poolMemberType == NodePortLocal {
 			//supported with antrea cni.
 			ctlr.updatePoolMembersForNPL(freshRsCfg, namespace)
 		} else {
@@ -520,6 +571,61 @@ func (ctlr *Controller) getVirtualsForTLSProfile(tls *cisapiv1.TLSProfile) []*ci
 	return virtualsForTLSProfile
 }
 
+// processSecret refreshes the secret in SSLContext and enqueues the resources consuming the secret,
+// a deleted secret is evicted from SSLContext so that the resources fetch it again
+func (ctlr *Controller) processSecret(secret *v1.Secret, isDelete bool) {
+	if cached, ok := ctlr.SSLContext[secret.Name]; ok && cached.Namespace == secret.Namespace {
+		if isDelete {
+			log.Debugf("Evicting deleted Secret %v/%v from SSLContext", secret.Namespace, secret.Name)
+			delete(ctlr.SSLContext, secret.Name)
+		} else {
+			ctlr.SSLContext[secret.Name] = secret
+		}
+	}
+	for _, rscRef := range ctlr.getResourcesForSecret(secret.Name) {
+		if rscRef.namespace != secret.Namespace {
+			continue
+		}
+		rscKey := rscRef.namespace + "/" + rscRef.name
+		switch rscRef.kind {
+		case VirtualServer:
+			crInf, ok := ctlr.getNamespacedInformer(rscRef.namespace)
+			if !ok {
+				continue
+			}
+			obj, found, _ := crInf.vsInformer.GetIndexer().GetByKey(rscKey)
+			if !found {
+				continue
+			}
+			log.Debugf("Enqueueing VirtualServer %v for update of Secret %v", rscKey, secret.Name)
+			ctlr.rscQueue.Add(&rqKey{
+				namespace: rscRef.namespace,
+				kind:      VirtualServer,
+				rscName:   rscRef.name,
+				rsc:       obj,
+				event:     Update,
+			})
+		case Route:
+			nrInf, ok := ctlr.getNamespacedNativeInformer(rscRef.namespace)
+			if !ok || nrInf.routeInformer == nil {
+				continue
+			}
+			obj, found, _ := nrInf.routeInformer.GetIndexer().GetByKey(rscKey)
+			if !found {
+				continue
+			}
+			log.Debugf("Enqueueing Route %v for update of Secret %v", rscKey, secret.Name)
+			ctlr.nativeResourceQueue.Add(&rqKey{
+				namespace: rscRef.namespace,
+				kind:      Route,
+				rscName:   rscRef.name,
+				rsc:       obj,
+				event:     Update,
+			})
+		}
+	}
+}
+
 func (ctlr *Controller) getVirtualsForCustomPolicy(plc *cisapiv1.Policy) []*cisapiv1.VirtualServer {
 	nsVirtuals := ctlr.getAllVirtualServers(plc.Namespace)
 	if nil == nsVirtuals {
@@ -677,28 +783,99 @@ func getVirtualServersForTLSProfile(allVirtuals []*cisapiv1.VirtualServer,
 	tlsNamespace := tls.ObjectMeta.Namespace
 
 	for _, vs := range allVirtuals {
-		if vs.ObjectMeta.Namespace == tlsNamespace && vs.Spec.TLSProfileName == tlsName {
-			found := false
-			for _, host := range tls.Spec.Hosts {
-				if vs.Spec.Host == host {
-					result = append(result, vs)
-					found = true
-					break
-				}
+		if vs.ObjectMeta.Namespace != tlsNamespace {
+			continue
+		}
+		for _, name := range getTLSProfileNames(vs) {
+			if name != tlsName {
+				continue
 			}
-			if !found {
-				log.Errorf("TLSProfile hostname is not same as virtual host %s for profile %s", vs.Spec.Host, vs.Spec.TLSProfileName)
+			if len(getTLSProfileServerNames(vs, tls)) > 0 {
+				result = append(result, vs)
+			} else {
+				log.Errorf("TLSProfile hostname is not same as virtual host %s for profile %s", vs.Spec.Host, tlsName)
 			}
+			break
 		}
 	}
 
 	return result
 }
 
+// getTLSProfileNames returns the names of the TLSProfiles referenced by the VirtualServer
+func getTLSProfileNames(vs *cisapiv1.VirtualServer) []string {
+	var names []string
+	if vs.Spec.TLSProfileName != "" {
+		names = append(names, vs.Spec.TLSProfileName)
+	}
+	for _, name := range vs.Spec.TLSProfileNames {
+		if name != "" && !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// getTLSProfileServerNames returns the hosts of the VirtualServer served by the TLSProfile
+func getTLSProfileServerNames(vs *cisapiv1.VirtualServer, tlsProfile *cisapiv1.TLSProfile) []string {
+	if len(vs.Spec.Host) == 0 {
+		return tlsProfile.Spec.Hosts
+	}
+	var serverNames []string
+	for _, host := range tlsProfile.Spec.Hosts {
+		serverName := ""
+		switch {
+		case host == vs.Spec.Host:
+			serverName = host
+		case strings.HasPrefix(vs.Spec.Host, "*") && strings.HasSuffix(host, strings.TrimPrefix(vs.Spec.Host, "*")):
+			// profile for one of the hosts of the wildcard virtual
+			serverName = host
+		case strings.HasPrefix(host, "*") && strings.HasSuffix(vs.Spec.Host, strings.TrimPrefix(host, "*")):
+			// wildcard profile covering the virtual host
+			serverName = vs.Spec.Host
+		}
+		if serverName != "" && !containsString(serverNames, serverName) {
+			serverNames = append(serverNames, serverName)
+		}
+	}
+	return serverNames
+}
+
+// getTLSProfilesForVirtualServer returns the TLSProfiles referenced by the VirtualServer,
+// all the profiles must be valid and use the same termination
+func (ctlr *Controller) getTLSProfilesForVirtualServer(
+	vs *cisapiv1.VirtualServer,
+	namespace string) []*cisapiv1.TLSProfile {
+	var tlsProfiles []*cisapiv1.TLSProfile
+	for _, tlsName := range getTLSProfileNames(vs) {
+		tlsProfile := ctlr.getTLSProfileByName(vs, namespace, tlsName)
+		if tlsProfile == nil {
+			return nil
+		}
+		if len(tlsProfiles) > 0 && tlsProfile.Spec.TLS.Termination != tlsProfiles[0].Spec.TLS.Termination {
+			log.Errorf("TLSProfile %s with termination %s is not consistent with TLSProfile %s with termination %s for virtual server %s",
+				tlsName, tlsProfile.Spec.TLS.Termination, tlsProfiles[0].Name, tlsProfiles[0].Spec.TLS.Termination, vs.ObjectMeta.Name)
+			return nil
+		}
+		tlsProfiles = append(tlsProfiles, tlsProfile)
+	}
+	return tlsProfiles
+}
+
 func (ctlr *Controller) getTLSProfileForVirtualServer(
 	vs *cisapiv1.VirtualServer,
 	namespace string) *cisapiv1.TLSProfile {
-	tlsName := vs.Spec.TLSProfileName
+	tlsProfiles := ctlr.getTLSProfilesForVirtualServer(vs, namespace)
+	if len(tlsProfiles) == 0 {
+		return nil
+	}
+	return tlsProfiles[0]
+}
+
+func (ctlr *Controller) getTLSProfileByName(
+	vs *cisapiv1.VirtualServer,
+	namespace string,
+	tlsName string) *cisapiv1.TLSProfile {
 	tlsKey := fmt.Sprintf("%s/%s", namespace, tlsName)
 
 	// Initialize CustomResource Informer for required namespace
@@ -725,11 +902,27 @@ func (ctlr *Controller) getTLSProfileForVirtualServer(
 	tlsProfile := obj.(*cisapiv1.TLSProfile)
 
 	if tlsProfile.Spec.TLS.Reference == "secret" {
-		clientSecret, _ := ctlr.kubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), tlsProfile.Spec.TLS.ClientSSL, metav1.GetOptions{})
-		//validate clientSSL certificates and hostname
-		match := checkCertificateHost(vs.Spec.Host, clientSecret.Data["tls.crt"], clientSecret.Data["tls.key"])
-		if match == false {
-			return nil
+		// the RSA and ECDSA certificates of clientSSLs are validated alike
+		clientSSLs := tlsProfile.Spec.TLS.ClientSSLs
+		if tlsProfile.Spec.TLS.ClientSSL != "" {
+			clientSSLs = []string{tlsProfile.Spec.TLS.ClientSSL}
+		}
+		for _, clientSSL := range clientSSLs {
+			clientSecret, err := ctlr.kubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), clientSSL, metav1.GetOptions{})
+			if err != nil {
+				log.Errorf("Secret %s of TLSProfile %s not found: %v", clientSSL, tlsName, err)
+				return nil
+			}
+			//validate clientSSL certificates and hostname
+			match := checkCertificateHost(vs.Spec.Host, clientSecret.Data["tls.crt"], clientSecret.Data["tls.key"])
+			if match == false {
+				return nil
+			}
+			if ctlr.strictCertificateHost && !certificateCoversHost(vs.Spec.Host, clientSecret.Data["tls.crt"]) {
+				log.Errorf("Certificate of TLSProfile %s does not match host %s of virtual server %s",
+					tlsName, vs.Spec.Host, vs.Name)
+				return nil
+			}
 		}
 	}
 	if len(vs.Spec.Host) == 0 {
@@ -740,19 +933,9 @@ func (ctlr *Controller) getTLSProfileForVirtualServer(
 		return tlsProfile
 	}
 
-	for _, host := range tlsProfile.Spec.Hosts {
-		if host == vs.Spec.Host {
-			// TLSProfile Object
-			return tlsProfile
-		}
-		// check for wildcard match
-		if strings.HasPrefix(host, "*") {
-			host = strings.TrimPrefix(host, "*")
-			if strings.HasSuffix(vs.Spec.Host, host) {
-				// TLSProfile Object
-				return tlsProfile
-			}
-		}
+	if len(getTLSProfileServerNames(vs, tlsProfile)) > 0 {
+		// TLSProfile Object
+		return tlsProfile
 	}
 	log.Errorf("TLSProfile %s with host %s does not match with virtual server %s host.", tlsName, vs.Spec.Host, vs.ObjectMeta.Name)
 	return nil
@@ -760,7 +943,7 @@ func (ctlr *Controller) getTLSProfileForVirtualServer(
 }
 
 func isTLSVirtualServer(vrt *cisapiv1.VirtualServer) bool {
-	return len(vrt.Spec.TLSProfileName) != 0
+	return len(getTLSProfileNames(vrt)) != 0
 }
 
 func doesVSHandleHTTP(vrt *cisapiv1.VirtualServer) bool {
@@ -882,6 +1065,27 @@ func (ctlr *Controller) processVirtualServers(
 			}
 		}
 	}
+	// VirtualServers whose TLSProfiles can't be resolved are reported with an error status,
+	// they are served without TLS when failing open, otherwise none of the virtuals are created
+	tlsUnresolved := make(map[string]struct{})
+	for i, vrt := range virtuals {
+		if !isTLSVirtualServer(vrt) || len(ctlr.getTLSProfilesForVirtualServer(vrt, vrt.Namespace)) > 0 {
+			continue
+		}
+		message := fmt.Sprintf("TLSProfile %v can't be resolved", strings.Join(getTLSProfileNames(vrt), ", "))
+		ctlr.updateVirtualServerStatus(vrt, ip, "Error", "TLSProfileNotResolved", message)
+		if !ctlr.tlsProfileFailOpen {
+			log.Errorf("Cannot Publish VirtualServer %s/%s: %v", vrt.Namespace, vrt.Name, message)
+			return nil
+		}
+		log.Warningf("Serving VirtualServer %s/%s without TLS: %v", vrt.Namespace, vrt.Name, message)
+		vrt = vrt.DeepCopy()
+		vrt.Spec.TLSProfileName = ""
+		vrt.Spec.TLSProfileNames = nil
+		virtuals[i] = vrt
+		tlsUnresolved[vrt.Namespace+"/"+vrt.Name] = struct{}{}
+	}
+
 	// Depending on the ports defined, TLS type or Unsecured we will populate the resource config.
 	portStructs := ctlr.virtualPorts(virtual)
 
@@ -892,9 +1096,13 @@ func (ctlr *Controller) processVirtualServers(
 		// TODO: Add Route Domain
 		var rsName string
 		if virtual.Spec.VirtualServerName != "" {
-			rsName = formatCustomVirtualServerName(
+			rsName = ctlr.resources.getUniqueAS3Name(
+				ctlr.Partition,
 				virtual.Spec.VirtualServerName,
-				portStruct.port,
+				formatCustomVirtualServerName(
+					virtual.Spec.VirtualServerName,
+					portStruct.port,
+				),
 			)
 		} else {
 			rsName = formatVirtualServerName(
@@ -907,7 +1115,7 @@ func (ctlr *Controller) processVirtualServers(
 		// Delete rsCfg if it is HTTP rsCfg and the CR VirtualServer does not handle HTTPTraffic
 		if (len(virtuals) == 0) ||
 			(portStruct.protocol == HTTP && !doVSHandleHTTP(virtuals, virtual)) ||
-			(isVSDeleted && portStruct.protocol == HTTPS && !doVSUseSameHTTPSPort(virtuals, virtual)) {
+			((isVSDeleted || len(tlsUnresolved) > 0) && portStruct.protocol == HTTPS && !doVSUseSameHTTPSPort(virtuals, virtual)) {
 			var hostnames []string
 			rsMap := ctlr.resources.getPartitionResourceMap(ctlr.Partition)
 
@@ -916,6 +1124,10 @@ func (ctlr *Controller) processVirtualServers(
 			}
 			ctlr.deleteSvcDepResource(rsName, rsMap[rsName])
 			ctlr.deleteVirtualServer(ctlr.Partition, rsName)
+			if virtual.Spec.VirtualServerName != "" {
+				ctlr.deleteRenamedVirtualServers("",
+					getVirtualDestination(ctlr.Partition, ip, portStruct.port, virtual.Spec.RouteDomain))
+			}
 			if len(hostnames) > 0 {
 				ctlr.ProcessAssociatedExternalDNS(hostnames)
 			}
@@ -925,8 +1137,12 @@ func (ctlr *Controller) processVirtualServers(
 		rsCfg := &ResourceConfig{}
 		rsCfg.Virtual.Partition = ctlr.Partition
 		rsCfg.MetaData.ResourceType = VirtualServer
-		rsCfg.Virtual.Enabled = true
+		rsCfg.Virtual.Enabled = isVirtualEnabled(virtual.Spec.Enabled)
 		rsCfg.Virtual.Name = rsName
+		if virtual.Spec.RouteDomain != nil {
+			routeDomain := *virtual.Spec.RouteDomain
+			rsCfg.Virtual.RouteDomain = &routeDomain
+		}
 		rsCfg.MetaData.hosts = append(rsCfg.MetaData.hosts, virtual.Spec.Host)
 		rsCfg.MetaData.Protocol = portStruct.protocol
 		rsCfg.MetaData.httpTraffic = virtual.Spec.HTTPTraffic
@@ -944,6 +1160,7 @@ func (ctlr *Controller) processVirtualServers(
 			err := ctlr.handleVSResourceConfigForPolicy(rsCfg, plc)
 			if err != nil {
 				processingError = true
+				log.Errorf("%v", err)
 				break
 			}
 		}
@@ -955,35 +1172,40 @@ func (ctlr *Controller) processVirtualServers(
 
 		for _, vrt := range virtuals {
 			passthroughVS := false
-			var tlsProf *cisapiv1.TLSProfile
+			var tlsProfs []*cisapiv1.TLSProfile
 			if isTLSVirtualServer(vrt) {
 				// Handle TLS configuration for VirtualServer Custom Resource
-				tlsProf = ctlr.getTLSProfileForVirtualServer(vrt, vrt.Namespace)
-				if tlsProf == nil {
+				tlsProfs = ctlr.getTLSProfilesForVirtualServer(vrt, vrt.Namespace)
+				if len(tlsProfs) == 0 {
 					// Processing failed
 					// Stop processing further virtuals
 					processingError = true
 					break
 				}
-				if tlsProf.Spec.TLS.Termination == TLSPassthrough {
+				if tlsProfs[0].Spec.TLS.Termination == TLSPassthrough {
 					passthroughVS = true
 				}
 			}
 
 			log.Debugf("Processing Virtual Server %s for port %v",
 				vrt.ObjectMeta.Name, portStruct.port)
-			rsCfg.MetaData.baseResources[vrt.Namespace+"/"+vrt.Name] = VirtualServer
+			// the error status of the VirtualServers served without TLS is kept
+			if _, ok := tlsUnresolved[vrt.Namespace+"/"+vrt.Name]; !ok {
+				rsCfg.MetaData.baseResources[vrt.Namespace+"/"+vrt.Name] = VirtualServer
+			}
 			err := ctlr.prepareRSConfigFromVirtualServer(
 				rsCfg,
 				vrt,
 				passthroughVS,
 			)
 			if err != nil {
+				log.Errorf("%v", err)
+				ctlr.updateVirtualServerStatus(vrt, ip, "Error", "InvalidConfig", err.Error())
 				processingError = true
 				break
 			}
 
-			if tlsProf != nil {
+			for _, tlsProf := range tlsProfs {
 				processed := ctlr.handleVirtualServerTLS(rsCfg, vrt, tlsProf, ip)
 				if !processed {
 					// Processing failed
@@ -993,7 +1215,10 @@ func (ctlr *Controller) processVirtualServers(
 				}
 
 				log.Debugf("Updated Virtual %s with TLSProfile %s",
-					vrt.ObjectMeta.Name, vrt.Spec.TLSProfileName)
+					vrt.ObjectMeta.Name, tlsProf.ObjectMeta.Name)
+			}
+			if processingError {
+				break
 			}
 
 			ctlr.updateSvcDepResources(rsName, rsCfg)
@@ -1006,6 +1231,13 @@ func (ctlr *Controller) processVirtualServers(
 
 		}
 
+		if !processingError {
+			if err := rsCfg.validateHTTP2Monitors(); err != nil {
+				log.Errorf("%v", err)
+				processingError = true
+			}
+		}
+
 		if processingError {
 			log.Errorf("Cannot Publish VirtualServer %s", virtual.ObjectMeta.Name)
 			break
@@ -1014,9 +1246,10 @@ func (ctlr *Controller) processVirtualServers(
 		// Save ResourceConfig in temporary Map
 		vsMap[rsName] = rsCfg
 
-		if ctlr.PoolMemberType == NodePort {
+		poolMemberType := ctlr.getPoolMemberType(rsCfg.MetaData.poolMemberType)
+		if poolMemberType == NodePort {
 			ctlr.updatePoolMembersForNodePort(rsCfg, virtual.ObjectMeta.Namespace)
-		} else if ctlr.PoolMemberType == NodePortLocal {
+		} else if poolMemberType == NodePortLocal {
 			//supported with antrea cni.
 			ctlr.updatePoolMembersForNPL(rsCfg, virtual.ObjectMeta.Namespace)
 		} else {
@@ -1033,6 +1266,9 @@ func (ctlr *Controller) processVirtualServers(
 			if _, ok := rsMap[rsName]; !ok {
 				hostnames = rsCfg.MetaData.hosts
 			}
+			if virtual.Spec.VirtualServerName != "" {
+				ctlr.deleteRenamedVirtualServers(rsName, rsCfg.Virtual.Destination)
+			}
 			rsMap[rsName] = rsCfg
 		}
 
@@ -1495,6 +1731,17 @@ func (ctlr *Controller) updatePoolMembersForNodePort(
 	}
 
 	for index, pool := range rsCfg.Pools {
+		if len(pool.WeightedBackends) > 0 {
+			if pool.ServiceNamespace == namespace {
+				rsCfg.MetaData.Active = true
+				rsCfg.Pools[index].Members = ctlr.getWeightedPoolMembers(pool,
+					func(pmi poolMembersInfo) ([]PoolMember, bool) {
+						return ctlr.getNodePortPoolMembers(pmi, pool)
+					})
+			}
+			continue
+		}
+
 		svcName := pool.ServiceName
 		svcKey := pool.ServiceNamespace + "/" + svcName
 
@@ -1510,14 +1757,27 @@ func (ctlr *Controller) updatePoolMembersForNodePort(
 				svcKey)
 		}
 
-		for _, svcPort := range poolMemInfo.portSpec {
-			if svcPort.TargetPort == pool.ServicePort {
-				rsCfg.MetaData.Active = true
-				rsCfg.Pools[index].Members =
-					ctlr.getEndpointsForNodePort(svcPort.NodePort, pool.NodeMemberLabel)
+		if members, ok := ctlr.getNodePortPoolMembers(poolMemInfo, pool); ok {
+			rsCfg.MetaData.Active = true
+			rsCfg.Pools[index].Members = members
+		}
+	}
+}
+
+// getNodePortPoolMembers returns the node members of the service port of the pool
+func (ctlr *Controller) getNodePortPoolMembers(poolMemInfo poolMembersInfo, pool Pool) ([]PoolMember, bool) {
+	for _, svcPort := range poolMemInfo.portSpec {
+		if svcPort.TargetPort == pool.ServicePort {
+			members := ctlr.getEndpointsForNodePort(svcPort.NodePort, pool.NodeMemberLabel)
+			if !poolMemInfo.drainExpiry.IsZero() {
+				for i := range members {
+					members[i].Session = MemberSessionDisabled
+				}
 			}
+			return applyMemberSessions(members, poolMemInfo.memberSessions), true
 		}
 	}
+	return nil, false
 }
 
 // updatePoolMembersForCluster updates the pool with pool members for a
@@ -1527,6 +1787,17 @@ func (ctlr *Controller) updatePoolMembersForCluster(
 	namespace string,
 ) {
 	for index, pool := range rsCfg.Pools {
+		if len(pool.WeightedBackends) > 0 {
+			if pool.ServiceNamespace == namespace {
+				rsCfg.MetaData.Active = true
+				rsCfg.Pools[index].Members = ctlr.getWeightedPoolMembers(pool,
+					func(pmi poolMembersInfo) ([]PoolMember, bool) {
+						return ctlr.getClusterPoolMembers(pmi, pool.ServicePort)
+					})
+			}
+			continue
+		}
+
 		svcName := pool.ServiceName
 		svcKey := pool.ServiceNamespace + "/" + svcName
 
@@ -1537,14 +1808,210 @@ func (ctlr *Controller) updatePoolMembersForCluster(
 			continue
 		}
 
-		for ref, mems := range poolMemInfo.memberMap {
-			if ref.name != pool.ServicePort.StrVal && ref.port != pool.ServicePort.IntVal {
+		if members, ok := ctlr.getClusterPoolMembers(poolMemInfo, pool.ServicePort); ok {
+			rsCfg.MetaData.Active = true
+			rsCfg.Pools[index].Members = members
+		}
+	}
+}
+
+// getClusterPoolMembers returns the endpoint members of the service port of the pool
+func (ctlr *Controller) getClusterPoolMembers(poolMemInfo poolMembersInfo, servicePort intstr.IntOrString) ([]PoolMember, bool) {
+	var members []PoolMember
+	found := false
+	for ref, mems := range poolMemInfo.memberMap {
+		if !ref.servesPort(servicePort) {
+			continue
+		}
+		found = true
+		members = ctlr.translatePoolMembers(ctlr.tagPoolMembers(applyMemberSessions(mems, poolMemInfo.memberSessions)))
+	}
+	return members, found
+}
+
+// getWeightedPoolMembers returns the members of the weighted backends of the pool, the ratio of
+// each backend is divided across its members and scaled to the 1-100 range of the member ratio
+func (ctlr *Controller) getWeightedPoolMembers(
+	pool Pool,
+	getMembers func(poolMemInfo poolMembersInfo) ([]PoolMember, bool),
+) []PoolMember {
+	var backendMembers [][]PoolMember
+	var shares []float64
+	maxShare := 0.0
+	for _, be := range pool.WeightedBackends {
+		poolMemInfo, ok := ctlr.resources.poolMemCache[pool.ServiceNamespace+"/"+be.ServiceName]
+		if !ok {
+			continue
+		}
+		mems, _ := getMembers(poolMemInfo)
+		if len(mems) == 0 {
+			continue
+		}
+		share := float64(be.Ratio) / float64(len(mems))
+		if share > maxShare {
+			maxShare = share
+		}
+		backendMembers = append(backendMembers, mems)
+		shares = append(shares, share)
+	}
+	members := []PoolMember{}
+	for i, mems := range backendMembers {
+		ratio := int(math.Round(shares[i] * 100 / maxShare))
+		if ratio == 0 {
+			ratio = 1
+		}
+		for _, mem := range mems {
+			mem.Ratio = ratio
+			members = append(members, mem)
+		}
+	}
+	return members
+}
+
+// hasNamedTargetPort returns whether any port of the service has a named target port
+func hasNamedTargetPort(svc *v1.Service) bool {
+	for _, port := range svc.Spec.Ports {
+		if port.TargetPort.Type == intstr.String {
+			return true
+		}
+	}
+	return false
+}
+
+// servesPort returns whether the endpoint port serves the service port of the pool,
+// named service ports are matched by name and the others by port number
+func (ref portRef) servesPort(servicePort intstr.IntOrString) bool {
+	if servicePort.Type == intstr.String {
+		return ref.name == servicePort.StrVal
+	}
+	return ref.port == servicePort.IntVal
+}
+
+// getPoolMemberType returns the pool member type overridden by a resource,
+// falling back to the pool member type of the controller
+func (ctlr *Controller) getPoolMemberType(memberType string) string {
+	if memberType != "" {
+		return memberType
+	}
+	return ctlr.PoolMemberType
+}
+
+// tagPoolMembers returns a copy of the pool members tagged with the cluster name,
+// the pool member cache is shared across virtuals and is left untouched
+func (ctlr *Controller) tagPoolMembers(mems []PoolMember) []PoolMember {
+	if ctlr.clusterName == "" {
+		return mems
+	}
+	taggedMems := make([]PoolMember, len(mems))
+	for i, mem := range mems {
+		mem.Cluster = ctlr.clusterName
+		taggedMems[i] = mem
+	}
+	return taggedMems
+}
+
+// translatePoolMembers returns a copy of the pool members with their addresses mapped
+// through the address translator of the controller, if any
+func (ctlr *Controller) translatePoolMembers(mems []PoolMember) []PoolMember {
+	if ctlr.translateMemberAddress == nil {
+		return mems
+	}
+	translatedMems := make([]PoolMember, len(mems))
+	for i, mem := range mems {
+		mem.Address = ctlr.translateMemberAddress(mem.Address)
+		translatedMems[i] = mem
+	}
+	return translatedMems
+}
+
+// newCIDRAddressTranslator returns an address translator which maps the addresses of the
+// first matching From CIDR into the To CIDR, the other addresses are left unchanged
+func newCIDRAddressTranslator(addressMaps []PoolMemberAddressMap) (addressTranslator, error) {
+	type cidrMap struct {
+		from, to *net.IPNet
+	}
+	var cidrMaps []cidrMap
+	for _, am := range addressMaps {
+		_, from, err := net.ParseCIDR(am.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from CIDR %v", am.From)
+		}
+		_, to, err := net.ParseCIDR(am.To)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to CIDR %v", am.To)
+		}
+		fromOnes, fromBits := from.Mask.Size()
+		toOnes, toBits := to.Mask.Size()
+		if fromOnes != toOnes || fromBits != toBits {
+			return nil, fmt.Errorf("CIDRs %v and %v must have the same address family and prefix length", am.From, am.To)
+		}
+		cidrMaps = append(cidrMaps, cidrMap{from: from, to: to})
+	}
+	return func(address string) string {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			return address
+		}
+		for _, cm := range cidrMaps {
+			if !cm.from.Contains(ip) {
 				continue
 			}
-			rsCfg.MetaData.Active = true
-			rsCfg.Pools[index].Members = mems
+			if ip4 := ip.To4(); ip4 != nil && len(cm.from.IP) == net.IPv4len {
+				ip = ip4
+			}
+			mapped := make(net.IP, len(ip))
+			for i := range ip {
+				mapped[i] = cm.to.IP[i] | (ip[i] &^ cm.from.Mask[i])
+			}
+			return mapped.String()
+		}
+		return address
+	}, nil
+}
+
+// applyMemberSessions returns a copy of the pool members with the session states given through
+// the pool member session annotation, members disabled while draining are not enabled again
+func applyMemberSessions(mems []PoolMember, sessions map[string]string) []PoolMember {
+	if len(sessions) == 0 {
+		return mems
+	}
+	sessionMems := make([]PoolMember, len(mems))
+	for i, mem := range mems {
+		if session, ok := sessions[mem.Address]; ok && session != MemberSessionEnabled {
+			mem.Session = session
+		}
+		sessionMems[i] = mem
+	}
+	return sessionMems
+}
+
+// getMemberSessions returns the session states of the pool members from the pool member session
+// annotation, which maps the member address to one of enabled, disabled and force-offline
+func getMemberSessions(rscKey string, annotations map[string]string) map[string]string {
+	annotation, ok := annotations[PoolMemberSessionAnnotation]
+	if !ok {
+		return nil
+	}
+	var states map[string]string
+	if err := json.Unmarshal([]byte(annotation), &states); err != nil {
+		log.Errorf("Invalid %v annotation in %v: %v", PoolMemberSessionAnnotation, rscKey, err)
+		return nil
+	}
+	sessions := make(map[string]string, len(states))
+	for addr, state := range states {
+		switch state {
+		case "enabled":
+			sessions[addr] = MemberSessionEnabled
+		case "disabled":
+			sessions[addr] = MemberSessionDisabled
+		case "force-offline":
+			sessions[addr] = MemberSessionForceOffline
+		default:
+			log.Errorf("Invalid session state %v of pool member %v in %v, supported states are "+
+				"enabled, disabled and force-offline", state, addr, rscKey)
 		}
 	}
+	return sessions
 }
 
 // updatePoolMembersForNodePortLocal updates the pool with pool members for a
@@ -1574,8 +2041,8 @@ func (ctlr *Controller) updatePoolMembersForNPL(
 				if svcPort.TargetPort == pool.ServicePort {
 					podPort := svcPort.TargetPort.IntVal
 					rsCfg.MetaData.Active = true
-					rsCfg.Pools[index].Members =
-						ctlr.getEndpointsForNPL(podPort, pods)
+					rsCfg.Pools[index].Members = applyMemberSessions(
+						ctlr.getEndpointsForNPL(podPort, pods), poolMemInfo.memberSessions)
 
 				}
 			}
@@ -1599,7 +2066,7 @@ func (ctlr *Controller) getEndpointsForNodePort(
 		member := PoolMember{
 			Address: v.Addr,
 			Port:    nodePort,
-			Session: "user-enabled",
+			Session: MemberSessionEnabled,
 		}
 		members = append(members, member)
 	}
@@ -1623,7 +2090,7 @@ func (ctlr *Controller) getEndpointsForNPL(
 				member := PoolMember{
 					Address: annotation.NodeIP,
 					Port:    annotation.NodePort,
-					Session: "user-enabled",
+					Session: MemberSessionEnabled,
 				}
 				members = append(members, member)
 			}
@@ -1642,6 +2109,16 @@ func containsNode(nodes []Node, name string) bool {
 	return false
 }
 
+// containsString returns true if the list contains the string.
+func containsString(list []string, str string) bool {
+	for _, item := range list {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
+
 // processTransportServers takes the Transport Server as input and processes all
 // associated TransportServers to create a resource config(Internal DataStructure)
 // or to update if exists already.
@@ -1666,6 +2143,11 @@ func (ctlr *Controller) processTransportServers(
 				vkey)
 			return nil
 		}
+		if err := validateTransportServer(virtual); err != nil {
+			log.Errorf("%v", err)
+			ctlr.updateTransportServerStatus(virtual, virtual.Spec.VirtualServerAddress, "Error", "InvalidConfig", err.Error())
+			return nil
+		}
 	}
 	ctlr.TeemData.Lock()
 	ctlr.TeemData.ResourceType.TransportServer[virtual.ObjectMeta.Namespace] = len(ctlr.getAllTransportServers(virtual.Namespace))
@@ -1714,9 +2196,13 @@ func (ctlr *Controller) processTransportServers(
 
 	var rsName string
 	if virtual.Spec.VirtualServerName != "" {
-		rsName = formatCustomVirtualServerName(
+		rsName = ctlr.resources.getUniqueAS3Name(
+			ctlr.Partition,
 			virtual.Spec.VirtualServerName,
-			virtual.Spec.VirtualServerPort,
+			formatCustomVirtualServerName(
+				virtual.Spec.VirtualServerName,
+				virtual.Spec.VirtualServerPort,
+			),
 		)
 	} else {
 		rsName = formatVirtualServerName(
@@ -1729,14 +2215,22 @@ func (ctlr *Controller) processTransportServers(
 		rsMap := ctlr.resources.getPartitionResourceMap(ctlr.Partition)
 		ctlr.deleteSvcDepResource(rsName, rsMap[rsName])
 		ctlr.deleteVirtualServer(ctlr.Partition, rsName)
+		if virtual.Spec.VirtualServerName != "" {
+			ctlr.deleteRenamedVirtualServers("", getVirtualDestination(ctlr.Partition, ip,
+				virtual.Spec.VirtualServerPort, virtual.Spec.RouteDomain))
+		}
 		return nil
 	}
 
 	rsCfg := &ResourceConfig{}
 	rsCfg.Virtual.Partition = ctlr.Partition
 	rsCfg.MetaData.ResourceType = TransportServer
-	rsCfg.Virtual.Enabled = true
+	rsCfg.Virtual.Enabled = isVirtualEnabled(virtual.Spec.Enabled)
 	rsCfg.Virtual.Name = rsName
+	if virtual.Spec.RouteDomain != nil {
+		routeDomain := *virtual.Spec.RouteDomain
+		rsCfg.Virtual.RouteDomain = &routeDomain
+	}
 	rsCfg.MetaData.hosts = append(rsCfg.MetaData.hosts, virtual.Spec.Host)
 	rsCfg.Virtual.IpProtocol = virtual.Spec.Type
 	rsCfg.MetaData.namespace = virtual.ObjectMeta.Namespace
@@ -1766,18 +2260,22 @@ func (ctlr *Controller) processTransportServers(
 		virtual,
 	)
 	if err != nil {
-		log.Errorf("Cannot Publish TransportServer %s", virtual.ObjectMeta.Name)
+		log.Errorf("Cannot Publish TransportServer %s: %v", virtual.ObjectMeta.Name, err)
+		ctlr.updateTransportServerStatus(virtual, ip, "Error", "InvalidConfig", err.Error())
 		return nil
 	}
 
 	ctlr.updateSvcDepResources(rsName, rsCfg)
 
-	if ctlr.PoolMemberType == NodePort {
+	if ctlr.getPoolMemberType(rsCfg.MetaData.poolMemberType) == NodePort {
 		ctlr.updatePoolMembersForNodePort(rsCfg, virtual.ObjectMeta.Namespace)
 	} else {
 		ctlr.updatePoolMembersForCluster(rsCfg, virtual.ObjectMeta.Namespace)
 	}
 
+	if virtual.Spec.VirtualServerName != "" {
+		ctlr.deleteRenamedVirtualServers(rsName, rsCfg.Virtual.Destination)
+	}
 	rsMap := ctlr.resources.getPartitionResourceMap(ctlr.Partition)
 	rsMap[rsName] = rsCfg
 
@@ -2083,7 +2581,6 @@ func (ctlr *Controller) processLBServices(
 
 		rsCfg := &ResourceConfig{}
 		rsCfg.Virtual.Partition = ctlr.Partition
-		rsCfg.Virtual.IpProtocol = strings.ToLower(string(portSpec.Protocol))
 		rsCfg.MetaData.ResourceType = TransportServer
 		rsCfg.MetaData.namespace = svc.ObjectMeta.Namespace
 		rsCfg.Virtual.Enabled = true
@@ -2112,7 +2609,11 @@ func (ctlr *Controller) processLBServices(
 			break
 		}
 
-		_ = ctlr.prepareRSConfigFromLBService(rsCfg, svc, portSpec)
+		err = ctlr.prepareRSConfigFromLBService(rsCfg, svc, portSpec)
+		if err != nil {
+			log.Errorf("Cannot Publish LB Service %s: %v", svc.ObjectMeta.Name, err)
+			continue
+		}
 
 		ctlr.updateSvcDepResources(rsName, rsCfg)
 
@@ -2141,10 +2642,25 @@ func (ctlr *Controller) processService(
 	namespace := svc.Namespace
 	svcKey := svc.Namespace + "/" + svc.Name
 	if isSVCDeleted {
+		if pmi, ok := ctlr.resources.poolMemCache[svcKey]; ok && ctlr.poolMemberDrainTimeout > 0 {
+			if pmi.drainExpiry.IsZero() {
+				ctlr.drainPoolMembers(svc, pmi)
+				return nil
+			}
+			if time.Now().Before(pmi.drainExpiry) {
+				// pool members are still being drained
+				return nil
+			}
+		}
 		delete(ctlr.resources.poolMemCache, svcKey)
 		return nil
 	}
 
+	// Service is recreated while its pool members are being drained
+	if pmi, ok := ctlr.resources.poolMemCache[svcKey]; ok && pmi.drainTimer != nil {
+		pmi.drainTimer.Stop()
+	}
+
 	if eps == nil {
 		var epInf cache.SharedIndexInformer
 		switch ctlr.mode {
@@ -2172,9 +2688,17 @@ func (ctlr *Controller) processService(
 	}
 
 	pmi := poolMembersInfo{
-		svcType:   svc.Spec.Type,
-		portSpec:  svc.Spec.Ports,
-		memberMap: make(map[portRef][]PoolMember),
+		svcType:        svc.Spec.Type,
+		portSpec:       svc.Spec.Ports,
+		memberMap:      make(map[portRef][]PoolMember),
+		memberSessions: getMemberSessions(svcKey, svc.Annotations),
+	}
+	// session states given on the endpoints override the ones given on the service
+	for addr, session := range getMemberSessions(svcKey, eps.Annotations) {
+		if pmi.memberSessions == nil {
+			pmi.memberSessions = make(map[string]string)
+		}
+		pmi.memberSessions[addr] = session
 	}
 
 	nodes := ctlr.getNodesFromCache()
@@ -2187,13 +2711,14 @@ func (ctlr *Controller) processService(
 					member := PoolMember{
 						Address: addr.IP,
 						Port:    p.Port,
-						Session: "user-enabled",
+						Session: MemberSessionEnabled,
 					}
 					members = append(members, member)
 				}
 			}
+			// the addresses of a port may be spread across multiple subsets
 			portKey := portRef{name: p.Name, port: p.Port}
-			pmi.memberMap[portKey] = members
+			pmi.memberMap[portKey] = append(pmi.memberMap[portKey], members...)
 		}
 	}
 
@@ -2202,6 +2727,29 @@ func (ctlr *Controller) processService(
 	return nil
 }
 
+// drainPoolMembers disables the pool members of a deleted service so that in-flight
+// connections are served, and removes them once the drain timeout expires
+func (ctlr *Controller) drainPoolMembers(svc *v1.Service, pmi poolMembersInfo) {
+	svcKey := svc.Namespace + "/" + svc.Name
+	log.Debugf("Draining pool members of service %v for %v", svcKey, ctlr.poolMemberDrainTimeout)
+	drainedMemberMap := make(map[portRef][]PoolMember, len(pmi.memberMap))
+	for ref, mems := range pmi.memberMap {
+		drainedMems := make([]PoolMember, len(mems))
+		for i, mem := range mems {
+			mem.Session = MemberSessionDisabled
+			drainedMems[i] = mem
+		}
+		drainedMemberMap[ref] = drainedMems
+	}
+	pmi.memberMap = drainedMemberMap
+	pmi.drainExpiry = time.Now().Add(ctlr.poolMemberDrainTimeout)
+	pmi.drainTimer = time.AfterFunc(ctlr.poolMemberDrainTimeout, func() {
+		// Requeue the deleted service to remove the drained pool members
+		ctlr.enqueueDeletedService(svc)
+	})
+	ctlr.resources.poolMemCache[svcKey] = pmi
+}
+
 func (ctlr *Controller) processExternalDNS(edns *cisapiv1.ExternalDNS, isDelete bool) {
 
 	if processedWIP, ok := ctlr.resources.gtmConfig[edns.Spec.DomainName]; ok {
@@ -2272,10 +2820,10 @@ func (ctlr *Controller) processExternalDNS(edns *cisapiv1.ExternalDNS, isDelete
 					continue
 				}
 				log.Debugf("Adding WideIP Pool Member: %v", fmt.Sprintf("%v:/%v/Shared/%v",
-					pl.DataServerName, DEFAULT_PARTITION, vsName))
+					pl.DataServerName, ctlr.Partition, vsName))
 				pool.Members = append(
 					pool.Members,
-					fmt.Sprintf("%v:/%v/Shared/%v", pl.DataServerName, DEFAULT_PARTITION, vsName),
+					fmt.Sprintf("%v:/%v/Shared/%v", pl.DataServerName, ctlr.Partition, vsName),
 				)
 			}
 		}
@@ -2376,7 +2924,7 @@ func (ctlr *Controller) ProcessAssociatedExternalDNS(hostnames []string) {
 	}
 }
 
-//Validate certificate hostname
+//Validate certificate and key, a certificate not covering the host is only reported
 func checkCertificateHost(host string, certificate []byte, key []byte) bool {
 	cert, certErr := tls.X509KeyPair(certificate, key)
 	if certErr != nil {
@@ -2388,13 +2936,88 @@ func checkCertificateHost(host string, certificate []byte, key []byte) bool {
 		log.Errorf("failed to parse certificate; %s", err)
 		return false
 	}
-	ok := x509cert.VerifyHostname(host)
-	if ok != nil {
-		log.Debugf("Error: Hostname in virtualserver does not match with certificate hostname: %v", ok)
+	if !matchCertificateHost(host, x509cert) {
+		log.Warningf("Hostname %v does not match with certificate hostname", host)
 	}
 	return true
 }
 
+// certificateCoversHost checks that the PEM encoded certificate covers the host
+func certificateCoversHost(host string, certificate []byte) bool {
+	block, _ := pem.Decode(certificate)
+	if block == nil {
+		return false
+	}
+	x509cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+	return matchCertificateHost(host, x509cert)
+}
+
+// matchCertificateHost matches the host against the certificate names, resources without host
+// are not bound to a certificate hostname and a wildcard host is covered by the certificate of
+// any of its hosts
+func matchCertificateHost(host string, x509cert *x509.Certificate) bool {
+	if host == "" {
+		return true
+	}
+	if strings.HasPrefix(host, "*.") {
+		names := x509cert.DNSNames
+		if len(names) == 0 {
+			names = []string{x509cert.Subject.CommonName}
+		}
+		for _, name := range names {
+			if matchCertificateHostname(host, name) {
+				return true
+			}
+		}
+		return false
+	}
+	// VerifyHostname matches the host against the SANs with single level
+	// wildcard semantics, but ignores the CN of the certificate.
+	if x509cert.VerifyHostname(host) == nil {
+		return true
+	}
+	return len(x509cert.DNSNames) == 0 && matchCertificateHostname(x509cert.Subject.CommonName, host)
+}
+
+// getCertificateKeyType returns the key type of the certificate after checking it matches the key
+func getCertificateKeyType(certificate []byte, key []byte) (string, error) {
+	cert, err := tls.X509KeyPair(certificate, key)
+	if err != nil {
+		return "", err
+	}
+	switch cert.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		return CertificateKeyRSA, nil
+	case *ecdsa.PrivateKey:
+		return CertificateKeyECDSA, nil
+	}
+	return "", fmt.Errorf("unsupported key type, expected %v or %v", CertificateKeyRSA, CertificateKeyECDSA)
+}
+
+// matchCertificateHostname matches the host against a certificate name,
+// where a leading wildcard label matches exactly one label of the host.
+func matchCertificateHostname(pattern, host string) bool {
+	pattern = strings.TrimSuffix(strings.ToLower(pattern), ".")
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if pattern == "" || host == "" {
+		return false
+	}
+	if pattern == host {
+		return true
+	}
+	if !strings.HasPrefix(pattern, "*.") {
+		return false
+	}
+	idx := strings.Index(host, ".")
+	if idx <= 0 {
+		return false
+	}
+	return host[idx+1:] == pattern[2:]
+}
+
 func (ctlr *Controller) processIPAM(ipam *ficV1.IPAM) error {
 	var keysToProcess []string
 	for _, ipSpec := range ipam.Status.IPStatus {
@@ -2615,8 +3238,9 @@ func (ctlr *Controller) processIngressLink(
 		rsCfg.MetaData.ResourceType = "TransportServer"
 		rsCfg.MetaData.hosts = append(rsCfg.MetaData.hosts, ingLink.Spec.Host)
 		rsCfg.Virtual.Mode = "standard"
-		rsCfg.Virtual.TranslateServerAddress = true
-		rsCfg.Virtual.TranslateServerPort = true
+		translate := true
+		rsCfg.Virtual.TranslateServerAddress = &translate
+		rsCfg.Virtual.TranslateServerPort = &translate
 		rsCfg.Virtual.Source = "0.0.0.0/0"
 		rsCfg.Virtual.Enabled = true
 		rsCfg.Virtual.Name = rsName
@@ -2641,13 +3265,14 @@ func (ctlr *Controller) processIngressLink(
 			ServiceName:      svc.ObjectMeta.Name,
 			ServicePort:      svcPort,
 			ServiceNamespace: svc.ObjectMeta.Namespace,
+			Metadata:         ctlr.getPoolMetadata(svc.ObjectMeta.Namespace, svc.ObjectMeta.Name),
 		}
 		monitorName := fmt.Sprintf("%s_monitor", pool.Name)
 		rsCfg.Monitors = append(
 			rsCfg.Monitors,
 			Monitor{Name: monitorName, Partition: rsCfg.Virtual.Partition, Interval: 20,
 				Type: "http", Send: "GET /nginx-ready HTTP/1.1\r\n", Recv: "", Timeout: 10, TargetPort: targetPort})
-		pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: monitorName})
+		pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: JoinBigipPath(rsCfg.Virtual.Partition, monitorName)})
 		rsCfg.Virtual.PoolName = pool.Name
 		rsCfg.Pools = append(rsCfg.Pools, pool)
 		// Update rsMap with ResourceConfigs created for the current ingresslink virtuals
@@ -2939,32 +3564,60 @@ func getNodeport(svc *v1.Service, servicePort int32) int32 {
 }
 
 //Update virtual server status with virtual server address
-func (ctlr *Controller) updateVirtualServerStatus(vs *cisapiv1.VirtualServer, ip string, statusOk string) {
+func (ctlr *Controller) updateVirtualServerStatus(vs *cisapiv1.VirtualServer, ip, statusOk, reason, message string) {
 	// Set the vs status to include the virtual IP address
-	vsStatus := cisapiv1.VirtualServerStatus{VSAddress: ip, StatusOk: statusOk}
-	log.Debugf("Updating VirtualServer Status with %v for resource name:%v , namespace: %v", vsStatus, vs.Name, vs.Namespace)
-	vs.Status = vsStatus
-	vs.Status.VSAddress = ip
-	vs.Status.StatusOk = statusOk
-	_, updateErr := ctlr.kubeCRClient.CisV1().VirtualServers(vs.ObjectMeta.Namespace).UpdateStatus(context.TODO(), vs, metav1.UpdateOptions{})
-	if nil != updateErr {
+	vsStatus := cisapiv1.VirtualServerStatus{VSAddress: ip, StatusOk: statusOk, Reason: reason, Message: message}
+	for retryCount := 0; retryCount < 3; retryCount++ {
+		if vs.Status == vsStatus {
+			return
+		}
+		log.Debugf("Updating VirtualServer Status with %v for resource name:%v , namespace: %v", vsStatus, vs.Name, vs.Namespace)
+		vs = vs.DeepCopy()
+		vs.Status = vsStatus
+		_, updateErr := ctlr.kubeCRClient.CisV1().VirtualServers(vs.ObjectMeta.Namespace).UpdateStatus(context.TODO(), vs, metav1.UpdateOptions{})
+		if nil == updateErr {
+			return
+		}
 		log.Debugf("Error while updating virtual server status:%v", updateErr)
-		return
+		if !apierrors.IsConflict(updateErr) {
+			return
+		}
+		// retry with the latest copy of the virtual server
+		latest, err := ctlr.kubeCRClient.CisV1().VirtualServers(vs.ObjectMeta.Namespace).Get(context.TODO(), vs.Name, metav1.GetOptions{})
+		if nil != err {
+			log.Debugf("Error while fetching virtual server %v/%v:%v", vs.Namespace, vs.Name, err)
+			return
+		}
+		vs = latest
 	}
 }
 
 //Update Transport server status with virtual server address
-func (ctlr *Controller) updateTransportServerStatus(ts *cisapiv1.TransportServer, ip string, statusOk string) {
-	// Set the vs status to include the virtual IP address
-	tsStatus := cisapiv1.TransportServerStatus{VSAddress: ip, StatusOk: statusOk}
-	log.Debugf("Updating VirtualServer Status with %v for resource name:%v , namespace: %v", tsStatus, ts.Name, ts.Namespace)
-	ts.Status = tsStatus
-	ts.Status.VSAddress = ip
-	ts.Status.StatusOk = statusOk
-	_, updateErr := ctlr.kubeCRClient.CisV1().TransportServers(ts.ObjectMeta.Namespace).UpdateStatus(context.TODO(), ts, metav1.UpdateOptions{})
-	if nil != updateErr {
+func (ctlr *Controller) updateTransportServerStatus(ts *cisapiv1.TransportServer, ip, statusOk, reason, message string) {
+	// Set the ts status to include the virtual IP address
+	tsStatus := cisapiv1.TransportServerStatus{VSAddress: ip, StatusOk: statusOk, Reason: reason, Message: message}
+	for retryCount := 0; retryCount < 3; retryCount++ {
+		if ts.Status == tsStatus {
+			return
+		}
+		log.Debugf("Updating TransportServer Status with %v for resource name:%v , namespace: %v", tsStatus, ts.Name, ts.Namespace)
+		ts = ts.DeepCopy()
+		ts.Status = tsStatus
+		_, updateErr := ctlr.kubeCRClient.CisV1().TransportServers(ts.ObjectMeta.Namespace).UpdateStatus(context.TODO(), ts, metav1.UpdateOptions{})
+		if nil == updateErr {
+			return
+		}
 		log.Debugf("Error while updating Transport server status:%v", updateErr)
-		return
+		if !apierrors.IsConflict(updateErr) {
+			return
+		}
+		// retry with the latest copy of the transport server
+		latest, err := ctlr.kubeCRClient.CisV1().TransportServers(ts.ObjectMeta.Namespace).Get(context.TODO(), ts.Name, metav1.GetOptions{})
+		if nil != err {
+			log.Debugf("Error while fetching Transport server %v/%v:%v", ts.Namespace, ts.Name, err)
+			return
+		}
+		ts = latest
 	}
 }
 
@@ -3102,7 +3755,7 @@ func skipVirtual(currentVS *cisapiv1.VirtualServer, vrt *cisapiv1.VirtualServer)
 	}
 	if effectiveCurrentVSHTTPSPort == effectiveVrtVSHTTPSPort && effectiveCurrentVSHTTPPort != effectiveVrtVSHTTPPort {
 		// virtuals have HTTPS port is common
-		if currentVS.Spec.TLSProfileName == "" || vrt.Spec.TLSProfileName == "" {
+		if !isTLSVirtualServer(currentVS) || !isTLSVirtualServer(vrt) {
 			// One of the vs is an unsecured vs so common HTTPS port is insignificant for this vs
 			return true
 		}
@@ -3120,7 +3773,7 @@ func skipVirtual(currentVS *cisapiv1.VirtualServer, vrt *cisapiv1.VirtualServer)
 	if vrtVSHTTPTraffic == "" || vrtVSHTTPTraffic == "none" {
 		vrtVSHTTPTraffic = ""
 	}
-	if currentVS.Spec.TLSProfileName != "" && vrt.Spec.TLSProfileName != "" {
+	if isTLSVirtualServer(currentVS) && isTLSVirtualServer(vrt) {
 		if currentVSHTTPTraffic == "" || vrtVSHTTPTraffic == "" {
 			// both vs are secured vs but one/both of them doesn't handle HTTP traffic so common HTTP port is insignificant
 			return true
@@ -3128,11 +3781,11 @@ func skipVirtual(currentVS *cisapiv1.VirtualServer, vrt *cisapiv1.VirtualServer)
 		// both vs are secured and both of them handle HTTP traffic via the common HTTP port
 		return false
 	}
-	if currentVS.Spec.TLSProfileName != "" && currentVSHTTPTraffic == "" {
+	if isTLSVirtualServer(currentVS) && currentVSHTTPTraffic == "" {
 		// current vs is secured vs, and it doesn't handle HTTP traffic so common HTTP port is insignificant
 		return true
 	}
-	if vrt.Spec.TLSProfileName != "" && vrtVSHTTPTraffic == "" {
+	if isTLSVirtualServer(vrt) && vrtVSHTTPTraffic == "" {
 		// It's a secured vs, and it doesn't handle HTTP traffic so common HTTP port is insignificant
 		return true
 	}
@@ -3145,7 +3798,7 @@ func skipVirtual(currentVS *cisapiv1.VirtualServer, vrt *cisapiv1.VirtualServer)
 func doVSUseSameHTTPSPort(virtuals []*cisapiv1.VirtualServer, currentVirtual *cisapiv1.VirtualServer) bool {
 	effectiveCurrentVSHTTPSPort := getEffectiveHTTPSPort(currentVirtual)
 	for _, virtual := range virtuals {
-		if virtual.Spec.TLSProfileName != "" && effectiveCurrentVSHTTPSPort == getEffectiveHTTPSPort(virtual) {
+		if isTLSVirtualServer(virtual) && effectiveCurrentVSHTTPSPort == getEffectiveHTTPSPort(virtual) {
 			return true
 		}
 	}
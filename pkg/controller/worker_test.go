@@ -2,8 +2,17 @@ package controller
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/teem"
+	"math/big"
 	"reflect"
 	"sort"
 	"time"
@@ -13,15 +22,23 @@ import (
 	crdfake "github.com/F5Networks/k8s-bigip-ctlr/config/client/clientset/versioned/fake"
 	cisinfv1 "github.com/F5Networks/k8s-bigip-ctlr/config/client/informers/externalversions/cis/v1"
 	apm "github.com/F5Networks/k8s-bigip-ctlr/pkg/appmanager"
+	bigIPPrometheus "github.com/F5Networks/k8s-bigip-ctlr/pkg/prometheus"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 
 	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/config/apis/cis/v1"
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/test"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 var _ = Describe("Worker Tests", func() {
@@ -469,6 +486,51 @@ var _ = Describe("Worker Tests", func() {
 			Expect(res[1]).To(Equal(vrt3), "Wrong list of Virtual Servers")
 		})
 
+		It("VS with multiple TLSProfiles", func() {
+			tlsProf1 := test.NewTLSProfile("sampleTLS1", namespace, cisapiv1.TLSProfileSpec{
+				Hosts: []string{"foo.test.com"},
+				TLS: cisapiv1.TLS{
+					Termination: TLSEdge,
+					Reference:   BIGIP,
+					ClientSSL:   "/Common/foo-clientssl",
+				},
+			})
+			tlsProf2 := test.NewTLSProfile("sampleTLS2", namespace, cisapiv1.TLSProfileSpec{
+				Hosts: []string{"bar.test.com"},
+				TLS: cisapiv1.TLS{
+					Termination: TLSEdge,
+					Reference:   BIGIP,
+					ClientSSL:   "/Common/bar-clientssl",
+				},
+			})
+			vrt2 := test.NewVirtualServer(
+				"SampleVS2",
+				namespace,
+				cisapiv1.VirtualServerSpec{
+					Host:                 "*.test.com",
+					VirtualServerAddress: "1.2.3.5",
+					TLSProfileNames:      []string{"sampleTLS1", "sampleTLS2"},
+				})
+			Expect(isTLSVirtualServer(vrt2)).To(BeTrue(), "VS with TLSProfiles should be secured")
+			Expect(getTLSProfileNames(vrt2)).To(Equal([]string{"sampleTLS1", "sampleTLS2"}))
+			Expect(getTLSProfileServerNames(vrt2, tlsProf1)).To(Equal([]string{"foo.test.com"}))
+
+			res := getVirtualServersForTLSProfile([]*cisapiv1.VirtualServer{vrt1, vrt2}, tlsProf2)
+			Expect(res).To(Equal([]*cisapiv1.VirtualServer{vrt2}), "Wrong list of Virtual Servers")
+
+			_ = mockCtlr.crInformers["default"].tlsInformer.GetStore().Add(tlsProf1)
+			_ = mockCtlr.crInformers["default"].tlsInformer.GetStore().Add(tlsProf2)
+			tlsProfs := mockCtlr.getTLSProfilesForVirtualServer(vrt2, namespace)
+			Expect(tlsProfs).To(Equal([]*cisapiv1.TLSProfile{tlsProf1, tlsProf2}), "Wrong list of TLSProfiles")
+
+			// terminations must be consistent across the TLSProfiles
+			tlsProf2.Spec.TLS.Termination = TLSReencrypt
+			tlsProf2.Spec.TLS.ServerSSL = "/Common/serverssl"
+			_ = mockCtlr.crInformers["default"].tlsInformer.GetStore().Update(tlsProf2)
+			Expect(mockCtlr.getTLSProfilesForVirtualServer(vrt2, namespace)).To(BeNil(),
+				"TLSProfiles with different terminations should not be accepted")
+		})
+
 		It("VS Handling HTTP", func() {
 			Expect(doesVSHandleHTTP(vrt1)).To(BeTrue(), "HTTP VS in invalid")
 			vrt1.Spec.TLSProfileName = "TLSProf"
@@ -791,6 +853,292 @@ var _ = Describe("Worker Tests", func() {
 			Expect(len(mems)).To(Equal(0), "Wrong set of Endpoints for NodePort")
 		})
 
+		It("Drain pool members on service delete", func() {
+			mockCtlr.poolMemberDrainTimeout = 200 * time.Millisecond
+			mockCtlr.rscQueue = workqueue.NewNamedRateLimitingQueue(
+				workqueue.DefaultControllerRateLimiter(), "custom-resource-controller")
+			svcPorts := []v1.ServicePort{{Port: 80, Name: "port0"}}
+			eps := test.NewEndpoints("svc1", "1", "worker1", namespace,
+				[]string{"10.1.1.1", "10.1.1.2"}, []string{}, convertSvcPortsToEndpointPorts(svcPorts))
+			svcKey := namespace + "/svc1"
+
+			rsCfg := &ResourceConfig{}
+			rsCfg.Pools = Pools{
+				Pool{
+					Name:             "svc1_80_default",
+					ServiceName:      "svc1",
+					ServiceNamespace: namespace,
+					ServicePort:      intstr.IntOrString{IntVal: 80},
+				},
+			}
+
+			Expect(mockCtlr.processService(svc1, eps, false)).To(BeNil())
+			mockCtlr.updatePoolMembersForCluster(rsCfg, namespace)
+			Expect(len(rsCfg.Pools[0].Members)).To(Equal(2))
+			for _, mem := range rsCfg.Pools[0].Members {
+				Expect(mem.Session).To(Equal("user-enabled"))
+			}
+
+			// deleted service members are disabled during the drain window
+			Expect(mockCtlr.processService(svc1, nil, true)).To(BeNil())
+			Expect(mockCtlr.resources.poolMemCache).To(HaveKey(svcKey))
+			mockCtlr.updatePoolMembersForCluster(rsCfg, namespace)
+			Expect(len(rsCfg.Pools[0].Members)).To(Equal(2))
+			for _, mem := range rsCfg.Pools[0].Members {
+				Expect(mem.Session).To(Equal("user-disabled"))
+			}
+			// repeated delete event during the drain window does not remove the members
+			Expect(mockCtlr.processService(svc1, eps, true)).To(BeNil())
+			Expect(mockCtlr.resources.poolMemCache).To(HaveKey(svcKey))
+
+			// deleted service is requeued on expiry of the drain window and members are removed
+			Eventually(mockCtlr.rscQueue.Len, time.Second).Should(Equal(1))
+			key, _ := mockCtlr.rscQueue.Get()
+			Expect(key.(*rqKey).event).To(Equal(Delete))
+			Expect(mockCtlr.processService(svc1, nil, true)).To(BeNil())
+			Expect(mockCtlr.resources.poolMemCache).NotTo(HaveKey(svcKey))
+			mockCtlr.updatePoolMembersForCluster(rsCfg, namespace)
+			Expect(len(rsCfg.Pools[0].Members)).To(Equal(0))
+		})
+
+		It("Pool members from endpoint subsets serving the pool port", func() {
+			nodeName := "worker1"
+			eps := test.NewEndpoints("svc1", "1", nodeName, namespace, nil, nil, nil)
+			eps.Subsets = []v1.EndpointSubset{
+				{
+					Addresses: []v1.EndpointAddress{{IP: "10.1.1.1", NodeName: &nodeName}},
+					Ports:     []v1.EndpointPort{{Port: 8080}},
+				},
+				{
+					Addresses: []v1.EndpointAddress{{IP: "10.1.1.2", NodeName: &nodeName}},
+					Ports:     []v1.EndpointPort{{Port: 9090}},
+				},
+				{
+					Addresses: []v1.EndpointAddress{{IP: "10.1.1.3", NodeName: &nodeName}},
+					Ports:     []v1.EndpointPort{{Port: 8080}, {Port: 9090}},
+				},
+			}
+			rsCfg := &ResourceConfig{}
+			rsCfg.Pools = Pools{
+				Pool{
+					Name:             "svc1_8080_default",
+					ServiceName:      "svc1",
+					ServiceNamespace: namespace,
+					ServicePort:      intstr.IntOrString{IntVal: 8080},
+				},
+			}
+
+			Expect(mockCtlr.processService(svc1, eps, false)).To(BeNil())
+			mockCtlr.updatePoolMembersForCluster(rsCfg, namespace)
+			var addrs []string
+			for _, mem := range rsCfg.Pools[0].Members {
+				Expect(mem.Port).To(Equal(int32(8080)), "pool member with wrong port")
+				addrs = append(addrs, mem.Address)
+			}
+			Expect(addrs).To(ConsistOf("10.1.1.1", "10.1.1.3"),
+				"pool members should only be added from subsets serving the pool port")
+
+			// named pool ports only match the endpoint ports with the same name
+			eps.Subsets = []v1.EndpointSubset{
+				{
+					Addresses: []v1.EndpointAddress{{IP: "10.1.1.1", NodeName: &nodeName}},
+					Ports:     []v1.EndpointPort{{Name: "http", Port: 8080}},
+				},
+				{
+					Addresses: []v1.EndpointAddress{{IP: "10.1.1.2", NodeName: &nodeName}},
+					Ports:     []v1.EndpointPort{{Name: "metrics", Port: 9090}},
+				},
+			}
+			rsCfg.Pools[0].ServicePort = intstr.FromString("http")
+			Expect(mockCtlr.processService(svc1, eps, false)).To(BeNil())
+			mockCtlr.updatePoolMembersForCluster(rsCfg, namespace)
+			Expect(len(rsCfg.Pools[0].Members)).To(Equal(1))
+			Expect(rsCfg.Pools[0].Members[0].Address).To(Equal("10.1.1.1"))
+		})
+
+		It("Tag pool members with cluster name", func() {
+			mockCtlr.clusterName = "cluster1"
+			svcPorts := []v1.ServicePort{{Port: 80, Name: "port0"}}
+			eps := test.NewEndpoints("svc1", "1", "worker1", namespace,
+				[]string{"10.1.1.1", "10.1.1.2"}, []string{}, convertSvcPortsToEndpointPorts(svcPorts))
+			newRsCfg := func() *ResourceConfig {
+				rsCfg := &ResourceConfig{}
+				rsCfg.Virtual.Name = "crd_vs_10_1_1_10_80"
+				rsCfg.Pools = Pools{
+					Pool{
+						Name:             "svc1_80_default",
+						ServiceName:      "svc1",
+						ServiceNamespace: namespace,
+						ServicePort:      intstr.IntOrString{IntVal: 80},
+					},
+				}
+				return rsCfg
+			}
+
+			Expect(mockCtlr.processService(svc1, eps, false)).To(BeNil())
+			rsCfg := newRsCfg()
+			mockCtlr.updatePoolMembersForCluster(rsCfg, namespace)
+			Expect(len(rsCfg.Pools[0].Members)).To(Equal(2))
+			for _, mem := range rsCfg.Pools[0].Members {
+				Expect(mem.Cluster).To(Equal("cluster1"), "pool member not tagged with the cluster")
+			}
+			for _, mems := range mockCtlr.resources.poolMemCache[namespace+"/svc1"].memberMap {
+				for _, mem := range mems {
+					Expect(mem.Cluster).To(BeEmpty(), "pool member cache should not be tagged")
+				}
+			}
+
+			mockCtlr.resources.getPartitionResourceMap("test")[rsCfg.Virtual.Name] = rsCfg
+			allMems := mockCtlr.resources.ltmConfig.GetAllPoolMembers()
+			Expect(len(allMems)).To(Equal(2))
+			Expect(allMems[0].Cluster).To(Equal("cluster1"))
+
+			sharedApp := as3Application{}
+			createPoolDecl(rsCfg, sharedApp, false, "test")
+			Expect(sharedApp["svc1_80_default"].(*as3Pool).Members[0].Remark).To(Equal("cluster: cluster1"))
+
+			// reprocessing with the same cluster tag does not update the config
+			mockCtlr.resources.updateCaches()
+			rsCfg = newRsCfg()
+			mockCtlr.updatePoolMembersForCluster(rsCfg, namespace)
+			mockCtlr.resources.getPartitionResourceMap("test")[rsCfg.Virtual.Name] = rsCfg
+			Expect(mockCtlr.resources.isConfigUpdated()).To(BeFalse(), "cluster tag should not cause a config update")
+		})
+
+		It("Pool member session states", func() {
+			svcPorts := []v1.ServicePort{{Port: 80, Name: "port0"}}
+			svc := svc1.DeepCopy()
+			svc.Annotations = map[string]string{
+				PoolMemberSessionAnnotation: `{"10.1.1.1": "enabled", "10.1.1.2": "disabled", "10.1.1.3": "disabled",
+					"10.1.1.5": "unknown"}`,
+			}
+			eps := test.NewEndpoints("svc1", "1", "worker1", namespace,
+				[]string{"10.1.1.1", "10.1.1.2", "10.1.1.3", "10.1.1.4", "10.1.1.5"}, []string{},
+				convertSvcPortsToEndpointPorts(svcPorts))
+			// endpoints override the session state given on the service
+			eps.Annotations = map[string]string{
+				PoolMemberSessionAnnotation: `{"10.1.1.3": "force-offline"}`,
+			}
+			rsCfg := &ResourceConfig{}
+			rsCfg.Virtual.Name = "crd_vs_10_1_1_10_80"
+			rsCfg.Pools = Pools{
+				Pool{
+					Name:             "svc1_80_default",
+					ServiceName:      "svc1",
+					ServiceNamespace: namespace,
+					ServicePort:      intstr.IntOrString{IntVal: 80},
+				},
+			}
+
+			Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+			mockCtlr.updatePoolMembersForCluster(rsCfg, namespace)
+			sessions := make(map[string]string)
+			for _, mem := range rsCfg.Pools[0].Members {
+				sessions[mem.Address] = mem.Session
+			}
+			Expect(sessions).To(Equal(map[string]string{
+				"10.1.1.1": MemberSessionEnabled,
+				"10.1.1.2": MemberSessionDisabled,
+				"10.1.1.3": MemberSessionForceOffline,
+				"10.1.1.4": MemberSessionEnabled,
+				"10.1.1.5": MemberSessionEnabled,
+			}), "Incorrect pool member session states")
+			for _, mems := range mockCtlr.resources.poolMemCache[namespace+"/svc1"].memberMap {
+				for _, mem := range mems {
+					Expect(mem.Session).To(Equal(MemberSessionEnabled), "pool member cache should not be updated")
+				}
+			}
+
+			// disabled members are still part of the pool
+			sharedApp := as3Application{}
+			createPoolDecl(rsCfg, sharedApp, false, "test")
+			adminStates := make(map[string]string)
+			for _, mem := range sharedApp["svc1_80_default"].(*as3Pool).Members {
+				adminStates[mem.ServerAddresses[0]] = mem.AdminState
+			}
+			Expect(adminStates).To(Equal(map[string]string{
+				"10.1.1.1": "",
+				"10.1.1.2": "disable",
+				"10.1.1.3": "offline",
+				"10.1.1.4": "",
+				"10.1.1.5": "",
+			}), "Incorrect pool member admin states")
+
+			// enabled state does not enable the members being drained
+			Expect(applyMemberSessions(
+				[]PoolMember{{Address: "10.1.1.1", Session: MemberSessionDisabled}},
+				map[string]string{"10.1.1.1": MemberSessionEnabled},
+			)[0].Session).To(Equal(MemberSessionDisabled))
+		})
+
+		It("Pool member type override", func() {
+			mockCtlr.PoolMemberType = Cluster
+			mockCtlr.mode = CustomResourceMode
+			mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+			mockCtlr.crInformers = make(map[string]*CRInformer)
+			mockCtlr.resourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+			_ = mockCtlr.addNamespacedInformers(namespace, false)
+
+			svc := test.NewService("svc1", "1", namespace, v1.ServiceTypeNodePort,
+				[]v1.ServicePort{{Port: 80, Name: "port0", NodePort: 30080, TargetPort: intstr.FromInt(80)}})
+			eps := test.NewEndpoints("svc1", "1", "worker1", namespace,
+				[]string{"10.1.1.1", "10.1.1.2"}, []string{},
+				convertSvcPortsToEndpointPorts([]v1.ServicePort{{Port: 80, Name: "port0"}}))
+			Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+
+			prepareRsCfg := func(rsName string, vs *cisapiv1.VirtualServer) *ResourceConfig {
+				rsCfg := &ResourceConfig{}
+				rsCfg.Virtual.Partition = "test"
+				rsCfg.Virtual.Name = rsName
+				rsCfg.MetaData.ResourceType = VirtualServer
+				rsCfg.Virtual.SetVirtualAddress("1.2.3.4", 80)
+				rsCfg.IntDgMap = make(InternalDataGroupMap)
+				rsCfg.IRulesMap = make(IRulesMap)
+				Expect(mockCtlr.prepareRSConfigFromVirtualServer(rsCfg, vs, false)).To(BeNil())
+				return rsCfg
+			}
+			pools := []cisapiv1.Pool{{Path: "/", Service: "svc1", ServicePort: 80}}
+
+			// VirtualServer overriding the pool member type gets the NodePort members
+			nodePortVS := test.NewVirtualServer("nodePortVS", namespace,
+				cisapiv1.VirtualServerSpec{Host: "foo.com", Pools: pools, PoolMemberType: NodePort})
+			npRsCfg := prepareRsCfg("crd_vs_foo_com", nodePortVS)
+			Expect(mockCtlr.getPoolMemberType(npRsCfg.MetaData.poolMemberType)).To(Equal(NodePort))
+
+			// VirtualServer without override falls back to the controller default
+			clusterVS := test.NewVirtualServer("clusterVS", namespace,
+				cisapiv1.VirtualServerSpec{Host: "bar.com", Pools: pools})
+			clRsCfg := prepareRsCfg("crd_vs_bar_com", clusterVS)
+			Expect(mockCtlr.getPoolMemberType(clRsCfg.MetaData.poolMemberType)).To(Equal(Cluster))
+
+			for _, rsCfg := range []*ResourceConfig{npRsCfg, clRsCfg} {
+				mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)[rsCfg.Virtual.Name] = rsCfg
+				mockCtlr.updateSvcDepResources(rsCfg.Virtual.Name, rsCfg)
+			}
+			mockCtlr.updatePoolMembersForVirtuals(svc)
+
+			getAddresses := func(rsName string) map[string]int32 {
+				addrs := make(map[string]int32)
+				for _, mem := range mockCtlr.getVirtualServer(mockCtlr.Partition, rsName).Pools[0].Members {
+					addrs[mem.Address] = mem.Port
+				}
+				return addrs
+			}
+			Expect(getAddresses("crd_vs_foo_com")).To(Equal(map[string]int32{
+				"10.10.10.1": 30080,
+				"10.10.10.2": 30080,
+				"10.10.10.3": 30080,
+			}), "VirtualServer should have NodePort members")
+			Expect(getAddresses("crd_vs_bar_com")).To(Equal(map[string]int32{
+				"10.1.1.1": 80,
+				"10.1.1.2": 80,
+			}), "VirtualServer should have Cluster members")
+
+			// VirtualServers sharing the virtual should not use different pool member types
+			Expect(mockCtlr.prepareRSConfigFromVirtualServer(npRsCfg, clusterVS, false)).NotTo(BeNil(),
+				"Conflicting pool member types should not be allowed")
+		})
+
 	})
 
 	Describe("Processing Resources", func() {
@@ -905,6 +1253,187 @@ var _ = Describe("Worker Tests", func() {
 			Expect(len(mockCtlr.resources.gtmConfig)).To(Equal(0))
 		})
 
+		It("Processing VirtualServer with a missing TLSProfile", func() {
+			mockCtlr.TeemData = &teem.TeemsData{
+				ResourceType: teem.ResourceTypes{
+					VirtualServer: make(map[string]int),
+				},
+			}
+			vrt1.Spec.TLSProfileName = "missingTLS"
+			vrt1.Spec.HTTPTraffic = TLSRedirectInsecure
+			Expect(mockCtlr.crInformers["default"].vsInformer.GetStore().Add(vrt1)).To(Succeed())
+			expectedStatus := cisapiv1.VirtualServerStatus{
+				VSAddress: "1.2.3.4",
+				StatusOk:  "Error",
+				Reason:    "TLSProfileNotResolved",
+				Message:   "TLSProfile missingTLS can't be resolved",
+			}
+
+			// fail closed, none of the virtuals are created
+			Expect(mockCtlr.processVirtualServers(vrt1, false)).To(BeNil())
+			Expect(mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)).To(BeEmpty(),
+				"Virtuals should not be created without the TLSProfile")
+			vs, err := mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Get(
+				context.TODO(), vrt1.Name, metav1.GetOptions{})
+			Expect(err).To(BeNil())
+			Expect(vs.Status).To(Equal(expectedStatus))
+
+			// fail open, the VirtualServer is served without TLS
+			mockCtlr.tlsProfileFailOpen = true
+			httpsName := formatVirtualServerName("1.2.3.4", DEFAULT_HTTPS_PORT)
+			mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)[httpsName] = &ResourceConfig{}
+			Expect(mockCtlr.processVirtualServers(vrt1, false)).To(BeNil())
+			rsMap := mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)
+			Expect(rsMap).NotTo(HaveKey(httpsName), "HTTPS virtual should be removed")
+			httpName := formatVirtualServerName("1.2.3.4", DEFAULT_HTTP_PORT)
+			Expect(rsMap).To(HaveKey(httpName))
+			Expect(rsMap[httpName].Pools).To(HaveLen(1))
+			Expect(rsMap[httpName].Policies).NotTo(BeEmpty(), "Requests should be forwarded without redirect")
+			Expect(rsMap[httpName].MetaData.baseResources).To(BeEmpty(),
+				"Error status should not be overwritten after posting")
+			Expect(vrt1.Spec.TLSProfileName).To(Equal("missingTLS"), "VirtualServer should not be modified")
+		})
+
+		It("Processing VirtualServer with an invalid pool", func() {
+			mockCtlr.TeemData = &teem.TeemsData{
+				ResourceType: teem.ResourceTypes{
+					VirtualServer: make(map[string]int),
+				},
+			}
+			vrt1.Spec.Pools[0].SNAT = "automap"
+			Expect(mockCtlr.crInformers["default"].vsInformer.GetStore().Add(vrt1)).To(Succeed())
+			Expect(mockCtlr.processVirtualServers(vrt1, false)).To(BeNil())
+			Expect(mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)).To(BeEmpty(),
+				"Virtual should not be created with an invalid pool")
+			vs, err := mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Get(
+				context.TODO(), vrt1.Name, metav1.GetOptions{})
+			Expect(err).To(BeNil())
+			Expect(vs.Status.VSAddress).To(Equal("1.2.3.4"))
+			Expect(vs.Status.StatusOk).To(Equal("Error"))
+			Expect(vs.Status.Reason).To(Equal("InvalidConfig"))
+			Expect(vs.Status.Message).To(ContainSubstring("invalid snat automap"))
+		})
+
+		It("Processing TransportServer with an invalid mode", func() {
+			ts := test.NewTransportServer("SampleTS", namespace, cisapiv1.TransportServerSpec{
+				VirtualServerAddress: "1.2.3.5",
+				VirtualServerPort:    1600,
+				Type:                 "sctp",
+				Mode:                 "performance",
+				Pool: cisapiv1.Pool{
+					Service:     "svc1",
+					ServicePort: 80,
+				},
+			})
+			mockCtlr.kubeCRClient = crdfake.NewSimpleClientset(ts)
+			Expect(mockCtlr.crInformers["default"].tsInformer.GetStore().Add(ts)).To(Succeed())
+			Expect(mockCtlr.processTransportServers(ts, false)).To(BeNil())
+			Expect(mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)).To(BeEmpty(),
+				"Virtual should not be created with an invalid mode")
+			latest, err := mockCtlr.kubeCRClient.CisV1().TransportServers(namespace).Get(
+				context.TODO(), ts.Name, metav1.GetOptions{})
+			Expect(err).To(BeNil())
+			Expect(latest.Status.VSAddress).To(Equal("1.2.3.5"))
+			Expect(latest.Status.StatusOk).To(Equal("Error"))
+			Expect(latest.Status.Reason).To(Equal("InvalidConfig"))
+			Expect(latest.Status.Message).To(ContainSubstring("invalid mode"))
+		})
+
+		It("Processing VirtualServer with a clientSSLs TLSProfile", func() {
+			mockCtlr.TeemData = &teem.TeemsData{
+				ResourceType: teem.ResourceTypes{
+					VirtualServer: make(map[string]int),
+				},
+			}
+			// generateCert returns a self signed certificate of the key for test.com in PEM format
+			generateCert := func(pub, priv interface{}) string {
+				tmpl := &x509.Certificate{
+					SerialNumber: big.NewInt(1),
+					Subject:      pkix.Name{CommonName: "test.com"},
+					DNSNames:     []string{"test.com"},
+					NotBefore:    time.Now().Add(-time.Hour),
+					NotAfter:     time.Now().Add(time.Hour),
+				}
+				der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, pub, priv)
+				Expect(err).To(BeNil())
+				return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+			}
+			rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).To(BeNil())
+			ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).To(BeNil())
+			ecKeyDer, err := x509.MarshalECPrivateKey(ecKey)
+			Expect(err).To(BeNil())
+			mockCtlr.kubeClient = k8sfake.NewSimpleClientset(
+				test.NewSecret("rsasecret", namespace, generateCert(&rsaKey.PublicKey, rsaKey),
+					string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}))),
+				test.NewSecret("ecdsasecret", namespace, generateCert(&ecKey.PublicKey, ecKey),
+					string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecKeyDer}))),
+			)
+			tlsProf := test.NewTLSProfile("dualTLS", namespace, cisapiv1.TLSProfileSpec{
+				Hosts: []string{"test.com"},
+				TLS: cisapiv1.TLS{
+					Termination: TLSEdge,
+					Reference:   Secret,
+					ClientSSLs:  []string{"rsasecret", "ecdsasecret"},
+				},
+			})
+			Expect(mockCtlr.crInformers["default"].tlsInformer.GetStore().Add(tlsProf)).To(Succeed())
+			mockCtlr.SSLContext = make(map[string]*v1.Secret)
+			vrt1.Spec.TLSProfileName = "dualTLS"
+			Expect(mockCtlr.crInformers["default"].vsInformer.GetStore().Add(vrt1)).To(Succeed())
+
+			Expect(mockCtlr.processVirtualServers(vrt1, false)).To(BeNil())
+			rsMap := mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)
+			httpsName := formatVirtualServerName("1.2.3.4", DEFAULT_HTTPS_PORT)
+			Expect(rsMap).To(HaveKey(httpsName), "HTTPS virtual should be created with the clientSSLs TLSProfile")
+			Expect(rsMap[httpsName].Virtual.Profiles).To(ContainElements(
+				ProfileRef{Name: "rsasecret", Partition: mockCtlr.Partition, Context: CustomProfileClient, Namespace: namespace},
+				ProfileRef{Name: "ecdsasecret", Partition: mockCtlr.Partition, Context: CustomProfileClient, Namespace: namespace},
+			))
+		})
+
+		It("Processing VirtualServer with a pool in another partition", func() {
+			mockCtlr.TeemData = &teem.TeemsData{
+				ResourceType: teem.ResourceTypes{
+					VirtualServer: make(map[string]int),
+				},
+			}
+			mockCtlr.poolPartitions = []string{"shared"}
+			mockCtlr.resources.setPoolPartitions(mockCtlr.poolPartitions)
+			agent := newMockAgent(nil)
+			postConfig := func() {
+				agent.createTenantAS3Declaration(ResourceConfigRequest{
+					ltmConfig:          mockCtlr.resources.getUpdatedLTMConfigDeepCopy(),
+					shareNodes:         true,
+					gtmConfig:          GTMConfig{},
+					defaultRouteDomain: 1,
+				})
+				mockCtlr.resources.updateCaches()
+			}
+			poolName := formatPoolName(namespace, "svc1", intstr.IntOrString{IntVal: 80}, "", "test.com")
+			vrt1.Spec.Pools = []cisapiv1.Pool{{Path: "/foo", Service: "svc1", ServicePort: 80, Partition: "shared"}}
+			Expect(mockCtlr.crInformers["default"].vsInformer.GetStore().Add(vrt1)).To(Succeed())
+
+			Expect(mockCtlr.processVirtualServers(vrt1, false)).To(BeNil())
+			postConfig()
+			Expect(agent.incomingTenantDeclMap).To(HaveKey("shared"))
+			Expect(agent.incomingTenantDeclMap["shared"][as3SharedApplication]).To(HaveKey(poolName),
+				"Pool should be declared in its partition")
+
+			// the pool partition is declared empty once its last pool is removed
+			vrt2 := vrt1.DeepCopy()
+			vrt2.Spec.Pools[0].Partition = ""
+			Expect(mockCtlr.crInformers["default"].vsInformer.GetStore().Update(vrt2)).To(Succeed())
+			Expect(mockCtlr.processVirtualServers(vrt2, false)).To(BeNil())
+			postConfig()
+			Expect(agent.incomingTenantDeclMap).To(HaveKey("shared"))
+			Expect(agent.incomingTenantDeclMap["shared"]).To(Equal(as3Tenant{"class": "Tenant"}))
+			Expect(agent.incomingTenantDeclMap["test"][as3SharedApplication]).To(HaveKey(poolName),
+				"Pool should be moved to the partition of the virtual")
+			Expect(mockCtlr.resources.ltmConfig).To(HaveKey("shared"), "Pool partition should be retained")
+		})
+
 		It("Processing IngressLink", func() {
 			// Creation of IngressLink
 			fooPorts := []v1.ServicePort{
@@ -1278,4 +1807,206 @@ var _ = Describe("Worker Tests", func() {
 
 		})
 	})
+
+	Describe("Certificate hostname validation", func() {
+		// generateCert returns a self signed certificate and key in PEM format
+		generateCert := func(cn string, sans []string) ([]byte, []byte) {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).To(BeNil())
+			tmpl := &x509.Certificate{
+				SerialNumber: big.NewInt(1),
+				Subject:      pkix.Name{CommonName: cn},
+				DNSNames:     sans,
+				NotBefore:    time.Now().Add(-time.Hour),
+				NotAfter:     time.Now().Add(time.Hour),
+			}
+			der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+			Expect(err).To(BeNil())
+			keyDer, err := x509.MarshalECPrivateKey(key)
+			Expect(err).To(BeNil())
+			return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+				pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+		}
+
+		It("Matches hosts against a wildcard SAN", func() {
+			cert, _ := generateCert("example.com", []string{"*.example.com"})
+			Expect(certificateCoversHost("a.example.com", cert)).To(BeTrue(),
+				"Subdomain should match the wildcard certificate")
+			Expect(certificateCoversHost("A.Example.com", cert)).To(BeTrue(),
+				"Host should be matched case insensitively")
+			Expect(certificateCoversHost("a.b.example.com", cert)).To(BeFalse(),
+				"Wildcard should match a single label only")
+			Expect(certificateCoversHost("example.com", cert)).To(BeFalse(),
+				"Wildcard should not match the parent domain")
+			Expect(certificateCoversHost("a.example.org", cert)).To(BeFalse(),
+				"Host of other domain should not match")
+			Expect(certificateCoversHost("", cert)).To(BeTrue(),
+				"Resources without host should not be validated")
+		})
+
+		It("Matches hosts against a wildcard CN", func() {
+			cert, _ := generateCert("*.example.com", nil)
+			Expect(certificateCoversHost("a.example.com", cert)).To(BeTrue(),
+				"Subdomain should match the wildcard CN")
+			Expect(certificateCoversHost("a.b.example.com", cert)).To(BeFalse(),
+				"Wildcard CN should match a single label only")
+
+			cert, _ = generateCert("foo.example.com", nil)
+			Expect(certificateCoversHost("foo.example.com", cert)).To(BeTrue())
+			Expect(certificateCoversHost("bar.example.com", cert)).To(BeFalse())
+		})
+
+		It("Matches wildcard hosts against the certificates of their hosts", func() {
+			cert, _ := generateCert("foo.example.com", []string{"foo.example.com"})
+			Expect(certificateCoversHost("*.example.com", cert)).To(BeTrue(),
+				"Certificate of a host of the wildcard should cover the wildcard host")
+			Expect(certificateCoversHost("*.example.org", cert)).To(BeFalse())
+		})
+
+		It("Rejects invalid certificate and key", func() {
+			Expect(checkCertificateHost("a.example.com", []byte("cert"), []byte("key"))).To(BeFalse())
+			cert, key := generateCert("foo.example.com", nil)
+			Expect(checkCertificateHost("bar.example.com", cert, key)).To(BeTrue(),
+				"Hostname mismatch should only be reported")
+		})
+	})
+
+	Describe("Failed resource retries", func() {
+		counterValue := func(counter *prometheus.CounterVec, kind string) float64 {
+			metric := &dto.Metric{}
+			Expect(counter.WithLabelValues(kind).Write(metric)).To(BeNil())
+			return metric.GetCounter().GetValue()
+		}
+
+		It("Drops a resource after the retries are exhausted", func() {
+			mockCtlr.maxResourceRetries = 3
+			mockCtlr.rscQueue = workqueue.NewNamedRateLimitingQueue(
+				workqueue.NewItemExponentialFailureRateLimiter(0, 0), "custom-resource-controller")
+			key := &rqKey{
+				namespace: namespace,
+				kind:      VirtualServer,
+				rscName:   vrt1.Name,
+				rsc:       vrt1,
+				event:     Update,
+			}
+			retries := counterValue(bigIPPrometheus.ResourceRetries, VirtualServer)
+			drops := counterValue(bigIPPrometheus.ResourceDrops, VirtualServer)
+
+			mockCtlr.rscQueue.Add(key)
+			for i := 0; i < mockCtlr.maxResourceRetries; i++ {
+				item, _ := mockCtlr.rscQueue.Get()
+				mockCtlr.requeueFailedResource(mockCtlr.rscQueue, item)
+				mockCtlr.rscQueue.Done(item)
+				Expect(mockCtlr.rscQueue.NumRequeues(key)).To(Equal(i + 1))
+			}
+			Expect(counterValue(bigIPPrometheus.ResourceRetries, VirtualServer) - retries).To(BeEquivalentTo(3))
+			Expect(counterValue(bigIPPrometheus.ResourceDrops, VirtualServer)).To(Equal(drops))
+
+			// the failure after the last retry drops the resource
+			item, _ := mockCtlr.rscQueue.Get()
+			mockCtlr.requeueFailedResource(mockCtlr.rscQueue, item)
+			mockCtlr.rscQueue.Done(item)
+			Expect(mockCtlr.rscQueue.Len()).To(BeZero(), "Resource should not be requeued")
+			Expect(mockCtlr.rscQueue.NumRequeues(key)).To(BeZero(), "Resource should be forgotten")
+			Expect(counterValue(bigIPPrometheus.ResourceDrops, VirtualServer) - drops).To(BeEquivalentTo(1))
+			vs, err := mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Get(
+				context.TODO(), vrt1.Name, metav1.GetOptions{})
+			Expect(err).To(BeNil())
+			Expect(vs.Status.StatusOk).To(Equal("Error"), "Dropped resource status should be reported")
+			Expect(vs.Status.Reason).To(Equal("RetriesExhausted"))
+		})
+
+		It("Does not count the deferrals at init time as retries", func() {
+			mockCtlr.rscQueue = workqueue.NewNamedRateLimitingQueue(
+				workqueue.NewItemExponentialFailureRateLimiter(0, 0), "custom-resource-controller")
+			mockCtlr.initState = true
+			mockCtlr.initialSvcCount = 1
+			key := &rqKey{
+				namespace: namespace,
+				kind:      VirtualServer,
+				rscName:   vrt1.Name,
+				rsc:       vrt1,
+				event:     Create,
+			}
+			mockCtlr.rscQueue.Add(key)
+			Expect(mockCtlr.processCustomResource()).To(BeTrue())
+			Expect(mockCtlr.rscQueue.NumRequeues(key)).To(BeZero(), "Deferred resource should not be rate limited")
+			Eventually(mockCtlr.rscQueue.Len).Should(Equal(1), "Deferred resource should be requeued")
+		})
+	})
+
+	Describe("Custom resource status", func() {
+		It("Updates the VirtualServer status", func() {
+			mockCtlr.updateVirtualServerStatus(vrt1, "1.2.3.4", "Ok", "", "")
+			vs, err := mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Get(
+				context.TODO(), vrt1.Name, metav1.GetOptions{})
+			Expect(err).To(BeNil())
+			Expect(vs.Status).To(Equal(cisapiv1.VirtualServerStatus{VSAddress: "1.2.3.4", StatusOk: "Ok"}))
+			Expect(vrt1.Status).To(Equal(cisapiv1.VirtualServerStatus{}), "Status should be updated on a copy")
+
+			// the update is retried with the latest copy on conflict
+			fakeClient := mockCtlr.kubeCRClient.(*crdfake.Clientset)
+			conflicts := 0
+			fakeClient.PrependReactor("update", "virtualservers",
+				func(action k8stesting.Action) (bool, runtime.Object, error) {
+					if conflicts > 0 {
+						return false, nil, nil
+					}
+					conflicts++
+					return true, nil, apierrors.NewConflict(cisapiv1.Resource("virtualservers"), vrt1.Name,
+						fmt.Errorf("the object has been modified"))
+				})
+			mockCtlr.updateVirtualServerStatus(vs, "1.2.3.4", "Error", "Failure while updating config",
+				"Please check logs for more information")
+			Expect(conflicts).To(Equal(1))
+			vs, err = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Get(
+				context.TODO(), vrt1.Name, metav1.GetOptions{})
+			Expect(err).To(BeNil())
+			Expect(vs.Status).To(Equal(cisapiv1.VirtualServerStatus{
+				VSAddress: "1.2.3.4",
+				StatusOk:  "Error",
+				Reason:    "Failure while updating config",
+				Message:   "Please check logs for more information",
+			}))
+		})
+
+		It("Gets the address of the virtual of the resource", func() {
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.baseResources = map[string]string{namespace + "/" + vrt1.Name: VirtualServer}
+			rsCfg.Virtual.SetVirtualAddress("10.1.1.1", DEFAULT_HTTP_PORT)
+			mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)["crd_10_1_1_1_80"] = rsCfg
+			Expect(mockCtlr.getResourceVirtualAddress(mockCtlr.Partition, namespace+"/"+vrt1.Name, VirtualServer)).
+				To(Equal("10.1.1.1"), "Address allocated for the resource should be reported")
+			Expect(mockCtlr.getResourceVirtualAddress(mockCtlr.Partition, namespace+"/"+vrt1.Name, TransportServer)).
+				To(BeEmpty(), "Address of another kind of resource should not be reported")
+			Expect(mockCtlr.getResourceVirtualAddress("other", namespace+"/"+vrt1.Name, VirtualServer)).
+				To(BeEmpty())
+		})
+
+		It("Updates the TransportServer status", func() {
+			ts := test.NewTransportServer("SampleTS", namespace, cisapiv1.TransportServerSpec{
+				VirtualServerAddress: "1.2.3.5",
+				VirtualServerPort:    1600,
+				Pool: cisapiv1.Pool{
+					Service:     "svc1",
+					ServicePort: 80,
+				},
+			})
+			mockCtlr.kubeCRClient = crdfake.NewSimpleClientset(ts)
+			mockCtlr.updateTransportServerStatus(ts, "1.2.3.5", "Ok", "", "")
+			latest, err := mockCtlr.kubeCRClient.CisV1().TransportServers(namespace).Get(
+				context.TODO(), ts.Name, metav1.GetOptions{})
+			Expect(err).To(BeNil())
+			Expect(latest.Status).To(Equal(cisapiv1.TransportServerStatus{VSAddress: "1.2.3.5", StatusOk: "Ok"}))
+
+			mockCtlr.updateTransportServerStatus(latest, "1.2.3.5", "Error", "RetriesExhausted",
+				"Dropped after 3 retries, please check logs for more information")
+			latest, err = mockCtlr.kubeCRClient.CisV1().TransportServers(namespace).Get(
+				context.TODO(), ts.Name, metav1.GetOptions{})
+			Expect(err).To(BeNil())
+			Expect(latest.Status.StatusOk).To(Equal("Error"))
+			Expect(latest.Status.Reason).To(Equal("RetriesExhausted"))
+			Expect(latest.Status.Message).To(Equal("Dropped after 3 retries, please check logs for more information"))
+		})
+	})
 })
@@ -3,14 +3,20 @@ package health
 import (
 	"net/http"
 	"os"
+	"time"
 
 	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
 )
 
 type HealthChecker struct {
 	SubPID int
+	// LastSyncTime returns the time the controller last posted the config, optional
+	LastSyncTime func() time.Time
 }
 
+// LastSyncTimeHeader is the response header of the health endpoint carrying the last sync time
+const LastSyncTimeHeader = "X-CIS-Last-Sync-Time"
+
 //TODO: Add additional health checks
 //TODO: add health check if Kubernetes API is still reachable
 func (hc HealthChecker) HealthCheckHandler() http.Handler {
@@ -19,6 +25,11 @@ func (hc HealthChecker) HealthCheckHandler() http.Handler {
 			_, err := os.FindProcess(hc.SubPID)
 			if err == nil {
 				// assume that Python process is still running
+				if hc.LastSyncTime != nil {
+					if syncTime := hc.LastSyncTime(); !syncTime.IsZero() {
+						w.Header().Set(LastSyncTimeHeader, syncTime.UTC().Format(time.RFC3339))
+					}
+				}
 				w.WriteHeader(http.StatusOK)
 				w.Write([]byte("Ok"))
 				return
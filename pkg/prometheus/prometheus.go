@@ -31,6 +31,22 @@ var CurrentErrors = prometheus.NewGaugeVec(
 	[]string{},
 )
 
+var ResourceRetries = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "bigip_resource_retries_total",
+		Help: "Total count of retries of the resources that failed to be processed",
+	},
+	[]string{"kind"},
+)
+
+var ResourceDrops = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "bigip_resource_drops_total",
+		Help: "Total count of resources dropped after exhausting the retries",
+	},
+	[]string{"kind"},
+)
+
 // further metrics? todo think about
 // RegisterMetrics registers all Prometheus metrics defined above
 func RegisterMetrics() {
@@ -38,4 +54,6 @@ func RegisterMetrics() {
 	prometheus.MustRegister(MonitoredNodes)
 	prometheus.MustRegister(MonitoredServices)
 	prometheus.MustRegister(CurrentErrors)
+	prometheus.MustRegister(ResourceRetries)
+	prometheus.MustRegister(ResourceDrops)
 }